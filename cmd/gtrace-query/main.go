@@ -0,0 +1,158 @@
+// Command gtrace-query searches a case timeline with the same query language as the GUI,
+// or runs the saved hunts of the case.
+//
+//	gtrace-query -case ./cases/ir-042 'CommandLine:*-enc* AND host:WS01'
+//	gtrace-query -case ./cases/ir-042 -hunts -pack team.yaml -record
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gtrace/internal/attack"
+	"gtrace/internal/hunt"
+	"gtrace/internal/query"
+	"gtrace/internal/storage"
+	"gtrace/pkg/model"
+)
+
+func main() {
+	casePath := flag.String("case", "", "case directory")
+	limit := flag.Int("limit", 0, "stop after this many events (0 = all)")
+	format := flag.String("format", "jsonl", "output format: jsonl or table")
+	explain := flag.Bool("explain", false, "print how the query was parsed and exit")
+	fields := flag.Bool("fields", false, "list the reserved field names and exit")
+	hunts := flag.Bool("hunts", false, "run the built-in, global and case hunts instead of a query")
+	pack := flag.String("pack", "", "with -hunts, also run the hunts of this YAML hunting pack")
+	record := flag.Bool("record", false, "with -hunts, save a finding for every hunt that matched and retract those of hunts that no longer do")
+	flag.Parse()
+
+	if *fields {
+		for _, name := range sortedFields() {
+			fmt.Printf("%-10s %s\n", name, query.Fields[name])
+		}
+		return
+	}
+
+	expr := strings.Join(flag.Args(), " ")
+	q, err := query.Parse(expr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *explain {
+		fmt.Println(q.Normalized())
+		return
+	}
+	if *casePath == "" {
+		fmt.Fprintln(os.Stderr, "-case is required")
+		os.Exit(2)
+	}
+
+	store, err := storage.NewFileStorage(*casePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	if *hunts {
+		if err := runHunts(ctx, store, *pack, *record); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	enc := json.NewEncoder(os.Stdout)
+	n := 0
+	err = store.ScanMatching(ctx, &model.TimelineFilter{SearchTerm: expr}, func(ev model.TimelineEvent) error {
+		if *format == "table" {
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\n", ev.EventTime.Format(time.RFC3339), ev.Host(), ev.Source, ev.Action, ev.Subject)
+		} else if err := enc.Encode(ev); err != nil {
+			return err
+		}
+		n++
+		if *limit > 0 && n >= *limit {
+			return errLimit
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLimit) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "%d events\n", n)
+}
+
+// errLimit stops the scan once -limit events were printed.
+var errLimit = errors.New("limit reached")
+
+func sortedFields() []string {
+	names := make([]string, 0, len(query.Fields))
+	for name := range query.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runHunts(ctx context.Context, store *storage.FileStorage, packPath string, record bool) error {
+	scopes := [][]hunt.Hunt{hunt.Builtin()}
+	if global, err := hunt.GlobalPath(); err == nil {
+		list, err := hunt.OpenLibrary(global, hunt.ScopeGlobal).List()
+		if err != nil {
+			return err
+		}
+		scopes = append(scopes, list)
+	}
+	list, err := hunt.OpenLibrary(hunt.CasePath(store.CasePath()), hunt.ScopeCase).List()
+	if err != nil {
+		return err
+	}
+	scopes = append(scopes, list)
+	if packPath != "" {
+		data, err := os.ReadFile(packPath)
+		if err != nil {
+			return err
+		}
+		p, err := hunt.ParsePack(data)
+		if err != nil {
+			return err
+		}
+		scopes = append(scopes, p.Hunts)
+	}
+
+	results, err := hunt.Run(ctx, hunt.Merge(scopes...), store.ScanTimeline)
+	if err != nil {
+		return err
+	}
+	var findings []model.Finding
+	var stale []string
+	for _, r := range results {
+		fmt.Printf("%8d\t%-8s\t%s\t%s\n", r.Hits, r.Hunt.Severity, r.Hunt.ID, r.Hunt.Name)
+		if r.Hits > 0 {
+			f := r.Finding()
+			f.Attack = attack.Default().Enrich(f.Attack)
+			findings = append(findings, f)
+		} else {
+			stale = append(stale, r.FindingID())
+		}
+	}
+	if !record {
+		return nil
+	}
+	if err := store.RetractFindings(ctx, stale); err != nil {
+		return err
+	}
+	if err := store.SaveFindings(ctx, findings); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d findings recorded\n", len(findings))
+	return nil
+}
@@ -1,35 +1,100 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {analysis} from '../models';
 import {storage} from '../models';
 import {model} from '../models';
 import {app} from '../models';
+import {analyzers} from '../models';
+import {attack} from '../models';
+import {hunt} from '../models';
+import {allowlist} from '../models';
+import {noise} from '../models';
+import {budget} from '../models';
+
+export function AddAnnotation(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<model.Annotation>;
+
+export function AggregateEvents(arg1:string,arg2:string,arg3:string,arg4:string,arg5:number,arg6:string,arg7:string,arg8:string):Promise<storage.AggregateResult>;
 
 export function BrowseEvidencePath():Promise<string>;
 
+export function DeleteAnnotation(arg1:string,arg2:string):Promise<void>;
+
+export function DeleteHunt(arg1:string,arg2:string):Promise<void>;
+
 export function ExecuteSQLQuery(arg1:string):Promise<Array<Record<string, any>>>;
 
+export function ExportAttackNavigatorLayer():Promise<string>;
+
+export function ExportHuntPack(arg1:Array<string>):Promise<string>;
+
+export function ExportLateralMovementGraph(arg1:string):Promise<string>;
+
+export function ExportTimeline(arg1:string,arg2:string,arg3:string,arg4:Array<string>,arg5:Array<string>):Promise<string>;
+
+export function GenerateHTMLReport(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function GetAllowlist():Promise<Array<allowlist.Entry>>;
+
+export function GetAnnotations(arg1:string,arg2:string):Promise<Array<model.Annotation>>;
+
+export function GetAttackMatrix():Promise<attack.Matrix>;
+
 export function GetDefaultCasePath():Promise<string>;
 
+export function GetDropSummary():Promise<Array<storage.DropSummary>>;
+
+export function GetEventProvenance(arg1:string):Promise<Array<model.EvidenceRef>>;
+
 export function GetEventStats():Promise<storage.EventStats>;
 
 export function GetFindings():Promise<Array<model.Finding>>;
 
+export function GetHostClocks():Promise<Array<storage.HostClock>>;
+
+export function GetLogonSessions():Promise<Array<analyzers.LogonSession>>;
+
+export function GetProcessLineage(arg1:string):Promise<analysis.ProcessLineage>;
+
+export function GetProcessTrees():Promise<Array<analysis.ProcessNode>>;
+
+export function GetSuppressedFindings():Promise<Array<model.Finding>>;
+
 export function GetSystemInfo():Promise<app.SystemInfo>;
 
 export function GetTimeline(arg1:number):Promise<Array<model.TimelineEvent>>;
 
 export function GetTotalEventCount():Promise<number>;
 
+export function ImportHuntPack(arg1:string,arg2:string):Promise<number>;
+
+export function ListBudgetPolicies():Promise<Array<budget.Policy>>;
+
+export function ListHunts():Promise<Array<hunt.Hunt>>;
+
+export function ListNoiseProfiles():Promise<Array<noise.Profile>>;
+
 export function Log(arg1:string,arg2:string,arg3:Array<any>):Promise<void>;
 
 export function OpenCase(arg1:string):Promise<void>;
 
+export function ReloadAllowlist():Promise<number>;
+
 export function ResetCase():Promise<void>;
 
 export function RunAnalysis():Promise<number>;
 
+export function RunHunts():Promise<Array<hunt.Result>>;
+
 export function RunSelfTest():Promise<string>;
 
+export function RunYaraScan(arg1:string,arg2:string):Promise<Array<model.Finding>>;
+
+export function SaveHunt(arg1:hunt.Hunt,arg2:string):Promise<hunt.Hunt>;
+
 export function SearchEvents(arg1:string,arg2:number,arg3:number,arg4:string,arg5:string):Promise<Array<model.TimelineEvent>>;
 
+export function SetHostClockSkew(arg1:string,arg2:number,arg3:string):Promise<void>;
+
+export function StackHosts(arg1:number,arg2:number):Promise<Array<model.Finding>>;
+
 export function StartTriage(arg1:string,arg2:Array<string>,arg3:Record<string, any>):Promise<void>;
@@ -2,18 +2,74 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddAnnotation(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['app']['App']['AddAnnotation'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function AggregateEvents(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8) {
+  return window['go']['app']['App']['AggregateEvents'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8);
+}
+
 export function BrowseEvidencePath() {
   return window['go']['app']['App']['BrowseEvidencePath']();
 }
 
+export function DeleteAnnotation(arg1, arg2) {
+  return window['go']['app']['App']['DeleteAnnotation'](arg1, arg2);
+}
+
+export function DeleteHunt(arg1, arg2) {
+  return window['go']['app']['App']['DeleteHunt'](arg1, arg2);
+}
+
 export function ExecuteSQLQuery(arg1) {
   return window['go']['app']['App']['ExecuteSQLQuery'](arg1);
 }
 
+export function ExportAttackNavigatorLayer() {
+  return window['go']['app']['App']['ExportAttackNavigatorLayer']();
+}
+
+export function ExportHuntPack(arg1) {
+  return window['go']['app']['App']['ExportHuntPack'](arg1);
+}
+
+export function ExportLateralMovementGraph(arg1) {
+  return window['go']['app']['App']['ExportLateralMovementGraph'](arg1);
+}
+
+export function ExportTimeline(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['app']['App']['ExportTimeline'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function GenerateHTMLReport(arg1, arg2, arg3) {
+  return window['go']['app']['App']['GenerateHTMLReport'](arg1, arg2, arg3);
+}
+
+export function GetAllowlist() {
+  return window['go']['app']['App']['GetAllowlist']();
+}
+
+export function GetAnnotations(arg1, arg2) {
+  return window['go']['app']['App']['GetAnnotations'](arg1, arg2);
+}
+
+export function GetAttackMatrix() {
+  return window['go']['app']['App']['GetAttackMatrix']();
+}
+
 export function GetDefaultCasePath() {
   return window['go']['app']['App']['GetDefaultCasePath']();
 }
 
+export function GetDropSummary() {
+  return window['go']['app']['App']['GetDropSummary']();
+}
+
+export function GetEventProvenance(arg1) {
+  return window['go']['app']['App']['GetEventProvenance'](arg1);
+}
+
 export function GetEventStats() {
   return window['go']['app']['App']['GetEventStats']();
 }
@@ -22,6 +78,26 @@ export function GetFindings() {
   return window['go']['app']['App']['GetFindings']();
 }
 
+export function GetHostClocks() {
+  return window['go']['app']['App']['GetHostClocks']();
+}
+
+export function GetLogonSessions() {
+  return window['go']['app']['App']['GetLogonSessions']();
+}
+
+export function GetProcessLineage(arg1) {
+  return window['go']['app']['App']['GetProcessLineage'](arg1);
+}
+
+export function GetProcessTrees() {
+  return window['go']['app']['App']['GetProcessTrees']();
+}
+
+export function GetSuppressedFindings() {
+  return window['go']['app']['App']['GetSuppressedFindings']();
+}
+
 export function GetSystemInfo() {
   return window['go']['app']['App']['GetSystemInfo']();
 }
@@ -34,6 +110,22 @@ export function GetTotalEventCount() {
   return window['go']['app']['App']['GetTotalEventCount']();
 }
 
+export function ImportHuntPack(arg1, arg2) {
+  return window['go']['app']['App']['ImportHuntPack'](arg1, arg2);
+}
+
+export function ListBudgetPolicies() {
+  return window['go']['app']['App']['ListBudgetPolicies']();
+}
+
+export function ListHunts() {
+  return window['go']['app']['App']['ListHunts']();
+}
+
+export function ListNoiseProfiles() {
+  return window['go']['app']['App']['ListNoiseProfiles']();
+}
+
 export function Log(arg1, arg2, arg3) {
   return window['go']['app']['App']['Log'](arg1, arg2, arg3);
 }
@@ -42,6 +134,10 @@ export function OpenCase(arg1) {
   return window['go']['app']['App']['OpenCase'](arg1);
 }
 
+export function ReloadAllowlist() {
+  return window['go']['app']['App']['ReloadAllowlist']();
+}
+
 export function ResetCase() {
   return window['go']['app']['App']['ResetCase']();
 }
@@ -50,14 +146,34 @@ export function RunAnalysis() {
   return window['go']['app']['App']['RunAnalysis']();
 }
 
+export function RunHunts() {
+  return window['go']['app']['App']['RunHunts']();
+}
+
 export function RunSelfTest() {
   return window['go']['app']['App']['RunSelfTest']();
 }
 
+export function RunYaraScan(arg1, arg2) {
+  return window['go']['app']['App']['RunYaraScan'](arg1, arg2);
+}
+
+export function SaveHunt(arg1, arg2) {
+  return window['go']['app']['App']['SaveHunt'](arg1, arg2);
+}
+
 export function SearchEvents(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['app']['App']['SearchEvents'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function SetHostClockSkew(arg1, arg2, arg3) {
+  return window['go']['app']['App']['SetHostClockSkew'](arg1, arg2, arg3);
+}
+
+export function StackHosts(arg1, arg2) {
+  return window['go']['app']['App']['StackHosts'](arg1, arg2);
+}
+
 export function StartTriage(arg1, arg2, arg3) {
   return window['go']['app']['App']['StartTriage'](arg1, arg2, arg3);
 }
@@ -1,28 +1,683 @@
+export namespace allowlist {
+	
+	export class Entry {
+	    id: string;
+	    reason: string;
+	    action: string;
+	    rules?: string[];
+	    paths?: string[];
+	    path_fields?: string[];
+	    command_lines?: string[];
+	    signers?: string[];
+	    hashes?: string[];
+	    hash_sets?: string[];
+	    source: string;
+	    hash_count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Entry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.reason = source["reason"];
+	        this.action = source["action"];
+	        this.rules = source["rules"];
+	        this.paths = source["paths"];
+	        this.path_fields = source["path_fields"];
+	        this.command_lines = source["command_lines"];
+	        this.signers = source["signers"];
+	        this.hashes = source["hashes"];
+	        this.hash_sets = source["hash_sets"];
+	        this.source = source["source"];
+	        this.hash_count = source["hash_count"];
+	    }
+	}
+
+}
+
+export namespace analysis {
+	
+	export class ProcessNode {
+	    key: string;
+	    event_id?: string;
+	    host?: string;
+	    pid: number;
+	    parent_pid: number;
+	    process_guid?: string;
+	    parent_process_guid?: string;
+	    image: string;
+	    command_line?: string;
+	    user?: string;
+	    logon_id?: string;
+	    // Go type: time
+	    start?: any;
+	    // Go type: time
+	    end?: any;
+	    origin: string;
+	    alerts?: string[];
+	    alert_level?: string;
+	    ioc_hits?: string[];
+	    subtree_hits: number;
+	    parent_key?: string;
+	    children?: ProcessNode[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProcessNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.event_id = source["event_id"];
+	        this.host = source["host"];
+	        this.pid = source["pid"];
+	        this.parent_pid = source["parent_pid"];
+	        this.process_guid = source["process_guid"];
+	        this.parent_process_guid = source["parent_process_guid"];
+	        this.image = source["image"];
+	        this.command_line = source["command_line"];
+	        this.user = source["user"];
+	        this.logon_id = source["logon_id"];
+	        this.start = this.convertValues(source["start"], null);
+	        this.end = this.convertValues(source["end"], null);
+	        this.origin = source["origin"];
+	        this.alerts = source["alerts"];
+	        this.alert_level = source["alert_level"];
+	        this.ioc_hits = source["ioc_hits"];
+	        this.subtree_hits = source["subtree_hits"];
+	        this.parent_key = source["parent_key"];
+	        this.children = this.convertValues(source["children"], ProcessNode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProcessLineage {
+	    process: ProcessNode;
+	    ancestors: ProcessNode[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProcessLineage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.process = this.convertValues(source["process"], ProcessNode);
+	        this.ancestors = this.convertValues(source["ancestors"], ProcessNode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace analyzers {
+	
+	export class ExplicitCred {
+	    // Go type: time
+	    time: any;
+	    user: string;
+	    target: string;
+	    process?: string;
+	    event_id: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExplicitCred(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = this.convertValues(source["time"], null);
+	        this.user = source["user"];
+	        this.target = source["target"];
+	        this.process = source["process"];
+	        this.event_id = source["event_id"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LogonSession {
+	    host: string;
+	    logon_id?: string;
+	    user: string;
+	    domain?: string;
+	    logon_type?: string;
+	    logon_type_name?: string;
+	    // Go type: time
+	    start?: any;
+	    // Go type: time
+	    end?: any;
+	    duration_seconds?: number;
+	    end_reason?: string;
+	    source_ip?: string;
+	    source_workstation?: string;
+	    auth_package?: string;
+	    elevated: boolean;
+	    privileges?: string;
+	    rdp: boolean;
+	    rdp_session_id?: string;
+	    activity?: SessionActivity[];
+	    explicit_creds?: ExplicitCred[];
+	    processes?: SessionProcess[];
+	    event_ids: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LogonSession(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.logon_id = source["logon_id"];
+	        this.user = source["user"];
+	        this.domain = source["domain"];
+	        this.logon_type = source["logon_type"];
+	        this.logon_type_name = source["logon_type_name"];
+	        this.start = this.convertValues(source["start"], null);
+	        this.end = this.convertValues(source["end"], null);
+	        this.duration_seconds = source["duration_seconds"];
+	        this.end_reason = source["end_reason"];
+	        this.source_ip = source["source_ip"];
+	        this.source_workstation = source["source_workstation"];
+	        this.auth_package = source["auth_package"];
+	        this.elevated = source["elevated"];
+	        this.privileges = source["privileges"];
+	        this.rdp = source["rdp"];
+	        this.rdp_session_id = source["rdp_session_id"];
+	        this.activity = this.convertValues(source["activity"], SessionActivity);
+	        this.explicit_creds = this.convertValues(source["explicit_creds"], ExplicitCred);
+	        this.processes = this.convertValues(source["processes"], SessionProcess);
+	        this.event_ids = source["event_ids"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionActivity {
+	    // Go type: time
+	    time: any;
+	    action: string;
+	    address?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionActivity(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = this.convertValues(source["time"], null);
+	        this.action = source["action"];
+	        this.address = source["address"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionProcess {
+	    // Go type: time
+	    time: any;
+	    event_id: string;
+	    image: string;
+	    command_line?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionProcess(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = this.convertValues(source["time"], null);
+	        this.event_id = source["event_id"];
+	        this.image = source["image"];
+	        this.command_line = source["command_line"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace app {
 	
-	export class SystemInfo {
-	    hostname: string;
-	    os: string;
-	    arch: string;
-	    ip: string;
+	export class SystemInfo {
+	    hostname: string;
+	    os: string;
+	    arch: string;
+	    ip: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SystemInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hostname = source["hostname"];
+	        this.os = source["os"];
+	        this.arch = source["arch"];
+	        this.ip = source["ip"];
+	    }
+	}
+
+}
+
+export namespace attack {
+	
+	export class Matrix {
+	    version: string;
+	    alerts: number;
+	    findings: number;
+	    tactics: MatrixTactic[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Matrix(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.alerts = source["alerts"];
+	        this.findings = source["findings"];
+	        this.tactics = this.convertValues(source["tactics"], MatrixTactic);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MatrixTactic {
+	    id: string;
+	    name: string;
+	    short_name: string;
+	    hits: number;
+	    techniques: MatrixCell[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MatrixTactic(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.short_name = source["short_name"];
+	        this.hits = source["hits"];
+	        this.techniques = this.convertValues(source["techniques"], MatrixCell);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MatrixCell {
+	    id: string;
+	    name: string;
+	    hits: number;
+	    alerts: number;
+	    findings: number;
+	    subtechniques?: MatrixCell[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MatrixCell(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.hits = source["hits"];
+	        this.alerts = source["alerts"];
+	        this.findings = source["findings"];
+	        this.subtechniques = this.convertValues(source["subtechniques"], MatrixCell);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace budget {
+	
+	export class Quota {
+	    id: string;
+	    source: string;
+	    host?: string;
+	    max?: number;
+	    share?: number;
+	    per_host?: boolean;
+	    mode: string;
+	    sample_every?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Quota(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.source = source["source"];
+	        this.host = source["host"];
+	        this.max = source["max"];
+	        this.share = source["share"];
+	        this.per_host = source["per_host"];
+	        this.mode = source["mode"];
+	        this.sample_every = source["sample_every"];
+	    }
+	}
+	export class Policy {
+	    name: string;
+	    description?: string;
+	    quotas: Quota[];
+	    source: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Policy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.quotas = this.convertValues(source["quotas"], Quota);
+	        this.source = source["source"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace hunt {
+	
+	export class Hunt {
+	    id: string;
+	    name: string;
+	    description?: string;
+	    query: string;
+	    attack?: string[];
+	    severity?: string;
+	    author?: string;
+	    scope: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Hunt(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.query = source["query"];
+	        this.attack = source["attack"];
+	        this.severity = source["severity"];
+	        this.author = source["author"];
+	        this.scope = source["scope"];
+	    }
+	}
+	export class Result {
+	    hunt: Hunt;
+	    hits: number;
+	    samples: model.TimelineEvent[];
+	    // Go type: time
+	    ran_at: any;
 	
 	    static createFrom(source: any = {}) {
-	        return new SystemInfo(source);
+	        return new Result(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.hostname = source["hostname"];
-	        this.os = source["os"];
-	        this.arch = source["arch"];
-	        this.ip = source["ip"];
+	        this.hunt = this.convertValues(source["hunt"], Hunt);
+	        this.hits = source["hits"];
+	        this.samples = this.convertValues(source["samples"], model.TimelineEvent);
+	        this.ran_at = this.convertValues(source["ran_at"], null);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
 
 export namespace model {
 	
+	export class AttackRef {
+	    technique_id: string;
+	    technique?: string;
+	    parent_id?: string;
+	    parent?: string;
+	    tactic_ids?: string[];
+	    tactics?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AttackRef(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.technique_id = source["technique_id"];
+	        this.technique = source["technique"];
+	        this.parent_id = source["parent_id"];
+	        this.parent = source["parent"];
+	        this.tactic_ids = source["tactic_ids"];
+	        this.tactics = source["tactics"];
+	    }
+	}
+	export class Annotation {
+	    id: string;
+	    target_type: string;
+	    target_id: string;
+	    kind: string;
+	    value?: string;
+	    author: string;
+	    // Go type: time
+	    created_at: any;
+	    deleted?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Annotation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.target_type = source["target_type"];
+	        this.target_id = source["target_id"];
+	        this.kind = source["kind"];
+	        this.value = source["value"];
+	        this.author = source["author"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.deleted = source["deleted"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class EvidenceRef {
 	    source_path: string;
 	    offset?: number;
@@ -41,6 +696,50 @@ export namespace model {
 	        this.sha256 = source["sha256"];
 	    }
 	}
+	export class HostTimezone {
+	    name: string;
+	    standard_name?: string;
+	    daylight_name?: string;
+	    bias: number;
+	    standard_bias?: number;
+	    daylight_bias?: number;
+	    standard_start?: TZRule;
+	    daylight_start?: TZRule;
+	
+	    static createFrom(source: any = {}) {
+	        return new HostTimezone(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.standard_name = source["standard_name"];
+	        this.daylight_name = source["daylight_name"];
+	        this.bias = source["bias"];
+	        this.standard_bias = source["standard_bias"];
+	        this.daylight_bias = source["daylight_bias"];
+	        this.standard_start = this.convertValues(source["standard_start"], TZRule);
+	        this.daylight_start = this.convertValues(source["daylight_start"], TZRule);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class IOCMaterial {
 	    type: string;
 	    value: string;
@@ -64,7 +763,11 @@ export namespace model {
 	    description?: string;
 	    rule_id?: string;
 	    evidence_refs?: EvidenceRef[];
+	    event_ids?: string[];
 	    iocs?: IOCMaterial[];
+	    attack?: AttackRef[];
+	    annotations?: Annotation[];
+	    suppression?: Suppression;
 	
 	    static createFrom(source: any = {}) {
 	        return new Finding(source);
@@ -78,7 +781,11 @@ export namespace model {
 	        this.description = source["description"];
 	        this.rule_id = source["rule_id"];
 	        this.evidence_refs = this.convertValues(source["evidence_refs"], EvidenceRef);
+	        this.event_ids = source["event_ids"];
 	        this.iocs = this.convertValues(source["iocs"], IOCMaterial);
+	        this.attack = this.convertValues(source["attack"], AttackRef);
+	        this.annotations = this.convertValues(source["annotations"], Annotation);
+	        this.suppression = this.convertValues(source["suppression"], Suppression);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -99,12 +806,53 @@ export namespace model {
 		    return a;
 		}
 	}
+	export class Suppression {
+	    action: string;
+	    reason: string;
+	    entry_id?: string;
+	    matched?: string;
+	    original_severity?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Suppression(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action = source["action"];
+	        this.reason = source["reason"];
+	        this.entry_id = source["entry_id"];
+	        this.matched = source["matched"];
+	        this.original_severity = source["original_severity"];
+	    }
+	}
+	export class TZRule {
+	    month: number;
+	    week: number;
+	    weekday: number;
+	    hour: number;
+	    minute: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TZRule(source);
+	    }
 	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.month = source["month"];
+	        this.week = source["week"];
+	        this.weekday = source["weekday"];
+	        this.hour = source["hour"];
+	        this.minute = source["minute"];
+	    }
+	}
 	export class TimelineEvent {
 	    id: string;
 	    // Go type: time
 	    event_time: any;
 	    utc_offset?: number;
+	    time_semantics?: string;
+	    skew_seconds?: number;
 	    source: string;
 	    artifact: string;
 	    action: string;
@@ -113,6 +861,9 @@ export namespace model {
 	    confidence?: string;
 	    evidence_ref: EvidenceRef;
 	    ioc_hits?: string[];
+	    attack?: AttackRef[];
+	    annotations?: Annotation[];
+	    fingerprint?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new TimelineEvent(source);
@@ -123,6 +874,8 @@ export namespace model {
 	        this.id = source["id"];
 	        this.event_time = this.convertValues(source["event_time"], null);
 	        this.utc_offset = source["utc_offset"];
+	        this.time_semantics = source["time_semantics"];
+	        this.skew_seconds = source["skew_seconds"];
 	        this.source = source["source"];
 	        this.artifact = source["artifact"];
 	        this.action = source["action"];
@@ -131,6 +884,76 @@ export namespace model {
 	        this.confidence = source["confidence"];
 	        this.evidence_ref = this.convertValues(source["evidence_ref"], EvidenceRef);
 	        this.ioc_hits = source["ioc_hits"];
+	        this.attack = this.convertValues(source["attack"], AttackRef);
+	        this.annotations = this.convertValues(source["annotations"], Annotation);
+	        this.fingerprint = source["fingerprint"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace noise {
+	
+	export class Rule {
+	    id: string;
+	    description?: string;
+	    event_ids?: number[];
+	    channels?: string[];
+	    field?: string;
+	    values?: string[];
+	    unknown?: boolean;
+	    keep?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Rule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.description = source["description"];
+	        this.event_ids = source["event_ids"];
+	        this.channels = source["channels"];
+	        this.field = source["field"];
+	        this.values = source["values"];
+	        this.unknown = source["unknown"];
+	        this.keep = source["keep"];
+	    }
+	}
+	export class Profile {
+	    name: string;
+	    description?: string;
+	    rules: Rule[];
+	    source: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Profile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.rules = this.convertValues(source["rules"], Rule);
+	        this.source = source["source"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -170,6 +993,152 @@ export namespace storage {
 	        this.levels = source["levels"];
 	    }
 	}
+	export class HostClock {
+	    host: string;
+	    timezone?: model.HostTimezone;
+	    skew_seconds: number;
+	    skew_reason?: string;
+	    // Go type: time
+	    updated_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new HostClock(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.timezone = this.convertValues(source["timezone"], model.HostTimezone);
+	        this.skew_seconds = source["skew_seconds"];
+	        this.skew_reason = source["skew_reason"];
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AggBucket {
+	    key: string;
+	    // Go type: time
+	    time?: any;
+	    count: number;
+	    sample_id?: string;
+	    groups?: AggBucket[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AggBucket(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.time = this.convertValues(source["time"], null);
+	        this.count = source["count"];
+	        this.sample_id = source["sample_id"];
+	        this.groups = this.convertValues(source["groups"], AggBucket);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AggregateResult {
+	    kind: string;
+	    field?: string;
+	    group_by?: string;
+	    interval?: string;
+	    total: number;
+	    missing: number;
+	    distinct: number;
+	    buckets: AggBucket[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AggregateResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.field = source["field"];
+	        this.group_by = source["group_by"];
+	        this.interval = source["interval"];
+	        this.total = source["total"];
+	        this.missing = source["missing"];
+	        this.distinct = source["distinct"];
+	        this.buckets = this.convertValues(source["buckets"], AggBucket);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DropSummary {
+	    stage: string;
+	    profile: string;
+	    rule: string;
+	    source?: string;
+	    host?: string;
+	    dropped: number;
+	    files: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DropSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.stage = source["stage"];
+	        this.profile = source["profile"];
+	        this.rule = source["rule"];
+	        this.source = source["source"];
+	        this.host = source["host"];
+	        this.dropped = source["dropped"];
+	        this.files = source["files"];
+	    }
+	}
 
 }
 
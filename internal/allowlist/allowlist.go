@@ -0,0 +1,509 @@
+// Package allowlist silences detections on known-good items. Entries match events by path
+// and command-line patterns, signer names and hashes, the latter inline or from hash sets
+// (NSRL RDS NSRLFile.txt or plain lists). A matching entry either suppresses the detection
+// or downgrades its severity; either way the reason is recorded on the event or finding.
+//
+// Paths match the image of the acting process (Image, NewProcessName); an entry that is about
+// other fields, such as ImageLoaded or a service's ImagePath, names them in path_fields. Signers match
+// only events that record a valid signature (SignatureStatus Valid, or Signed true); version
+// resource fields such as Company are set by whoever built the file and are never trusted.
+//
+// Allowlists are YAML files:
+//
+//	name: corp baseline
+//	entries:
+//	  - id: sccm-cache
+//	    reason: SCCM client cache
+//	    paths: ['C:\Windows\ccmcache\*']
+//	  - id: vendor-driver
+//	    reason: Vendor driver loaded by every host
+//	    paths: ['C:\Program Files\Vendor\*.sys']
+//	    path_fields: [ImageLoaded]
+//	  - id: ms-signed
+//	    reason: Microsoft-signed binaries
+//	    signers: [Microsoft Windows]
+//	    action: downgrade
+//	  - id: nsrl
+//	    reason: NSRL known file
+//	    hash_sets: [nsrl/NSRLFile.txt]
+package allowlist
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"gtrace/pkg/model"
+)
+
+// Actions.
+const (
+	ActionSuppress  = "suppress"
+	ActionDowngrade = "downgrade"
+)
+
+// Entry is one allowlisted item. Criteria of different kinds must all match (a path and a
+// signer, say); any value within one kind may. Patterns use * and ? wildcards and are
+// case-insensitive; a "re:" prefix gives a regular expression instead.
+type Entry struct {
+	ID           string   `yaml:"id,omitempty" json:"id"`
+	Reason       string   `yaml:"reason" json:"reason"`
+	Action       string   `yaml:"action,omitempty" json:"action"`         // suppress (default) or downgrade
+	Rules        []string `yaml:"rules,omitempty" json:"rules,omitempty"` // only for these rule IDs or titles (patterns)
+	Paths        []string `yaml:"paths,omitempty" json:"paths,omitempty"`
+	PathFields   []string `yaml:"path_fields,omitempty" json:"path_fields,omitempty"` // fields paths match instead of the acting image; "Subject" for the event subject
+	CommandLines []string `yaml:"command_lines,omitempty" json:"command_lines,omitempty"`
+	Signers      []string `yaml:"signers,omitempty" json:"signers,omitempty"`
+	Hashes       []string `yaml:"hashes,omitempty" json:"hashes,omitempty"`
+	HashSets     []string `yaml:"hash_sets,omitempty" json:"hash_sets,omitempty"` // files, relative to the allowlist
+	Source       string   `yaml:"-" json:"source"`                                // allowlist file the entry came from
+	HashCount    int      `yaml:"-" json:"hash_count"`                            // hashes loaded, inline and from sets
+
+	rules, paths, cmds, signers []*regexp.Regexp
+	hashes                      map[string]bool
+}
+
+type file struct {
+	Name    string  `yaml:"name,omitempty"`
+	Entries []Entry `yaml:"entries"`
+}
+
+// List is a set of entries, checked in order.
+type List struct {
+	entries []*Entry
+}
+
+// Load reads allowlist files in order; files that do not exist are skipped.
+func Load(paths ...string) (*List, error) {
+	l := &List{}
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries, err := Parse(data, filepath.Dir(p))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		for _, e := range entries {
+			e.Source = p
+		}
+		l.entries = append(l.entries, entries...)
+	}
+	return l, nil
+}
+
+// Parse reads an allowlist document. Hash sets are resolved against baseDir.
+func Parse(data []byte, baseDir string) ([]*Entry, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse allowlist: %w", err)
+	}
+	var out []*Entry
+	for i := range f.Entries {
+		e := &f.Entries[i]
+		if err := e.compile(baseDir); err != nil {
+			return nil, fmt.Errorf("entry %d (%s): %w", i+1, e.ID, err)
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func (e *Entry) compile(baseDir string) error {
+	if strings.TrimSpace(e.Reason) == "" {
+		return fmt.Errorf("reason required")
+	}
+	switch strings.ToLower(e.Action) {
+	case "", ActionSuppress:
+		e.Action = ActionSuppress
+	case ActionDowngrade:
+		e.Action = ActionDowngrade
+	default:
+		return fmt.Errorf("unknown action %q", e.Action)
+	}
+	var err error
+	for _, c := range []struct {
+		src []string
+		dst *[]*regexp.Regexp
+	}{{e.Rules, &e.rules}, {e.Paths, &e.paths}, {e.CommandLines, &e.cmds}, {e.Signers, &e.signers}} {
+		if *c.dst, err = compilePatterns(c.src); err != nil {
+			return err
+		}
+	}
+	e.hashes = make(map[string]bool)
+	for _, h := range e.Hashes {
+		if h = normalizeHash(h); h != "" {
+			e.hashes[h] = true
+		}
+	}
+	for _, set := range e.HashSets {
+		if !filepath.IsAbs(set) {
+			set = filepath.Join(baseDir, set)
+		}
+		if err := LoadHashSet(set, e.hashes); err != nil {
+			return err
+		}
+	}
+	e.HashCount = len(e.hashes)
+	if len(e.paths)+len(e.cmds)+len(e.signers) == 0 && len(e.hashes) == 0 {
+		return fmt.Errorf("no paths, command lines, signers or hashes")
+	}
+	return nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, p := range patterns {
+		var expr string
+		if re, ok := strings.CutPrefix(p, "re:"); ok {
+			expr = "(?i)" + re
+		} else {
+			expr = "(?is)^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(p)) + "$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", p, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// LoadHashSet adds the hashes of a hash set file to set. NSRL RDS files (a quoted CSV with a
+// "SHA-1","MD5",... header) contribute their SHA-1, MD5 and SHA-256 columns; any other file
+// is read as one hash per line, optionally followed by a file name as in sha256sum output.
+func LoadHashSet(path string, set map[string]bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("hash set: %w", err)
+	}
+	defer f.Close()
+	br := bufio.NewReaderSize(f, 1<<20)
+	head, _ := br.Peek(64)
+	if strings.HasPrefix(strings.TrimPrefix(string(head), "\ufeff"), `"SHA-1"`) {
+		return loadRDS(br, set)
+	}
+	sc := bufio.NewScanner(br)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if h := normalizeHash(strings.Fields(line)[0]); h != "" {
+			set[h] = true
+		}
+	}
+	return sc.Err()
+}
+
+func loadRDS(r io.Reader, set map[string]bool) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("hash set header: %w", err)
+	}
+	var cols []int
+	for i, name := range header {
+		switch strings.TrimPrefix(strings.ToUpper(name), "\ufeff") {
+		case "SHA-1", "MD5", "SHA-256":
+			cols = append(cols, i)
+		}
+	}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("hash set: %w", err)
+		}
+		for _, i := range cols {
+			if i < len(rec) {
+				if h := normalizeHash(rec[i]); h != "" {
+					set[h] = true
+				}
+			}
+		}
+	}
+}
+
+// normalizeHash lower-cases an MD5, SHA-1 or SHA-256 hex string, or returns "" for anything else.
+func normalizeHash(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	switch len(h) {
+	case 32, 40, 64:
+	default:
+		return ""
+	}
+	for i := 0; i < len(h); i++ {
+		if !(h[i] >= '0' && h[i] <= '9' || h[i] >= 'a' && h[i] <= 'f') {
+			return ""
+		}
+	}
+	return h
+}
+
+// Entries returns the loaded entries.
+func (l *List) Entries() []Entry {
+	out := make([]Entry, 0, len(l.entries))
+	for _, e := range l.entries {
+		out = append(out, *e)
+	}
+	return out
+}
+
+// Len returns the number of entries.
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.entries)
+}
+
+// Match returns the suppression of the first entry that covers ev for the given rule (a
+// rule ID or title; empty matches entries without a rule restriction only), or nil.
+func (l *List) Match(ev *model.TimelineEvent, rules ...string) *model.Suppression {
+	if l == nil || len(l.entries) == 0 {
+		return nil
+	}
+	f := fieldsOf(ev)
+	for _, e := range l.entries {
+		if matched, ok := e.match(f, rules); ok {
+			return &model.Suppression{Action: e.Action, Reason: e.Reason, EntryID: e.ID, Matched: matched}
+		}
+	}
+	return nil
+}
+
+func (e *Entry) match(f eventFields, rules []string) (string, bool) {
+	if len(e.rules) > 0 && !anyMatch(e.rules, rules) {
+		return "", false
+	}
+	var matched string
+	for _, c := range []struct {
+		patterns []*regexp.Regexp
+		values   []string
+	}{{e.paths, f.pathsFor(e.PathFields)}, {e.cmds, f.cmds}, {e.signers, f.signers}} {
+		if len(c.patterns) == 0 {
+			continue
+		}
+		v, ok := firstMatch(c.patterns, c.values)
+		if !ok {
+			return "", false
+		}
+		if matched == "" {
+			matched = v
+		}
+	}
+	if len(e.hashes) > 0 {
+		found := ""
+		for _, h := range f.hashes {
+			if e.hashes[h] {
+				found = h
+				break
+			}
+		}
+		if found == "" {
+			return "", false
+		}
+		if matched == "" {
+			matched = found
+		}
+	}
+	return matched, true
+}
+
+func anyMatch(patterns []*regexp.Regexp, values []string) bool {
+	_, ok := firstMatch(patterns, values)
+	return ok
+}
+
+func firstMatch(patterns []*regexp.Regexp, values []string) (string, bool) {
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		for _, re := range patterns {
+			if re.MatchString(v) {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// eventFields are the values of an event that entries are matched against.
+type eventFields struct {
+	ev                            *model.TimelineEvent
+	images, cmds, signers, hashes []string
+}
+
+var (
+	// imageKeys name the image of the process that acted; paths match these by default.
+	imageKeys = []string{"Image", "NewProcessName"}
+	cmdKeys   = []string{"CommandLine", "ParentCommandLine", "HostApplication"}
+	// signerKeys hold the subject of a signature; they count only when signatureValid.
+	signerKeys = []string{"Signature", "Signer"}
+	hashKeys   = []string{"sha1", "SHA1", "md5", "MD5", "sha256", "SHA256"}
+)
+
+func fieldsOf(ev *model.TimelineEvent) eventFields {
+	d := ev.Details
+	f := eventFields{ev: ev}
+	for _, k := range imageKeys {
+		if v := pathValue(d[k]); v != "" {
+			f.images = append(f.images, v)
+		}
+	}
+	for _, k := range cmdKeys {
+		if v := d[k]; v != "" && v != "-" {
+			f.cmds = append(f.cmds, v)
+		}
+	}
+	if ev.Artifact == "ScheduledTask" {
+		f.cmds = append(f.cmds, strings.TrimSpace(ev.Subject+" "+d["arguments"]))
+	}
+	if signatureValid(d) {
+		for _, k := range signerKeys {
+			if v := d[k]; v != "" && v != "-" {
+				f.signers = append(f.signers, v)
+			}
+		}
+	}
+	for _, k := range hashKeys {
+		if h := normalizeHash(d[k]); h != "" {
+			f.hashes = append(f.hashes, h)
+		}
+	}
+	// Sysmon: Hashes="SHA1=...,MD5=...,SHA256=...,IMPHASH=..."
+	for _, part := range strings.Split(d["Hashes"], ",") {
+		if _, v, ok := strings.Cut(part, "="); ok {
+			if h := normalizeHash(v); h != "" {
+				f.hashes = append(f.hashes, h)
+			}
+		}
+	}
+	return f
+}
+
+// pathsFor returns the values of an entry's path fields, by default the acting image.
+func (f eventFields) pathsFor(fields []string) []string {
+	if len(fields) == 0 {
+		return f.images
+	}
+	var out []string
+	for _, k := range fields {
+		if k == "Subject" {
+			if strings.ContainsAny(f.ev.Subject, `\/`) {
+				out = append(out, f.ev.Subject)
+			}
+		} else if v := pathValue(f.ev.Details[k]); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func pathValue(v string) string {
+	if v == "-" {
+		return ""
+	}
+	return strings.Trim(v, `"`)
+}
+
+// signatureValid reports whether an event records a verified signature: Sysmon's
+// SignatureStatus "Valid", or Signed "true" where no status is given.
+func signatureValid(d map[string]string) bool {
+	if status := d["SignatureStatus"]; status != "" {
+		return strings.EqualFold(status, "Valid")
+	}
+	return strings.EqualFold(d["Signed"], "true")
+}
+
+// severities from most to least severe; downgrading moves one step right.
+var severities = []string{"critical", "high", "medium", "low", "informational"}
+
+// Downgrade returns the next lower severity.
+func Downgrade(severity string) string {
+	s := strings.ToLower(severity)
+	if s == "info" {
+		s = "informational"
+	}
+	for i, v := range severities {
+		if v == s && i+1 < len(severities) {
+			return severities[i+1]
+		}
+	}
+	return "informational"
+}
+
+// ApplyToEvent records s on a Sigma-flagged event. A suppressed alert keeps its title in
+// _SuppressedAlert but no longer counts as an alert; a downgraded one drops one level.
+func ApplyToEvent(ev *model.TimelineEvent, s *model.Suppression) {
+	d := ev.Details
+	level := d["_AlertLevel"]
+	d["_Allowlist"] = s.Reason
+	d["_AllowlistAction"] = s.Action
+	if s.EntryID != "" {
+		d["_AllowlistEntry"] = s.EntryID
+	}
+	if s.Matched != "" {
+		d["_AllowlistMatched"] = s.Matched
+	}
+	d["_AllowlistOriginalLevel"] = level
+	switch s.Action {
+	case ActionDowngrade:
+		d["_AlertLevel"] = Downgrade(level)
+	default:
+		d["_SuppressedAlert"] = d["_Alert"]
+		delete(d, "_Alert")
+		delete(d, "_AlertLevel")
+	}
+}
+
+// ApplyToFinding records s on a finding and lowers its severity when downgrading.
+func ApplyToFinding(f *model.Finding, s *model.Suppression) {
+	sup := *s
+	sup.OriginalSeverity = f.Severity
+	if s.Action == ActionDowngrade {
+		f.Severity = Downgrade(f.Severity)
+	}
+	f.Suppression = &sup
+}
+
+// GlobalPath is the allowlist shared by all cases, in the user's configuration directory.
+func GlobalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gtrace", "allowlist.yaml"), nil
+}
+
+// CasePath is the case allowlist. Like the case hunt library it sits beside the data
+// directory, which is cleared when the application exits.
+func CasePath(casePath string) string {
+	return filepath.Join(casePath, "allowlist.yaml")
+}
+
+// LoadForCase loads the global allowlist followed by the case one.
+func LoadForCase(casePath string) (*List, error) {
+	var paths []string
+	if p, err := GlobalPath(); err == nil {
+		paths = append(paths, p)
+	}
+	return Load(append(paths, CasePath(casePath))...)
+}
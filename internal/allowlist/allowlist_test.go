@@ -0,0 +1,130 @@
+package allowlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gtrace/pkg/model"
+)
+
+func TestMatch(t *testing.T) {
+	dir := t.TempDir()
+	rds := "\"SHA-1\",\"MD5\",\"CRC32\",\"FileName\",\"FileSize\",\"ProductCode\",\"OpSystemCode\",\"SpecialCode\"\n" +
+		"\"0000004DA6391F7F5D2F7FCCF36CEBDA60C6EA02\",\"0E53C14A3E48D94FF596A2824307B492\",\"AA6A7B16\",\"00br2026.gif\",2226,228,\"WIN\",\"\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "NSRLFile.txt"), []byte(rds), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "team.txt"), []byte("# team baseline\n"+
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  tool.exe\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	yml := `
+entries:
+  - id: sccm
+    reason: SCCM client cache
+    paths: ['C:\Windows\ccmcache\*']
+  - id: ms-signed-psexec
+    reason: Admin PsExec use
+    action: downgrade
+    rules: ['*psexec*']
+    signers: [Microsoft Corporation, Sysinternals*]
+    command_lines: ['re:-accepteula']
+  - id: known
+    reason: Known file
+    hash_sets: [NSRLFile.txt, team.txt]
+  - id: vendor-driver
+    reason: Vendor driver
+    paths: ['C:\Program Files\Vendor\*']
+    path_fields: [ImageLoaded]
+`
+	if err := os.WriteFile(filepath.Join(dir, "allowlist.yaml"), []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Load(filepath.Join(dir, "allowlist.yaml"), filepath.Join(dir, "missing.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Len() != 4 || l.Entries()[2].HashCount != 3 {
+		t.Fatalf("entries = %+v", l.Entries())
+	}
+
+	ev := func(details map[string]string) *model.TimelineEvent {
+		return &model.TimelineEvent{Source: "EventLog", Details: details}
+	}
+	if s := l.Match(ev(map[string]string{"Image": `c:\windows\CCMCACHE\1\setup.exe`}), "proc_creation_x"); s == nil || s.EntryID != "sccm" || s.Action != ActionSuppress {
+		t.Errorf("path: %+v", s)
+	}
+
+	// Paths match the acting image unless the entry names other fields.
+	if s := l.Match(ev(map[string]string{"Image": `C:\Users\Public\evil.exe`, "TargetFilename": `C:\Windows\ccmcache\1\evil.exe`})); s != nil {
+		t.Errorf("written file matched a path entry for the acting image: %+v", s)
+	}
+	if s := l.Match(ev(map[string]string{"Image": `C:\Users\Public\evil.exe`, "ImageLoaded": `C:\Program Files\Vendor\hook.dll`})); s == nil || s.EntryID != "vendor-driver" {
+		t.Errorf("path_fields: %+v", s)
+	}
+	if s := l.Match(ev(map[string]string{"Image": `C:\Program Files\Vendor\svc.exe`, "ImageLoaded": `C:\Users\Public\hook.dll`})); s != nil {
+		t.Errorf("path_fields entry matched the acting image: %+v", s)
+	}
+
+	psexec := ev(map[string]string{"Signature": "Sysinternals - www.sysinternals.com", "SignatureStatus": "Valid", "CommandLine": `psexec.exe -AcceptEULA \\srv cmd`})
+	if s := l.Match(psexec, "Sysmon PsExec Execution"); s == nil || s.Action != ActionDowngrade || s.Matched != psexec.Details["CommandLine"] {
+		t.Errorf("signer + command line: %+v", s)
+	}
+	if s := l.Match(psexec, "Other rule"); s != nil {
+		t.Errorf("rule restriction ignored: %+v", s)
+	}
+	// Signers count only for a valid signature, and never from version resource fields.
+	for _, d := range []map[string]string{
+		{"Signature": "Sysinternals - www.sysinternals.com"},
+		{"Signature": "Sysinternals - www.sysinternals.com", "SignatureStatus": "Expired", "Signed": "true"},
+		{"Signature": "Sysinternals - www.sysinternals.com", "Signed": "false"},
+		{"Company": "Sysinternals - www.sysinternals.com", "Publisher": "Microsoft Corporation", "Signed": "true"},
+	} {
+		d["CommandLine"] = psexec.Details["CommandLine"]
+		if s := l.Match(ev(d), "Sysmon PsExec Execution"); s != nil {
+			t.Errorf("%v matched a signer entry: %+v", d, s)
+		}
+	}
+	if s := l.Match(ev(map[string]string{"Signer": "Microsoft Corporation", "Signed": "true", "CommandLine": "psexec -accepteula"}), "psexec"); s == nil {
+		t.Error("Signed true without a status not matched")
+	}
+
+	delete(psexec.Details, "CommandLine")
+	if s := l.Match(psexec, "Sysmon PsExec Execution"); s != nil {
+		t.Errorf("signer alone matched an entry that also needs a command line: %+v", s)
+	}
+
+	if s := l.Match(ev(map[string]string{"Hashes": "MD5=0E53C14A3E48D94FF596A2824307B492,IMPHASH=00"})); s == nil || s.EntryID != "known" {
+		t.Errorf("NSRL hash: %+v", s)
+	}
+	if s := l.Match(ev(map[string]string{"sha256": "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855"})); s == nil {
+		t.Error("team hash set not matched")
+	}
+	if s := l.Match(ev(map[string]string{"Image": `C:\Users\Public\evil.exe`})); s != nil {
+		t.Errorf("unlisted event matched: %+v", s)
+	}
+
+	if _, err := Parse([]byte("entries:\n  - paths: ['x']\n"), dir); err == nil {
+		t.Error("entry without a reason accepted")
+	}
+}
+
+func TestApply(t *testing.T) {
+	ev := &model.TimelineEvent{Details: map[string]string{"_Alert": "PsExec", "_AlertLevel": "high"}}
+	ApplyToEvent(ev, &model.Suppression{Action: ActionDowngrade, Reason: "admin"})
+	if ev.Details["_AlertLevel"] != "medium" || ev.Details["_AllowlistOriginalLevel"] != "high" || ev.Details["_Alert"] != "PsExec" {
+		t.Errorf("downgraded event = %v", ev.Details)
+	}
+	ev = &model.TimelineEvent{Details: map[string]string{"_Alert": "PsExec", "_AlertLevel": "high"}}
+	ApplyToEvent(ev, &model.Suppression{Action: ActionSuppress, Reason: "admin"})
+	if ev.Details["_Alert"] != "" || ev.Details["_SuppressedAlert"] != "PsExec" || ev.Details["_Allowlist"] != "admin" {
+		t.Errorf("suppressed event = %v", ev.Details)
+	}
+
+	f := model.Finding{Severity: "critical"}
+	ApplyToFinding(&f, &model.Suppression{Action: ActionDowngrade, Reason: "admin"})
+	if f.Severity != "high" || f.Suppression == nil || f.Suppression.OriginalSeverity != "critical" {
+		t.Errorf("finding = %+v", f)
+	}
+}
@@ -92,6 +92,34 @@ func NewEngineV2(ruleFS fs.FS, rootDir string) (*EngineV2, error) {
 	}, nil
 }
 
+// sysmonCategories maps Sysmon event IDs to Sigma logsource categories, most specific first.
+var sysmonCategories = map[string][]string{
+	"1":  {"process_creation"},
+	"2":  {"file_change"},
+	"3":  {"network_connection"},
+	"5":  {"process_termination"},
+	"6":  {"driver_load"},
+	"7":  {"image_load"},
+	"8":  {"create_remote_thread"},
+	"9":  {"raw_access_thread"},
+	"10": {"process_access"},
+	"11": {"file_event"},
+	"12": {"registry_add", "registry_delete", "registry_event"},
+	"13": {"registry_set", "registry_event"},
+	"14": {"registry_rename", "registry_event"},
+	"15": {"create_stream_hash"},
+	"17": {"pipe_created"},
+	"18": {"pipe_created"},
+	"19": {"wmi_event"},
+	"20": {"wmi_event"},
+	"21": {"wmi_event"},
+	"22": {"dns_query"},
+	"23": {"file_delete"},
+	"25": {"process_tampering"},
+	"26": {"file_delete"},
+	"29": {"file_executable_detected"},
+}
+
 // Evaluate checks an event against all loaded rules
 func (e *EngineV2) Evaluate(ev model.TimelineEvent) *ActiveRule {
 	// 1. Adapter: Convert TimelineEvent to map for Sigma
@@ -143,18 +171,30 @@ func (e *EngineV2) Evaluate(ev model.TimelineEvent) *ActiveRule {
 	ensure("DestinationPort", "RemotePort")
 	ensure("Protocol", "Protocol")
 
+	// PowerShell (4104 / PSReadLine / transcripts)
+	ensure("ScriptBlockText", "ScriptBlockText", "Command")
+
 	// 2. Identify Category to filter rules
-	cat := ""
+	// Sysmon IDs overlap the Security/System ones, so the channel decides the table.
+	var cats []string
 	eid := fmt.Sprintf("%v", obj["EventID"])
-	switch eid {
-	case "4688", "1":
-		cat = "process_creation"
-	case "4624", "4625", "2":
-		cat = "network_connection" // simplification
-	case "4663", "11", "23", "26":
-		cat = "file_event"
-	case "12", "13", "14":
-		cat = "registry_event"
+	if strings.EqualFold(ev.Details["Channel"], "Microsoft-Windows-Sysmon/Operational") {
+		cats = sysmonCategories[eid]
+	} else {
+		switch eid {
+		case "4688":
+			cats = []string{"process_creation"}
+		case "4624", "4625":
+			cats = []string{"network_connection"} // simplification
+		case "4663":
+			cats = []string{"file_event"}
+		case "4104":
+			cats = []string{"ps_script"}
+		}
+	}
+	cat := ""
+	if len(cats) > 0 {
+		cat = cats[0]
 	}
 
 	// Also check artifact type
@@ -164,6 +204,8 @@ func (e *EngineV2) Evaluate(ev model.TimelineEvent) *ActiveRule {
 			cat = "registry_event"
 		case "Prefetch":
 			cat = "process_creation"
+		case "PSReadLine", "Transcript":
+			cat = "ps_script"
 		}
 	}
 
@@ -180,8 +222,23 @@ func (e *EngineV2) Evaluate(ev model.TimelineEvent) *ActiveRule {
 	}
 
 	// First check categorical rules
-	if cat != "" {
-		if matched := evaluate(e.RulesByCat[cat]); matched != nil {
+	if len(cats) == 0 && cat != "" {
+		cats = []string{cat}
+	}
+	for _, c := range cats {
+		if matched := evaluate(e.RulesByCat[c]); matched != nil {
+			return matched
+		}
+	}
+
+	// Decoded -EncodedCommand payloads are scripts too: run them through ps_script rules.
+	if decoded := ev.Details["DecodedCommand"]; decoded != "" {
+		if cat == "ps_script" {
+			obj["ScriptBlockText"] = fmt.Sprintf("%v\n%s", obj["ScriptBlockText"], decoded)
+		} else {
+			obj["ScriptBlockText"] = decoded
+		}
+		if matched := evaluate(e.RulesByCat["ps_script"]); matched != nil {
 			return matched
 		}
 	}
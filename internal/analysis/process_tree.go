@@ -0,0 +1,424 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+// ProcessNode is one process instance reconstructed from 4688 or Sysmon 1 events.
+type ProcessNode struct {
+	Key               string         `json:"key"`
+	EventID           string         `json:"event_id,omitempty"` // timeline event that created the node
+	Host              string         `json:"host,omitempty"`
+	PID               uint64         `json:"pid"`
+	ParentPID         uint64         `json:"parent_pid"`
+	ProcessGuid       string         `json:"process_guid,omitempty"`
+	ParentProcessGuid string         `json:"parent_process_guid,omitempty"`
+	Image             string         `json:"image"`
+	CommandLine       string         `json:"command_line,omitempty"`
+	User              string         `json:"user,omitempty"`
+	LogonID           string         `json:"logon_id,omitempty"`
+	Start             *time.Time     `json:"start,omitempty"`
+	End               *time.Time     `json:"end,omitempty"`
+	Origin            string         `json:"origin"` // "4688", "sysmon" or "inferred" (parent seen only as a reference)
+	Alerts            []string       `json:"alerts,omitempty"`
+	AlertLevel        string         `json:"alert_level,omitempty"`
+	IOCHits           []string       `json:"ioc_hits,omitempty"`
+	SubtreeHits       int            `json:"subtree_hits"` // Sigma + IOC hits on this node and its descendants
+	ParentKey         string         `json:"parent_key,omitempty"`
+	Children          []*ProcessNode `json:"children,omitempty"`
+
+	creatorLogon      string // 4688 SubjectLogonId: the session of the process that created this one
+	parentImage       string
+	parentCommandLine string
+}
+
+// ProcessLineage answers "what spawned this, and what did it spawn".
+type ProcessLineage struct {
+	Process   *ProcessNode   `json:"process"`   // includes the descendant subtree
+	Ancestors []*ProcessNode `json:"ancestors"` // nearest parent first, without children
+}
+
+// ProcessTree links process instances into parent/child trees.
+//
+// Sysmon instances are keyed by ProcessGuid. 4688 instances are keyed by host + PID + start
+// time and linked to the latest process with the parent PID that was running when the child
+// started, in the same logon session when both sides record it, since PIDs are reused.
+type ProcessTree struct {
+	Roots   []*ProcessNode
+	nodes   map[string]*ProcessNode
+	byEvent map[string]*ProcessNode
+	byPID   map[string][]*ProcessNode // host|pid → instances ordered by start
+}
+
+// sameProcessWindow is how far apart a 4688 and a Sysmon 1 for the same PID may be logged.
+const sameProcessWindow = 2 * time.Second
+
+// IsProcessEvent reports whether ev feeds the process tree (creation or termination).
+func IsProcessEvent(ev model.TimelineEvent) bool {
+	switch ev.Details["EventID"] {
+	case "4688", "4689":
+		return !isSysmonEvent(ev)
+	case "1", "5":
+		return isSysmonEvent(ev)
+	}
+	return false
+}
+
+func isSysmonEvent(ev model.TimelineEvent) bool {
+	return strings.EqualFold(ev.Details["Channel"], "Microsoft-Windows-Sysmon/Operational")
+}
+
+// BuildProcessTree reconstructs process trees from process creation/termination events.
+// Other events are ignored, so the full timeline may be passed.
+func BuildProcessTree(events []model.TimelineEvent) *ProcessTree {
+	t := &ProcessTree{
+		nodes:   make(map[string]*ProcessNode),
+		byEvent: make(map[string]*ProcessNode),
+		byPID:   make(map[string][]*ProcessNode),
+	}
+
+	sorted := make([]model.TimelineEvent, 0, len(events))
+	for _, ev := range events {
+		if IsProcessEvent(ev) {
+			sorted = append(sorted, ev)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].EventTime.Before(sorted[j].EventTime) })
+
+	var sec []*ProcessNode
+	var terminations []model.TimelineEvent
+	for _, ev := range sorted {
+		switch {
+		case ev.Details["EventID"] == "1":
+			n := sysmonNode(ev)
+			if existing := t.nodes[n.Key]; existing != nil {
+				t.byEvent[ev.ID] = existing
+				continue
+			}
+			t.add(n, ev.ID)
+		case ev.Details["EventID"] == "4688":
+			n := securityNode(ev)
+			sec = append(sec, n)
+			t.byEvent[ev.ID] = n
+		default:
+			terminations = append(terminations, ev)
+		}
+	}
+
+	// A 4688 duplicating a Sysmon 1 for the same process becomes an alias of the Sysmon node.
+	for _, n := range sec {
+		if dup := t.findInstance(n.Host, n.PID, *n.Start, sameProcessWindow); dup != nil && dup.Origin == "sysmon" {
+			t.byEvent[n.EventID] = dup
+			continue
+		}
+		t.add(n, n.EventID)
+	}
+
+	for _, ev := range terminations {
+		t.applyTermination(ev)
+	}
+
+	t.link()
+	return t
+}
+
+func (t *ProcessTree) add(n *ProcessNode, eventID string) {
+	t.nodes[n.Key] = n
+	if eventID != "" {
+		t.byEvent[eventID] = n
+	}
+	if n.Start != nil {
+		k := pidKey(n.Host, n.PID)
+		list := append(t.byPID[k], n)
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Start.Before(*list[j].Start) })
+		t.byPID[k] = list
+	}
+}
+
+func sysmonNode(ev model.TimelineEvent) *ProcessNode {
+	d := ev.Details
+	start := ev.EventTime
+	n := &ProcessNode{
+		Key:               "guid:" + d["ProcessGuid"],
+		EventID:           ev.ID,
+		Host:              d["Computer"],
+		PID:               parsePID(d["ProcessId"]),
+		ParentPID:         parsePID(d["ParentProcessId"]),
+		ProcessGuid:       d["ProcessGuid"],
+		ParentProcessGuid: d["ParentProcessGuid"],
+		Image:             d["Image"],
+		CommandLine:       d["CommandLine"],
+		User:              d["User"],
+		LogonID:           strings.ToLower(d["LogonId"]),
+		Start:             &start,
+		Origin:            "sysmon",
+		parentImage:       d["ParentImage"],
+		parentCommandLine: d["ParentCommandLine"],
+	}
+	if n.ProcessGuid == "" {
+		n.Key = fmt.Sprintf("pid:%s|%d|%d", n.Host, n.PID, start.UnixNano())
+	}
+	annotate(n, ev)
+	return n
+}
+
+func securityNode(ev model.TimelineEvent) *ProcessNode {
+	d := ev.Details
+	start := ev.EventTime
+	user := d["TargetUserName"]
+	if user == "" || user == "-" {
+		user = d["SubjectUserName"]
+	}
+	// Win10+ records the new process's own session in TargetLogonId; older builds only the creator's.
+	logon := d["TargetLogonId"]
+	if logon == "" || logon == "0x0" {
+		logon = d["SubjectLogonId"]
+	}
+	n := &ProcessNode{
+		EventID:     ev.ID,
+		Host:        d["Computer"],
+		PID:         parsePID(d["NewProcessId"]),
+		ParentPID:   parsePID(d["ProcessId"]),
+		Image:       d["NewProcessName"],
+		CommandLine: d["CommandLine"],
+		User:        user,
+		LogonID:     strings.ToLower(logon),
+		Start:       &start,
+		Origin:      "4688",
+
+		creatorLogon: strings.ToLower(d["SubjectLogonId"]),
+		parentImage:  d["ParentProcessName"],
+	}
+	n.Key = fmt.Sprintf("pid:%s|%d|%d", n.Host, n.PID, start.UnixNano())
+	annotate(n, ev)
+	return n
+}
+
+// annotate copies Sigma and IOC hits from the creating event.
+func annotate(n *ProcessNode, ev model.TimelineEvent) {
+	if alert := ev.Details["_Alert"]; alert != "" {
+		n.Alerts = append(n.Alerts, alert)
+		n.AlertLevel = ev.Details["_AlertLevel"]
+	}
+	n.IOCHits = append(n.IOCHits, ev.IOCHits...)
+}
+
+func (t *ProcessTree) applyTermination(ev model.TimelineEvent) {
+	end := ev.EventTime
+	var n *ProcessNode
+	if g := ev.Details["ProcessGuid"]; g != "" && isSysmonEvent(ev) {
+		n = t.nodes["guid:"+g]
+	} else {
+		n = t.findRunning(ev.Details["Computer"], parsePID(ev.Details["ProcessId"]), end, strings.ToLower(ev.Details["SubjectLogonId"]))
+	}
+	if n != nil && n.End == nil {
+		n.End = &end
+	}
+}
+
+// findInstance returns the process with pid on host started within window of at.
+func (t *ProcessTree) findInstance(host string, pid uint64, at time.Time, window time.Duration) *ProcessNode {
+	for _, n := range t.byPID[pidKey(host, pid)] {
+		if d := n.Start.Sub(at); d <= window && d >= -window {
+			return n
+		}
+	}
+	return nil
+}
+
+// findRunning returns the latest instance of pid on host that started at or before at and had not
+// exited yet. A non-empty logonID must match the instance's session when it is known.
+func (t *ProcessTree) findRunning(host string, pid uint64, at time.Time, logonID string) *ProcessNode {
+	list := t.byPID[pidKey(host, pid)]
+	for i := len(list) - 1; i >= 0; i-- {
+		n := list[i]
+		if n.Start.After(at) {
+			continue
+		}
+		if n.End != nil && n.End.Before(at) {
+			continue
+		}
+		if logonID != "" && n.LogonID != "" && !sameLogon(logonID, n.LogonID) {
+			continue
+		}
+		return n
+	}
+	return nil
+}
+
+// sameLogon compares logon IDs, treating SYSTEM (0x3e7) as compatible with any session because
+// services and session-0 processes spawn children across sessions.
+func sameLogon(a, b string) bool {
+	return a == b || a == "0x3e7" || b == "0x3e7"
+}
+
+// link resolves parents, creating inferred nodes for parents seen only by reference.
+func (t *ProcessTree) link() {
+	keys := make([]string, 0, len(t.nodes))
+	for k := range t.nodes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		n := t.nodes[k]
+		parent := t.resolveParent(n)
+		if parent == nil || parent == n {
+			continue
+		}
+		n.ParentKey = parent.Key
+		parent.Children = append(parent.Children, n)
+	}
+
+	for _, n := range t.nodes {
+		sort.SliceStable(n.Children, func(i, j int) bool { return startOf(n.Children[i]).Before(startOf(n.Children[j])) })
+		if n.ParentKey == "" {
+			t.Roots = append(t.Roots, n)
+		}
+	}
+	sort.SliceStable(t.Roots, func(i, j int) bool { return startOf(t.Roots[i]).Before(startOf(t.Roots[j])) })
+	for _, r := range t.Roots {
+		countHits(r, make(map[*ProcessNode]bool))
+	}
+}
+
+func (t *ProcessTree) resolveParent(n *ProcessNode) *ProcessNode {
+	if n.Origin == "inferred" {
+		return nil
+	}
+	if n.ParentProcessGuid != "" {
+		if p := t.nodes["guid:"+n.ParentProcessGuid]; p != nil {
+			return p
+		}
+	}
+	if n.Start != nil && n.ParentPID != 0 {
+		if p := t.findRunning(n.Host, n.ParentPID, *n.Start, n.creatorLogon); p != nil && p != n {
+			return p
+		}
+	}
+	return t.inferredParent(n)
+}
+
+// inferredParent returns a placeholder for a parent referenced but never seen being created.
+// Siblings referencing the same parent share the placeholder.
+func (t *ProcessTree) inferredParent(n *ProcessNode) *ProcessNode {
+	if n.ParentPID == 0 && n.ParentProcessGuid == "" {
+		return nil
+	}
+	key := fmt.Sprintf("inferred:%s|%d|%s", n.Host, n.ParentPID, strings.ToLower(n.parentImage))
+	if n.ParentProcessGuid != "" {
+		key = "guid:" + n.ParentProcessGuid
+	}
+	if p := t.nodes[key]; p != nil {
+		return p
+	}
+	p := &ProcessNode{
+		Key:         key,
+		Host:        n.Host,
+		PID:         n.ParentPID,
+		ProcessGuid: n.ParentProcessGuid,
+		Image:       n.parentImage,
+		CommandLine: n.parentCommandLine,
+		Origin:      "inferred",
+	}
+	t.nodes[key] = p
+	return p
+}
+
+func countHits(n *ProcessNode, seen map[*ProcessNode]bool) int {
+	if seen[n] {
+		return 0
+	}
+	seen[n] = true
+	total := len(n.Alerts) + len(n.IOCHits)
+	for _, c := range n.Children {
+		total += countHits(c, seen)
+	}
+	n.SubtreeHits = total
+	return total
+}
+
+// Node returns the process created by, or owning, the given event.
+func (t *ProcessTree) Node(ev model.TimelineEvent) *ProcessNode {
+	if n := t.byEvent[ev.ID]; n != nil {
+		return n
+	}
+	d := ev.Details
+	// Sysmon events other than 1 carry the acting process GUID.
+	for _, k := range []string{"ProcessGuid", "SourceProcessGUID", "SourceProcessGuid"} {
+		if g := d[k]; g != "" {
+			if n := t.nodes["guid:"+g]; n != nil {
+				return n
+			}
+		}
+	}
+	for _, k := range []string{"ProcessId", "NewProcessId", "PID"} {
+		if pid := parsePID(d[k]); pid != 0 {
+			if n := t.findRunning(d["Computer"], pid, ev.EventTime, ""); n != nil {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+// Lineage returns the ancestry and descendants of the process behind ev.
+func (t *ProcessTree) Lineage(ev model.TimelineEvent) (*ProcessLineage, error) {
+	n := t.Node(ev)
+	if n == nil {
+		return nil, fmt.Errorf("no process found for event %s", ev.ID)
+	}
+	out := &ProcessLineage{Process: n, Ancestors: []*ProcessNode{}}
+	seen := map[string]bool{n.Key: true}
+	for key := n.ParentKey; key != "" && !seen[key]; {
+		seen[key] = true
+		p := t.nodes[key]
+		if p == nil {
+			break
+		}
+		shallow := *p
+		shallow.Children = nil
+		out.Ancestors = append(out.Ancestors, &shallow)
+		key = p.ParentKey
+	}
+	return out, nil
+}
+
+// HitTrees returns the root trees containing at least one Sigma or IOC hit.
+func (t *ProcessTree) HitTrees() []*ProcessNode {
+	var out []*ProcessNode
+	for _, r := range t.Roots {
+		if r.SubtreeHits > 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func startOf(n *ProcessNode) time.Time {
+	if n.Start == nil {
+		return time.Time{}
+	}
+	return *n.Start
+}
+
+func pidKey(host string, pid uint64) string {
+	return strings.ToLower(host) + "|" + strconv.FormatUint(pid, 10)
+}
+
+// parsePID accepts the hex form used by Security events ("0x1a2c") and decimal Sysmon PIDs.
+func parsePID(s string) uint64 {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(strings.ToLower(s), "0x") {
+		v, _ := strconv.ParseUint(s[2:], 16, 64)
+		return v
+	}
+	v, _ := strconv.ParseUint(s, 10, 64)
+	return v
+}
@@ -0,0 +1,79 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+func secEvent(id string, at time.Time, eid string, details map[string]string) model.TimelineEvent {
+	d := map[string]string{"EventID": eid, "Channel": "Security", "Computer": "WS01"}
+	for k, v := range details {
+		d[k] = v
+	}
+	return model.TimelineEvent{ID: id, EventTime: at, Source: "EventLog", Details: d}
+}
+
+func TestProcessTreePIDReuse(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	events := []model.TimelineEvent{
+		// First cmd.exe with PID 0x100, exits, then the PID is reused by a second cmd.exe.
+		secEvent("cmd1", base, "4688", map[string]string{"NewProcessId": "0x100", "ProcessId": "0x10", "NewProcessName": `C:\Windows\System32\cmd.exe`, "SubjectLogonId": "0x1111", "TargetLogonId": "0x1111"}),
+		secEvent("cmd1-exit", base.Add(time.Minute), "4689", map[string]string{"ProcessId": "0x100", "SubjectLogonId": "0x1111"}),
+		secEvent("cmd2", base.Add(2*time.Minute), "4688", map[string]string{"NewProcessId": "0x100", "ProcessId": "0x20", "NewProcessName": `C:\Windows\System32\cmd.exe`, "SubjectLogonId": "0x2222", "TargetLogonId": "0x2222"}),
+		// whoami spawned by the second cmd.exe.
+		secEvent("whoami", base.Add(3*time.Minute), "4688", map[string]string{"NewProcessId": "0x200", "ProcessId": "0x100", "NewProcessName": `C:\Windows\System32\whoami.exe`, "SubjectLogonId": "0x2222", "TargetLogonId": "0x2222", "_Alert": "Whoami Execution", "_AlertLevel": "medium"}),
+	}
+	events[3].IOCHits = []string{"path:whoami.exe"}
+
+	tree := BuildProcessTree(events)
+	lin, err := tree.Lineage(events[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lin.Ancestors) == 0 || lin.Ancestors[0].EventID != "cmd2" {
+		t.Fatalf("whoami should descend from the second cmd.exe, got %+v", lin.Ancestors)
+	}
+	if len(lin.Process.Alerts) != 1 || len(lin.Process.IOCHits) != 1 {
+		t.Fatalf("hits not annotated: %+v", lin.Process)
+	}
+
+	cmd2 := tree.Node(events[2])
+	if cmd2.SubtreeHits != 2 {
+		t.Errorf("SubtreeHits = %d, want 2", cmd2.SubtreeHits)
+	}
+	if cmd1 := tree.Node(events[0]); cmd1.End == nil || len(cmd1.Children) != 0 {
+		t.Errorf("first cmd.exe should have exited with no children: %+v", cmd1)
+	}
+}
+
+func TestProcessTreeSysmon(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	sysmon := func(id string, at time.Time, d map[string]string) model.TimelineEvent {
+		ev := secEvent(id, at, "1", d)
+		ev.Details["Channel"] = "Microsoft-Windows-Sysmon/Operational"
+		return ev
+	}
+	events := []model.TimelineEvent{
+		sysmon("ps", base, map[string]string{"ProcessGuid": "{A}", "ParentProcessGuid": "{P}", "ProcessId": "300", "ParentProcessId": "4", "Image": `C:\ps.exe`, "ParentImage": `C:\explorer.exe`}),
+		sysmon("child", base.Add(time.Second), map[string]string{"ProcessGuid": "{B}", "ParentProcessGuid": "{A}", "ProcessId": "400", "ParentProcessId": "300", "Image": `C:\whoami.exe`}),
+		// The Security log saw the same whoami; it must collapse onto the Sysmon node.
+		secEvent("child-4688", base.Add(time.Second), "4688", map[string]string{"NewProcessId": "0x190", "ProcessId": "0x12c", "NewProcessName": `C:\whoami.exe`}),
+	}
+
+	tree := BuildProcessTree(events)
+	if tree.Node(events[2]) != tree.Node(events[1]) {
+		t.Fatal("4688 duplicate was not merged with the Sysmon node")
+	}
+	lin, err := tree.Lineage(events[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lin.Ancestors) != 2 || lin.Ancestors[0].ProcessGuid != "{A}" || lin.Ancestors[1].Origin != "inferred" || lin.Ancestors[1].Image != `C:\explorer.exe` {
+		t.Fatalf("unexpected ancestry: %+v", lin.Ancestors)
+	}
+	if len(tree.Roots) != 1 {
+		t.Errorf("Roots = %d, want 1", len(tree.Roots))
+	}
+}
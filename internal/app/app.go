@@ -2,18 +2,31 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/user"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 
+	"gtrace/internal/allowlist"
+	"gtrace/internal/analysis"
+	"gtrace/internal/attack"
+	"gtrace/internal/budget"
 	"gtrace/internal/engine"
+	"gtrace/internal/hunt"
+	"gtrace/internal/noise"
+	"gtrace/internal/normalize"
 	"gtrace/internal/plugin"
+	"gtrace/internal/report"
 	"gtrace/internal/storage"
+	"gtrace/internal/yara"
+	"gtrace/pkg/analyzers"
 	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -107,6 +120,15 @@ func (a *App) OpenCase(casePath string) error {
 	a.pipeline = engine.NewPipeline(a.store, a.registry.Parsers(), a.registry.Analyzers(), func(format string, args ...interface{}) {
 		a.Log("Pipeline", format, args...)
 	})
+	if _, err := a.ReloadAllowlist(); err != nil {
+		a.log("Allowlist not loaded: %v", err)
+	}
+	if _, err := a.ListNoiseProfiles(); err != nil {
+		a.log("Noise profiles not loaded: %v", err)
+	}
+	if _, err := a.ListBudgetPolicies(); err != nil {
+		a.log("Budget policies not loaded: %v", err)
+	}
 
 	a.log("Case initialized successfully")
 	return nil
@@ -123,7 +145,7 @@ func (a *App) ResetCase() error {
 // StartTriage runs the triage process on an evidence path.
 // If evidencePath is empty, it attempts Live Triage on detected system paths.
 // components: List of artifact types to collect (e.g. "EventLogs", "Registry", "Prefetch"). Empty means all.
-// options: Configuration map (e.g. "max_events": 5000, "days": 7)
+// options: Configuration map (e.g. "max_events": 5000, "days": 7, "noise_profile": "full", "budget_policy": "alerts-first")
 func (a *App) StartTriage(evidencePath string, components []string, options map[string]interface{}) error {
 	if a.pipeline == nil {
 		return fmt.Errorf("case not open")
@@ -153,14 +175,29 @@ func (a *App) StartTriage(evidencePath string, components []string, options map[
 	return a.pipeline.Triage(a.ctx, evidencePath, options, progressFunc)
 }
 
+// maxAnalysisEvents bounds the timeline RunAnalysis loads for the analyzers.
+const maxAnalysisEvents = 100000
+
+var errAnalysisLimit = errors.New("analysis event limit reached")
+
 // RunAnalysis executes analyzers.
 func (a *App) RunAnalysis() (int, error) {
 	if a.pipeline == nil || a.store == nil {
 		return 0, fmt.Errorf("case not open")
 	}
-	// Fetch all events
-	events, err := a.store.QueryTimeline(a.ctx, &model.TimelineFilter{MaxResults: 10000})
-	if err != nil {
+	// Session and graph analyzers need more than one page of the timeline, but the events are
+	// held in memory: stop at maxAnalysisEvents.
+	var events []model.TimelineEvent
+	err := a.store.ScanTimeline(a.ctx, func(ev model.TimelineEvent) error {
+		if len(events) == maxAnalysisEvents {
+			return errAnalysisLimit
+		}
+		events = append(events, ev)
+		return nil
+	})
+	if errors.Is(err, errAnalysisLimit) {
+		a.log("Analysis: limited to the first %d timeline events", maxAnalysisEvents)
+	} else if err != nil {
 		return 0, err
 	}
 
@@ -173,6 +210,168 @@ func (a *App) RunAnalysis() (int, error) {
 	return len(findings), nil
 }
 
+// StackHosts compares services, scheduled tasks, Run keys, WMI persistence, Amcache hashes
+// and Prefetch executables across the hosts of the case and records a finding for every
+// entry present on at most maxPrevalence (0-1) of them. Categories seen on fewer than
+// minHosts hosts are skipped. Zero values use the analyzer defaults (10%, 3 hosts).
+func (a *App) StackHosts(maxPrevalence float64, minHosts int) ([]model.Finding, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	var events []model.TimelineEvent
+	if err := a.store.ScanTimeline(a.ctx, func(ev model.TimelineEvent) error {
+		events = append(events, ev)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	stacker := &analyzers.HostStackingAnalyzer{MaxPrevalence: maxPrevalence, MinHosts: minHosts}
+	resp, err := stacker.Analyze(a.ctx, pluginsdk.AnalyzeRequest{Timeline: events})
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.Findings {
+		resp.Findings[i].Attack = attack.Default().Enrich(resp.Findings[i].Attack)
+	}
+	a.pipeline.ApplyAllowlist(resp.Findings, events)
+	if err := a.store.SaveFindings(a.ctx, resp.Findings); err != nil {
+		return nil, err
+	}
+	a.log("Host stacking: %d rare entries", len(resp.Findings))
+	if resp.Findings == nil {
+		resp.Findings = []model.Finding{}
+	}
+	return resp.Findings, nil
+}
+
+// RunYaraScan scans the evidence files of the case, and the binaries Prefetch, Amcache and
+// ShimCache events reference, with YARA rules and records a finding, linked to the
+// referencing events, for every rule that matched a file. rulesPath is a rule file or
+// directory; empty uses <case>/yara and the global gtrace/yara directory. The built-in
+// scanner handles the common rule syntax without modules; externalBinary names a yara
+// executable to use instead. Referenced paths are resolved inside the collected image and,
+// for artifacts live triage read from this machine, on the running system.
+func (a *App) RunYaraScan(rulesPath string, externalBinary string) ([]model.Finding, error) {
+	if a.store == nil || a.pipeline == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	paths := yara.DefaultPaths(a.store.CasePath())
+	if rulesPath != "" {
+		paths = []string{rulesPath}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no YARA rules: add .yar files to %s or choose a rule file", yara.CasePath(a.store.CasePath()))
+	}
+	var scanner yara.Scanner
+	if externalBinary != "" {
+		s, err := yara.NewExecScanner(externalBinary, paths...)
+		if err != nil {
+			return nil, err
+		}
+		scanner = s
+	} else {
+		rules, err := yara.Load(paths...)
+		if err != nil {
+			return nil, err
+		}
+		if rules.Len() == 0 {
+			return nil, fmt.Errorf("no YARA rules in %s", strings.Join(paths, ", "))
+		}
+		scanner = rules
+	}
+
+	evidence, err := a.store.QueryEvidence(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	var evidencePaths []string
+	liveSources := make(map[string]bool)
+	host, _ := os.Hostname()
+	for _, loc := range evidence {
+		evidencePaths = append(evidencePaths, loc.Path)
+		if loc.LiveHost != "" && strings.EqualFold(loc.LiveHost, host) && runtime.GOOS == "windows" {
+			liveSources[strings.ToLower(loc.Path)] = true
+		}
+	}
+	targets, err := yara.EvidenceTargets(a.ctx, evidencePaths)
+	if err != nil {
+		return nil, err
+	}
+	referenced, events, err := yara.ReferencedTargets(a.ctx, a.store.ScanTimeline, func(sourcePath string) bool {
+		return liveSources[strings.ToLower(sourcePath)]
+	})
+	if err != nil {
+		return nil, err
+	}
+	targets = append(targets, referenced...)
+
+	hits, stats, err := yara.Scan(a.ctx, scanner, targets, 0, func(current, total int) {
+		wailsRuntime.EventsEmit(a.ctx, "yara:progress", map[string]interface{}{
+			"current": current,
+			"total":   total,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	findings := []model.Finding{}
+	for _, h := range hits {
+		f := h.Finding()
+		f.Attack = attack.Default().Enrich(f.Attack)
+		findings = append(findings, f)
+	}
+	a.pipeline.ApplyAllowlist(findings, events)
+	if err := a.store.SaveFindings(a.ctx, findings); err != nil {
+		return nil, err
+	}
+	a.log("YARA: scanned %d files (%d evidence, %d referenced, %d skipped), %d hits",
+		stats.Scanned, len(targets)-len(referenced), len(referenced), stats.Skipped, len(hits))
+	return findings, nil
+}
+
+// ListNoiseProfiles returns the event log noise-reduction profiles a triage run can select
+// with its "noise_profile" option: the built-in "triage" and "full" profiles and any
+// defined in the global or case noise_profiles.yaml, which are re-read on every call.
+func (a *App) ListNoiseProfiles() ([]noise.Profile, error) {
+	if a.store == nil || a.pipeline == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	profiles, err := noise.LoadForCase(a.store.CasePath())
+	if err != nil {
+		return nil, err
+	}
+	a.pipeline.SetNoiseProfiles(profiles)
+	return profiles, nil
+}
+
+// ListBudgetPolicies returns the event budget policies a triage run can select with its
+// "budget_policy" option: the built-in "balanced", "alerts-first" and "per-host" policies and
+// any defined in the global or case budget_policies.yaml, which are re-read on every call.
+func (a *App) ListBudgetPolicies() ([]budget.Policy, error) {
+	if a.store == nil || a.pipeline == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	policies, err := budget.LoadForCase(a.store.CasePath())
+	if err != nil {
+		return nil, err
+	}
+	a.pipeline.SetBudgetPolicies(policies)
+	return policies, nil
+}
+
+// GetDropSummary returns how many events were dropped during triage and where: by each
+// noise-reduction rule, and by each budget quota per source and host, totalled over the case.
+func (a *App) GetDropSummary() ([]storage.DropSummary, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	records, err := a.store.QueryDrops(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return storage.SummarizeDrops(records), nil
+}
+
 // GetTimeline returns timeline events for the frontend grid.
 func (a *App) GetTimeline(limit int) ([]model.TimelineEvent, error) {
 	if a.store == nil {
@@ -207,6 +406,31 @@ func (a *App) SearchEvents(query string, page int, pageSize int, source string,
 	})
 }
 
+// AggregateEvents counts the events matching a search (query, source and level as in
+// SearchEvents; time ranges go in the query) by time bucket or field value. kind is
+// "histogram" (interval such as "1h", empty for automatic), "terms" (the size most common
+// values of field) or "rare" (the least common ones first). groupBy splits each bucket by
+// host, user, source or any detail key.
+func (a *App) AggregateEvents(kind string, field string, groupBy string, interval string, size int, query string, source string, level string) (*storage.AggregateResult, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	if strings.EqualFold(source, "all") {
+		source = ""
+	}
+	if strings.EqualFold(level, "all") {
+		level = ""
+	}
+	return a.store.Aggregate(a.ctx, storage.AggregateRequest{
+		Kind:     kind,
+		Field:    field,
+		GroupBy:  groupBy,
+		Interval: interval,
+		Size:     size,
+		Filter:   model.TimelineFilter{SearchTerm: query, Source: source, Level: level},
+	})
+}
+
 // GetTotalEventCount returns the number of events in storage
 func (a *App) GetTotalEventCount() (int, error) {
 	if a.store == nil {
@@ -239,12 +463,549 @@ func (a *App) ExecuteSQLQuery(query string) ([]map[string]any, error) {
 	return a.store.ExecuteSQLQuery(a.ctx, query)
 }
 
-// GetFindings returns findings for the dashboard.
+// GetFindings returns findings for the dashboard. Findings suppressed by the allowlist are
+// left out; GetSuppressedFindings lists them. Downgraded findings are included.
 func (a *App) GetFindings() ([]model.Finding, error) {
+	return a.findings(false)
+}
+
+// GetSuppressedFindings returns the findings an allowlist entry suppressed, with the
+// entry and reason, for review.
+func (a *App) GetSuppressedFindings() ([]model.Finding, error) {
+	return a.findings(true)
+}
+
+func (a *App) findings(suppressed bool) ([]model.Finding, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	all, err := a.store.QueryFindings(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := []model.Finding{}
+	for _, f := range all {
+		if (f.Suppression != nil && f.Suppression.Action == allowlist.ActionSuppress) == suppressed {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// GetAllowlist returns the loaded allowlist entries, global ones first.
+func (a *App) GetAllowlist() ([]allowlist.Entry, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	l, err := allowlist.LoadForCase(a.store.CasePath())
+	if err != nil {
+		return nil, err
+	}
+	return l.Entries(), nil
+}
+
+// ReloadAllowlist re-reads the global and case allowlists (<case>/allowlist.yaml) and
+// applies them to subsequent triage and analysis. Returns the number of entries.
+func (a *App) ReloadAllowlist() (int, error) {
+	if a.store == nil || a.pipeline == nil {
+		return 0, fmt.Errorf("case not open")
+	}
+	l, err := allowlist.LoadForCase(a.store.CasePath())
+	if err != nil {
+		a.pipeline.SetAllowlist(nil)
+		return 0, err
+	}
+	a.pipeline.SetAllowlist(l)
+	a.log("Allowlist: %d entries", l.Len())
+	return l.Len(), nil
+}
+
+// AddAnnotation tags, bookmarks, notes or records a verdict on an event or finding.
+// targetType is "event" or "finding"; an empty author defaults to the OS account.
+func (a *App) AddAnnotation(targetType string, targetID string, kind string, value string, author string) (model.Annotation, error) {
+	if a.store == nil {
+		return model.Annotation{}, fmt.Errorf("case not open")
+	}
+	if author == "" {
+		author = currentUser()
+	}
+	return a.store.AddAnnotation(a.ctx, model.Annotation{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Kind:       kind,
+		Value:      value,
+		Author:     author,
+	})
+}
+
+// DeleteAnnotation retracts an annotation; the case keeps the original for audit.
+func (a *App) DeleteAnnotation(id string, author string) error {
+	if a.store == nil {
+		return fmt.Errorf("case not open")
+	}
+	if author == "" {
+		author = currentUser()
+	}
+	return a.store.DeleteAnnotation(a.ctx, id, author)
+}
+
+// GetAnnotations returns the annotations on one event or finding, or all of them when targetType is empty.
+func (a *App) GetAnnotations(targetType string, targetID string) ([]model.Annotation, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	anns, err := a.store.QueryAnnotations(a.ctx, targetType, targetID)
+	if anns == nil {
+		anns = []model.Annotation{}
+	}
+	return anns, err
+}
+
+// GetEventProvenance lists every piece of evidence an event was read from. Duplicate records in
+// overlapping collections are stored once and point back here.
+func (a *App) GetEventProvenance(eventID string) ([]model.EvidenceRef, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	refs, err := a.store.QueryProvenance(a.ctx, eventID)
+	if refs == nil {
+		refs = []model.EvidenceRef{}
+	}
+	return refs, err
+}
+
+// GetHostClocks returns the time zone and clock skew known for each host in the case.
+func (a *App) GetHostClocks() ([]storage.HostClock, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	clocks, err := a.store.QueryHostClocks(a.ctx)
+	if clocks == nil {
+		clocks = []storage.HostClock{}
+	}
+	return clocks, err
+}
+
+// SetHostClockSkew records how many seconds a host's clock ran ahead of true time (negative
+// when behind). Host "*" covers hosts without their own setting. Stored events are unchanged;
+// the correction applies whenever the timeline is read.
+func (a *App) SetHostClockSkew(host string, skewSeconds int, reason string) error {
+	if a.store == nil {
+		return fmt.Errorf("case not open")
+	}
+	return a.store.SetHostSkew(a.ctx, host, time.Duration(skewSeconds)*time.Second, reason, currentUser())
+}
+
+// ListHunts returns the saved searches of every scope: built-in, the global library and,
+// when a case is open, the case library. Case hunts override global ones with the same ID,
+// and global ones override built-ins.
+func (a *App) ListHunts() ([]hunt.Hunt, error) {
+	scopes := [][]hunt.Hunt{hunt.Builtin()}
+	for _, scope := range []string{hunt.ScopeGlobal, hunt.ScopeCase} {
+		if scope == hunt.ScopeCase && a.store == nil {
+			continue
+		}
+		lib, err := a.huntLibrary(scope)
+		if err != nil {
+			return nil, err
+		}
+		list, err := lib.List()
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, list)
+	}
+	hunts := hunt.Merge(scopes...)
+	if hunts == nil {
+		hunts = []hunt.Hunt{}
+	}
+	return hunts, nil
+}
+
+// SaveHunt adds or replaces a saved search in the "case" or "global" library.
+func (a *App) SaveHunt(h hunt.Hunt, scope string) (hunt.Hunt, error) {
+	lib, err := a.huntLibrary(scope)
+	if err != nil {
+		return h, err
+	}
+	if h.Author == "" {
+		h.Author = currentUser()
+	}
+	return lib.Save(h)
+}
+
+// DeleteHunt removes a saved search from the "case" or "global" library.
+func (a *App) DeleteHunt(id string, scope string) error {
+	lib, err := a.huntLibrary(scope)
+	if err != nil {
+		return err
+	}
+	return lib.Delete(id)
+}
+
+// ImportHuntPack merges a YAML hunting pack file into the "case" or "global" library and
+// returns the number of hunts imported.
+func (a *App) ImportHuntPack(path string, scope string) (int, error) {
+	lib, err := a.huntLibrary(scope)
+	if err != nil {
+		return 0, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := lib.Import(data)
+	if err != nil {
+		return 0, err
+	}
+	a.log("Imported %d hunts from %s into the %s library", n, path, scope)
+	return n, nil
+}
+
+// ExportHuntPack writes the given hunts (all when ids is empty) as a YAML hunting pack to
+// <case>/data/hunting_pack.yaml.
+func (a *App) ExportHuntPack(ids []string) (string, error) {
+	if a.store == nil {
+		return "", fmt.Errorf("case not open")
+	}
+	all, err := a.ListHunts()
+	if err != nil {
+		return "", err
+	}
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	var hunts []hunt.Hunt
+	for _, h := range all {
+		if len(ids) == 0 || want[h.ID] {
+			hunts = append(hunts, h)
+		}
+	}
+	data, err := hunt.Export(filepath.Base(a.store.CasePath())+" hunts", hunts)
+	if err != nil {
+		return "", err
+	}
+	outPath := filepath.Join(a.store.CasePath(), "data", "hunting_pack.yaml")
+	if err := os.WriteFile(outPath, data, 0o644); err != nil {
+		return "", err
+	}
+	a.log("Exported %d hunts to %s", len(hunts), outPath)
+	return outPath, nil
+}
+
+// RunHunts executes every saved search against the timeline and records a finding, with
+// the hit count and sample events, for each hunt that matched. Findings of earlier runs
+// of hunts that no longer match are retracted.
+func (a *App) RunHunts() ([]hunt.Result, error) {
 	if a.store == nil {
 		return nil, fmt.Errorf("case not open")
 	}
-	return a.store.QueryFindings(a.ctx)
+	hunts, err := a.ListHunts()
+	if err != nil {
+		return nil, err
+	}
+	results, err := hunt.Run(a.ctx, hunts, a.store.ScanTimeline)
+	if err != nil {
+		return nil, err
+	}
+	var findings []model.Finding
+	var stale []string
+	for _, r := range results {
+		if r.Hits == 0 {
+			stale = append(stale, r.FindingID())
+			continue
+		}
+		f := r.Finding()
+		f.Attack = attack.Default().Enrich(f.Attack)
+		findings = append(findings, f)
+	}
+	if err := a.store.RetractFindings(a.ctx, stale); err != nil {
+		return nil, err
+	}
+	if err := a.store.SaveFindings(a.ctx, findings); err != nil {
+		return nil, err
+	}
+	a.log("Ran %d hunts: %d matched", len(results), len(findings))
+	return results, nil
+}
+
+func (a *App) huntLibrary(scope string) (*hunt.Library, error) {
+	switch scope {
+	case hunt.ScopeCase:
+		if a.store == nil {
+			return nil, fmt.Errorf("case not open")
+		}
+		return hunt.OpenLibrary(hunt.CasePath(a.store.CasePath()), scope), nil
+	case hunt.ScopeGlobal:
+		path, err := hunt.GlobalPath()
+		if err != nil {
+			return nil, err
+		}
+		return hunt.OpenLibrary(path, scope), nil
+	}
+	return nil, fmt.Errorf("unknown hunt library %q", scope)
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// GetProcessLineage returns the ancestry and descendants of the process behind an event,
+// e.g. what spawned a given whoami and what it spawned in turn.
+func (a *App) GetProcessLineage(eventID string) (*analysis.ProcessLineage, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	var target *model.TimelineEvent
+	tree, err := a.loadProcessTree(func(ev model.TimelineEvent) {
+		if ev.ID == eventID {
+			target = &ev
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, fmt.Errorf("event %s not found", eventID)
+	}
+	return tree.Lineage(*target)
+}
+
+// GetProcessTrees returns the reconstructed process trees that contain Sigma or IOC hits.
+func (a *App) GetProcessTrees() ([]*analysis.ProcessNode, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	tree, err := a.loadProcessTree(nil)
+	if err != nil {
+		return nil, err
+	}
+	roots := tree.HitTrees()
+	if roots == nil {
+		roots = []*analysis.ProcessNode{}
+	}
+	return roots, nil
+}
+
+// loadProcessTree builds the process tree from every stored process event; visit, if set,
+// sees every event in the timeline.
+func (a *App) loadProcessTree(visit func(ev model.TimelineEvent)) (*analysis.ProcessTree, error) {
+	var events []model.TimelineEvent
+	err := a.store.ScanTimeline(a.ctx, func(ev model.TimelineEvent) error {
+		if visit != nil {
+			visit(ev)
+		}
+		if analysis.IsProcessEvent(ev) {
+			events = append(events, ev)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return analysis.BuildProcessTree(events), nil
+}
+
+// GetLogonSessions returns logon sessions reconstructed from the case timeline.
+func (a *App) GetLogonSessions() ([]*analyzers.LogonSession, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	sessions, err := a.loadLogonSessions()
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		sessions = []*analyzers.LogonSession{}
+	}
+	return sessions, nil
+}
+
+// ExportLateralMovementGraph writes the cross-host authentication graph under the case data
+// directory as "json" or "graphml" and returns the file path.
+func (a *App) ExportLateralMovementGraph(format string) (string, error) {
+	if a.store == nil {
+		return "", fmt.Errorf("case not open")
+	}
+	format = strings.ToLower(format)
+	if format != "json" && format != "graphml" {
+		return "", fmt.Errorf("unsupported graph format %q", format)
+	}
+	sessions, err := a.loadLogonSessions()
+	if err != nil {
+		return "", err
+	}
+	graph := analyzers.BuildLateralGraph(sessions)
+
+	outPath := filepath.Join(a.store.CasePath(), "data", "lateral_movement."+format)
+	f, err := os.Create(outPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if format == "graphml" {
+		err = graph.WriteGraphML(f)
+	} else {
+		err = graph.WriteJSON(f)
+	}
+	if err != nil {
+		return "", err
+	}
+	return outPath, f.Close()
+}
+
+func (a *App) loadLogonSessions() ([]*analyzers.LogonSession, error) {
+	var events []model.TimelineEvent
+	err := a.store.ScanTimeline(a.ctx, func(ev model.TimelineEvent) error {
+		if ev.Details["EventID"] != "" {
+			events = append(events, ev)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return analyzers.BuildLogonSessions(events), nil
+}
+
+// ExportTimeline streams the case timeline to a bodyfile, l2t_csv, Timesketch JSONL, or ECS /
+// OCSF NDJSON file under the case data directory and returns its path. start and end are RFC 3339 and may be
+// empty; empty sources/hosts export everything.
+func (a *App) ExportTimeline(format string, start string, end string, sources []string, hosts []string) (string, error) {
+	if a.store == nil {
+		return "", fmt.Errorf("case not open")
+	}
+	ext, ok := report.ExportFormats[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported export format %q", format)
+	}
+	filter := report.ExportFilter{Sources: sources, Hosts: hosts}
+	for _, bound := range []struct {
+		value string
+		dst   **time.Time
+	}{{start, &filter.TimeStart}, {end, &filter.TimeEnd}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			return "", fmt.Errorf("invalid time %q: %w", bound.value, err)
+		}
+		*bound.dst = &t
+	}
+
+	outPath := filepath.Join(a.store.CasePath(), "data", "timeline"+ext)
+	f, err := os.Create(outPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var n int
+	if format == report.FormatECS || format == report.FormatOCSF {
+		// Include the field mappings registered parsers declare in their manifests.
+		table, terr := normalize.NewTable(a.registry.Parsers())
+		if terr != nil {
+			return "", terr
+		}
+		n, err = report.ExportNormalized(a.ctx, a.store, table, format, f, filter)
+	} else {
+		n, err = report.ExportTimeline(a.ctx, a.store, format, f, filter)
+	}
+	if err != nil {
+		return "", err
+	}
+	a.log("Exported %d events to %s", n, outPath)
+	return outPath, f.Close()
+}
+
+// GetAttackMatrix returns the case's Sigma alerts and findings as a tactic × technique matrix.
+func (a *App) GetAttackMatrix() (*attack.Matrix, error) {
+	if a.store == nil {
+		return nil, fmt.Errorf("case not open")
+	}
+	counter := attack.NewCounter(attack.Default())
+	if err := a.store.ScanTimeline(a.ctx, func(ev model.TimelineEvent) error {
+		counter.AddEvent(ev)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	findings, err := a.findings(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range findings {
+		counter.AddFinding(f)
+	}
+	return counter.Matrix(), nil
+}
+
+// ExportAttackNavigatorLayer writes the ATT&CK matrix as a Navigator layer to <case>/data/attack_navigator.json.
+func (a *App) ExportAttackNavigatorLayer() (string, error) {
+	m, err := a.GetAttackMatrix()
+	if err != nil {
+		return "", err
+	}
+	casePath := a.store.CasePath()
+	name := filepath.Base(casePath)
+	data, err := attack.NavigatorLayer(m, name, fmt.Sprintf("gtrace case %s: %d Sigma alerts, %d findings", name, m.Alerts, m.Findings))
+	if err != nil {
+		return "", err
+	}
+	outPath := filepath.Join(casePath, "data", "attack_navigator.json")
+	if err := os.WriteFile(outPath, data, 0o644); err != nil {
+		return "", err
+	}
+	a.log("ATT&CK Navigator layer written to %s", outPath)
+	return outPath, nil
+}
+
+// GenerateHTMLReport writes a self-contained HTML report to <case>/data/report.html.
+// templatePath overrides the layout; when empty, <case>/templates/report.html.tmpl is used if present.
+func (a *App) GenerateHTMLReport(title string, examiner string, templatePath string) (string, error) {
+	if a.store == nil {
+		return "", fmt.Errorf("case not open")
+	}
+	casePath := a.store.CasePath()
+	if templatePath == "" {
+		override := filepath.Join(casePath, "templates", report.TemplateOverrideName)
+		if _, err := os.Stat(override); err == nil {
+			templatePath = override
+		}
+	}
+	tmpl, err := report.LoadTemplate(templatePath)
+	if err != nil {
+		return "", err
+	}
+	findings, err := a.findings(false)
+	if err != nil {
+		return "", err
+	}
+	evidence, err := a.store.QueryEvidence(a.ctx)
+	if err != nil {
+		return "", err
+	}
+	info := report.CaseInfo{Title: title, Name: filepath.Base(casePath), Path: casePath, Examiner: examiner}
+	rep, err := report.BuildHTMLReport(a.ctx, a.store, findings, evidence, info)
+	if err != nil {
+		return "", err
+	}
+
+	outPath := filepath.Join(casePath, "data", "report.html")
+	f, err := os.Create(outPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := report.RenderHTML(f, rep, tmpl); err != nil {
+		return "", fmt.Errorf("render report: %w", err)
+	}
+	a.log("HTML report written to %s", outPath)
+	return outPath, f.Close()
 }
 
 // RunSelfTest simulates a "whoami.exe" execution to verify Sigma rules are working.
@@ -0,0 +1,364 @@
+// Package attack resolves MITRE ATT&CK technique and tactic IDs against an embedded copy of the
+// enterprise STIX data and aggregates hits into a tactic × technique matrix.
+package attack
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gtrace/pkg/model"
+)
+
+// enterprise-attack.json is a STIX 2.1 bundle in the upstream attack-stix-data layout, trimmed to
+// the tactics and the techniques the bundled Sigma rules, hunts and parsers reference. gensubset.go
+// writes it from an upstream release, keeping the upstream STIX IDs; the release's ATT&CK version
+// is the collection's x_mitre_version (Catalog.Version). The full upstream file placed at
+// GlobalPath replaces it.
+//
+//go:embed enterprise-attack.json
+var enterpriseBundle []byte
+
+type Tactic struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ShortName string `json:"short_name"`
+}
+
+type Technique struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Tactics      []string `json:"tactics"` // tactic short names, in matrix order
+	Subtechnique bool     `json:"subtechnique,omitempty"`
+	URL          string   `json:"url,omitempty"`
+}
+
+// ParentID returns the technique a sub-technique belongs to ("T1003" for "T1003.001").
+func (t Technique) ParentID() string {
+	if i := strings.IndexByte(t.ID, '.'); i > 0 {
+		return t.ID[:i]
+	}
+	return ""
+}
+
+// Catalog is a parsed ATT&CK domain.
+type Catalog struct {
+	Version    string
+	tactics    []Tactic
+	byShort    map[string]int
+	techniques map[string]Technique
+}
+
+type stixBundle struct {
+	Objects []stixObject `json:"objects"`
+}
+
+type stixObject struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	ShortName  string   `json:"x_mitre_shortname"`
+	Version    string   `json:"x_mitre_version"`
+	Revoked    bool     `json:"revoked"`
+	Deprecated bool     `json:"x_mitre_deprecated"`
+	Sub        bool     `json:"x_mitre_is_subtechnique"`
+	TacticRefs []string `json:"tactic_refs"`
+	Phases     []struct {
+		KillChain string `json:"kill_chain_name"`
+		Phase     string `json:"phase_name"`
+	} `json:"kill_chain_phases"`
+	Refs []struct {
+		Source     string `json:"source_name"`
+		ExternalID string `json:"external_id"`
+		URL        string `json:"url"`
+	} `json:"external_references"`
+}
+
+func (o stixObject) attackRef() (id, url string) {
+	for _, r := range o.Refs {
+		if r.Source == "mitre-attack" {
+			return r.ExternalID, r.URL
+		}
+	}
+	return "", ""
+}
+
+// Parse reads a STIX 2.x ATT&CK bundle. Revoked and deprecated objects are skipped.
+func Parse(data []byte) (*Catalog, error) {
+	var b stixBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parse ATT&CK bundle: %w", err)
+	}
+	c := &Catalog{byShort: make(map[string]int), techniques: make(map[string]Technique)}
+
+	tactics := make(map[string]Tactic) // STIX id → tactic
+	var order []string
+	for _, o := range b.Objects {
+		if o.Revoked || o.Deprecated {
+			continue
+		}
+		switch o.Type {
+		case "x-mitre-collection":
+			c.Version = o.Version
+		case "x-mitre-matrix":
+			if order == nil {
+				order = o.TacticRefs
+			}
+		case "x-mitre-tactic":
+			id, _ := o.attackRef()
+			tactics[o.ID] = Tactic{ID: id, Name: o.Name, ShortName: o.ShortName}
+		}
+	}
+	if len(tactics) == 0 {
+		return nil, fmt.Errorf("parse ATT&CK bundle: no tactics")
+	}
+	for _, ref := range order {
+		if t, ok := tactics[ref]; ok {
+			c.byShort[t.ShortName] = len(c.tactics)
+			c.tactics = append(c.tactics, t)
+		}
+	}
+	for _, t := range tactics {
+		if _, ok := c.byShort[t.ShortName]; !ok {
+			c.byShort[t.ShortName] = len(c.tactics)
+			c.tactics = append(c.tactics, t)
+		}
+	}
+
+	for _, o := range b.Objects {
+		if o.Type != "attack-pattern" || o.Revoked || o.Deprecated {
+			continue
+		}
+		id, url := o.attackRef()
+		if id == "" {
+			continue
+		}
+		t := Technique{ID: id, Name: o.Name, Subtechnique: o.Sub, URL: url}
+		for _, p := range o.Phases {
+			if p.KillChain == "mitre-attack" {
+				t.Tactics = append(t.Tactics, p.Phase)
+			}
+		}
+		c.sortTactics(t.Tactics)
+		c.techniques[id] = t
+	}
+	return c, nil
+}
+
+// LoadFile parses an ATT&CK bundle from disk, e.g. the upstream enterprise-attack.json.
+func LoadFile(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// GlobalPath is where a full upstream enterprise-attack.json may be installed for the current
+// user; Default prefers it to the embedded subset.
+func GlobalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gtrace", "enterprise-attack.json"), nil
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultCatalog *Catalog
+)
+
+// Default returns the catalog parsed from the bundle at GlobalPath, or from the embedded
+// bundle when none is installed or it cannot be parsed.
+func Default() *Catalog {
+	defaultOnce.Do(func() {
+		path, _ := GlobalPath()
+		defaultCatalog = loadDefault(path)
+	})
+	return defaultCatalog
+}
+
+func loadDefault(path string) *Catalog {
+	if path != "" {
+		c, err := LoadFile(path)
+		if err == nil {
+			return c
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("ATT&CK bundle %s: %v; using the embedded subset", path, err)
+		}
+	}
+	c, err := Parse(enterpriseBundle)
+	if err != nil {
+		panic(err) // the embedded bundle is covered by tests
+	}
+	return c
+}
+
+// Tactics returns the tactics in matrix (kill-chain) order.
+func (c *Catalog) Tactics() []Tactic {
+	return c.tactics
+}
+
+// Tactic looks a tactic up by short name ("lateral-movement"); Sigma's "lateral_movement" works too.
+func (c *Catalog) Tactic(short string) (Tactic, bool) {
+	i, ok := c.byShort[normalizeShort(short)]
+	if !ok {
+		return Tactic{}, false
+	}
+	return c.tactics[i], true
+}
+
+// Technique looks a technique or sub-technique up by ID, case-insensitively.
+func (c *Catalog) Technique(id string) (Technique, bool) {
+	t, ok := c.techniques[strings.ToUpper(strings.TrimSpace(id))]
+	return t, ok
+}
+
+func (c *Catalog) sortTactics(shorts []string) {
+	for i := 1; i < len(shorts); i++ {
+		for j := i; j > 0 && c.tacticIndex(shorts[j]) < c.tacticIndex(shorts[j-1]); j-- {
+			shorts[j], shorts[j-1] = shorts[j-1], shorts[j]
+		}
+	}
+}
+
+func (c *Catalog) tacticIndex(short string) int {
+	if i, ok := c.byShort[short]; ok {
+		return i
+	}
+	return len(c.tactics)
+}
+
+func normalizeShort(s string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "_", "-")
+}
+
+// Resolve builds a reference for a technique. Tactic hints (short names) narrow a multi-tactic
+// technique to the tactics the detection was written for; hints that don't apply are ignored.
+func (c *Catalog) Resolve(techniqueID string, tacticHints ...string) model.AttackRef {
+	id := strings.ToUpper(strings.TrimSpace(techniqueID))
+	ref := model.AttackRef{TechniqueID: id}
+	t, known := c.techniques[id]
+	if known {
+		ref.Technique = t.Name
+	}
+	if p := (Technique{ID: id}).ParentID(); p != "" {
+		ref.ParentID = p
+		if parent, ok := c.techniques[p]; ok {
+			ref.Parent = parent.Name
+			if !known {
+				t.Tactics = parent.Tactics
+			}
+		}
+	}
+
+	var tactics []string
+	for _, h := range tacticHints {
+		h = normalizeShort(h)
+		if containsString(t.Tactics, h) || (len(t.Tactics) == 0 && c.hasTactic(h)) {
+			tactics = append(tactics, h)
+		}
+	}
+	if len(tactics) == 0 {
+		tactics = append(tactics, t.Tactics...)
+	}
+	c.sortTactics(tactics)
+	for _, short := range tactics {
+		if tac, ok := c.Tactic(short); ok && !containsString(ref.TacticIDs, tac.ID) {
+			ref.TacticIDs = append(ref.TacticIDs, tac.ID)
+			ref.Tactics = append(ref.Tactics, tac.Name)
+		}
+	}
+	return ref
+}
+
+func (c *Catalog) hasTactic(short string) bool {
+	_, ok := c.byShort[short]
+	return ok
+}
+
+var techniqueTagRe = regexp.MustCompile(`^t\d{4}(\.\d{3})?$`)
+
+// FromTags resolves Sigma rule tags ("attack.discovery", "attack.t1033") into references.
+// Tactic-only tags narrow the rule's techniques and are otherwise dropped.
+func (c *Catalog) FromTags(tags []string) []model.AttackRef {
+	var techniques, tactics []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		v, ok := strings.CutPrefix(tag, "attack.")
+		if !ok {
+			continue
+		}
+		if techniqueTagRe.MatchString(v) {
+			if !containsString(techniques, v) {
+				techniques = append(techniques, v)
+			}
+		} else if c.hasTactic(normalizeShort(v)) {
+			tactics = append(tactics, v)
+		}
+	}
+	var refs []model.AttackRef
+	for _, id := range techniques {
+		refs = append(refs, c.Resolve(id, tactics...))
+	}
+	return refs
+}
+
+// FromTagString is FromTags for the comma-joined "_Mitre" detail the pipeline stores on alerts.
+func (c *Catalog) FromTagString(tags string) []model.AttackRef {
+	if tags == "" {
+		return nil
+	}
+	return c.FromTags(strings.Split(tags, ","))
+}
+
+// Enrich fills in names and tactics for references that only carry IDs, keeping any tactics set.
+func (c *Catalog) Enrich(refs []model.AttackRef) []model.AttackRef {
+	for i, r := range refs {
+		var hints []string
+		for _, id := range r.TacticIDs {
+			for _, t := range c.tactics {
+				if t.ID == id {
+					hints = append(hints, t.ShortName)
+				}
+			}
+		}
+		for _, name := range r.Tactics {
+			for _, t := range c.tactics {
+				if strings.EqualFold(t.Name, name) {
+					hints = append(hints, t.ShortName)
+				}
+			}
+		}
+		refs[i] = c.Resolve(r.TechniqueID, hints...)
+	}
+	return refs
+}
+
+// EventRefs returns the ATT&CK references of an event, falling back to its Sigma tags for
+// events stored before references were resolved at ingest.
+func (c *Catalog) EventRefs(ev model.TimelineEvent) []model.AttackRef {
+	if len(ev.Attack) > 0 {
+		return ev.Attack
+	}
+	return c.FromTagString(ev.Details["_Mitre"])
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
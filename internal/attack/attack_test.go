@@ -0,0 +1,132 @@
+package attack
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gtrace/internal/hunt"
+	"gtrace/pkg/model"
+)
+
+func TestCatalog(t *testing.T) {
+	c := Default()
+	if len(c.Tactics()) != 14 || c.Tactics()[0].ID != "TA0043" || c.Version == "" {
+		t.Fatalf("tactics = %v, version %q", c.Tactics(), c.Version)
+	}
+	if tech, ok := c.Technique("t1003.001"); !ok || tech.Name != "LSASS Memory" || !tech.Subtechnique || tech.ParentID() != "T1003" {
+		t.Errorf("T1003.001 = %+v", tech)
+	}
+
+	// T1078 spans four tactics; the rule's own tactic tag narrows it.
+	refs := c.FromTags([]string{"attack.defense_evasion", "attack.t1078.003", "attack.t1033", "car.2013-05-004"})
+	if len(refs) != 2 {
+		t.Fatalf("refs = %+v", refs)
+	}
+	if r := refs[0]; r.Technique != "Local Accounts" || r.Parent != "Valid Accounts" || len(r.TacticIDs) != 1 || r.TacticIDs[0] != "TA0005" {
+		t.Errorf("T1078.003 = %+v", r)
+	}
+	// A hint that doesn't apply falls back to the technique's own tactics.
+	if r := refs[1]; r.Technique != "System Owner/User Discovery" || r.Tactics[0] != "Discovery" {
+		t.Errorf("T1033 = %+v", r)
+	}
+
+	enriched := c.Enrich([]model.AttackRef{{TechniqueID: "T1021.001", TacticIDs: []string{"TA0008"}}})
+	if r := enriched[0]; r.Technique != "Remote Desktop Protocol" || r.Tactics[0] != "Lateral Movement" {
+		t.Errorf("enriched = %+v", r)
+	}
+}
+
+func TestMatrixAndNavigator(t *testing.T) {
+	c := Default()
+	counter := NewCounter(c)
+	alert := func(tags string) model.TimelineEvent {
+		return model.TimelineEvent{Details: map[string]string{"_Alert": "x", "_Mitre": tags}}
+	}
+	counter.AddEvent(alert("attack.credential_access, attack.t1003.001"))
+	counter.AddEvent(alert("attack.credential_access, attack.t1003.001"))
+	counter.AddEvent(alert("attack.credential_access, attack.t1003"))
+	counter.AddEvent(model.TimelineEvent{Details: map[string]string{"_Mitre": "attack.t1033"}}) // not an alert
+	counter.AddFinding(model.Finding{Attack: []model.AttackRef{{TechniqueID: "T1021.001", TacticIDs: []string{"TA0008"}}}})
+
+	m := counter.Matrix()
+	if m.Alerts != 3 || m.Findings != 1 || len(m.Tactics) != 2 {
+		t.Fatalf("matrix = %+v", m)
+	}
+	cred := m.Tactics[0]
+	if cred.ShortName != "credential-access" || cred.Hits != 3 {
+		t.Errorf("credential access = %+v", cred)
+	}
+	if cell := cred.Techniques[0]; cell.ID != "T1003" || cell.Hits != 3 || len(cell.Subtechniques) != 1 || cell.Subtechniques[0].Hits != 2 {
+		t.Errorf("T1003 cell = %+v", cell)
+	}
+	if lm := m.Tactics[1]; lm.Name != "Lateral Movement" || lm.Techniques[0].Findings != 1 {
+		t.Errorf("lateral movement = %+v", lm)
+	}
+
+	data, err := NavigatorLayer(m, "case", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var layer struct {
+		Domain     string `json:"domain"`
+		Versions   map[string]string
+		Techniques []struct {
+			TechniqueID string `json:"techniqueID"`
+			Tactic      string `json:"tactic"`
+			Score       int    `json:"score"`
+		} `json:"techniques"`
+	}
+	if err := json.Unmarshal(data, &layer); err != nil {
+		t.Fatal(err)
+	}
+	if layer.Domain != "enterprise-attack" || layer.Versions["attack"] != "18" || len(layer.Techniques) != 4 {
+		t.Fatalf("layer = %+v", layer)
+	}
+	if tech := layer.Techniques[1]; tech.TechniqueID != "T1003.001" || tech.Tactic != "credential-access" || tech.Score != 2 {
+		t.Errorf("layer technique = %+v", tech)
+	}
+}
+
+func TestLoadDefaultOverride(t *testing.T) {
+	dir := t.TempDir()
+	embedded := loadDefault(filepath.Join(dir, "missing.json"))
+	if _, ok := embedded.Technique("T1110.003"); !ok {
+		t.Error("embedded bundle should contain T1110.003")
+	}
+
+	full := filepath.Join(dir, "enterprise-attack.json")
+	bundle := `{"type":"bundle","objects":[
+{"type":"x-mitre-collection","x_mitre_version":"99.0"},
+{"type":"x-mitre-tactic","id":"x-mitre-tactic--1","name":"Impact","x_mitre_shortname":"impact","external_references":[{"source_name":"mitre-attack","external_id":"TA0040"}]},
+{"type":"attack-pattern","id":"attack-pattern--1","name":"Data Destruction","kill_chain_phases":[{"kill_chain_name":"mitre-attack","phase_name":"impact"}],"external_references":[{"source_name":"mitre-attack","external_id":"T1485"}]}]}`
+	if err := os.WriteFile(full, []byte(bundle), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if c := loadDefault(full); c.Version != "99.0" || len(c.Tactics()) != 1 {
+		t.Errorf("installed bundle not used: version %q, %d tactics", c.Version, len(c.Tactics()))
+	}
+
+	if err := os.WriteFile(full, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if c := loadDefault(full); c.Version != embedded.Version {
+		t.Errorf("unparsable bundle should fall back to the embedded one, got version %q", c.Version)
+	}
+}
+
+// The embedded subset covers every technique the built-in hunts tag.
+func TestBuiltinHuntTechniquesKnown(t *testing.T) {
+	c, err := Parse(enterpriseBundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hunt.Builtin() {
+		for _, id := range h.Attack {
+			if _, ok := c.Technique(id); !ok {
+				t.Errorf("hunt %s: technique %s missing from the ATT&CK bundle", h.ID, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+//go:build ignore
+
+// gensubset writes enterprise-attack.json: the upstream enterprise bundle trimmed to its
+// collection, matrix and tactics plus the techniques the repository references. Objects keep
+// their upstream STIX IDs; only fields the catalog does not read are dropped.
+//
+// Run from the repository root with the upstream file from mitre-attack/attack-stix-data:
+//
+//	go run ./internal/attack/gensubset.go -upstream enterprise-attack-18.1.json
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sources are scanned for technique IDs: Sigma rule tags, hunt packs and the Go code of parsers
+// and analyzers that attach ATT&CK references to findings.
+var sources = []string{"internal/rules/sigma_rules_repo", "internal/hunt", "internal/plugin", "pkg/analyzers"}
+
+var techniqueRe = regexp.MustCompile(`(?i)\bt\d{4}(?:\.\d{3})?\b`)
+
+// keep lists the fields copied from each upstream object; the rest (descriptions, detection
+// notes, platforms) are not read by the catalog.
+var keep = []string{
+	"type", "spec_version", "id", "created", "modified", "name", "x_mitre_version",
+	"x_mitre_shortname", "tactic_refs", "kill_chain_phases", "external_references",
+	"x_mitre_is_subtechnique", "x_mitre_domains", "revoked", "x_mitre_deprecated",
+}
+
+func main() {
+	upstream := flag.String("upstream", "", "upstream enterprise-attack.json")
+	out := flag.String("out", "internal/attack/enterprise-attack.json", "subset to write")
+	flag.Parse()
+	if *upstream == "" {
+		log.Fatal("-upstream is required")
+	}
+
+	wanted, err := referencedTechniques()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := os.ReadFile(*upstream)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var bundle struct {
+		ID      string                       `json:"id"`
+		Objects []map[string]json.RawMessage `json:"objects"`
+	}
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		log.Fatalf("parse %s: %v", *upstream, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `{"type":"bundle","id":%q,"objects":[`, bundle.ID)
+	version, n, found := "", 0, make(map[string]bool)
+	for _, o := range bundle.Objects {
+		var typ string
+		json.Unmarshal(o["type"], &typ)
+		if isTrue(o["revoked"]) || isTrue(o["x_mitre_deprecated"]) {
+			continue
+		}
+		switch typ {
+		case "x-mitre-collection":
+			json.Unmarshal(o["x_mitre_version"], &version)
+		case "x-mitre-matrix", "x-mitre-tactic":
+		case "attack-pattern":
+			id := attackID(o)
+			if !wanted[id] {
+				continue
+			}
+			found[id] = true
+		default:
+			continue
+		}
+		line, err := trim(o)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString("\n")
+		buf.Write(line)
+		n++
+	}
+	buf.WriteString("\n]}\n")
+	if version == "" {
+		log.Fatalf("%s: no x-mitre-collection version; is this the enterprise bundle?", *upstream)
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	var missing []string
+	for id := range wanted {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	fmt.Printf("wrote %s: ATT&CK v%s, %d techniques\n", *out, version, len(found))
+	if len(missing) > 0 {
+		fmt.Printf("not in this ATT&CK version (revoked or unknown): %s\n", strings.Join(missing, " "))
+	}
+}
+
+// referencedTechniques collects technique IDs from the sources, adding the parent of each
+// sub-technique so names resolve for both.
+func referencedTechniques() (map[string]bool, error) {
+	ids := make(map[string]bool)
+	for _, root := range sources {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".yml", ".yaml", ".go":
+			default:
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, m := range techniqueRe.FindAll(data, -1) {
+				id := strings.ToUpper(string(m))
+				ids[id] = true
+				if i := strings.IndexByte(id, '.'); i > 0 {
+					ids[id[:i]] = true
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func attackID(o map[string]json.RawMessage) string {
+	var refs []struct {
+		Source     string `json:"source_name"`
+		ExternalID string `json:"external_id"`
+	}
+	json.Unmarshal(o["external_references"], &refs)
+	for _, r := range refs {
+		if r.Source == "mitre-attack" {
+			return r.ExternalID
+		}
+	}
+	return ""
+}
+
+// trim keeps the catalog's fields, and of the external references only the ATT&CK one.
+func trim(o map[string]json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for _, k := range keep {
+		v, ok := o[k]
+		if !ok {
+			continue
+		}
+		if k == "external_references" {
+			var refs []map[string]json.RawMessage
+			if err := json.Unmarshal(v, &refs); err != nil {
+				return nil, err
+			}
+			var attack []map[string]json.RawMessage
+			for _, r := range refs {
+				if string(r["source_name"]) == `"mitre-attack"` {
+					attack = append(attack, r)
+				}
+			}
+			var err error
+			if v, err = json.Marshal(attack); err != nil {
+				return nil, err
+			}
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		fmt.Fprintf(&buf, "%q:", k)
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func isTrue(v json.RawMessage) bool {
+	return string(v) == "true"
+}
@@ -0,0 +1,242 @@
+package attack
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gtrace/pkg/model"
+)
+
+// Matrix is the tactic × technique view of a case. Only techniques with hits are listed;
+// sub-technique hits also count toward their parent technique.
+type Matrix struct {
+	Version  string         `json:"version"`
+	Alerts   int            `json:"alerts"`
+	Findings int            `json:"findings"`
+	Tactics  []MatrixTactic `json:"tactics"`
+}
+
+type MatrixTactic struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	ShortName  string       `json:"short_name"`
+	Hits       int          `json:"hits"`
+	Techniques []MatrixCell `json:"techniques"`
+}
+
+type MatrixCell struct {
+	ID            string       `json:"id"`
+	Name          string       `json:"name"`
+	Hits          int          `json:"hits"`
+	Alerts        int          `json:"alerts"`
+	Findings      int          `json:"findings"`
+	Subtechniques []MatrixCell `json:"subtechniques,omitempty"`
+}
+
+type cellKey struct{ tactic, technique string }
+
+// Counter accumulates alert and finding references into a Matrix.
+type Counter struct {
+	catalog  *Catalog
+	cells    map[cellKey]*MatrixCell
+	alerts   int
+	findings int
+}
+
+func NewCounter(c *Catalog) *Counter {
+	return &Counter{catalog: c, cells: make(map[cellKey]*MatrixCell)}
+}
+
+// AddEvent counts a Sigma-matched event; events without an alert are ignored.
+func (m *Counter) AddEvent(ev model.TimelineEvent) {
+	if ev.Details["_Alert"] == "" {
+		return
+	}
+	m.alerts++
+	m.add(m.catalog.EventRefs(ev), true)
+}
+
+// AddFinding counts an analyzer finding.
+func (m *Counter) AddFinding(f model.Finding) {
+	m.findings++
+	m.add(m.catalog.Enrich(append([]model.AttackRef(nil), f.Attack...)), false)
+}
+
+func (m *Counter) add(refs []model.AttackRef, alert bool) {
+	for _, r := range refs {
+		for _, tid := range r.TacticIDs {
+			tac, ok := m.tacticByID(tid)
+			if !ok {
+				continue
+			}
+			cell := m.cell(tac.ShortName, r.TechniqueID, r.Technique)
+			if alert {
+				cell.Alerts++
+			} else {
+				cell.Findings++
+			}
+			cell.Hits++
+		}
+	}
+}
+
+func (m *Counter) tacticByID(id string) (Tactic, bool) {
+	for _, t := range m.catalog.tactics {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Tactic{}, false
+}
+
+func (m *Counter) cell(tactic, id, name string) *MatrixCell {
+	k := cellKey{tactic, id}
+	c := m.cells[k]
+	if c == nil {
+		if name == "" {
+			name = id
+		}
+		c = &MatrixCell{ID: id, Name: name}
+		m.cells[k] = c
+	}
+	return c
+}
+
+// Matrix returns the accumulated counts in kill-chain order, busiest techniques first.
+func (m *Counter) Matrix() *Matrix {
+	out := &Matrix{Version: m.catalog.Version, Alerts: m.alerts, Findings: m.findings, Tactics: []MatrixTactic{}}
+	for _, tac := range m.catalog.tactics {
+		parents := make(map[string]*MatrixCell)
+		var subs []*MatrixCell
+		for k, c := range m.cells {
+			if k.tactic != tac.ShortName {
+				continue
+			}
+			if p := (Technique{ID: c.ID}).ParentID(); p != "" {
+				subs = append(subs, c)
+				continue
+			}
+			cp := *c
+			parents[c.ID] = &cp
+		}
+		sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+		for _, s := range subs {
+			pid := (Technique{ID: s.ID}).ParentID()
+			p := parents[pid]
+			if p == nil {
+				name := pid
+				if t, ok := m.catalog.techniques[pid]; ok {
+					name = t.Name
+				}
+				p = &MatrixCell{ID: pid, Name: name}
+				parents[pid] = p
+			}
+			p.Subtechniques = append(p.Subtechniques, *s)
+			p.Hits += s.Hits
+			p.Alerts += s.Alerts
+			p.Findings += s.Findings
+		}
+		if len(parents) == 0 {
+			continue
+		}
+
+		mt := MatrixTactic{ID: tac.ID, Name: tac.Name, ShortName: tac.ShortName}
+		for _, p := range parents {
+			mt.Techniques = append(mt.Techniques, *p)
+			mt.Hits += p.Hits
+		}
+		sort.Slice(mt.Techniques, func(i, j int) bool {
+			a, b := mt.Techniques[i], mt.Techniques[j]
+			if a.Hits != b.Hits {
+				return a.Hits > b.Hits
+			}
+			return a.ID < b.ID
+		})
+		out.Tactics = append(out.Tactics, mt)
+	}
+	return out
+}
+
+// Navigator layer versions written by NavigatorLayer.
+const (
+	navigatorVersion = "4.9.1"
+	layerVersion     = "4.5"
+)
+
+type navigatorLayer struct {
+	Name                          string               `json:"name"`
+	Versions                      map[string]string    `json:"versions"`
+	Domain                        string               `json:"domain"`
+	Description                   string               `json:"description"`
+	Sorting                       int                  `json:"sorting"`
+	Layout                        map[string]any       `json:"layout"`
+	HideDisabled                  bool                 `json:"hideDisabled"`
+	Techniques                    []navigatorTechnique `json:"techniques"`
+	Gradient                      map[string]any       `json:"gradient"`
+	LegendItems                   []any                `json:"legendItems"`
+	Metadata                      []map[string]string  `json:"metadata"`
+	ShowTacticRowBackground       bool                 `json:"showTacticRowBackground"`
+	SelectTechniquesAcrossTactics bool                 `json:"selectTechniquesAcrossTactics"`
+	SelectSubtechniquesWithParent bool                 `json:"selectSubtechniquesWithParent"`
+}
+
+type navigatorTechnique struct {
+	TechniqueID       string `json:"techniqueID"`
+	Tactic            string `json:"tactic"`
+	Score             int    `json:"score"`
+	Comment           string `json:"comment,omitempty"`
+	Enabled           bool   `json:"enabled"`
+	ShowSubtechniques bool   `json:"showSubtechniques"`
+}
+
+// NavigatorLayer renders m as an ATT&CK Navigator layer scored by hit count.
+func NavigatorLayer(m *Matrix, name, description string) ([]byte, error) {
+	layer := navigatorLayer{
+		Name:                          name,
+		Versions:                      map[string]string{"navigator": navigatorVersion, "layer": layerVersion},
+		Domain:                        "enterprise-attack",
+		Description:                   description,
+		Sorting:                       3, // by score, descending
+		Layout:                        map[string]any{"layout": "side", "showID": true, "showName": true},
+		Techniques:                    []navigatorTechnique{},
+		LegendItems:                   []any{},
+		Metadata:                      []map[string]string{{"name": "generator", "value": "gtrace"}},
+		ShowTacticRowBackground:       false,
+		SelectTechniquesAcrossTactics: true,
+		SelectSubtechniquesWithParent: false,
+	}
+	if m.Version != "" {
+		layer.Versions["attack"] = strings.SplitN(m.Version, ".", 2)[0]
+	}
+
+	max := 0
+	for _, tac := range m.Tactics {
+		for _, t := range tac.Techniques {
+			layer.Techniques = append(layer.Techniques, navigatorEntry(tac.ShortName, t, len(t.Subtechniques) > 0))
+			if t.Hits > max {
+				max = t.Hits
+			}
+			for _, s := range t.Subtechniques {
+				layer.Techniques = append(layer.Techniques, navigatorEntry(tac.ShortName, s, false))
+			}
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+	layer.Gradient = map[string]any{"colors": []string{"#ffe766ff", "#ff6666ff"}, "minValue": 0, "maxValue": max}
+	return json.MarshalIndent(layer, "", "  ")
+}
+
+func navigatorEntry(tactic string, c MatrixCell, showSubs bool) navigatorTechnique {
+	return navigatorTechnique{
+		TechniqueID:       c.ID,
+		Tactic:            tactic,
+		Score:             c.Hits,
+		Comment:           fmt.Sprintf("%d alert(s), %d finding(s)", c.Alerts, c.Findings),
+		Enabled:           true,
+		ShowSubtechniques: showSubs,
+	}
+}
@@ -0,0 +1,328 @@
+// Package budget decides which events of a triage run are written once the run's event
+// budget (max_events) runs short. A policy gives sources, optionally per host, a quota and
+// says what happens when it is used up: drop, sample, or keep only notable events. Sigma
+// hits are always kept, and every dropped event is counted against the quota that dropped it.
+package budget
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed builtin.yaml
+var builtinPolicies []byte
+
+// DefaultPolicy is used when a triage run names none.
+const DefaultPolicy = "balanced"
+
+// OptionKey is the triage option naming the policy.
+const OptionKey = "budget_policy"
+
+// What a full quota does with further events.
+const (
+	ModeQuota  = "quota"  // drop them
+	ModeSample = "sample" // keep one in SampleEvery
+	ModeAlerts = "alerts" // keep those with an alert or an error/warning level
+)
+
+// TotalRule names drops caused by the run's overall max_events rather than a source quota.
+const TotalRule = "total"
+
+// Quota budgets the events of the sources (and hosts) it matches. The limit is Max events,
+// or Share of the run's max_events; with neither, only the overall budget applies. PerHost
+// gives every host its own limit.
+type Quota struct {
+	ID          string  `yaml:"id" json:"id"`
+	Source      string  `yaml:"source" json:"source"`                 // EventLog, Registry, Prefetch, PowerShell, Other or an event source; * and ? wildcards
+	Host        string  `yaml:"host,omitempty" json:"host,omitempty"` // host name pattern; empty matches every host
+	Max         int     `yaml:"max,omitempty" json:"max,omitempty"`
+	Share       float64 `yaml:"share,omitempty" json:"share,omitempty"`
+	PerHost     bool    `yaml:"per_host,omitempty" json:"per_host,omitempty"`
+	Mode        string  `yaml:"mode,omitempty" json:"mode"`
+	SampleEvery int     `yaml:"sample_every,omitempty" json:"sample_every,omitempty"`
+
+	source, host *regexp.Regexp
+}
+
+// Policy is a named list of quotas; the first quota matching an event applies.
+type Policy struct {
+	Name        string  `yaml:"name" json:"name"`
+	Description string  `yaml:"description,omitempty" json:"description,omitempty"`
+	Quotas      []Quota `yaml:"quotas,omitempty" json:"quotas"`
+	Source      string  `yaml:"-" json:"source"` // "builtin" or the file it was loaded from
+}
+
+type file struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+// Compile checks the policy and prepares its patterns.
+func (p *Policy) Compile() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("policy name required")
+	}
+	seen := make(map[string]bool)
+	for i := range p.Quotas {
+		q := &p.Quotas[i]
+		if q.ID == "" {
+			q.ID = "quota-" + strconv.Itoa(i+1)
+		}
+		if seen[q.ID] || q.ID == TotalRule {
+			return fmt.Errorf("policy %s: duplicate or reserved quota id %q", p.Name, q.ID)
+		}
+		seen[q.ID] = true
+		if q.Source == "" {
+			return fmt.Errorf("policy %s, quota %s: source required", p.Name, q.ID)
+		}
+		if q.Max < 0 || q.Share < 0 || q.Share > 1 {
+			return fmt.Errorf("policy %s, quota %s: max must be positive and share between 0 and 1", p.Name, q.ID)
+		}
+		switch strings.ToLower(q.Mode) {
+		case "", ModeQuota:
+			q.Mode = ModeQuota
+		case ModeSample:
+			q.Mode = ModeSample
+			if q.SampleEvery < 2 {
+				return fmt.Errorf("policy %s, quota %s: sample_every must be at least 2", p.Name, q.ID)
+			}
+		case ModeAlerts:
+			q.Mode = ModeAlerts
+		default:
+			return fmt.Errorf("policy %s, quota %s: unknown mode %q", p.Name, q.ID, q.Mode)
+		}
+		q.source = glob(q.Source)
+		if q.Host != "" {
+			q.host = glob(q.Host)
+		}
+	}
+	return nil
+}
+
+func glob(pattern string) *regexp.Regexp {
+	expr := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern))
+	return regexp.MustCompile("(?is)^" + expr + "$")
+}
+
+// Parse reads a policy file.
+func Parse(data []byte, source string) ([]Policy, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse budget policies: %w", err)
+	}
+	for i := range f.Policies {
+		if err := f.Policies[i].Compile(); err != nil {
+			return nil, err
+		}
+		f.Policies[i].Source = source
+	}
+	return f.Policies, nil
+}
+
+// Builtin returns the policies shipped with gtrace.
+func Builtin() []Policy {
+	p, err := Parse(builtinPolicies, "builtin")
+	if err != nil {
+		panic(fmt.Sprintf("built-in budget policies: %v", err))
+	}
+	return p
+}
+
+// Load returns the built-in policies overridden, by name, by those in the given files in
+// order. Files that do not exist are skipped.
+func Load(paths ...string) ([]Policy, error) {
+	policies := Builtin()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := Parse(data, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	next:
+		for _, p := range loaded {
+			for i := range policies {
+				if strings.EqualFold(policies[i].Name, p.Name) {
+					policies[i] = p
+					continue next
+				}
+			}
+			policies = append(policies, p)
+		}
+	}
+	return policies, nil
+}
+
+// GlobalPath is the policy file shared by every case of the current user.
+func GlobalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gtrace", "budget_policies.yaml"), nil
+}
+
+// CasePath is the case policy file, beside the case data directory.
+func CasePath(casePath string) string {
+	return filepath.Join(casePath, "budget_policies.yaml")
+}
+
+// LoadForCase loads the built-in, global and case policies.
+func LoadForCase(casePath string) ([]Policy, error) {
+	var paths []string
+	if p, err := GlobalPath(); err == nil {
+		paths = append(paths, p)
+	}
+	return Load(append(paths, CasePath(casePath))...)
+}
+
+// Lookup finds a policy by name; an empty name selects DefaultPolicy.
+func Lookup(policies []Policy, name string) (*Policy, error) {
+	if name == "" {
+		name = DefaultPolicy
+	}
+	for i := range policies {
+		if strings.EqualFold(policies[i].Name, name) {
+			return &policies[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown budget policy %q", name)
+}
+
+// Event is what the budget needs to know about an event.
+type Event struct {
+	Source  string // budget category (EventLog, Registry, ...) or event source
+	Host    string
+	Alert   bool // a Sigma hit; always kept
+	Notable bool // carries an alert or an error/warning level; kept by alerts-mode quotas
+}
+
+type counter struct {
+	quota int
+	host  string
+}
+
+// Drop counts the events one quota dropped, per host when the quota is per host.
+type Drop struct {
+	Rule    string `json:"rule"`
+	Source  string `json:"source"`
+	Host    string `json:"host,omitempty"`
+	Dropped int    `json:"dropped"`
+}
+
+// Budget applies a policy to the events of one run.
+type Budget struct {
+	policy  *Policy
+	total   int
+	written int
+	used    map[counter]int // events written under a quota
+	over    map[counter]int // events seen after the quota was full, for sampling
+	dropped map[Drop]int
+}
+
+// New returns a budget of total events (max_events) under p.
+func New(p *Policy, total int) *Budget {
+	return &Budget{policy: p, total: total, used: make(map[counter]int), over: make(map[counter]int), dropped: make(map[Drop]int)}
+}
+
+func (b *Budget) quota(ev Event) (int, *Quota) {
+	for i := range b.policy.Quotas {
+		q := &b.policy.Quotas[i]
+		if q.source.MatchString(ev.Source) && (q.host == nil || q.host.MatchString(ev.Host)) {
+			return i, q
+		}
+	}
+	return -1, nil
+}
+
+func (b *Budget) counter(i int, q *Quota, host string) counter {
+	c := counter{quota: i}
+	if q.PerHost {
+		c.host = strings.ToLower(host)
+	}
+	return c
+}
+
+// Admit reports whether ev may be written. Call Commit once it actually was.
+func (b *Budget) Admit(ev Event) bool {
+	if ev.Alert {
+		return true
+	}
+	i, q := b.quota(ev)
+	if q == nil {
+		return true
+	}
+	c := b.counter(i, q, ev.Host)
+	limit := q.Max
+	if q.Share > 0 {
+		limit = int(q.Share * float64(b.total))
+	}
+	rule := ""
+	switch {
+	case limit > 0 && b.used[c] >= limit:
+		rule = q.ID
+	case b.written >= b.total:
+		rule = TotalRule
+	default:
+		return true
+	}
+	switch q.Mode {
+	case ModeSample:
+		b.over[c]++
+		if b.over[c]%q.SampleEvery == 0 {
+			return true
+		}
+	case ModeAlerts:
+		if ev.Notable {
+			return true
+		}
+	}
+	d := Drop{Rule: rule, Source: q.Source}
+	if q.PerHost {
+		d.Host = ev.Host
+	}
+	b.dropped[d]++
+	return false
+}
+
+// Commit counts a written event.
+func (b *Budget) Commit(ev Event) {
+	b.written++
+	if i, q := b.quota(ev); q != nil {
+		b.used[b.counter(i, q, ev.Host)]++
+	}
+}
+
+// Dropped returns the drop counts, largest first.
+func (b *Budget) Dropped() []Drop {
+	out := make([]Drop, 0, len(b.dropped))
+	for d, n := range b.dropped {
+		d.Dropped = n
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Dropped != out[j].Dropped {
+			return out[i].Dropped > out[j].Dropped
+		}
+		return out[i].Rule+out[i].Host < out[j].Rule+out[j].Host
+	})
+	return out
+}
+
+// PolicyName is the name of the applied policy.
+func (b *Budget) PolicyName() string {
+	return b.policy.Name
+}
@@ -0,0 +1,86 @@
+package budget
+
+import (
+	"testing"
+)
+
+func run(b *Budget, ev Event, n int) int {
+	kept := 0
+	for i := 0; i < n; i++ {
+		if b.Admit(ev) {
+			b.Commit(ev)
+			kept++
+		}
+	}
+	return kept
+}
+
+func TestBalanced(t *testing.T) {
+	p, err := Lookup(Builtin(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := New(p, 100)
+	if kept := run(b, Event{Source: "EventLog"}, 80); kept != 60 {
+		t.Errorf("event logs kept = %d, want 60", kept)
+	}
+	// Sigma hits are kept past the quota.
+	if kept := run(b, Event{Source: "EventLog", Alert: true}, 5); kept != 5 {
+		t.Errorf("alerts kept = %d", kept)
+	}
+	if kept := run(b, Event{Source: "Registry"}, 50); kept != 35 {
+		t.Errorf("registry kept = %d, want 35 (total budget)", kept)
+	}
+	// Sources without a quota are not budgeted.
+	if kept := run(b, Event{Source: "Other"}, 10); kept != 10 {
+		t.Errorf("other kept = %d", kept)
+	}
+	drops := b.Dropped()
+	if len(drops) != 2 || drops[0] != (Drop{Rule: "eventlog-share", Source: "EventLog", Dropped: 20}) ||
+		drops[1] != (Drop{Rule: TotalRule, Source: "Registry", Dropped: 15}) {
+		t.Errorf("drops = %+v", drops)
+	}
+}
+
+func TestModes(t *testing.T) {
+	policies, err := Parse([]byte(`
+policies:
+  - name: test
+    quotas:
+      - id: dc
+        source: EventLog
+        host: DC*
+        max: 10
+        mode: alerts
+      - id: hosts
+        source: EventLog
+        max: 10
+        per_host: true
+        mode: sample
+        sample_every: 5
+`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := New(&policies[0], 1000)
+	if kept := run(b, Event{Source: "EventLog", Host: "DC01"}, 20); kept != 10 {
+		t.Errorf("dc kept = %d", kept)
+	}
+	if kept := run(b, Event{Source: "EventLog", Host: "DC01", Notable: true}, 3); kept != 3 {
+		t.Errorf("notable dc events kept = %d", kept)
+	}
+	if kept := run(b, Event{Source: "EventLog", Host: "WS01"}, 60); kept != 20 {
+		t.Errorf("ws01 kept = %d, want 10 + 50/5", kept)
+	}
+	if kept := run(b, Event{Source: "EventLog", Host: "WS02"}, 10); kept != 10 {
+		t.Errorf("ws02 kept = %d, per-host quota shared", kept)
+	}
+	drops := b.Dropped()
+	if len(drops) != 2 || drops[0] != (Drop{Rule: "hosts", Source: "EventLog", Host: "WS01", Dropped: 40}) || drops[1].Rule != "dc" || drops[1].Dropped != 10 {
+		t.Errorf("drops = %+v", drops)
+	}
+
+	if _, err := Parse([]byte("policies:\n  - name: bad\n    quotas:\n      - source: EventLog\n        mode: sample\n"), "x"); err == nil {
+		t.Error("sample mode without sample_every accepted")
+	}
+}
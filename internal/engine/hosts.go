@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gtrace/pkg/model"
+)
+
+// hostIndex names the host of events that do not name one themselves. Event logs carry their
+// computer; registry, Prefetch, browser and file system events do not, so at ingest they are
+// stamped with the host of the collection they were read from (model.CollectionRoot): the
+// ComputerName of the SYSTEM hive in that collection, or else the computer named by the event
+// logs of the collection read so far. Live triage stamps the local host name.
+//
+// stamp is only called from the timeline writer, so the index needs no lock.
+type hostIndex struct {
+	byRoot map[string]string // lower-case collection root → host
+	local  string
+}
+
+// newHostIndex reads the ComputerName of every SYSTEM hive among the candidates up front, so
+// that events parsed before the hive are attributed too.
+func newHostIndex(candidates []string, computerName func(path string) string) *hostIndex {
+	h := &hostIndex{byRoot: make(map[string]string)}
+	for _, c := range candidates {
+		if !strings.HasPrefix(strings.ToUpper(filepath.Base(c)), "SYSTEM") {
+			continue
+		}
+		root := rootKey(c)
+		if _, ok := h.byRoot[root]; ok {
+			continue
+		}
+		if name := computerName(c); name != "" {
+			h.byRoot[root] = name
+		}
+	}
+	return h
+}
+
+// liveHostIndex attributes every event to the machine being triaged.
+func liveHostIndex() *hostIndex {
+	name, _ := os.Hostname()
+	return &hostIndex{byRoot: make(map[string]string), local: name}
+}
+
+func rootKey(path string) string {
+	return strings.ToLower(model.CollectionRoot(path))
+}
+
+// stamp sets the Hostname detail of an event that names no host.
+func (h *hostIndex) stamp(ev *model.TimelineEvent) {
+	if host := ev.Host(); host != "" {
+		if h.local == "" && ev.EvidenceRef.SourcePath != "" {
+			root := rootKey(ev.EvidenceRef.SourcePath)
+			if _, ok := h.byRoot[root]; !ok {
+				h.byRoot[root] = host
+			}
+		}
+		return
+	}
+	host := h.local
+	if host == "" && ev.EvidenceRef.SourcePath != "" {
+		host = h.byRoot[rootKey(ev.EvidenceRef.SourcePath)]
+	}
+	if host == "" {
+		return
+	}
+	if ev.Details == nil {
+		ev.Details = make(map[string]string)
+	}
+	ev.Details["Hostname"] = host
+}
@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"testing"
+
+	"gtrace/pkg/model"
+)
+
+func TestHostIndexStampsCollectionHost(t *testing.T) {
+	hives := map[string]string{
+		"/cases/WS07/C/Windows/System32/config/SYSTEM": "WS07",
+	}
+	idx := newHostIndex([]string{
+		"/cases/WS07/C/Windows/System32/config/SYSTEM",
+		"/cases/WS07/C/Windows/Prefetch/CMD.EXE-4A81B364.pf",
+		"/cases/DC01/C/Windows/System32/winevt/Logs/Security.evtx",
+	}, func(path string) string { return hives[path] })
+
+	event := func(path string, details map[string]string) model.TimelineEvent {
+		return model.TimelineEvent{Source: "Prefetch", Details: details, EvidenceRef: model.EvidenceRef{SourcePath: path}}
+	}
+
+	// Prefetch names no host: it takes the ComputerName of the collection's SYSTEM hive.
+	pf := event("/cases/WS07/C/Windows/Prefetch/CMD.EXE-4A81B364.pf", nil)
+	idx.stamp(&pf)
+	if pf.Host() != "WS07" {
+		t.Errorf("Prefetch host = %q, want WS07", pf.Host())
+	}
+
+	// Without a SYSTEM hive the collection's event logs name the host.
+	evtx := event("/cases/DC01/C/Windows/System32/winevt/Logs/Security.evtx", map[string]string{"Computer": "DC01.corp.local"})
+	idx.stamp(&evtx)
+	if evtx.Details["Hostname"] != "" {
+		t.Errorf("event naming its computer was stamped: %q", evtx.Details["Hostname"])
+	}
+	reg := event(`/cases/DC01/C/Users/admin/NTUSER.DAT`, map[string]string{"Key": "Run"})
+	idx.stamp(&reg)
+	if reg.Host() != "DC01.corp.local" {
+		t.Errorf("registry host = %q", reg.Host())
+	}
+
+	other := event("/cases/unknown/file.txt", nil)
+	idx.stamp(&other)
+	if other.Host() != "" {
+		t.Errorf("event of an unknown collection stamped %q", other.Host())
+	}
+}
@@ -2,7 +2,10 @@ package engine
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -11,7 +14,11 @@ import (
 	"sync"
 	"time"
 
+	"gtrace/internal/allowlist"
 	"gtrace/internal/analysis"
+	"gtrace/internal/attack"
+	"gtrace/internal/budget"
+	"gtrace/internal/noise"
 	"gtrace/internal/plugin"
 	"gtrace/internal/rules"
 	"gtrace/internal/storage"
@@ -25,6 +32,15 @@ type Pipeline struct {
 	parsers   []pluginsdk.ParserPlugin
 	analyzers []pluginsdk.AnalyzerPlugin
 	logger    func(string, ...interface{})
+
+	// mu guards the settings below, which the app may change while a triage runs. A run
+	// reads them once when it starts.
+	mu        sync.Mutex
+	allowlist *allowlist.List
+	// noiseProfiles are the event log noise-reduction profiles triage runs choose from.
+	noiseProfiles []noise.Profile
+	// budgetPolicies decide which events a run keeps once max_events runs short.
+	budgetPolicies []budget.Policy
 }
 
 // NewPipeline constructs a pipeline bound to storage and parser set.
@@ -33,10 +49,12 @@ func NewPipeline(store storage.Storage, parsers []pluginsdk.ParserPlugin, analyz
 	go cleanupOrphanedDumps(logger)
 
 	return &Pipeline{
-		store:     store,
-		parsers:   parsers,
-		analyzers: analyzers,
-		logger:    logger,
+		store:          store,
+		parsers:        parsers,
+		analyzers:      analyzers,
+		logger:         logger,
+		noiseProfiles:  noise.Builtin(),
+		budgetPolicies: budget.Builtin(),
 	}
 }
 
@@ -59,6 +77,36 @@ func cleanupOrphanedDumps(logger func(string, ...interface{})) {
 	}
 }
 
+// SetAllowlist sets the known-good entries that suppress or downgrade Sigma hits during
+// triage and analyzer findings in Analyze. Nil disables allowlisting.
+func (p *Pipeline) SetAllowlist(l *allowlist.List) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.allowlist = l
+}
+
+func (p *Pipeline) currentAllowlist() *allowlist.List {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.allowlist
+}
+
+// SetNoiseProfiles sets the profiles a triage run's "noise_profile" option is resolved
+// against. The built-in profiles are used until it is called.
+func (p *Pipeline) SetNoiseProfiles(profiles []noise.Profile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.noiseProfiles = profiles
+}
+
+// SetBudgetPolicies sets the policies a triage run's "budget_policy" option is resolved
+// against. The built-in policies are used until it is called.
+func (p *Pipeline) SetBudgetPolicies(policies []budget.Policy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.budgetPolicies = policies
+}
+
 func (p *Pipeline) log(format string, args ...interface{}) {
 	if p.logger != nil {
 		p.logger(format, args...)
@@ -101,7 +149,7 @@ func (p *Pipeline) Triage(ctx context.Context, evidencePath string, options map[
 	}
 
 	p.log("Found %d candidate files", len(candidates))
-	return p.runTriage(ctx, candidates, options, progressCb)
+	return p.runTriage(ctx, candidates, newHostIndex(candidates, plugin.HiveComputerName), options, progressCb)
 }
 
 // TriageLive automatically finds and processes known artifacts from the live system.
@@ -142,6 +190,8 @@ func (p *Pipeline) TriageLive(ctx context.Context, components []string, options
 			`C:\Windows\System32\config\SAM`,
 			`C:\Windows\System32\config\SECURITY`,
 			`C:\Windows\System32\config\Amcache.hve`,
+			// Device install log, correlated with USBSTOR by the USB parser
+			`C:\Windows\INF\setupapi.dev.log`,
 		)
 
 		// User Profiles: NTUSER.DAT
@@ -155,6 +205,22 @@ func (p *Pipeline) TriageLive(ctx context.Context, components []string, options
 		searchPaths = append(searchPaths, "LIVE_HKCU")
 	}
 
+	if isEnabled("SRUM") {
+		p.log("  [+] SRUM component selected")
+		searchPaths = append(searchPaths, `C:\Windows\System32\sru\SRUDB.dat`)
+	}
+
+	if isEnabled("UserActivity") {
+		p.log("  [+] UserActivity component selected")
+		timelines, _ := filepath.Glob(`C:\Users\*\AppData\Local\ConnectedDevicesPlatform\*\ActivitiesCache.db`)
+		searchPaths = append(searchPaths, timelines...)
+		recycled, _ := filepath.Glob(`C:\$Recycle.Bin\*\$I*`)
+		if len(timelines) > 0 || len(recycled) > 0 {
+			p.log("  [+] Found %d Timeline databases, %d Recycle Bin entries", len(timelines), len(recycled))
+		}
+		searchPaths = append(searchPaths, recycled...)
+	}
+
 	if isEnabled("Tasks") {
 		p.log("  [+] Tasks component selected")
 		searchPaths = append(searchPaths, `C:\Windows\System32\Tasks`)
@@ -167,7 +233,17 @@ func (p *Pipeline) TriageLive(ctx context.Context, components []string, options
 			`C:\Windows\System32\winevt\Logs\System.evtx`,
 			`C:\Windows\System32\winevt\Logs\Microsoft-Windows-TaskScheduler%4Operational.evtx`,
 			`C:\Windows\System32\winevt\Logs\Microsoft-Windows-TerminalServices-LocalSessionManager%4Operational.evtx`,
+			`C:\Windows\System32\winevt\Logs\Microsoft-Windows-TerminalServices-RemoteConnectionManager%4Operational.evtx`,
+			`C:\Windows\System32\winevt\Logs\Microsoft-Windows-PowerShell%4Operational.evtx`,
+			`C:\Windows\System32\winevt\Logs\Windows PowerShell.evtx`,
+			`C:\Windows\System32\winevt\Logs\Microsoft-Windows-Sysmon%4Operational.evtx`,
 		)
+
+		// PSReadLine history and default-location transcripts, per user
+		psHistory, _ := filepath.Glob(`C:\Users\*\AppData\Roaming\Microsoft\Windows\PowerShell\PSReadLine\*_history.txt`)
+		transcripts, _ := filepath.Glob(`C:\Users\*\Documents\*\PowerShell_transcript.*.txt`)
+		searchPaths = append(searchPaths, psHistory...)
+		searchPaths = append(searchPaths, transcripts...)
 	}
 
 	// ...
@@ -226,14 +302,16 @@ func (p *Pipeline) TriageLive(ctx context.Context, components []string, options
 	}
 
 	p.log("Total candidates for processing: %d", len(candidates))
-	return p.runTriage(ctx, candidates, options, progressCb)
+	return p.runTriage(ctx, candidates, liveHostIndex(), options, progressCb)
 }
 
-func (p *Pipeline) runTriage(ctx context.Context, candidates []string, options map[string]interface{}, progressCb func(current, total int)) error {
+func (p *Pipeline) runTriage(ctx context.Context, candidates []string, hosts *hostIndex, options map[string]interface{}, progressCb func(current, total int)) error {
 	total := len(candidates)
 	if progressCb != nil {
 		progressCb(0, total)
 	}
+	// Names this run in its drop records, so that a re-run supersedes them.
+	run := fmt.Sprintf("run-%d", time.Now().UnixNano())
 
 	// Channels
 	// We separate Events stream from logical File result
@@ -260,6 +338,32 @@ func (p *Pipeline) runTriage(ctx context.Context, candidates []string, options m
 	}
 	p.log("Pipeline: Global MaxEvents Limit = %d", globalMaxEvents)
 
+	p.mu.Lock()
+	allow, noiseProfiles, budgetPolicies := p.allowlist, p.noiseProfiles, p.budgetPolicies
+	p.mu.Unlock()
+
+	// Noise-reduction profile for event logs, passed to parsers fully resolved.
+	profileName, _ := options[noise.OptionKey].(string)
+	profile, err := noise.Lookup(noiseProfiles, profileName)
+	if err != nil {
+		return err
+	}
+	parseOptions := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		parseOptions[k] = v
+	}
+	parseOptions[noise.MetadataKey] = profile.Encode()
+	p.log("Pipeline: Noise profile %q (%d rules)", profile.Name, len(profile.Rules))
+
+	// Budget policy: which events are kept once max_events runs short.
+	policyName, _ := options[budget.OptionKey].(string)
+	policy, err := budget.Lookup(budgetPolicies, policyName)
+	if err != nil {
+		return err
+	}
+	bgt := budget.New(policy, globalMaxEvents)
+	p.log("Pipeline: Budget policy %q (%d quotas)", policy.Name, len(policy.Quotas))
+
 	// Initialize Sigma Engine
 	sigmaEng, err := analysis.NewEngineV2(rules.WindowsRules, "sigma_rules_repo/rules/windows")
 	if err != nil {
@@ -276,8 +380,9 @@ func (p *Pipeline) runTriage(ctx context.Context, candidates []string, options m
 			}
 		}()
 
-		// Create efficient buffered writer for timeline
-		writeEvent, closeEvents, err := p.store.NewStreamWriter("timeline.jsonl")
+		// Appends to the case timeline; records already ingested from another copy of the
+		// same evidence are skipped and kept as provenance of the stored event.
+		tw, err := p.store.NewTimelineWriter(ctx)
 		if err != nil {
 			writeErrChan <- err
 			// Drain eventsChan to prevent deadlock
@@ -285,14 +390,15 @@ func (p *Pipeline) runTriage(ctx context.Context, candidates []string, options m
 			}
 			return
 		}
-		defer closeEvents()
+		defer tw.Close()
 
-		// Counters for balanced collection
+		allowlisted := 0
 		writtenCount := 0
-		bulkCounts := make(map[string]int) // Track EventLog, Registry, Prefetch separately
-		bulkLimit := globalMaxEvents
 
 		for ev := range eventsChan {
+			// Events that name no host take the host of their collection.
+			hosts.stamp(&ev)
+
 			// 1. Identify category for fairness
 			cat := "Other"
 			if strings.EqualFold(ev.Source, "EventLog") {
@@ -301,27 +407,16 @@ func (p *Pipeline) runTriage(ctx context.Context, candidates []string, options m
 				cat = "Registry"
 			} else if strings.EqualFold(ev.Artifact, "Prefetch") || strings.EqualFold(ev.Source, "Prefetch") {
 				cat = "Prefetch"
+			} else if strings.EqualFold(ev.Source, "PowerShell") {
+				cat = "PowerShell"
 			}
 
-			// 2. Apply Limits
-			isBulk := (cat == "EventLog" || cat == "Registry" || cat == "Prefetch")
-			if isBulk {
-				// Global Cap
-				if writtenCount >= bulkLimit {
-					continue
-				}
-				// Fairness Cap: One category shouldn't take > 60% of total budget
-				// if there are multiple candidates. This prevents EVTX from drowning out Registry/Prefetch.
-				if bulkCounts[cat] >= (bulkLimit * 6 / 10) {
-					continue
-				}
-			}
-
-			// Run Sigma Checks (Only for EventLogs and Registry to save time and prevent panics)
-			// ...
-			// (Keep existing Sigma logic)
-			if sigmaEng != nil && (cat == "EventLog" || cat == "Registry") {
+			// 2. Run Sigma Checks (Only for EventLogs, Registry and PowerShell to save time and prevent panics).
+			// This comes before the budget: Sigma hits are kept however full it is.
+			sigmaHit := false
+			if sigmaEng != nil && (cat == "EventLog" || cat == "Registry" || cat == "PowerShell") {
 				if matched := sigmaEng.Evaluate(ev); matched != nil {
+					sigmaHit = true
 					if ev.Details == nil {
 						ev.Details = make(map[string]string)
 					}
@@ -333,22 +428,46 @@ func (p *Pipeline) runTriage(ctx context.Context, candidates []string, options m
 					// Add MITRE ATT&CK tags if available
 					if len(matched.Tags) > 0 {
 						ev.Details["_Mitre"] = strings.Join(matched.Tags, ", ")
+						ev.Attack = attack.Default().FromTags(matched.Tags)
+					}
+					if s := allow.Match(&ev, matched.ID, matched.Title); s != nil {
+						allowlist.ApplyToEvent(&ev, s)
+						allowlisted++
 					}
 				}
 			}
 
-			if err := writeEvent(ev); err != nil {
+			// 3. Apply the budget policy. Events were stamped with their host above, so
+			// per-host quotas cover registry, Prefetch and file events as well as event logs.
+			be := budget.Event{
+				Source:  cat,
+				Host:    ev.Host(),
+				Alert:   sigmaHit,
+				Notable: ev.Details["_Alert"] != "" || ev.Details["_AlertLevel"] != "",
+			}
+			if !bgt.Admit(be) {
+				continue
+			}
+
+			written, err := tw.Write(ev)
+			if err != nil {
 				p.log("Error writing event: %v", err)
 			}
-			writtenCount++
-			if isBulk {
-				bulkCounts[cat]++
+			if !written {
+				continue
 			}
+			writtenCount++
+			bgt.Commit(be)
 			if writtenCount%500 == 0 {
 				p.log("Pipeline Progress: Written %d events...", writtenCount)
 			}
 		}
-		p.log("Pipeline: Finalizing. Total events written = %d (limit was %d)", writtenCount, globalMaxEvents)
+		_, duplicates := tw.Stats()
+		p.log("Pipeline: Finalizing. Total events written = %d (limit was %d, %d duplicates skipped)", writtenCount, globalMaxEvents, duplicates)
+		if allowlisted > 0 {
+			p.log("Pipeline: %d Sigma hits matched the allowlist", allowlisted)
+		}
+		p.recordBudgetDrops(ctx, run, bgt)
 		writeErrChan <- nil
 	}()
 
@@ -374,11 +493,12 @@ func (p *Pipeline) runTriage(ctx context.Context, candidates []string, options m
 						eventsChan <- ev
 					}
 
-					resp, err = p.processFile(ctx, file, options, streamCb)
+					resp, err = p.processFile(ctx, file, parseOptions, allow, hosts.local, streamCb)
 				}()
 
 				if err == nil && resp != nil {
 					p.log("[W%d] SUCCESS %s", workerID, file)
+					p.recordDrops(ctx, run, file, profile, policy.Name, resp)
 					responseChan <- resp
 				} else {
 					if err != nil {
@@ -408,6 +528,17 @@ func (p *Pipeline) runTriage(ctx context.Context, candidates []string, options m
 				if len(resp.Artifacts) > 0 {
 					artifactBatch = append(artifactBatch, resp.Artifacts...)
 				}
+				p.recordHostTimezones(ctx, resp.Artifacts)
+
+				// 3. Parser-level findings are few; save them straight away
+				if len(resp.Findings) > 0 {
+					for i := range resp.Findings {
+						resp.Findings[i].Attack = attack.Default().Enrich(resp.Findings[i].Attack)
+					}
+					if err := p.store.SaveFindings(ctx, resp.Findings); err != nil {
+						p.log("Finding save error: %v", err)
+					}
+				}
 			}
 
 			if len(artifactBatch) >= 100 {
@@ -443,19 +574,20 @@ func (p *Pipeline) runTriage(ctx context.Context, candidates []string, options m
 	return nil
 }
 
-// processFile handles a single file: identification, parsing.
-func (p *Pipeline) processFile(ctx context.Context, file string, options map[string]interface{}, streamCb func(model.TimelineEvent)) (*pluginsdk.ParseResponse, error) {
+// processFile handles a single file: identification, parsing. Findings of the parsers come
+// back naming the stored IDs of their events, with allow applied. liveHost is the local host
+// name when the file was read from the running system.
+func (p *Pipeline) processFile(ctx context.Context, file string, options map[string]interface{}, allow *allowlist.List, liveHost string, streamCb func(model.TimelineEvent)) (*pluginsdk.ParseResponse, error) {
 	var targetFile string
 	var tempFile string
+	var hkcuOwner string
 
 	// Pre-processing for Windows Live Artifacts (Registry Dumping)
 	if runtime.GOOS == "windows" {
 		// Virtual Artifact: Network (Command Based)
 		if file == "LIVE_NETWORK" {
 			if streamCb != nil {
-				// We need to adapt the callback: CollectNetwork expects func(model.TimelineEvent)
-				// streamCb is already func(model.TimelineEvent)
-				if err := plugin.CollectNetwork(ctx, streamCb); err != nil {
+				if err := plugin.CollectNetwork(ctx, identified(streamCb, "", "live-network")); err != nil {
 					p.log("Error collecting Network info: %v", err)
 				}
 			}
@@ -464,7 +596,7 @@ func (p *Pipeline) processFile(ctx context.Context, file string, options map[str
 		// Virtual Artifact: WMI (COM Based)
 		if file == "LIVE_WMI" {
 			if streamCb != nil {
-				if err := plugin.CollectWMIPersistence(ctx, streamCb); err != nil {
+				if err := plugin.CollectWMIPersistence(ctx, identified(streamCb, "", "live-wmi")); err != nil {
 					p.log("Error collecting WMI persistence: %v", err)
 				}
 			}
@@ -473,7 +605,7 @@ func (p *Pipeline) processFile(ctx context.Context, file string, options map[str
 		// Virtual Artifact: Browser History (SQLite)
 		if file == "LIVE_BROWSER" {
 			if streamCb != nil {
-				if err := plugin.CollectBrowserHistory(ctx, streamCb); err != nil {
+				if err := plugin.CollectBrowserHistory(ctx, identified(streamCb, "", "live-browser")); err != nil {
 					p.log("Error collecting Browser History: %v", err)
 				}
 			}
@@ -491,6 +623,7 @@ func (p *Pipeline) processFile(ctx context.Context, file string, options map[str
 			targetFile = dumpPath
 			tempFile = dumpPath
 			file = "NTUSER.DAT" // Pretend to be NTUSER.DAT for parser detection
+			hkcuOwner = os.Getenv("USERNAME")
 		} else {
 			// Case 2: System Hives (SYSTEM, SAM, etc)
 			isHive, hiveKey := isSystemHive(file)
@@ -510,7 +643,7 @@ func (p *Pipeline) processFile(ctx context.Context, file string, options map[str
 			} else {
 				// Case 3: Regular Files (including unlocked NTUSER.DAT, and LOCKED .evtx/.pf)
 				ext := strings.ToLower(filepath.Ext(file))
-				if ext == ".evtx" || ext == ".pf" || ext == ".dat" {
+				if ext == ".evtx" || ext == ".pf" || ext == ".dat" || ext == ".db" {
 					// We suspect these might be locked. Try to copy.
 					dumpPath := filepath.Join(os.TempDir(), fmt.Sprintf("gtrace_dump_file_%d%s", time.Now().UnixNano(), filepath.Ext(file)))
 
@@ -565,8 +698,8 @@ func (p *Pipeline) processFile(ctx context.Context, file string, options map[str
 		return nil, nil // treat as skip
 	}
 
-	parser := p.findParserFor(file, header) // Use original filename for matcher (extension based)
-	if parser == nil {
+	parsers := p.findParsersFor(file, header) // Use original filename for matcher (extension based)
+	if len(parsers) == 0 {
 		// p.log("Skipping %s (No parser matched)", filepath.Base(file))
 		return nil, nil // Skip unknown files
 	}
@@ -576,60 +709,126 @@ func (p *Pipeline) processFile(ctx context.Context, file string, options map[str
 	for k, v := range options {
 		meta[k] = fmt.Sprintf("%v", v)
 	}
+	// Parsers correlating sibling evidence (e.g. SYSTEM + SOFTWARE + setupapi.dev.log)
+	// need the real location, not the temp dump.
+	meta["original_path"] = file
+	if hkcuOwner != "" {
+		meta["hive_owner"] = hkcuOwner
+	}
+
+	// Event IDs are derived from the evidence content, so hash before parsing.
+	hash, size, err := hashFile(targetFile)
+	if err != nil {
+		p.log("Could not hash evidence %s: %v", file, err)
+	}
 
-	// Wrap callback to fixup source paths if needed
-	var wrappedCb func(model.TimelineEvent)
-	if streamCb != nil {
-		wrappedCb = func(ev model.TimelineEvent) {
-			// Always try to fixup Artifact if it looks like a dump file
-			if strings.Contains(ev.Artifact, "gtrace_dump_file_") || (tempFile != "" && ev.Artifact == filepath.Base(targetFile)) {
-				ev.Artifact = filepath.Base(file)
+	// Fixup source paths if needed
+	fixup := func(ev *model.TimelineEvent) {
+		// Always try to fixup Artifact if it looks like a dump file
+		if strings.Contains(ev.Artifact, "gtrace_dump_file_") || (tempFile != "" && ev.Artifact == filepath.Base(targetFile)) {
+			ev.Artifact = filepath.Base(file)
+		}
+
+		if tempFile != "" {
+			ev.EvidenceRef.SourcePath = file
+			// Fix Source if it's missing or generic
+			if ev.Source == "File" || ev.Source == "" {
+				ev.Source = inferSource(file)
 			}
+		}
+	}
 
-			if tempFile != "" {
-				ev.EvidenceRef.SourcePath = file
-				// Fix Source if it's missing or generic
-				if ev.Source == "File" || ev.Source == "" {
-					ev.Source = inferSource(file)
+	// A single file may feed several parsers (SYSTEM → ShimCache, USB, ...).
+	// One parser failing must not hide the output of the others.
+	resp := &pluginsdk.ParseResponse{}
+	var lastErr error
+	succeeded := 0
+	var parsedBy []string
+	for _, parser := range parsers {
+		name := parser.Manifest().Name
+		ids := newEventIdentifier(hash, name)
+		// Findings of a parser name the events it flagged by the parser's own IDs, which are
+		// replaced on ingest; remember those events under their original ID.
+		var flaggedMu sync.Mutex
+		flagged := make(map[string]model.TimelineEvent)
+		identify := func(ev *model.TimelineEvent) {
+			orig := ev.ID
+			fixup(ev)
+			ids.identify(ev)
+			if orig != "" && ev.Details["_Alert"] != "" {
+				flaggedMu.Lock()
+				flagged[orig] = *ev
+				flaggedMu.Unlock()
+			}
+		}
+		var wrappedCb func(model.TimelineEvent)
+		if streamCb != nil {
+			wrappedCb = func(ev model.TimelineEvent) {
+				identify(&ev)
+				streamCb(ev)
+			}
+		}
+		r, err := parser.Parse(ctx, pluginsdk.ParseRequest{
+			EvidencePath:   targetFile,
+			Metadata:       meta,
+			StreamCallback: wrappedCb,
+			ProgressCallback: func(percent int) {
+				p.log("[PARSER] %s (%s) Progress: %d%%", filepath.Base(file), name, percent)
+			},
+		})
+		if err != nil {
+			p.log("Parser %s failed on %s: %v", name, filepath.Base(file), err)
+			lastErr = err
+			continue
+		}
+		succeeded++
+		parsedBy = append(parsedBy, name)
+		if r != nil {
+			for i := range r.Events {
+				identify(&r.Events[i])
+			}
+			var backing []model.TimelineEvent
+			for i := range r.Findings {
+				for j, id := range r.Findings[i].EventIDs {
+					if ev, ok := flagged[id]; ok {
+						r.Findings[i].EventIDs[j] = ev.ID
+						backing = append(backing, ev)
+					}
 				}
 			}
-			streamCb(ev)
-		}
-	}
-
-	resp, err := parser.Parse(ctx, pluginsdk.ParseRequest{
-		EvidencePath:   targetFile,
-		Metadata:       meta,
-		StreamCallback: wrappedCb,
-		ProgressCallback: func(percent int) {
-			// We can emit app events here if we have context?
-			// But processFile is deep inside.
-			// We can log for now, or find a way to emit "triage:progress" fine-grained.
-			// Current architecture progressCb handles TOTAL files (1/N).
-			// We can emit a specific "parsing_progress" event?
-			// Or we just update the log?
-			// Let's rely on EventEmit via a new mechanism if possible, but App struct holds the context.
-			// Pipeline struct holds a logger.
-			// Let's log it for debug first.
-			p.log("[PARSER] %s Progress: %d%%", filepath.Base(file), percent)
-		},
-	})
-	if err != nil {
-		return nil, err
+			applyAllowlist(allow, r.Findings, backing)
+			resp.Artifacts = append(resp.Artifacts, r.Artifacts...)
+			resp.Events = append(resp.Events, r.Events...)
+			resp.Findings = append(resp.Findings, r.Findings...)
+			for rule, n := range r.Dropped {
+				if resp.Dropped == nil {
+					resp.Dropped = make(map[string]int)
+				}
+				resp.Dropped[rule] += n
+			}
+			for limit, n := range r.Truncated {
+				if resp.Truncated == nil {
+					resp.Truncated = make(map[string]int)
+				}
+				resp.Truncated[limit] += n
+			}
+		}
+	}
+	if succeeded == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	if hash != "" {
+		p.registerEvidence(ctx, file, hash, size, parsedBy, liveHost)
 	}
 
-	// Fixup Artifacts SourcePaths and Artifact names if we used a temp file
-	if tempFile != "" && resp != nil {
+	// Fixup Artifacts SourcePaths if we used a temp file
+	if tempFile != "" {
 		for i := range resp.Artifacts {
 			resp.Artifacts[i].EvidenceRef.SourcePath = file
 		}
-		for i := range resp.Events {
-			resp.Events[i].EvidenceRef.SourcePath = file
-			if strings.Contains(resp.Events[i].Artifact, "gtrace_dump_file_") || (tempFile != "" && resp.Events[i].Artifact == filepath.Base(targetFile)) {
-				resp.Events[i].Artifact = filepath.Base(file)
-			}
-			if resp.Events[i].Source == "File" || resp.Events[i].Source == "" {
-				resp.Events[i].Source = inferSource(file)
+		for i := range resp.Findings {
+			for j := range resp.Findings[i].EvidenceRefs {
+				resp.Findings[i].EvidenceRefs[j].SourcePath = file
 			}
 		}
 	}
@@ -637,6 +836,149 @@ func (p *Pipeline) processFile(ctx context.Context, file string, options map[str
 	return resp, nil
 }
 
+// recordDrops stores how many events of one evidence file the parsers dropped: by rules of
+// the noise profile, or because a log reached its share of the budget.
+func (p *Pipeline) recordDrops(ctx context.Context, run, file string, profile *noise.Profile, policy string, resp *pluginsdk.ParseResponse) {
+	if len(resp.Dropped) == 0 && len(resp.Truncated) == 0 {
+		return
+	}
+	records := make([]storage.DropRecord, 0, len(resp.Dropped)+len(resp.Truncated))
+	noisy, truncated := 0, 0
+	for rule, n := range resp.Dropped {
+		records = append(records, storage.DropRecord{Run: run, Stage: storage.DropStageNoise, Profile: profile.Name, Rule: rule, Evidence: file, Dropped: n})
+		noisy += n
+	}
+	for limit, n := range resp.Truncated {
+		records = append(records, storage.DropRecord{Run: run, Stage: storage.DropStageBudget, Profile: policy, Rule: limit, Evidence: file, Dropped: n})
+		truncated += n
+	}
+	if err := p.store.RecordDrops(ctx, records); err != nil {
+		p.log("Could not record dropped events of %s: %v", file, err)
+	}
+	if noisy > 0 {
+		p.log("Noise profile %q dropped %d events from %s", profile.Name, noisy, filepath.Base(file))
+	}
+	if truncated > 0 {
+		p.log("Budget: %d records of %s left unread (max_events per log or scan limit)", truncated, filepath.Base(file))
+	}
+}
+
+// recordBudgetDrops stores what the budget policy dropped during a run and logs a summary,
+// so the analyst knows the timeline is incomplete and where.
+func (p *Pipeline) recordBudgetDrops(ctx context.Context, run string, b *budget.Budget) {
+	drops := b.Dropped()
+	if len(drops) == 0 {
+		return
+	}
+	records := make([]storage.DropRecord, 0, len(drops))
+	total := 0
+	var parts []string
+	for _, d := range drops {
+		records = append(records, storage.DropRecord{Run: run, Stage: storage.DropStageBudget, Profile: b.PolicyName(), Rule: d.Rule, Source: d.Source, Host: d.Host, Dropped: d.Dropped})
+		total += d.Dropped
+		where := d.Source
+		if d.Host != "" {
+			where += "@" + d.Host
+		}
+		parts = append(parts, fmt.Sprintf("%s %d (%s)", where, d.Dropped, d.Rule))
+	}
+	if err := p.store.RecordDrops(ctx, records); err != nil {
+		p.log("Could not record budget drops: %v", err)
+	}
+	if len(parts) > 10 {
+		parts = append(parts[:10], fmt.Sprintf("%d more", len(parts)-10))
+	}
+	p.log("Pipeline: Budget policy %q dropped %d events, the timeline is incomplete: %s", b.PolicyName(), total, strings.Join(parts, ", "))
+}
+
+// recordHostTimezones stores host time zones read from SYSTEM hives, which the store uses to
+// show events in host time.
+func (p *Pipeline) recordHostTimezones(ctx context.Context, artifacts []model.Artifact) {
+	for _, a := range artifacts {
+		if a.Type != plugin.HostTimezoneArtifact || a.Host == "" {
+			continue
+		}
+		tz, err := model.ParseHostTimezone(a.Metadata)
+		if err != nil {
+			p.log("Ignoring time zone of %s: %v", a.Host, err)
+			continue
+		}
+		if err := p.store.SetHostTimezone(ctx, a.Host, tz); err != nil {
+			p.log("Could not record time zone of %s: %v", a.Host, err)
+		}
+	}
+}
+
+// hashFile returns the SHA-256 and size of the bytes actually parsed (the locked-file copy
+// when one was made).
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// eventIdentifier stamps the events of one parser run with a content fingerprint and a stable
+// ID, so that re-ingesting the same evidence yields the same IDs and duplicates can be
+// recognised. Identical records of the run are numbered (model.RepeatFingerprint) so the
+// timeline does not collapse them into one.
+type eventIdentifier struct {
+	evidenceHash string
+	parser       string
+	mu           sync.Mutex
+	seen         map[string]int // fingerprint → records seen with it
+}
+
+func newEventIdentifier(evidenceHash, parser string) *eventIdentifier {
+	return &eventIdentifier{evidenceHash: evidenceHash, parser: parser, seen: make(map[string]int)}
+}
+
+func (id *eventIdentifier) identify(ev *model.TimelineEvent) {
+	if ev.EvidenceRef.SHA256 == "" {
+		ev.EvidenceRef.SHA256 = id.evidenceHash
+	}
+	fp := model.EventFingerprint(id.parser, *ev)
+	id.mu.Lock()
+	n := id.seen[fp]
+	id.seen[fp] = n + 1
+	id.mu.Unlock()
+	ev.Fingerprint = model.RepeatFingerprint(fp, n)
+	ev.ID = model.StableEventID(id.evidenceHash, id.parser, ev.EvidenceRef.Offset, ev.Fingerprint)
+}
+
+// identified wraps a stream callback with a fresh eventIdentifier.
+func identified(cb func(model.TimelineEvent), evidenceHash, parser string) func(model.TimelineEvent) {
+	id := newEventIdentifier(evidenceHash, parser)
+	return func(ev model.TimelineEvent) {
+		id.identify(&ev)
+		cb(ev)
+	}
+}
+
+// registerEvidence records a parsed file in the case with its SHA-256.
+func (p *Pipeline) registerEvidence(ctx context.Context, original, hash string, size int64, parsers []string, liveHost string) {
+	if len(parsers) == 0 {
+		return
+	}
+	loc := storage.EvidenceLocation{
+		Path:      original,
+		SizeBytes: size,
+		SHA256:    hash,
+		Parsers:   parsers,
+		LiveHost:  liveHost,
+	}
+	if err := p.store.RegisterEvidence(ctx, loc); err != nil {
+		p.log("Could not register evidence %s: %v", original, err)
+	}
+}
+
 func isSystemHive(path string) (bool, string) {
 	// Simple check: is it in config folder?
 	// C:\Windows\System32\config\SYSTEM
@@ -674,13 +1016,15 @@ func isSystemHive(path string) (bool, string) {
 	return false, ""
 }
 
-func (p *Pipeline) findParserFor(path string, header []byte) pluginsdk.ParserPlugin {
+// findParsersFor returns every registered parser accepting the file, in registry order.
+func (p *Pipeline) findParsersFor(path string, header []byte) []pluginsdk.ParserPlugin {
+	var matched []pluginsdk.ParserPlugin
 	for _, parser := range p.parsers {
 		if parser.CanParse(path, header) {
-			return parser
+			matched = append(matched, parser)
 		}
 	}
-	return nil
+	return matched
 }
 
 func inferSource(path string) string {
@@ -713,9 +1057,55 @@ func (p *Pipeline) Analyze(ctx context.Context, analyzers []pluginsdk.AnalyzerPl
 		if err != nil {
 			return fmt.Errorf("analyzer %s: %w", analyzer.Manifest().Name, err)
 		}
+		for i := range resp.Findings {
+			resp.Findings[i].Attack = attack.Default().Enrich(resp.Findings[i].Attack)
+		}
+		p.ApplyAllowlist(resp.Findings, timeline)
 		if err := p.store.SaveFindings(ctx, resp.Findings); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// ApplyAllowlist marks findings whose events are all covered by an allowlist entry. The
+// findings are kept, with the suppression recorded, so the decision stays reviewable.
+// Findings that name no events are left alone.
+func (p *Pipeline) ApplyAllowlist(findings []model.Finding, timeline []model.TimelineEvent) {
+	applyAllowlist(p.currentAllowlist(), findings, timeline)
+}
+
+func applyAllowlist(allow *allowlist.List, findings []model.Finding, timeline []model.TimelineEvent) {
+	if allow.Len() == 0 || len(findings) == 0 {
+		return
+	}
+	byID := make(map[string]*model.TimelineEvent, len(timeline))
+	for i := range timeline {
+		byID[timeline[i].ID] = &timeline[i]
+	}
+	for i := range findings {
+		f := &findings[i]
+		if len(f.EventIDs) == 0 {
+			continue
+		}
+		var sup *model.Suppression
+		for _, id := range f.EventIDs {
+			ev := byID[id]
+			if ev == nil {
+				sup = nil
+				break
+			}
+			s := allow.Match(ev, f.RuleID, f.Title)
+			if s == nil || (sup != nil && s.Action != sup.Action) {
+				sup = nil
+				break
+			}
+			if sup == nil {
+				sup = s
+			}
+		}
+		if sup != nil {
+			allowlist.ApplyToFinding(f, sup)
+		}
+	}
+}
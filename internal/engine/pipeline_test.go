@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gtrace/internal/allowlist"
+	"gtrace/internal/storage"
+	"gtrace/pkg/analyzers"
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+// flaggingParser streams one flagged service event and reports a finding on it, the way
+// the services parser does.
+type flaggingParser struct{}
+
+func (flaggingParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{Name: "test-flagging-parser", Type: "parser"}
+}
+
+func (flaggingParser) CanParse(string, []byte) bool { return true }
+
+func (flaggingParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	ev := model.TimelineEvent{
+		ID: "svc-Updater", Source: "Registry", Artifact: "Service", Subject: "Updater",
+		Details:     map[string]string{"ImagePath": `C:\Users\Public\updater.exe`, "_Alert": "user-writable path"},
+		EvidenceRef: model.EvidenceRef{SourcePath: in.EvidencePath},
+	}
+	in.StreamCallback(ev)
+	return &pluginsdk.ParseResponse{Findings: []model.Finding{{
+		ID: "svc-user-writable-path-Updater", Severity: "high", RuleID: "svc-user-writable-path", EventIDs: []string{ev.ID},
+	}}}, nil
+}
+
+func TestParserFindingsAllowlisted(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	evidence := filepath.Join(t.TempDir(), "SYSTEM")
+	if err := os.WriteFile(evidence, []byte("regf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rules := filepath.Join(t.TempDir(), "allowlist.yaml")
+	if err := os.WriteFile(rules, []byte(`entries:
+  - reason: vendor updater
+    action: downgrade
+    paths: ['C:\Users\Public\updater.exe']
+    path_fields: [ImagePath]
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	list, err := allowlist.Load(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPipeline(store, []pluginsdk.ParserPlugin{flaggingParser{}}, nil, nil)
+	p.SetAllowlist(list)
+	if err := p.Triage(ctx, evidence, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := store.QueryTimeline(ctx, &model.TimelineFilter{})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("timeline = %v, %v", events, err)
+	}
+	findings, err := store.QueryFindings(ctx)
+	if err != nil || len(findings) != 1 {
+		t.Fatalf("findings = %v, %v", findings, err)
+	}
+	f := findings[0]
+	if len(f.EventIDs) != 1 || f.EventIDs[0] != events[0].ID {
+		t.Errorf("finding names %v, stored event is %s", f.EventIDs, events[0].ID)
+	}
+	if f.Suppression == nil || f.Severity != "medium" {
+		t.Errorf("finding not allowlisted: severity %s, suppression %+v", f.Severity, f.Suppression)
+	}
+}
+
+// serviceParser reads "name=image" service lines from a fake SYSTEM hive; the same lines on
+// several hosts stand for a service of their common image.
+type serviceParser struct{}
+
+func (serviceParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{Name: "test-service-parser", Type: "parser"}
+}
+
+func (serviceParser) CanParse(string, []byte) bool { return true }
+
+func (serviceParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	data, err := os.ReadFile(in.EvidencePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		name, image, _ := strings.Cut(line, "=")
+		in.StreamCallback(model.TimelineEvent{
+			EventTime: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Source: "Registry", Artifact: "Service", Subject: name,
+			Details:     map[string]string{"ServiceName": name, "ImagePath": image},
+			EvidenceRef: model.EvidenceRef{SourcePath: in.EvidencePath},
+		})
+	}
+	return &pluginsdk.ParseResponse{}, nil
+}
+
+// triageHosts ingests one collection per host, each with a SYSTEM hive holding the given
+// service lines.
+func triageHosts(t *testing.T, p *Pipeline, services map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	hives := make(map[string]string)
+	var candidates []string
+	for host, lines := range services {
+		dir := filepath.Join(root, host, "C", "Windows", "System32", "config")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		hive := filepath.Join(dir, "SYSTEM")
+		if err := os.WriteFile(hive, []byte(lines), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		hives[hive] = host
+		candidates = append(candidates, hive)
+	}
+	computerName := func(path string) string { return hives[path] }
+	if err := p.runTriage(context.Background(), candidates, newHostIndex(candidates, computerName), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTriageKeepsSameRecordOnEachHost(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPipeline(store, []pluginsdk.ParserPlugin{serviceParser{}}, nil, nil)
+	const updater = `Updater=C:\Program Files\Vendor\updater.exe`
+	services := map[string]string{"WS01": updater, "WS02": updater}
+	// The second ingest of the same collections adds nothing.
+	triageHosts(t, p, services)
+	triageHosts(t, p, services)
+
+	events, err := store.QueryTimeline(ctx, &model.TimelineFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]int)
+	for _, ev := range events {
+		got[ev.Host()]++
+	}
+	if len(events) != 2 || got["WS01"] != 1 || got["WS02"] != 1 {
+		t.Fatalf("events per host = %v, want one on WS01 and one on WS02", got)
+	}
+}
+
+// Stacking runs on the stored timeline: a service installed on every host must be stored once
+// per host, or it would look as rare as the one only WS03 has.
+func TestIngestedHostsStack(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPipeline(store, []pluginsdk.ParserPlugin{serviceParser{}}, nil, nil)
+	const common = "Spooler=C:\\Windows\\System32\\spoolsv.exe\nUpdater=C:\\Program Files\\Vendor\\updater.exe"
+	triageHosts(t, p, map[string]string{
+		"WS01": common,
+		"WS02": common,
+		"WS03": common + "\nWinSvcHelper=C:\\Users\\Public\\helper.exe",
+		"WS04": common,
+	})
+
+	events, err := store.QueryTimeline(ctx, &model.TimelineFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&analyzers.HostStackingAnalyzer{MaxPrevalence: 0.25}).Analyze(ctx, pluginsdk.AnalyzeRequest{Timeline: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Findings) != 1 || !strings.Contains(resp.Findings[0].Title, "1 of 4 hosts") || !strings.Contains(resp.Findings[0].Description, "WinSvcHelper") {
+		t.Fatalf("findings = %+v", resp.Findings)
+	}
+}
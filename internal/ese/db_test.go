@@ -0,0 +1,317 @@
+package ese
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+const testPageSize = 4096
+
+// testEntry is a B-tree node entry; common >= 0 reuses that many bytes of the page prefix.
+type testEntry struct {
+	common int
+	key    []byte
+	data   []byte
+}
+
+// buildPage lays out a small-page (40 byte header) page: values after the header and the
+// tag array growing down from the end, tag 0 holding the common key prefix.
+func buildPage(flags uint32, prefix []byte, entries []testEntry) []byte {
+	p := make([]byte, testPageSize)
+	values := [][]byte{prefix}
+	tagFlags := []uint16{0}
+	for _, e := range entries {
+		var v []byte
+		var f uint16
+		if e.common >= 0 {
+			v = binary.LittleEndian.AppendUint16(v, uint16(e.common))
+			f = tagFlagCommonKey
+		}
+		v = binary.LittleEndian.AppendUint16(v, uint16(len(e.key)))
+		v = append(v, e.key...)
+		values = append(values, append(v, e.data...))
+		tagFlags = append(tagFlags, f)
+	}
+	off := 0
+	for i, v := range values {
+		copy(p[40+off:], v)
+		pos := len(p) - 4*(i+1)
+		binary.LittleEndian.PutUint16(p[pos:], uint16(len(v)))
+		binary.LittleEndian.PutUint16(p[pos+2:], uint16(off)|tagFlags[i]<<13)
+		off += len(v)
+	}
+	binary.LittleEndian.PutUint16(p[34:], uint16(len(values)))
+	binary.LittleEndian.PutUint32(p[36:], flags)
+	return p
+}
+
+// testTagged is a tagged column value with its flag byte.
+type testTagged struct {
+	id    uint16
+	flag  byte
+	value []byte
+}
+
+// buildRecord encodes a record: header, fixed values, variable end offsets and data
+// (nil marks NULL), then the tagged column directory and data.
+func buildRecord(lastFixed byte, fixed [][]byte, vars [][]byte, tagged []testTagged) []byte {
+	rec := []byte{lastFixed, 0, 0, 0}
+	if len(vars) > 0 {
+		rec[1] = byte(127 + len(vars))
+	}
+	for _, f := range fixed {
+		rec = append(rec, f...)
+	}
+	binary.LittleEndian.PutUint16(rec[2:], uint16(len(rec)))
+	var data []byte
+	for _, v := range vars {
+		end := uint16(len(data) + len(v))
+		if v == nil {
+			end |= 0x8000
+		}
+		rec = binary.LittleEndian.AppendUint16(rec, end)
+		data = append(data, v...)
+	}
+	rec = append(rec, data...)
+	off := 4 * len(tagged)
+	var body []byte
+	for _, t := range tagged {
+		rec = binary.LittleEndian.AppendUint16(rec, t.id)
+		rec = binary.LittleEndian.AppendUint16(rec, uint16(off)|0x4000)
+		body = append(body, t.flag)
+		body = append(body, t.value...)
+		off += 1 + len(t.value)
+	}
+	return append(rec, body...)
+}
+
+func u16(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+func u32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func utf16le(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+func oleDate(t time.Time) []byte {
+	days := t.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	return binary.LittleEndian.AppendUint64(nil, math.Float64bits(days))
+}
+
+// catalogRecord encodes an MSysObjects row.
+func catalogRecord(objID uint32, typ uint16, id, coltypOrFDP, size, codePage uint32, name string) []byte {
+	fixed := [][]byte{u32(objID), u16(typ), u32(id), u32(coltypOrFDP), u32(size), u32(0), u32(codePage)}
+	return buildRecord(7, fixed, [][]byte{[]byte(name)}, nil)
+}
+
+var testWhen = time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+
+// buildTestDB writes a database with one table, "Events", whose catalog is a two-level
+// B-tree and whose long values live in a separate LV tree.
+func buildTestDB(t *testing.T) []byte {
+	t.Helper()
+	const (
+		objID     = 2
+		tablePage = 10
+		lvPage    = 12
+		lid       = 0x2A
+	)
+	pages := make(map[uint32][]byte)
+
+	// Catalog root (branch) -> leaves 5 and 6; branch entries end with the child page.
+	pages[catalogPage] = buildPage(0, nil, []testEntry{
+		{common: -1, key: []byte{0x01}, data: u32(5)},
+		{common: -1, key: []byte{0x02}, data: u32(6)},
+	})
+	pages[5] = buildPage(pageFlagLeaf, nil, []testEntry{
+		{common: -1, key: []byte{0x01, 0x01}, data: catalogRecord(objID, catalogTypeTable, objID, tablePage, 0, 0, "Events")},
+	})
+	pages[6] = buildPage(pageFlagLeaf, []byte{0x02, 0x00}, []testEntry{
+		{common: 1, key: []byte{0x01}, data: catalogRecord(objID, catalogTypeColumn, 1, ColumnTypeLong, 4, 0, "Id")},
+		{common: 1, key: []byte{0x02}, data: catalogRecord(objID, catalogTypeColumn, 2, ColumnTypeDateTime, 8, 0, "When")},
+		{common: 2, key: []byte{0x03}, data: catalogRecord(objID, catalogTypeColumn, 128, ColumnTypeText, 0, 1200, "Name")},
+		{common: 2, key: []byte{0x04}, data: catalogRecord(objID, catalogTypeColumn, 129, ColumnTypeText, 0, 1200, "Comment")},
+		{common: 2, key: []byte{0x05}, data: catalogRecord(objID, catalogTypeColumn, 256, ColumnTypeLongText, 0, 1200, "Note")},
+		{common: 2, key: []byte{0x06}, data: catalogRecord(objID, catalogTypeColumn, 257, ColumnTypeText, 0, 1252, "App")},
+		{common: 2, key: []byte{0x07}, data: catalogRecord(objID, catalogTypeColumn, 258, ColumnTypeBinary, 0, 0, "Tags")},
+		{common: 2, key: []byte{0x08}, data: catalogRecord(objID, catalogTypeLongValue, 0, lvPage, 0, 0, "LV")},
+	})
+
+	note := utf16le("a long value split across chunks")
+	pages[tablePage] = buildPage(pageFlagLeaf, nil, []testEntry{
+		{common: -1, key: []byte{0x7F, 0x01}, data: buildRecord(2,
+			[][]byte{u32(7), oleDate(testWhen)},
+			[][]byte{utf16le("first"), nil},
+			[]testTagged{
+				{id: 256, flag: taggedFlagLongValue, value: u32(lid)},
+				{id: 257, flag: taggedFlagCompressed, value: []byte{0x0b, 0x53, 0x69, 0xb5, 0x09}},
+				{id: 258, flag: taggedFlagMultiValue, value: []byte{4, 0, 7, 0, 'a', 'b', 'c', 'd', 'e'}},
+			})},
+		{common: -1, key: []byte{0x7F, 0x02}, data: buildRecord(1,
+			[][]byte{u32(8)},
+			[][]byte{utf16le("second"), utf16le("hello")},
+			nil)},
+	})
+
+	// LV tree: the LID key holds the header; (LID, offset) keys hold the chunks, stored
+	// out of order and sharing the LID as common prefix.
+	pages[lvPage] = buildPage(pageFlagLeaf|pageFlagLongValue, be32(lid), []testEntry{
+		{common: 4, key: nil, data: u32(uint32(len(note)))},
+		{common: 4, key: be32(20), data: note[20:]},
+		{common: 4, key: be32(0), data: note[:20]},
+		{common: -1, key: append(be32(lid+1), be32(0)...), data: []byte("other")},
+	})
+
+	db := make([]byte, (lvPage+2)*testPageSize)
+	binary.LittleEndian.PutUint32(db[4:], fileSignature)
+	binary.LittleEndian.PutUint32(db[8:], 0x620)
+	binary.LittleEndian.PutUint32(db[232:], 0x11)
+	binary.LittleEndian.PutUint32(db[236:], testPageSize)
+	for n, p := range pages {
+		copy(db[int(n+1)*testPageSize:], p)
+	}
+	return db
+}
+
+func TestOpenCatalog(t *testing.T) {
+	db, err := Open(bytes.NewReader(buildTestDB(t)))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := db.Tables(); len(got) != 1 || got[0] != "Events" {
+		t.Fatalf("tables = %v", got)
+	}
+	tbl := db.Table("EVENTS")
+	if tbl == nil {
+		t.Fatal("table lookup should be case-insensitive")
+	}
+	if tbl.FDP != 10 || tbl.LVFDP != 12 {
+		t.Errorf("FDP = %d, LVFDP = %d", tbl.FDP, tbl.LVFDP)
+	}
+	var names []string
+	for _, c := range tbl.Columns {
+		names = append(names, c.Name)
+	}
+	if want := "Id When Name Comment Note App Tags"; strings.Join(names, " ") != want {
+		t.Errorf("columns = %s, want %s", strings.Join(names, " "), want)
+	}
+}
+
+func TestRows(t *testing.T) {
+	db, err := Open(bytes.NewReader(buildTestDB(t)))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	var recs []*Record
+	if err := db.Rows(db.Table("Events"), func(r *Record) error {
+		recs = append(recs, r)
+		return nil
+	}); err != nil {
+		t.Fatalf("Rows: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(recs))
+	}
+
+	r := recs[0]
+	if r.Int("Id") != 7 || r.Format("Id") != "7" {
+		t.Errorf("Id = %d", r.Int("Id"))
+	}
+	if !r.Time("When").Equal(testWhen) {
+		t.Errorf("When = %s", r.Time("When"))
+	}
+	if r.String("Name") != "first" {
+		t.Errorf("Name = %q", r.String("Name"))
+	}
+	if r.Has("Comment") {
+		t.Errorf("NULL variable column should be absent, got %q", r.Bytes("Comment"))
+	}
+	if got := r.String("Note"); got != "a long value split across chunks" {
+		t.Errorf("long value Note = %q", got)
+	}
+	if got := r.String("App"); got != "SRUM" {
+		t.Errorf("compressed App = %q", got)
+	}
+	if got := string(r.Bytes("Tags")); got != "abc" {
+		t.Errorf("multi-value Tags = %q, want first instance", got)
+	}
+
+	r = recs[1]
+	if r.Int("Id") != 8 || r.Has("When") {
+		t.Errorf("Id = %d, When present = %v; fixed columns past lastFixed must be absent", r.Int("Id"), r.Has("When"))
+	}
+	if r.String("Name") != "second" || r.String("Comment") != "hello" {
+		t.Errorf("Name = %q, Comment = %q", r.String("Name"), r.String("Comment"))
+	}
+	if r.Has("Note") {
+		t.Error("record without tagged data should have no tagged columns")
+	}
+}
+
+func TestEntriesCommonKey(t *testing.T) {
+	db := &DB{pageSize: testPageSize}
+	data := buildPage(pageFlagLeaf, []byte("prefix"), []testEntry{
+		{common: 3, key: []byte("-a"), data: []byte("A")},
+		{common: -1, key: []byte("own"), data: []byte("B")},
+		{common: 99, key: []byte("!"), data: []byte("C")}, // clamped to the prefix length
+	})
+	p := &page{data: data, flags: pageFlagLeaf, header: 40, tags: 4}
+	got := db.entries(p)
+	want := []struct{ key, data string }{{"pre-a", "A"}, {"own", "B"}, {"prefix!", "C"}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d entries, got %d", len(want), len(got))
+	}
+	for i, w := range want {
+		if string(got[i].key) != w.key || string(got[i].data) != w.data {
+			t.Errorf("entry %d = %q/%q, want %q/%q", i, got[i].key, got[i].data, w.key, w.data)
+		}
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	if _, err := Open(bytes.NewReader(make([]byte, testPageSize))); err == nil {
+		t.Error("expected error for missing signature")
+	}
+	db := buildTestDB(t)
+	binary.LittleEndian.PutUint32(db[236:], 1000)
+	if _, err := Open(bytes.NewReader(db)); err == nil {
+		t.Error("expected error for unsupported page size")
+	}
+}
+
+// pageCounter counts page reads by page number.
+type pageCounter struct {
+	r     *bytes.Reader
+	reads map[int64]int
+}
+
+func (c *pageCounter) ReadAt(p []byte, off int64) (int, error) {
+	c.reads[off/testPageSize-1]++
+	return c.r.ReadAt(p, off)
+}
+
+func TestLongValueTreeWalkedOnce(t *testing.T) {
+	r := &pageCounter{r: bytes.NewReader(buildTestDB(t)), reads: make(map[int64]int)}
+	db, err := Open(r)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := db.Rows(db.Table("Events"), func(*Record) error { return nil }); err != nil {
+			t.Fatalf("Rows: %v", err)
+		}
+	}
+	if n := r.reads[12]; n != 1 {
+		t.Errorf("LV page read %d times, want 1", n)
+	}
+}
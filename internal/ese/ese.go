@@ -0,0 +1,670 @@
+// Package ese is a minimal read-only reader for Extensible Storage Engine (JET Blue) databases
+// such as SRUDB.dat, Windows.edb and ActiveSync stores.
+//
+// It walks table B-trees from the catalog (MSysObjects) and decodes records into raw column
+// values. Only what forensic parsers need is implemented: fixed, variable and tagged columns,
+// 7-bit compressed tagged values and long values. Indexes, space trees and XPRESS compressed
+// values are ignored.
+package ese
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	fileSignature = 0x89ABCDEF
+	catalogPage   = 4
+
+	pageFlagLeaf      = 0x0002
+	pageFlagSpaceTree = 0x0020
+	pageFlagLongValue = 0x0080
+
+	tagFlagCommonKey = 0x4
+
+	taggedFlagCompressed = 0x02
+	taggedFlagLongValue  = 0x04
+	taggedFlagMultiValue = 0x08
+
+	catalogTypeTable     = 1
+	catalogTypeColumn    = 2
+	catalogTypeLongValue = 4
+)
+
+// Column types (JET_coltyp).
+const (
+	ColumnTypeBit           = 1
+	ColumnTypeUnsignedByte  = 2
+	ColumnTypeShort         = 3
+	ColumnTypeLong          = 4
+	ColumnTypeCurrency      = 5
+	ColumnTypeIEEESingle    = 6
+	ColumnTypeIEEEDouble    = 7
+	ColumnTypeDateTime      = 8
+	ColumnTypeBinary        = 9
+	ColumnTypeText          = 10
+	ColumnTypeLongBinary    = 11
+	ColumnTypeLongText      = 12
+	ColumnTypeUnsignedLong  = 14
+	ColumnTypeLongLong      = 15
+	ColumnTypeGUID          = 16
+	ColumnTypeUnsignedShort = 17
+)
+
+// Column describes a table column from the catalog.
+type Column struct {
+	ID       uint32
+	Name     string
+	Type     uint32
+	Size     uint32
+	CodePage uint32
+}
+
+// Table describes a table from the catalog.
+type Table struct {
+	Name    string
+	ObjID   uint32
+	FDP     uint32
+	LVFDP   uint32
+	Columns []Column
+}
+
+// DB is an opened ESE database.
+type DB struct {
+	r        io.ReaderAt
+	pageSize int
+	version  uint32
+	revision uint32
+	tables   map[string]*Table
+
+	lvMu    sync.Mutex
+	lvIndex map[uint32]map[string][]lvChunk // LV tree root → LID key → chunks in offset order
+}
+
+// lvChunk is one piece of a separated long value.
+type lvChunk struct {
+	offset uint32
+	data   []byte
+}
+
+// Open reads the database header and catalog.
+func Open(r io.ReaderAt) (*DB, error) {
+	hdr := make([]byte, 240)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if binary.LittleEndian.Uint32(hdr[4:8]) != fileSignature {
+		return nil, fmt.Errorf("not an ESE database")
+	}
+	db := &DB{
+		r:        r,
+		version:  binary.LittleEndian.Uint32(hdr[8:12]),
+		revision: binary.LittleEndian.Uint32(hdr[232:236]),
+		pageSize: int(binary.LittleEndian.Uint32(hdr[236:240])),
+		tables:   make(map[string]*Table),
+	}
+	switch db.pageSize {
+	case 2048, 4096, 8192, 16384, 32768:
+	default:
+		return nil, fmt.Errorf("unsupported page size %d", db.pageSize)
+	}
+	if err := db.loadCatalog(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Tables returns table names in the catalog.
+func (db *DB) Tables() []string {
+	var names []string
+	for _, t := range db.tables {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Table returns the named table (case-insensitive) or nil.
+func (db *DB) Table(name string) *Table {
+	return db.tables[strings.ToLower(name)]
+}
+
+// largePages reports the Win7+ layout with 80-byte page headers and in-value tag flags.
+func (db *DB) largePages() bool {
+	return db.version == 0x620 && db.revision >= 17 && db.pageSize > 8192
+}
+
+// page is one decoded database page.
+type page struct {
+	data   []byte
+	flags  uint32
+	header int
+	tags   int
+}
+
+func (db *DB) readPage(n uint32) (*page, error) {
+	buf := make([]byte, db.pageSize)
+	if _, err := db.r.ReadAt(buf, int64(n+1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("read page %d: %w", n, err)
+	}
+	p := &page{
+		data:   buf,
+		flags:  binary.LittleEndian.Uint32(buf[36:40]),
+		header: 40,
+		tags:   int(binary.LittleEndian.Uint16(buf[34:36])),
+	}
+	if db.largePages() {
+		p.header = 80
+	}
+	return p, nil
+}
+
+// tag returns the flags and value of page tag i.
+func (db *DB) tag(p *page, i int) (uint16, []byte) {
+	pos := len(p.data) - 4*(i+1)
+	if pos < p.header {
+		return 0, nil
+	}
+	raw := p.data[pos : pos+4]
+	var size, offset int
+	var flags uint16
+	if db.largePages() {
+		size = int(binary.LittleEndian.Uint16(raw[0:2]) & 0x7fff)
+		offset = int(binary.LittleEndian.Uint16(raw[2:4]) & 0x7fff)
+	} else {
+		size = int(binary.LittleEndian.Uint16(raw[0:2]) & 0x1fff)
+		v := binary.LittleEndian.Uint16(raw[2:4])
+		offset = int(v & 0x1fff)
+		flags = v >> 13
+	}
+	start := p.header + offset
+	if start+size > len(p.data) || size == 0 {
+		return flags, nil
+	}
+	value := append([]byte(nil), p.data[start:start+size]...)
+	if db.largePages() && len(value) >= 2 {
+		flags = uint16(value[1]) >> 5
+		value[1] &= 0x1f
+	}
+	return flags, value
+}
+
+// entry is a B-tree node entry with its reconstructed key.
+type entry struct {
+	key  []byte
+	data []byte
+}
+
+// entries decodes the node entries of page p (tags 1..n), rebuilding keys from the
+// page's common key prefix stored in tag 0.
+func (db *DB) entries(p *page) []entry {
+	_, prefix := db.tag(p, 0)
+	var out []entry
+	for i := 1; i < p.tags; i++ {
+		flags, v := db.tag(p, i)
+		if len(v) < 2 {
+			continue
+		}
+		var key []byte
+		off := 0
+		if flags&tagFlagCommonKey != 0 {
+			common := int(binary.LittleEndian.Uint16(v[0:2]))
+			off = 2
+			if common > len(prefix) {
+				common = len(prefix)
+			}
+			key = append(key, prefix[:common]...)
+		}
+		if off+2 > len(v) {
+			continue
+		}
+		local := int(binary.LittleEndian.Uint16(v[off : off+2]))
+		off += 2
+		if off+local > len(v) {
+			continue
+		}
+		key = append(key, v[off:off+local]...)
+		out = append(out, entry{key: key, data: v[off+local:]})
+	}
+	return out
+}
+
+// walk visits every leaf entry of the B-tree rooted at root.
+func (db *DB) walk(root uint32, fn func(e entry) error) error {
+	visited := make(map[uint32]bool)
+	var visit func(n uint32, depth int) error
+	visit = func(n uint32, depth int) error {
+		if visited[n] || depth > 64 {
+			return nil
+		}
+		visited[n] = true
+		p, err := db.readPage(n)
+		if err != nil {
+			return err
+		}
+		if p.flags&pageFlagSpaceTree != 0 {
+			return nil
+		}
+		for _, e := range db.entries(p) {
+			if p.flags&pageFlagLeaf != 0 {
+				if err := fn(e); err != nil {
+					return err
+				}
+				continue
+			}
+			if len(e.data) >= 4 {
+				if err := visit(binary.LittleEndian.Uint32(e.data[len(e.data)-4:]), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return visit(root, 0)
+}
+
+// catalogColumns is the fixed layout of MSysObjects needed to bootstrap the catalog.
+var catalogColumns = []Column{
+	{ID: 1, Name: "ObjidTable", Type: ColumnTypeLong, Size: 4},
+	{ID: 2, Name: "Type", Type: ColumnTypeShort, Size: 2},
+	{ID: 3, Name: "Id", Type: ColumnTypeLong, Size: 4},
+	{ID: 4, Name: "ColtypOrPgnoFDP", Type: ColumnTypeLong, Size: 4},
+	{ID: 5, Name: "SpaceUsage", Type: ColumnTypeLong, Size: 4},
+	{ID: 6, Name: "Flags", Type: ColumnTypeLong, Size: 4},
+	{ID: 7, Name: "PagesOrLocale", Type: ColumnTypeLong, Size: 4},
+	{ID: 128, Name: "Name", Type: ColumnTypeText},
+}
+
+func (db *DB) loadCatalog() error {
+	byObj := make(map[uint32]*Table)
+	catalog := &Table{Name: "MSysObjects", FDP: catalogPage, Columns: catalogColumns}
+	err := db.walk(catalogPage, func(e entry) error {
+		rec := db.decode(catalog, e.data)
+		objID := uint32(rec.Uint("ObjidTable"))
+		name := string(rec.Values["Name"])
+		switch rec.Uint("Type") {
+		case catalogTypeTable:
+			t := &Table{Name: name, ObjID: objID, FDP: uint32(rec.Uint("ColtypOrPgnoFDP"))}
+			byObj[objID] = t
+			db.tables[strings.ToLower(name)] = t
+		case catalogTypeColumn:
+			if t := byObj[objID]; t != nil {
+				t.Columns = append(t.Columns, Column{
+					ID:       uint32(rec.Uint("Id")),
+					Name:     name,
+					Type:     uint32(rec.Uint("ColtypOrPgnoFDP")),
+					Size:     uint32(rec.Uint("SpaceUsage")),
+					CodePage: uint32(rec.Uint("PagesOrLocale")),
+				})
+			}
+		case catalogTypeLongValue:
+			if t := byObj[objID]; t != nil {
+				t.LVFDP = uint32(rec.Uint("ColtypOrPgnoFDP"))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("read catalog: %w", err)
+	}
+	for _, t := range db.tables {
+		sort.Slice(t.Columns, func(i, j int) bool { return t.Columns[i].ID < t.Columns[j].ID })
+	}
+	if len(db.tables) == 0 {
+		return fmt.Errorf("empty catalog")
+	}
+	return nil
+}
+
+// Record is one decoded table row. Values holds raw column bytes keyed by column name;
+// absent or NULL columns are missing from the map.
+type Record struct {
+	Values  map[string][]byte
+	columns map[string]Column
+}
+
+// Rows calls fn for every record of the table.
+func (db *DB) Rows(t *Table, fn func(*Record) error) error {
+	if t == nil {
+		return fmt.Errorf("nil table")
+	}
+	return db.walk(t.FDP, func(e entry) error {
+		return fn(db.decode(t, e.data))
+	})
+}
+
+// decode parses a record using the data definition layout:
+// header (last fixed id, last variable id, variable offset), fixed values in column order,
+// variable end-offset array + data, then the tagged column directory and data.
+func (db *DB) decode(t *Table, data []byte) *Record {
+	rec := &Record{Values: make(map[string][]byte), columns: make(map[string]Column)}
+	for _, c := range t.Columns {
+		rec.columns[c.Name] = c
+	}
+	if len(data) < 4 {
+		return rec
+	}
+	lastFixed := uint32(data[0])
+	lastVar := uint32(data[1])
+	varOffset := int(binary.LittleEndian.Uint16(data[2:4]))
+	if varOffset > len(data) {
+		return rec
+	}
+
+	// Fixed columns
+	fixedOff := 4
+	for _, c := range t.Columns {
+		if c.ID > lastFixed || c.ID >= 128 {
+			continue
+		}
+		size := int(c.Size)
+		if fixedOff+size > varOffset {
+			break
+		}
+		rec.Values[c.Name] = data[fixedOff : fixedOff+size]
+		fixedOff += size
+	}
+
+	// Variable columns
+	varCount := 0
+	if lastVar >= 128 {
+		varCount = int(lastVar - 127)
+	}
+	varDataStart := varOffset + 2*varCount
+	if varDataStart > len(data) {
+		return rec
+	}
+	prevEnd := 0
+	varEnds := make(map[uint32][2]int)
+	for i := 0; i < varCount; i++ {
+		raw := binary.LittleEndian.Uint16(data[varOffset+2*i:])
+		end := int(raw & 0x7fff)
+		if raw&0x8000 == 0 {
+			varEnds[uint32(128+i)] = [2]int{prevEnd, end}
+		}
+		prevEnd = end
+	}
+	for _, c := range t.Columns {
+		if se, ok := varEnds[c.ID]; ok && varDataStart+se[1] <= len(data) && se[0] <= se[1] {
+			rec.Values[c.Name] = data[varDataStart+se[0] : varDataStart+se[1]]
+		}
+	}
+
+	// Tagged columns
+	taggedStart := varDataStart + prevEnd
+	if taggedStart+4 > len(data) {
+		return rec
+	}
+	tagged := data[taggedStart:]
+	mask := uint16(0x3fff)
+	if db.largePages() {
+		mask = 0x7fff
+	}
+	type taggedItem struct {
+		id     uint32
+		offset int
+		flags  bool
+	}
+	var items []taggedItem
+	dirEnd := int(binary.LittleEndian.Uint16(tagged[2:4]) & mask)
+	for pos := 0; pos+4 <= dirEnd && pos+4 <= len(tagged); pos += 4 {
+		id := uint32(binary.LittleEndian.Uint16(tagged[pos:]))
+		raw := binary.LittleEndian.Uint16(tagged[pos+2:])
+		items = append(items, taggedItem{
+			id:     id,
+			offset: int(raw & mask),
+			flags:  db.largePages() || raw&0x4000 != 0,
+		})
+	}
+	byID := make(map[uint32]Column)
+	for _, c := range t.Columns {
+		byID[c.ID] = c
+	}
+	for i, it := range items {
+		end := len(tagged)
+		if i+1 < len(items) {
+			end = items[i+1].offset
+		}
+		if it.offset > end || end > len(tagged) {
+			continue
+		}
+		c, ok := byID[it.id]
+		if !ok {
+			continue
+		}
+		value := tagged[it.offset:end]
+		var flag byte
+		if it.flags && len(value) > 0 {
+			flag = value[0]
+			value = value[1:]
+		}
+		switch {
+		case flag&taggedFlagLongValue != 0:
+			if lv, err := db.longValue(t, value); err == nil {
+				value = lv
+			} else {
+				continue
+			}
+		case flag&taggedFlagCompressed != 0:
+			dec, err := decompress(value)
+			if err != nil {
+				continue
+			}
+			value = dec
+		case flag&taggedFlagMultiValue != 0:
+			// Multi-valued columns: keep only the first instance.
+			if len(value) >= 2 {
+				first := int(binary.LittleEndian.Uint16(value[0:2]) & 0x7fff)
+				if len(value) >= 4 {
+					next := int(binary.LittleEndian.Uint16(value[2:4]) & 0x7fff)
+					if first < next && next <= len(value) {
+						value = value[first:next]
+					}
+				}
+			}
+		}
+		rec.Values[c.Name] = value
+	}
+	return rec
+}
+
+// longValue reassembles a separated long value from the table's LV tree.
+func (db *DB) longValue(t *Table, ref []byte) ([]byte, error) {
+	if t.LVFDP == 0 || (len(ref) != 4 && len(ref) != 8) {
+		return nil, fmt.Errorf("no long value tree")
+	}
+	index, err := db.longValues(t.LVFDP)
+	if err != nil {
+		return nil, err
+	}
+	// The record stores the LID little-endian; keys store it big-endian.
+	lid := make([]byte, len(ref))
+	for i := range ref {
+		lid[i] = ref[len(ref)-1-i]
+	}
+	chunks := index[string(lid)]
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("long value not found")
+	}
+	var out []byte
+	for _, c := range chunks {
+		out = append(out, c.data...)
+	}
+	return out, nil
+}
+
+// longValues indexes an LV tree by LID, walking it once per database rather than once per
+// value. LV chunk keys are big-endian (LID, offset); the LID key alone holds the LV header
+// and is skipped.
+func (db *DB) longValues(root uint32) (map[string][]lvChunk, error) {
+	db.lvMu.Lock()
+	defer db.lvMu.Unlock()
+	if index, ok := db.lvIndex[root]; ok {
+		return index, nil
+	}
+	index := make(map[string][]lvChunk)
+	err := db.walk(root, func(e entry) error {
+		if n := len(e.key) - 4; n == 4 || n == 8 {
+			lid := string(e.key[:n])
+			index[lid] = append(index[lid], lvChunk{offset: binary.BigEndian.Uint32(e.key[n:]), data: e.data})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, chunks := range index {
+		sort.Slice(chunks, func(i, j int) bool { return chunks[i].offset < chunks[j].offset })
+	}
+	if db.lvIndex == nil {
+		db.lvIndex = make(map[uint32]map[string][]lvChunk)
+	}
+	db.lvIndex[root] = index
+	return index, nil
+}
+
+// decompress handles the 7-bit ASCII/Unicode encodings ESE uses for short compressed values.
+func decompress(data []byte) ([]byte, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("short compressed value")
+	}
+	kind := data[0] >> 3
+	if kind != 1 && kind != 2 {
+		return nil, fmt.Errorf("unsupported compression type %d", kind)
+	}
+	lastBits := int(data[0]&0x07) + 1
+	payload := data[1:]
+	totalBits := (len(payload)-1)*8 + lastBits
+	count := totalBits / 7
+
+	var out []byte
+	var acc uint32
+	var bits int
+	idx := 0
+	for n := 0; n < count; n++ {
+		for bits < 7 && idx < len(payload) {
+			acc |= uint32(payload[idx]) << bits
+			bits += 8
+			idx++
+		}
+		ch := byte(acc & 0x7f)
+		acc >>= 7
+		bits -= 7
+		out = append(out, ch)
+		if kind == 2 {
+			out = append(out, 0)
+		}
+	}
+	return out, nil
+}
+
+// Has reports whether the column has a value.
+func (r *Record) Has(name string) bool {
+	_, ok := r.Values[name]
+	return ok
+}
+
+// Bytes returns the raw column value.
+func (r *Record) Bytes(name string) []byte {
+	return r.Values[name]
+}
+
+// Uint returns an integer column (1, 2, 4 or 8 bytes little-endian).
+func (r *Record) Uint(name string) uint64 {
+	v := r.Values[name]
+	switch len(v) {
+	case 1:
+		return uint64(v[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(v))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(v))
+	case 8:
+		return binary.LittleEndian.Uint64(v)
+	}
+	return 0
+}
+
+// Int returns a signed integer column.
+func (r *Record) Int(name string) int64 {
+	v := r.Values[name]
+	switch len(v) {
+	case 1:
+		return int64(int8(v[0]))
+	case 2:
+		return int64(int16(binary.LittleEndian.Uint16(v)))
+	case 4:
+		return int64(int32(binary.LittleEndian.Uint32(v)))
+	}
+	return int64(r.Uint(name))
+}
+
+// Time decodes a JET DateTime (OLE automation date, days since 1899-12-30) column.
+func (r *Record) Time(name string) time.Time {
+	v := r.Values[name]
+	if len(v) != 8 {
+		return time.Time{}
+	}
+	days := math.Float64frombits(binary.LittleEndian.Uint64(v))
+	if days <= 0 || math.IsNaN(days) || days > 2958465 {
+		return time.Time{}
+	}
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return base.Add(time.Duration(days * float64(24*time.Hour)))
+}
+
+// String decodes a text column using its code page (1200 = UTF-16LE).
+func (r *Record) String(name string) string {
+	v := r.Values[name]
+	if c, ok := r.columns[name]; ok && c.CodePage != 1200 && c.Type == ColumnTypeText {
+		return strings.TrimRight(string(v), "\x00")
+	}
+	return UTF16(v)
+}
+
+// Format renders any column as display text according to its type.
+func (r *Record) Format(name string) string {
+	c := r.columns[name]
+	v := r.Values[name]
+	switch c.Type {
+	case ColumnTypeBit, ColumnTypeUnsignedByte, ColumnTypeUnsignedShort, ColumnTypeUnsignedLong:
+		return fmt.Sprintf("%d", r.Uint(name))
+	case ColumnTypeShort, ColumnTypeLong, ColumnTypeLongLong, ColumnTypeCurrency:
+		return fmt.Sprintf("%d", r.Int(name))
+	case ColumnTypeIEEEDouble:
+		if len(v) == 8 {
+			return fmt.Sprintf("%g", math.Float64frombits(binary.LittleEndian.Uint64(v)))
+		}
+	case ColumnTypeIEEESingle:
+		if len(v) == 4 {
+			return fmt.Sprintf("%g", math.Float32frombits(binary.LittleEndian.Uint32(v)))
+		}
+	case ColumnTypeDateTime:
+		if ts := r.Time(name); !ts.IsZero() {
+			return ts.Format(time.RFC3339)
+		}
+	case ColumnTypeText, ColumnTypeLongText:
+		return r.String(name)
+	}
+	return fmt.Sprintf("%x", v)
+}
+
+// UTF16 decodes a little-endian UTF-16 byte slice, stopping at the first NUL.
+func UTF16(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		c := binary.LittleEndian.Uint16(b[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
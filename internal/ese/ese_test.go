@@ -0,0 +1,17 @@
+package ese
+
+import "testing"
+
+func TestDecompress7Bit(t *testing.T) {
+	// "SRUM" packed as 7-bit ASCII: 28 bits over 4 bytes, 4 bits used in the last byte.
+	got, err := decompress([]byte{0x0b, 0x53, 0x69, 0xb5, 0x09})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "SRUM" {
+		t.Errorf("decompress = %q", got)
+	}
+	if _, err := decompress([]byte{0x18, 0x00}); err == nil {
+		t.Error("expected error for XPRESS-compressed value")
+	}
+}
@@ -0,0 +1,285 @@
+// Package hunt manages saved searches ("hunts") and the YAML hunting packs they are shared in.
+// A hunt is a named timeline query with a description, ATT&CK techniques and a severity.
+// Hunts live in three scopes: the built-in pack, a global library in the user's config
+// directory, and a per-case library; later scopes override earlier ones by id.
+package hunt
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"gtrace/internal/query"
+)
+
+//go:embed builtin.yaml
+var builtinPack []byte
+
+// Scopes, in override order.
+const (
+	ScopeBuiltin = "builtin"
+	ScopeGlobal  = "global"
+	ScopeCase    = "case"
+)
+
+// Hunt is a saved search.
+type Hunt struct {
+	ID          string   `yaml:"id" json:"id"`
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Query       string   `yaml:"query" json:"query"`
+	Attack      []string `yaml:"attack,omitempty" json:"attack,omitempty"` // technique IDs
+	Severity    string   `yaml:"severity,omitempty" json:"severity,omitempty"`
+	Author      string   `yaml:"author,omitempty" json:"author,omitempty"`
+	Scope       string   `yaml:"-" json:"scope"`
+}
+
+// Pack is the YAML document hunts are imported from and exported to.
+type Pack struct {
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Hunts       []Hunt `yaml:"hunts"`
+}
+
+// Validate fills in a missing ID from the name and checks that the query parses.
+func (h *Hunt) Validate() error {
+	h.Name = strings.TrimSpace(h.Name)
+	h.Query = strings.TrimSpace(h.Query)
+	if h.Name == "" {
+		return fmt.Errorf("hunt name required")
+	}
+	if h.Query == "" {
+		return fmt.Errorf("hunt %q: query required", h.Name)
+	}
+	if h.ID == "" {
+		h.ID = slug(h.Name)
+	}
+	if _, err := query.Parse(h.Query); err != nil {
+		return fmt.Errorf("hunt %q: %w", h.ID, err)
+	}
+	switch strings.ToLower(h.Severity) {
+	case "":
+		h.Severity = "medium"
+	case "info", "low", "medium", "high", "critical":
+		h.Severity = strings.ToLower(h.Severity)
+	default:
+		return fmt.Errorf("hunt %q: unknown severity %q", h.ID, h.Severity)
+	}
+	return nil
+}
+
+// slug turns "Failed network logons" into "failed-network-logons".
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// ParsePack reads a hunting pack and validates every hunt in it.
+func ParsePack(data []byte) (*Pack, error) {
+	var p Pack
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse hunting pack: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i := range p.Hunts {
+		if err := p.Hunts[i].Validate(); err != nil {
+			return nil, err
+		}
+		if seen[p.Hunts[i].ID] {
+			return nil, fmt.Errorf("duplicate hunt id %q", p.Hunts[i].ID)
+		}
+		seen[p.Hunts[i].ID] = true
+	}
+	return &p, nil
+}
+
+// Marshal encodes the pack as YAML.
+func (p *Pack) Marshal() ([]byte, error) {
+	return yaml.Marshal(p)
+}
+
+// Builtin returns the hunts shipped with gtrace.
+func Builtin() []Hunt {
+	p, err := ParsePack(builtinPack)
+	if err != nil {
+		panic(fmt.Sprintf("built-in hunting pack: %v", err))
+	}
+	for i := range p.Hunts {
+		p.Hunts[i].Scope = ScopeBuiltin
+	}
+	return p.Hunts
+}
+
+// GlobalPath is the global library file, shared by every case of the current user.
+func GlobalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gtrace", "hunts.yaml"), nil
+}
+
+// CasePath is the case library file. It sits beside, not in, the case data directory,
+// which is cleared when the application exits.
+func CasePath(casePath string) string {
+	return filepath.Join(casePath, "hunts.yaml")
+}
+
+// Library is a hunting pack file that hunts are saved to.
+type Library struct {
+	path  string
+	scope string
+	mu    sync.Mutex
+}
+
+// OpenLibrary returns the library stored at path. The file is created on first save.
+func OpenLibrary(path, scope string) *Library {
+	return &Library{path: path, scope: scope}
+}
+
+// Path returns the library file.
+func (l *Library) Path() string { return l.path }
+
+func (l *Library) load() (*Pack, error) {
+	data, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return &Pack{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParsePack(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.path, err)
+	}
+	for i := range p.Hunts {
+		p.Hunts[i].Scope = l.scope
+	}
+	return p, nil
+}
+
+func (l *Library) store(p *Pack) error {
+	data, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// List returns the hunts in the library.
+func (l *Library) List() ([]Hunt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	return p.Hunts, nil
+}
+
+// Save adds h, or replaces the hunt with the same ID.
+func (l *Library) Save(h Hunt) (Hunt, error) {
+	if err := h.Validate(); err != nil {
+		return h, err
+	}
+	h.Scope = l.scope
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, err := l.load()
+	if err != nil {
+		return h, err
+	}
+	p.Hunts = upsert(p.Hunts, h)
+	return h, l.store(p)
+}
+
+// Delete removes a hunt by ID.
+func (l *Library) Delete(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, err := l.load()
+	if err != nil {
+		return err
+	}
+	for i, h := range p.Hunts {
+		if h.ID == id {
+			p.Hunts = append(p.Hunts[:i], p.Hunts[i+1:]...)
+			return l.store(p)
+		}
+	}
+	return fmt.Errorf("hunt %q not found", id)
+}
+
+// Import merges a hunting pack into the library, replacing hunts with the same IDs.
+// It returns the number of hunts imported.
+func (l *Library) Import(data []byte) (int, error) {
+	in, err := ParsePack(data)
+	if err != nil {
+		return 0, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, err := l.load()
+	if err != nil {
+		return 0, err
+	}
+	if p.Name == "" {
+		p.Name, p.Description = in.Name, in.Description
+	}
+	for _, h := range in.Hunts {
+		h.Scope = l.scope
+		p.Hunts = upsert(p.Hunts, h)
+	}
+	return len(in.Hunts), l.store(p)
+}
+
+func upsert(list []Hunt, h Hunt) []Hunt {
+	for i := range list {
+		if list[i].ID == h.ID {
+			list[i] = h
+			return list
+		}
+	}
+	return append(list, h)
+}
+
+// Merge combines scopes; a hunt in a later list replaces one with the same ID in an earlier
+// list. The result is sorted by name.
+func Merge(scopes ...[]Hunt) []Hunt {
+	var out []Hunt
+	for _, list := range scopes {
+		for _, h := range list {
+			out = upsert(out, h)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	return out
+}
+
+// Export encodes hunts as a hunting pack.
+func Export(name string, hunts []Hunt) ([]byte, error) {
+	return (&Pack{Name: name, Hunts: hunts}).Marshal()
+}
@@ -0,0 +1,123 @@
+package hunt
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gtrace/pkg/model"
+)
+
+func TestLibraryImportSaveDelete(t *testing.T) {
+	lib := OpenLibrary(filepath.Join(t.TempDir(), "hunts.yaml"), ScopeCase)
+	pack := []byte(`
+name: team pack
+hunts:
+  - name: Services from Users
+    query: ImagePath:*\\Users\\*
+    attack: [T1543.003]
+    severity: HIGH
+  - id: log-cleared
+    name: Our log clearing hunt
+    query: eid:1102
+`)
+	n, err := lib.Import(pack)
+	if err != nil || n != 2 {
+		t.Fatalf("Import = %d, %v", n, err)
+	}
+	if _, err := lib.Save(Hunt{Name: "whoami", Query: "whoami"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lib.Save(Hunt{Name: "broken", Query: "(eid:4688"}); err == nil {
+		t.Error("invalid query saved")
+	}
+	if err := lib.Delete("whoami"); err != nil {
+		t.Fatal(err)
+	}
+
+	hunts, err := lib.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hunts) != 2 || hunts[0].ID != "services-from-users" || hunts[0].Severity != "high" || hunts[0].Scope != ScopeCase {
+		t.Fatalf("hunts = %+v", hunts)
+	}
+
+	merged := Merge(Builtin(), hunts)
+	var cleared *Hunt
+	for i := range merged {
+		if merged[i].ID == "log-cleared" {
+			if cleared != nil {
+				t.Fatal("log-cleared listed twice")
+			}
+			cleared = &merged[i]
+		}
+	}
+	if cleared == nil || cleared.Scope != ScopeCase || cleared.Query != "eid:1102" {
+		t.Errorf("case hunt did not override built-in: %+v", cleared)
+	}
+
+	if _, err := ParsePack([]byte("hunts:\n  - {name: a, query: x}\n  - {name: a, query: y}\n")); err == nil {
+		t.Error("duplicate ids accepted")
+	}
+}
+
+func TestRun(t *testing.T) {
+	events := []model.TimelineEvent{
+		{ID: "ev-1", Source: "EventLog", Details: map[string]string{"EventID": "4688",
+			"NewProcessName": `C:\Windows\System32\rundll32.exe`, "CommandLine": `"C:\Windows\System32\rundll32.exe"`}},
+		{ID: "ev-2", Source: "EventLog", Details: map[string]string{"EventID": "4688",
+			"NewProcessName": `C:\Windows\System32\rundll32.exe`, "CommandLine": `rundll32.exe shell32.dll,Control_RunDLL`}},
+		{ID: "ev-3", Source: "Registry", EvidenceRef: model.EvidenceRef{SourcePath: "SYSTEM"},
+			Details: map[string]string{"ImagePath": `C:\Users\bob\AppData\svc.exe`}},
+	}
+	// Every abbreviation of -EncodedCommand, as powershell.go decodes them.
+	for i, cmd := range []string{
+		"powershell.exe -nop -w hidden -enco SQBFAFgA",
+		"powershell /ec SQBFAFgA",
+		"pwsh -EncodedComm SQBFAFgA",
+		"powershell -e SQBFAFgA",
+		"powershell.exe -ExecutionPolicy Bypass -File run.ps1",
+		"7z.exe a -ep out.7z x",
+	} {
+		events = append(events, model.TimelineEvent{ID: fmt.Sprintf("ps-%d", i), Source: "EventLog",
+			Details: map[string]string{"EventID": "4688", "CommandLine": cmd}})
+	}
+	scan := func(ctx context.Context, fn func(ev model.TimelineEvent) error) error {
+		for _, ev := range events {
+			if err := fn(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	results, err := Run(context.Background(), Builtin(), scan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits := make(map[string]Result)
+	for _, r := range results {
+		hits[r.Hunt.ID] = r
+	}
+	if r := hits["rundll32-no-arguments"]; r.Hits != 1 || r.Samples[0].ID != "ev-1" {
+		t.Errorf("rundll32 hunt = %d hits %+v", r.Hits, r.Samples)
+	}
+	r := hits["service-from-users"]
+	if r.Hits != 1 {
+		t.Fatalf("service hunt = %d hits", r.Hits)
+	}
+	f := r.Finding()
+	if f.ID != "hunt-service-from-users" || f.Severity != "high" || len(f.EventIDs) != 1 || f.EventIDs[0] != "ev-3" ||
+		len(f.EvidenceRefs) != 1 || len(f.Attack) != 1 || !strings.Contains(f.Description, "1 events matched") {
+		t.Errorf("finding = %+v", f)
+	}
+	if r := hits["encoded-powershell"]; r.Hits != 4 {
+		t.Errorf("encoded-powershell = %d hits %+v", r.Hits, r.Samples)
+	}
+	if hits["log-cleared"].Hits != 0 {
+		t.Error("log-cleared matched")
+	}
+}
@@ -0,0 +1,87 @@
+package hunt
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gtrace/internal/query"
+	"gtrace/pkg/model"
+)
+
+// MaxSamples is how many matching events a result keeps.
+const MaxSamples = 10
+
+// Result is the outcome of one hunt over the timeline.
+type Result struct {
+	Hunt    Hunt                  `json:"hunt"`
+	Hits    int                   `json:"hits"`
+	Samples []model.TimelineEvent `json:"samples"`
+	RanAt   time.Time             `json:"ran_at"`
+}
+
+// ScanFunc streams the timeline, with annotations attached, to fn.
+type ScanFunc func(ctx context.Context, fn func(ev model.TimelineEvent) error) error
+
+// Run executes every hunt in a single pass over the timeline. Results keep the order of hunts.
+func Run(ctx context.Context, hunts []Hunt, scan ScanFunc) ([]Result, error) {
+	queries := make([]*query.Query, len(hunts))
+	results := make([]Result, len(hunts))
+	now := time.Now().UTC()
+	for i, h := range hunts {
+		q, err := query.Parse(h.Query)
+		if err != nil {
+			return nil, fmt.Errorf("hunt %q: %w", h.ID, err)
+		}
+		queries[i] = q
+		results[i] = Result{Hunt: h, RanAt: now}
+	}
+	err := scan(ctx, func(ev model.TimelineEvent) error {
+		for i, q := range queries {
+			if !q.Match(&ev) {
+				continue
+			}
+			r := &results[i]
+			r.Hits++
+			if len(r.Samples) < MaxSamples {
+				r.Samples = append(r.Samples, ev)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// FindingID is the ID of the finding the hunt records. It is derived from the hunt, so a
+// later run replaces the finding of an earlier one, or retracts it when nothing matches.
+func (r Result) FindingID() string {
+	return "hunt-" + r.Hunt.ID
+}
+
+// Finding records a result with hits.
+func (r Result) Finding() model.Finding {
+	h := r.Hunt
+	f := model.Finding{
+		ID:          r.FindingID(),
+		Severity:    h.Severity,
+		Title:       "Hunt: " + h.Name,
+		Description: fmt.Sprintf("%d events matched %s (run %s).", r.Hits, h.Query, r.RanAt.Format(time.RFC3339)),
+		RuleID:      "hunt:" + h.ID,
+	}
+	if h.Description != "" {
+		f.Description = h.Description + "\n" + f.Description
+	}
+	for _, ev := range r.Samples {
+		f.EventIDs = append(f.EventIDs, ev.ID)
+		if ev.EvidenceRef.SourcePath != "" {
+			f.EvidenceRefs = append(f.EvidenceRefs, ev.EvidenceRef)
+		}
+	}
+	for _, id := range h.Attack {
+		f.Attack = append(f.Attack, model.AttackRef{TechniqueID: id})
+	}
+	return f
+}
@@ -0,0 +1,281 @@
+// Package noise holds the noise-reduction profiles applied while event logs are parsed. A
+// profile is a named list of drop rules; the EVTX parser counts what each rule dropped so the
+// pipeline can report it. Profiles come from the built-in set ("triage", "full"), a global
+// file in the user's config directory and a per-case file; later files override by name.
+package noise
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed builtin.yaml
+var builtinProfiles []byte
+
+// DefaultProfile is used when a triage run names none.
+const DefaultProfile = "triage"
+
+// OptionKey is the triage option naming the profile; MetadataKey is the parse-request
+// metadata entry the pipeline passes the resolved, encoded profile in.
+const (
+	OptionKey   = "noise_profile"
+	MetadataKey = "noise_profile_rules"
+)
+
+// Rule drops the events it matches. All criteria that are set must match: an event ID, a
+// channel pattern, and a value pattern of Field. Unknown restricts the rule to events gtrace
+// has no name for. With Keep > 0 the first Keep matching events of each log are kept.
+type Rule struct {
+	ID          string   `yaml:"id" json:"id"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	EventIDs    []int64  `yaml:"event_ids,omitempty" json:"event_ids,omitempty"`
+	Channels    []string `yaml:"channels,omitempty" json:"channels,omitempty"`
+	Field       string   `yaml:"field,omitempty" json:"field,omitempty"`
+	Values      []string `yaml:"values,omitempty" json:"values,omitempty"` // * and ? wildcards, case-insensitive
+	Unknown     bool     `yaml:"unknown,omitempty" json:"unknown,omitempty"`
+	Keep        int      `yaml:"keep,omitempty" json:"keep,omitempty"`
+
+	channels, values []*regexp.Regexp
+}
+
+// Profile is a named set of rules.
+type Profile struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Rules       []Rule `yaml:"rules,omitempty" json:"rules"`
+	Source      string `yaml:"-" json:"source"` // "builtin" or the file it was loaded from
+}
+
+type file struct {
+	Profiles []Profile `yaml:"profiles"`
+}
+
+// Compile checks the profile and prepares its patterns.
+func (p *Profile) Compile() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("profile name required")
+	}
+	seen := make(map[string]bool)
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.ID == "" {
+			r.ID = "rule-" + strconv.Itoa(i+1)
+		}
+		if seen[r.ID] {
+			return fmt.Errorf("profile %s: duplicate rule id %q", p.Name, r.ID)
+		}
+		seen[r.ID] = true
+		if (r.Field == "") != (len(r.Values) == 0) {
+			return fmt.Errorf("profile %s, rule %s: field and values go together", p.Name, r.ID)
+		}
+		if len(r.EventIDs) == 0 && len(r.Channels) == 0 && r.Field == "" && !r.Unknown {
+			return fmt.Errorf("profile %s, rule %s: would drop every event", p.Name, r.ID)
+		}
+		r.channels = globs(r.Channels)
+		r.values = globs(r.Values)
+	}
+	return nil
+}
+
+func globs(patterns []string) []*regexp.Regexp {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		expr := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(p))
+		out = append(out, regexp.MustCompile("(?is)^"+expr+"$"))
+	}
+	return out
+}
+
+// Parse reads a profile file.
+func Parse(data []byte, source string) ([]Profile, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse noise profiles: %w", err)
+	}
+	for i := range f.Profiles {
+		if err := f.Profiles[i].Compile(); err != nil {
+			return nil, err
+		}
+		f.Profiles[i].Source = source
+	}
+	return f.Profiles, nil
+}
+
+// Builtin returns the profiles shipped with gtrace.
+func Builtin() []Profile {
+	p, err := Parse(builtinProfiles, "builtin")
+	if err != nil {
+		panic(fmt.Sprintf("built-in noise profiles: %v", err))
+	}
+	return p
+}
+
+// Load returns the built-in profiles overridden, by name, by those in the given files in
+// order. Files that do not exist are skipped.
+func Load(paths ...string) ([]Profile, error) {
+	profiles := Builtin()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := Parse(data, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	next:
+		for _, p := range loaded {
+			for i := range profiles {
+				if strings.EqualFold(profiles[i].Name, p.Name) {
+					profiles[i] = p
+					continue next
+				}
+			}
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles, nil
+}
+
+// GlobalPath is the profile file shared by every case of the current user.
+func GlobalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gtrace", "noise_profiles.yaml"), nil
+}
+
+// CasePath is the case profile file, beside the case data directory.
+func CasePath(casePath string) string {
+	return filepath.Join(casePath, "noise_profiles.yaml")
+}
+
+// LoadForCase loads the built-in, global and case profiles.
+func LoadForCase(casePath string) ([]Profile, error) {
+	var paths []string
+	if p, err := GlobalPath(); err == nil {
+		paths = append(paths, p)
+	}
+	return Load(append(paths, CasePath(casePath))...)
+}
+
+// Lookup finds a profile by name; an empty name selects DefaultProfile.
+func Lookup(profiles []Profile, name string) (*Profile, error) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	for i := range profiles {
+		if strings.EqualFold(profiles[i].Name, name) {
+			return &profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown noise profile %q", name)
+}
+
+// Encode serializes a profile for a parser's request metadata.
+func (p *Profile) Encode() string {
+	data, _ := json.Marshal(p)
+	return string(data)
+}
+
+// Decode reads a profile written by Encode.
+func Decode(s string) (*Profile, error) {
+	var p Profile
+	if err := json.Unmarshal([]byte(s), &p); err != nil {
+		return nil, fmt.Errorf("decode noise profile: %w", err)
+	}
+	if err := p.Compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Filter applies a profile to the events of one log and counts the drops of every rule.
+type Filter struct {
+	profile *Profile
+	matched []int
+	dropped map[string]int
+}
+
+// NewFilter returns a filter for p; a nil profile drops nothing.
+func NewFilter(p *Profile) *Filter {
+	if p == nil {
+		p = &Profile{}
+	}
+	return &Filter{profile: p, matched: make([]int, len(p.Rules)), dropped: make(map[string]int)}
+}
+
+// Drop reports whether the event should be discarded. known is whether gtrace has a name
+// for the event ID; fields are the event's data fields.
+func (f *Filter) Drop(eventID int64, channel string, known bool, fields map[string]string) bool {
+	for i := range f.profile.Rules {
+		r := &f.profile.Rules[i]
+		if !r.matches(eventID, channel, known, fields) {
+			continue
+		}
+		f.matched[i]++
+		if r.Keep > 0 && f.matched[i] <= r.Keep {
+			continue
+		}
+		f.dropped[r.ID]++
+		return true
+	}
+	return false
+}
+
+func (r *Rule) matches(eventID int64, channel string, known bool, fields map[string]string) bool {
+	if r.Unknown && known {
+		return false
+	}
+	if len(r.EventIDs) > 0 {
+		found := false
+		for _, id := range r.EventIDs {
+			if id == eventID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.channels) > 0 && !anyMatch(r.channels, channel) {
+		return false
+	}
+	if r.Field != "" && !anyMatch(r.values, fields[r.Field]) {
+		return false
+	}
+	return true
+}
+
+func anyMatch(patterns []*regexp.Regexp, v string) bool {
+	for _, re := range patterns {
+		if re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dropped returns the number of events each rule dropped; rules that dropped nothing are absent.
+func (f *Filter) Dropped() map[string]int {
+	return f.dropped
+}
+
+// ProfileName is the name of the applied profile.
+func (f *Filter) ProfileName() string {
+	return f.profile.Name
+}
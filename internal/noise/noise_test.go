@@ -0,0 +1,99 @@
+package noise
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTriageProfile(t *testing.T) {
+	p, err := Lookup(Builtin(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The profile travels to the parser encoded in request metadata.
+	p, err = Decode(p.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFilter(p)
+	logon := func(user string) map[string]string { return map[string]string{"TargetUserName": user} }
+	for _, tc := range []struct {
+		eid    int64
+		known  bool
+		fields map[string]string
+		drop   bool
+	}{
+		{4624, true, logon("WS01$"), true},
+		{4625, true, logon("DWM-3"), true},
+		{4624, true, logon("alice"), false},
+		{4672, true, logon("WS01$"), false},
+		{4907, true, map[string]string{"ProcessName": `C:\Windows\WinSxS\x\TiWorker.exe`}, true},
+		{4907, true, map[string]string{"ProcessName": `C:\Windows\System32\auditpol.exe`}, false},
+	} {
+		if got := f.Drop(tc.eid, "Security", tc.known, tc.fields); got != tc.drop {
+			t.Errorf("%d %v: drop = %v", tc.eid, tc.fields, got)
+		}
+	}
+	for i := 0; i < 1005; i++ {
+		f.Drop(9999, "Application", false, nil)
+	}
+	want := map[string]int{"machine-account-logons": 1, "service-account-logons": 1, "tiworker-audit-policy": 1, "unknown-event-cap": 5}
+	got := f.Dropped()
+	if len(got) != len(want) {
+		t.Fatalf("dropped = %v", got)
+	}
+	for rule, n := range want {
+		if got[rule] != n {
+			t.Errorf("dropped[%s] = %d, want %d", rule, got[rule], n)
+		}
+	}
+
+	full, err := Lookup(Builtin(), "FULL")
+	if err != nil || NewFilter(full).Drop(4624, "Security", true, logon("WS01$")) {
+		t.Errorf("full profile dropped a machine logon (%v)", err)
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "noise_profiles.yaml")
+	if err := os.WriteFile(path, []byte(`
+profiles:
+  - name: triage
+    rules:
+      - id: machine-account-logons
+        event_ids: [4624]
+        field: TargetUserName
+        values: ['*$']
+  - name: dc
+    description: Domain controllers
+    rules:
+      - id: kerberos-service-tickets
+        event_ids: [4769]
+        channels: [Security]
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := Load(path, filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 3 {
+		t.Fatalf("profiles = %+v", profiles)
+	}
+	triage, _ := Lookup(profiles, "triage")
+	if triage.Source != path || len(triage.Rules) != 1 {
+		t.Errorf("triage not overridden: %+v", triage)
+	}
+	dc, err := Lookup(profiles, "dc")
+	if err != nil || !NewFilter(dc).Drop(4769, "security", true, nil) {
+		t.Errorf("dc profile: %v", err)
+	}
+	if _, err := Lookup(profiles, "nope"); err == nil {
+		t.Error("unknown profile found")
+	}
+
+	if _, err := Parse([]byte("profiles:\n  - name: bad\n    rules:\n      - id: all\n"), "x"); err == nil {
+		t.Error("rule without criteria accepted")
+	}
+}
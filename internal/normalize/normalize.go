@@ -0,0 +1,421 @@
+// Package normalize maps timeline events onto Elastic Common Schema (ECS) and OCSF documents
+// for SIEM ingestion.
+package normalize
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"gtrace/internal/attack"
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+//go:embed mappings.yaml
+var builtinMappings []byte
+
+// ECSVersion and OCSFVersion are the schema versions the documents declare.
+const (
+	ECSVersion  = "8.11.0"
+	OCSFVersion = "1.1.0"
+)
+
+// Table is an ordered set of field mappings. Later entries win ties, so plugin and team
+// mappings added after the built-ins override them.
+type Table struct {
+	mappings []pluginsdk.FieldMapping
+}
+
+// NewTable returns the built-in table extended by the FieldMappings each parser declares.
+func NewTable(parsers []pluginsdk.ParserPlugin) (*Table, error) {
+	t := &Table{}
+	if err := t.AddYAML(builtinMappings); err != nil {
+		return nil, fmt.Errorf("built-in mappings: %w", err)
+	}
+	for _, p := range parsers {
+		t.Add(p.Manifest().FieldMappings...)
+	}
+	return t, nil
+}
+
+// Add appends mappings to the table.
+func (t *Table) Add(m ...pluginsdk.FieldMapping) {
+	t.mappings = append(t.mappings, m...)
+}
+
+// AddYAML appends mappings from a YAML list in the mappings.yaml format.
+func (t *Table) AddYAML(data []byte) error {
+	var list []pluginsdk.FieldMapping
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	for i, m := range list {
+		if m.Source == "" {
+			return fmt.Errorf("mapping %d: source is required", i)
+		}
+	}
+	t.Add(list...)
+	return nil
+}
+
+// Lookup returns the most specific mapping for ev, or nil.
+func (t *Table) Lookup(ev model.TimelineEvent) *pluginsdk.FieldMapping {
+	var best *pluginsdk.FieldMapping
+	bestScore := -1
+	for i := range t.mappings {
+		m := &t.mappings[i]
+		score, ok := matchScore(m, ev)
+		if ok && score >= bestScore {
+			best, bestScore = m, score
+		}
+	}
+	return best
+}
+
+func matchScore(m *pluginsdk.FieldMapping, ev model.TimelineEvent) (int, bool) {
+	if !strings.EqualFold(m.Source, ev.Source) {
+		return 0, false
+	}
+	score := 0
+	if m.Artifact != "" {
+		if !strings.EqualFold(m.Artifact, ev.Artifact) {
+			return 0, false
+		}
+		score += 2
+	}
+	if m.Channel != "" {
+		if !strings.EqualFold(m.Channel, ev.Details["Channel"]) {
+			return 0, false
+		}
+		score += 2
+	}
+	if len(m.EventIDs) > 0 {
+		eid := ev.Details["EventID"]
+		found := false
+		for _, id := range m.EventIDs {
+			if id == eid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+		score += 4
+	}
+	return score, true
+}
+
+// resolve returns the first non-empty candidate value for a mapped field.
+func resolve(ev model.TimelineEvent, candidates []string) string {
+	for _, c := range candidates {
+		var v string
+		switch {
+		case c == "$subject":
+			v = ev.Subject
+		case c == "$action":
+			v = ev.Action
+		case strings.HasSuffix(c, "]") && strings.Contains(c, "["):
+			i := strings.IndexByte(c, '[')
+			v = listValue(ev.Details[c[:i]], c[i+1:len(c)-1])
+		default:
+			v = ev.Details[c]
+		}
+		if v = strings.TrimSpace(v); v != "" && v != "-" {
+			return v
+		}
+	}
+	return ""
+}
+
+// listValue reads name out of "A=1,B=2" (Sysmon Hashes).
+func listValue(list, name string) string {
+	for _, part := range strings.Split(list, ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok && strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// numericFields are leaf names whose values are numbers in both schemas.
+var numericFields = map[string]bool{
+	"pid": true, "port": true, "size": true, "logon_type_id": true,
+}
+
+// typedValue converts numeric leaves, accepting the hex PIDs Windows logs.
+func typedValue(field, v string) any {
+	leaf := field[strings.LastIndexByte(field, '.')+1:]
+	if !numericFields[leaf] {
+		return v
+	}
+	if strings.HasPrefix(strings.ToLower(v), "0x") {
+		if n, err := strconv.ParseInt(v[2:], 16, 64); err == nil {
+			return n
+		}
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return n
+	}
+	return v
+}
+
+// setPath stores v at a dotted path, creating nested objects. An existing value wins.
+func setPath(doc map[string]any, path string, v any) {
+	parts := strings.Split(path, ".")
+	cur := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(map[string]any)
+		if !ok {
+			if _, taken := cur[p]; taken {
+				return
+			}
+			next = make(map[string]any)
+			cur[p] = next
+		}
+		cur = next
+	}
+	if _, taken := cur[parts[len(parts)-1]]; !taken {
+		cur[parts[len(parts)-1]] = v
+	}
+}
+
+func applyFields(doc map[string]any, ev model.TimelineEvent, fields map[string][]string) {
+	for field, candidates := range fields {
+		if v := resolve(ev, candidates); v != "" {
+			setPath(doc, field, typedValue(field, v))
+		}
+	}
+}
+
+// originalDetails keeps the parser's own keys, without the internal "_" annotations.
+func originalDetails(ev model.TimelineEvent) map[string]string {
+	out := make(map[string]string, len(ev.Details))
+	for k, v := range ev.Details {
+		if v != "" && !strings.HasPrefix(k, "_") {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func message(ev model.TimelineEvent) string {
+	if ev.Subject == "" {
+		return ev.Action
+	}
+	return ev.Action + ": " + ev.Subject
+}
+
+// techniques returns the ATT&CK technique IDs, tactic IDs and names an alert maps to.
+func techniques(ev model.TimelineEvent) (ids, names, tacticIDs, tactics []string) {
+	for _, r := range attack.Default().EventRefs(ev) {
+		ids = append(ids, r.TechniqueID)
+		names = append(names, r.Technique)
+		for i, id := range r.TacticIDs {
+			if !contains(tacticIDs, id) {
+				tacticIDs = append(tacticIDs, id)
+				tactics = append(tactics, r.Tactics[i])
+			}
+		}
+	}
+	return ids, names, tacticIDs, tactics
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ECS renders ev as an ECS document.
+func (t *Table) ECS(ev model.TimelineEvent) map[string]any {
+	doc := map[string]any{
+		"@timestamp": ev.EventTime.UTC().Format(time.RFC3339Nano),
+		"message":    message(ev),
+		"ecs":        map[string]any{"version": ECSVersion},
+	}
+	event := map[string]any{
+		"id":       ev.ID,
+		"kind":     "event",
+		"action":   ev.Action,
+		"provider": ev.Source,
+		"dataset":  "gtrace." + strings.ToLower(strings.ReplaceAll(ev.Source, " ", "_")),
+		"module":   "gtrace",
+	}
+	if code := ev.Details["EventID"]; code != "" {
+		event["code"] = code
+	}
+	doc["event"] = event
+
+	if m := t.Lookup(ev); m != nil {
+		if len(m.ECSCategory) > 0 {
+			event["category"] = m.ECSCategory
+		}
+		if len(m.ECSType) > 0 {
+			event["type"] = m.ECSType
+		}
+		applyFields(doc, ev, m.ECS)
+	}
+
+	if alert := ev.Details["_Alert"]; alert != "" {
+		event["kind"] = "alert"
+		setPath(doc, "rule.name", alert)
+		if id := ev.Details["_AlertRuleID"]; id != "" {
+			setPath(doc, "rule.id", id)
+		}
+		if level := ev.Details["_AlertLevel"]; level != "" {
+			setPath(doc, "event.severity", severityID(level))
+			setPath(doc, "log.level", strings.ToLower(level))
+		}
+		if ids, names, tacticIDs, tactics := techniques(ev); len(ids) > 0 {
+			setPath(doc, "threat.framework", "MITRE ATT&CK")
+			setPath(doc, "threat.technique.id", ids)
+			setPath(doc, "threat.technique.name", names)
+			if len(tacticIDs) > 0 {
+				setPath(doc, "threat.tactic.id", tacticIDs)
+				setPath(doc, "threat.tactic.name", tactics)
+			}
+		}
+	}
+	if h := ev.Host(); h != "" {
+		setPath(doc, "host.name", h)
+	}
+	if u := ev.User(); u != "" {
+		setPath(doc, "user.name", u)
+	}
+	if ev.EvidenceRef.SourcePath != "" {
+		setPath(doc, "log.file.path", ev.EvidenceRef.SourcePath)
+	}
+	if len(ev.IOCHits) > 0 {
+		setPath(doc, "threat.indicator.description", strings.Join(ev.IOCHits, ", "))
+	}
+
+	gt := map[string]any{
+		"source":   ev.Source,
+		"artifact": ev.Artifact,
+		"subject":  ev.Subject,
+		"details":  originalDetails(ev),
+	}
+	if len(ev.Annotations) > 0 {
+		gt["annotations"] = ev.Annotations
+		if tags := model.AnnotationValues(ev.Annotations, model.AnnotationTag); len(tags) > 0 {
+			doc["tags"] = tags
+		}
+	}
+	doc["gtrace"] = gt
+	return doc
+}
+
+// ocsfClasses names the OCSF classes used by the mapping table.
+var ocsfClasses = map[int]struct {
+	name        string
+	categoryUID int
+	category    string
+}{
+	0:      {"Base Event", 0, "Uncategorized"},
+	1001:   {"File System Activity", 1, "System Activity"},
+	1005:   {"Module Activity", 1, "System Activity"},
+	1006:   {"Scheduled Job Activity", 1, "System Activity"},
+	1007:   {"Process Activity", 1, "System Activity"},
+	201002: {"Registry Value Activity", 1, "System Activity"},
+	3001:   {"Account Change", 3, "Identity & Access Management"},
+	3002:   {"Authentication", 3, "Identity & Access Management"},
+	4001:   {"Network Activity", 4, "Network Activity"},
+	4002:   {"HTTP Activity", 4, "Network Activity"},
+	4003:   {"DNS Activity", 4, "Network Activity"},
+	5001:   {"Device Inventory Info", 5, "Discovery"},
+}
+
+// severityID maps Sigma/Windows levels onto OCSF severity_id (also used for ECS event.severity).
+func severityID(level string) int {
+	switch strings.ToLower(level) {
+	case "informational", "info":
+		return 1
+	case "low":
+		return 2
+	case "medium":
+		return 3
+	case "high":
+		return 4
+	case "critical":
+		return 5
+	}
+	return 0
+}
+
+// OCSF renders ev as an OCSF event of the mapped class, or Base Event when unmapped.
+func (t *Table) OCSF(ev model.TimelineEvent) map[string]any {
+	class, activity := 0, 0
+	m := t.Lookup(ev)
+	if m != nil {
+		class, activity = m.OCSFClass, m.OCSFActivity
+	}
+	info, ok := ocsfClasses[class]
+	if !ok {
+		// A plugin mapped to a class this table doesn't name; the UID still routes it.
+		info.name = fmt.Sprintf("Class %d", class)
+		info.categoryUID = (class % 100000) / 1000
+	}
+
+	severity := 1
+	if level := ev.Details["_AlertLevel"]; level != "" {
+		if id := severityID(level); id > 0 {
+			severity = id
+		}
+	}
+
+	doc := map[string]any{
+		"class_uid":     class,
+		"class_name":    info.name,
+		"category_uid":  info.categoryUID,
+		"category_name": info.category,
+		"activity_id":   activity,
+		"type_uid":      class*100 + activity,
+		"time":          ev.EventTime.UnixMilli(),
+		"severity_id":   severity,
+		"message":       message(ev),
+		"metadata": map[string]any{
+			"version":  OCSFVersion,
+			"uid":      ev.ID,
+			"log_name": ev.Source,
+			"product":  map[string]any{"name": "gtrace", "vendor_name": "gtrace"},
+		},
+		"unmapped": originalDetails(ev),
+	}
+	if tags := model.AnnotationValues(ev.Annotations, model.AnnotationTag); len(tags) > 0 {
+		doc["metadata"].(map[string]any)["labels"] = tags
+	}
+	if m != nil {
+		applyFields(doc, ev, m.OCSF)
+	}
+	if h := ev.Host(); h != "" {
+		setPath(doc, "device.hostname", h)
+	}
+	if u := ev.User(); u != "" && m == nil {
+		setPath(doc, "actor.user.name", u)
+	}
+	if alert := ev.Details["_Alert"]; alert != "" {
+		enrichment := map[string]any{"name": "sigma", "value": alert, "type": "rule"}
+		if id := ev.Details["_AlertRuleID"]; id != "" {
+			ids, _, tacticIDs, _ := techniques(ev)
+			enrichment["data"] = map[string]any{"rule_id": id, "techniques": ids, "tactics": tacticIDs}
+		}
+		doc["enrichments"] = []any{enrichment}
+	}
+	if len(ev.IOCHits) > 0 {
+		observables := make([]any, 0, len(ev.IOCHits))
+		for _, hit := range ev.IOCHits {
+			observables = append(observables, map[string]any{"name": "ioc", "type_id": 0, "value": hit})
+		}
+		doc["observables"] = observables
+	}
+	return doc
+}
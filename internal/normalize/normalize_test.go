@@ -0,0 +1,96 @@
+package normalize
+
+import (
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+func TestECSAndOCSF(t *testing.T) {
+	table, err := NewTable(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := model.TimelineEvent{
+		ID:        "evtx-1-2",
+		EventTime: time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC),
+		Source:    "EventLog",
+		Action:    "Process Created (Sysmon)",
+		Subject:   "whoami.exe",
+		Details: map[string]string{
+			"EventID":     "1",
+			"Channel":     "Microsoft-Windows-Sysmon/Operational",
+			"Computer":    "WS01",
+			"Image":       `C:\Windows\System32\whoami.exe`,
+			"ProcessId":   "4242",
+			"Hashes":      "MD5=AA,SHA256=BB,IMPHASH=CC",
+			"User":        `CORP\alice`,
+			"_Alert":      "Whoami Execution",
+			"_AlertLevel": "medium",
+			"_Mitre":      "attack.discovery, attack.t1033",
+		},
+	}
+
+	ecs := table.ECS(ev)
+	process := ecs["process"].(map[string]any)
+	if process["executable"] != `C:\Windows\System32\whoami.exe` || process["pid"] != int64(4242) {
+		t.Errorf("process = %v", process)
+	}
+	if hash := process["hash"].(map[string]any); hash["sha256"] != "BB" {
+		t.Errorf("process.hash = %v", hash)
+	}
+	event := ecs["event"].(map[string]any)
+	if event["kind"] != "alert" || event["code"] != "1" {
+		t.Errorf("event = %v", event)
+	}
+	if tech := ecs["threat"].(map[string]any)["technique"].(map[string]any)["id"].([]string); tech[0] != "T1033" {
+		t.Errorf("technique = %v", tech)
+	}
+
+	ocsf := table.OCSF(ev)
+	if ocsf["class_uid"] != 1007 || ocsf["type_uid"] != 100701 || ocsf["severity_id"] != 3 {
+		t.Errorf("ocsf header = %v %v %v", ocsf["class_uid"], ocsf["type_uid"], ocsf["severity_id"])
+	}
+	if ocsf["device"].(map[string]any)["hostname"] != "WS01" {
+		t.Errorf("device = %v", ocsf["device"])
+	}
+
+	// A plugin mapping for the same events overrides the built-in one.
+	table.Add(pluginsdk.FieldMapping{
+		Source:    "EventLog",
+		Channel:   "Microsoft-Windows-Sysmon/Operational",
+		EventIDs:  []string{"1"},
+		OCSFClass: 1007,
+		ECS:       map[string][]string{"process.name": {"$subject"}},
+	})
+	if name := table.ECS(ev)["process"].(map[string]any)["name"]; name != "whoami.exe" {
+		t.Errorf("override process.name = %v", name)
+	}
+
+	// Unmapped sources fall back to the OCSF base event.
+	if base := table.OCSF(model.TimelineEvent{Source: "Unknown"}); base["class_uid"] != 0 {
+		t.Errorf("unmapped class = %v", base["class_uid"])
+	}
+}
+
+func TestSpecialPrivilegesSubject(t *testing.T) {
+	table, err := NewTable(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := model.TimelineEvent{
+		Source: "EventLog",
+		Details: map[string]string{
+			"EventID": "4672", "Channel": "Security", "SubjectUserName": "alice", "SubjectDomainName": "CORP",
+			"SubjectLogonId": "0x3e7a1", "PrivilegeList": "SeDebugPrivilege",
+		},
+	}
+	if user := table.ECS(ev)["user"].(map[string]any); user["name"] != "alice" || user["domain"] != "CORP" {
+		t.Errorf("ECS user = %v", user)
+	}
+	if user := table.OCSF(ev)["user"].(map[string]any); user["name"] != "alice" {
+		t.Errorf("OCSF user = %v", user)
+	}
+}
@@ -0,0 +1,213 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+
+	_ "modernc.org/sqlite" // Register sqlite driver
+)
+
+// ActivitiesCacheParser reads the Windows 10 Timeline database
+// (AppData\Local\ConnectedDevicesPlatform\<account>\ActivitiesCache.db).
+// Each Activity row records an application or document in use with start and end times.
+type ActivitiesCacheParser struct{}
+
+// activityTypes names the ActivityType values seen in ActivitiesCache.db.
+var activityTypes = map[int64]string{
+	2:  "Notification",
+	3:  "Backup Restore",
+	5:  "Open App/File/Page",
+	6:  "App In Use",
+	10: "Clipboard",
+	11: "System",
+	12: "System",
+	15: "System",
+	16: "Copy/Paste",
+}
+
+func (p *ActivitiesCacheParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "win-activitiescache-parser",
+		Version:   "1.0.0",
+		Type:      "parser",
+		Platforms: []string{"windows"},
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "application/vnd.sqlite3",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "windows_timeline",
+		},
+	}
+}
+
+func (p *ActivitiesCacheParser) CanParse(path string, header []byte) bool {
+	return strings.EqualFold(filepath.Base(path), "ActivitiesCache.db") &&
+		strings.HasPrefix(string(header), "SQLite format 3")
+}
+
+func (p *ActivitiesCacheParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(in.EvidencePath)+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite DB: %w", err)
+	}
+	defer db.Close()
+
+	// ClipboardPayload only exists from Windows 10 1809 on.
+	clipboard := "''"
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('Activity') WHERE name = 'ClipboardPayload'`).Scan(&n); err == nil && n > 0 {
+		clipboard = "COALESCE(ClipboardPayload, '')"
+	}
+
+	// Times are Unix seconds; EndTime is 0 while the activity is still open. The platform
+	// of the application is in the AppId JSON; PlatformDeviceId names the device.
+	rows, err := db.QueryContext(ctx, `
+		SELECT hex(Id), AppId, COALESCE(AppActivityId, ''), ActivityType, StartTime,
+		       COALESCE(EndTime, 0), COALESCE(LastModifiedTime, 0), COALESCE(Payload, ''),
+		       COALESCE(PlatformDeviceId, ''), `+clipboard+`
+		FROM Activity
+		ORDER BY StartTime
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	owner := hiveOwner(in)
+	var events []model.TimelineEvent
+	for rows.Next() {
+		var (
+			id, appID, appActivityID  string
+			activityType              int64
+			start, end, modified      int64
+			payload, device, clipData string
+		)
+		if err := rows.Scan(&id, &appID, &appActivityID, &activityType, &start, &end, &modified, &payload, &device, &clipData); err != nil {
+			continue
+		}
+		if start <= 0 {
+			continue
+		}
+		startTime := time.Unix(start, 0).UTC()
+
+		info := parseActivityPayload(payload)
+		app, platform := activityApplication(appID)
+		subject := info.DisplayText
+		if subject == "" {
+			subject = app
+		}
+
+		details := map[string]string{
+			"User":          owner,
+			"Application":   app,
+			"AppActivityId": appActivityID,
+			"ActivityType":  activityTypes[activityType],
+			"DisplayText":   info.DisplayText,
+			"AppDisplay":    info.AppDisplayName,
+			"Description":   info.Description,
+			"ContentUri":    info.ContentURI,
+			"Platform":      platform,
+			"DeviceId":      device,
+			"StartTime":     startTime.Format(time.RFC3339),
+		}
+		if details["ActivityType"] == "" {
+			details["ActivityType"] = strconv.FormatInt(activityType, 10)
+		}
+		if end > 0 {
+			endTime := time.Unix(end, 0).UTC()
+			details["EndTime"] = endTime.Format(time.RFC3339)
+			details["Duration"] = endTime.Sub(startTime).String()
+		}
+		if info.ActiveDuration > 0 {
+			details["ActiveDurationSeconds"] = strconv.FormatInt(info.ActiveDuration, 10)
+		}
+		if modified > 0 {
+			details["LastModifiedTime"] = time.Unix(modified, 0).UTC().Format(time.RFC3339)
+		}
+		if clipData != "" {
+			details["ClipboardPayload"] = clipData
+		}
+
+		evt := model.TimelineEvent{
+			ID:            fmt.Sprintf("activity-%s", id),
+			EventTime:     startTime,
+			TimeSemantics: model.TimeAccessed,
+			Source:        "WindowsTimeline",
+			Artifact:      "ActivitiesCache",
+			Action:        details["ActivityType"],
+			Subject:       subject,
+			Details:       details,
+			Confidence:    "high",
+			EvidenceRef: model.EvidenceRef{
+				SourcePath: in.EvidencePath,
+			},
+		}
+		if in.StreamCallback != nil {
+			in.StreamCallback(evt)
+		} else {
+			events = append(events, evt)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &pluginsdk.ParseResponse{
+		Events: events,
+	}, nil
+}
+
+// activityPayload holds the Payload JSON fields worth surfacing.
+type activityPayload struct {
+	DisplayText    string `json:"displayText"`
+	AppDisplayName string `json:"appDisplayName"`
+	Description    string `json:"description"`
+	ContentURI     string `json:"contentUri"`
+	ActiveDuration int64  `json:"activeDurationSeconds"`
+}
+
+func parseActivityPayload(payload string) activityPayload {
+	var out activityPayload
+	_ = json.Unmarshal([]byte(payload), &out)
+	return out
+}
+
+// activityApplication picks the most useful identifier from the AppId JSON array, preferring
+// Win32 paths over packaged app IDs, and returns it with its platform.
+func activityApplication(appID string) (string, string) {
+	var entries []struct {
+		Application string `json:"application"`
+		Platform    string `json:"platform"`
+	}
+	if err := json.Unmarshal([]byte(appID), &entries); err != nil {
+		return appID, ""
+	}
+	best := -1
+	for i, e := range entries {
+		switch e.Platform {
+		case "windows_win32", "x_exe_path":
+			return e.Application, e.Platform
+		case "windows_universal", "packageId":
+			if best < 0 {
+				best = i
+			}
+		}
+	}
+	if best < 0 && len(entries) > 0 {
+		best = 0
+	}
+	if best < 0 {
+		return "", ""
+	}
+	return entries[best].Application, entries[best].Platform
+}
@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gtrace/pkg/pluginsdk"
+)
+
+func TestActivityApplication(t *testing.T) {
+	appID := `[{"application":"Microsoft.Windows.Explorer","platform":"windows_universal"},{"application":"C:\\Windows\\notepad.exe","platform":"x_exe_path"}]`
+	if app, platform := activityApplication(appID); app != `C:\Windows\notepad.exe` || platform != "x_exe_path" {
+		t.Errorf("activityApplication = %q, %q", app, platform)
+	}
+}
+
+// activitySchema is the Activity table of ActivitiesCache.db as created by Windows 10 1809
+// and later; 1803 lacks the columns from ClipboardPayload on.
+const activitySchema = `CREATE TABLE [Activity]([Id] GUID PRIMARY KEY NOT NULL, [AppId] TEXT NOT NULL,
+	[PackageIdHash] TEXT, [AppActivityId] TEXT, [ActivityType] INT NOT NULL, [ActivityStatus] INT NOT NULL,
+	[ParentActivityId] GUID, [Tag] TEXT, [Group] TEXT, [MatchId] TEXT, [LastModifiedTime] DATETIME NOT NULL,
+	[ExpirationTime] DATETIME NOT NULL, [Payload] BLOB, [Priority] INT, [IsLocalOnly] INT,
+	[PlatformDeviceId] TEXT, [CreatedInCloud] DATETIME, [StartTime] DATETIME, [EndTime] DATETIME,
+	[LastModifiedOnClient] DATETIME, [GroupAppActivityId] TEXT%s, [ETag] INT NOT NULL)`
+
+func TestActivitiesCacheParserParse(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		columns string
+	}{
+		{"1803", ""},
+		{"1809", ", [ClipboardPayload] BLOB, [EnterpriseId] TEXT, [OriginalPayload] BLOB, [OriginalLastModifiedOnClient] DATETIME"},
+	} {
+		dir := filepath.Join(t.TempDir(), "Users", "alice", "AppData", "Local", "ConnectedDevicesPlatform", "L.alice")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "ActivitiesCache.db")
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.Exec(strings.Replace(activitySchema, "%s", tc.columns, 1))
+		if err == nil {
+			_, err = db.Exec(`INSERT INTO Activity (Id, AppId, AppActivityId, ActivityType, ActivityStatus,
+				LastModifiedTime, ExpirationTime, Payload, PlatformDeviceId, StartTime, EndTime, ETag)
+				VALUES (X'0102030405060708090A0B0C0D0E0F10', ?, 'ECB32AF3-1440-4086-94E3-5311F97F89C4', 5, 1,
+				1700000100, 1702592000, ?, 'fKAkW1/tKOcxQ6N0uAAAAA==', 1700000000, 1700000060, 3)`,
+				`[{"application":"{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\\notepad.exe","platform":"windows_win32"},{"application":"Microsoft.Windows.Notepad","platform":"packageId"}]`,
+				[]byte(`{"displayText":"secrets.txt","appDisplayName":"Notepad","activeDurationSeconds":42}`))
+		}
+		db.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		resp, err := (&ActivitiesCacheParser{}).Parse(context.Background(), pluginsdk.ParseRequest{EvidencePath: path})
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tc.name, err)
+		}
+		if len(resp.Events) != 1 {
+			t.Fatalf("%s: %d events, want 1", tc.name, len(resp.Events))
+		}
+		ev := resp.Events[0]
+		if ev.Subject != "secrets.txt" || ev.EventTime.Unix() != 1700000000 || ev.Action != "Open App/File/Page" {
+			t.Errorf("%s: event = %s %s %s", tc.name, ev.Subject, ev.EventTime, ev.Action)
+		}
+		d := ev.Details
+		if d["Application"] != `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\notepad.exe` || d["Platform"] != "windows_win32" ||
+			d["DeviceId"] != "fKAkW1/tKOcxQ6N0uAAAAA==" || d["User"] != "alice" || d["Duration"] != "1m0s" {
+			t.Errorf("%s: details = %v", tc.name, d)
+		}
+	}
+}
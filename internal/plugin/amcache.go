@@ -96,15 +96,16 @@ func (p *AmcacheParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*
 			if fullPath != "" {
 				// Timestamp from Key LastWriteTime
 				// file (CM_KEY_NODE) has LastWriteTime method
-				ts := file.LastWriteTime().Time
+				ts := file.LastWriteTime().Time.UTC()
 
 				evt := model.TimelineEvent{
-					ID:        fmt.Sprintf("amcache-%s-%d", sha1, ts.UnixNano()),
-					EventTime: ts,
-					Source:    "Amcache",
-					Artifact:  "Amcache",
-					Action:    "EXECUTION_EVIDENCE",
-					Subject:   fullPath,
+					ID:            fmt.Sprintf("amcache-%s-%d", sha1, ts.UnixNano()),
+					EventTime:     ts,
+					TimeSemantics: model.TimeKeyWrite,
+					Source:        "Amcache",
+					Artifact:      "Amcache",
+					Action:        "EXECUTION_EVIDENCE",
+					Subject:       fullPath,
 					Details: map[string]string{
 						"path": fullPath,
 						"sha1": sha1,
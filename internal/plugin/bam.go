@@ -0,0 +1,134 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+
+	"www.velocidex.com/golang/regparser"
+)
+
+// BAMParser extracts per-SID last execution times from the Background/Desktop Activity Moderator
+// keys in the SYSTEM hive (Win10 1709+).
+type BAMParser struct{}
+
+func (p *BAMParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "win-bam-parser",
+		Version:   "1.0.0",
+		Type:      "parser",
+		Platforms: []string{"windows"},
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "application/octet-stream",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "bam",
+		},
+	}
+}
+
+func (p *BAMParser) CanParse(path string, header []byte) bool {
+	return isHiveHeader(header) && hiveKind(path) == "SYSTEM"
+}
+
+func (p *BAMParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	f, reg, err := openHive(in.EvidencePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ccs := currentControlSet(reg)
+	profiles := loadProfileList(filepath.Dir(originalPath(in)))
+
+	var events []model.TimelineEvent
+	for _, service := range []string{"bam", "dam"} {
+		// 1809+ moved the per-user keys under State\.
+		for _, sub := range []string{`State\UserSettings`, `UserSettings`} {
+			settings := reg.OpenKey(fmt.Sprintf(`%s\Services\%s\%s`, ccs, service, sub))
+			if settings == nil {
+				continue
+			}
+			for _, sidKey := range settings.Subkeys() {
+				sid := sidKey.Name()
+				user := profiles[strings.ToUpper(sid)]
+				for _, v := range sidKey.Values() {
+					name := v.ValueName()
+					if strings.EqualFold(name, "Version") || strings.EqualFold(name, "SequenceNumber") {
+						continue
+					}
+					vd := v.ValueData()
+					if vd == nil || len(vd.Data) < 8 {
+						continue
+					}
+					ts := regFiletime(vd.Data)
+					if ts.IsZero() {
+						continue
+					}
+					evt := model.TimelineEvent{
+						ID:            fmt.Sprintf("%s-%s-%s-%d", service, sid, name, ts.UnixNano()),
+						EventTime:     ts,
+						TimeSemantics: model.TimeLastRun,
+						Source:        "Registry",
+						Artifact:      strings.ToUpper(service),
+						Action:        "Last Execution",
+						Subject:       name,
+						Details: map[string]string{
+							"ExePath": name,
+							"SID":     sid,
+							"User":    user,
+							"Key":     fmt.Sprintf(`%s\Services\%s\%s\%s`, ccs, service, sub, sid),
+						},
+						EvidenceRef: model.EvidenceRef{
+							SourcePath: in.EvidencePath,
+						},
+					}
+					if in.StreamCallback != nil {
+						in.StreamCallback(evt)
+					} else {
+						events = append(events, evt)
+					}
+				}
+			}
+			break
+		}
+	}
+
+	return &pluginsdk.ParseResponse{
+		Events: events,
+	}, nil
+}
+
+// loadProfileList maps SIDs to profile names using the SOFTWARE hive next to configDir.
+// Returns an empty map when the hive is missing or locked.
+func loadProfileList(configDir string) map[string]string {
+	out := make(map[string]string)
+	path := findSibling(configDir, "SOFTWARE")
+	if path == "" {
+		return out
+	}
+	f, reg, err := openHive(path)
+	if err != nil {
+		return out
+	}
+	defer f.Close()
+	collectProfiles(reg, out)
+	return out
+}
+
+func collectProfiles(reg *regparser.Registry, out map[string]string) {
+	list := reg.OpenKey(`Microsoft\Windows NT\CurrentVersion\ProfileList`)
+	if list == nil {
+		return
+	}
+	for _, sidKey := range list.Subkeys() {
+		if img := regString(sidKey, "ProfileImagePath"); img != "" {
+			out[strings.ToUpper(sidKey.Name())] = extractFileName(img)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gtrace/pkg/pluginsdk"
+)
+
+func TestBAMParserParse(t *testing.T) {
+	const sid = "S-1-5-21-1111111111-2222222222-3333333333-1001"
+	const exe = `\Device\HarddiskVolume3\Users\alice\Downloads\tool.exe`
+	ran := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	root := &testKey{name: "ROOT", subkeys: []*testKey{{
+		name: "ControlSet001",
+		subkeys: []*testKey{{
+			name: "Services",
+			subkeys: []*testKey{{
+				name: "bam",
+				subkeys: []*testKey{{
+					name: "State",
+					subkeys: []*testKey{{
+						name: "UserSettings",
+						subkeys: []*testKey{{
+							name: sid,
+							values: []testValue{
+								{name: "Version", typ: 4, data: []byte{1, 0, 0, 0}},
+								binValue("SequenceNumber", []byte{9, 0, 0, 0, 0, 0, 0, 0}),
+								binValue(exe, append(filetimeBytes(ran), make([]byte, 16)...)),
+							},
+						}},
+					}},
+				}},
+			}},
+		}},
+	}}}
+	path := buildHive(t, "SYSTEM", root)
+
+	p := &BAMParser{}
+	resp, err := p.Parse(context.Background(), pluginsdk.ParseRequest{EvidencePath: path})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(resp.Events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(resp.Events))
+	}
+	ev := resp.Events[0]
+	if !ev.EventTime.Equal(ran) {
+		t.Errorf("time = %s, want %s", ev.EventTime, ran)
+	}
+	if ev.Artifact != "BAM" || ev.Subject != exe || ev.Details["SID"] != sid {
+		t.Errorf("event = %s %q %q", ev.Artifact, ev.Subject, ev.Details["SID"])
+	}
+	if want := `ControlSet001\Services\bam\State\UserSettings\` + sid; ev.Details["Key"] != want {
+		t.Errorf("key = %q", ev.Details["Key"])
+	}
+}
@@ -0,0 +1,740 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+
+	_ "modernc.org/sqlite" // Register sqlite driver
+)
+
+// BrowserParser reads Chromium (Chrome, Edge, Brave, ...) and Firefox profile databases found
+// in evidence trees: History, Cookies and Web Data for Chromium; places.sqlite, downloads.sqlite,
+// cookies.sqlite and formhistory.sqlite for Firefox. Every profile directory is handled,
+// not just Default.
+type BrowserParser struct{}
+
+// browserSource identifies where a database came from.
+type browserSource struct {
+	Browser    string
+	Profile    string
+	User       string
+	SourcePath string
+}
+
+// browserDBKinds maps database file names to the reader handling them.
+var browserDBKinds = map[string]func(context.Context, *sql.DB, browserSource, func(model.TimelineEvent)) error{
+	"history":            readChromiumHistory,
+	"cookies":            readChromiumCookies,
+	"web data":           readChromiumAutofill,
+	"places.sqlite":      readFirefoxPlaces,
+	"downloads.sqlite":   readFirefoxLegacyDownloads,
+	"cookies.sqlite":     readFirefoxCookies,
+	"formhistory.sqlite": readFirefoxFormHistory,
+}
+
+// chromiumTransitions names the core transition type (low byte of visits.transition).
+var chromiumTransitions = []string{
+	"link", "typed", "auto_bookmark", "auto_subframe", "manual_subframe", "generated",
+	"auto_toplevel", "form_submit", "reload", "keyword", "keyword_generated",
+}
+
+// chromiumTransitionQualifiers are the high bits of visits.transition.
+var chromiumTransitionQualifiers = []struct {
+	mask uint32
+	name string
+}{
+	{0x00800000, "blocked"},
+	{0x01000000, "forward_back"},
+	{0x02000000, "from_address_bar"},
+	{0x04000000, "home_page"},
+	{0x08000000, "from_api"},
+	{0x10000000, "chain_start"},
+	{0x20000000, "chain_end"},
+	{0x40000000, "client_redirect"},
+	{0x80000000, "server_redirect"},
+}
+
+var chromiumDangerTypes = map[int64]string{
+	0:  "not_dangerous",
+	1:  "dangerous_file",
+	2:  "dangerous_url",
+	3:  "dangerous_content",
+	4:  "maybe_dangerous_content",
+	5:  "uncommon_content",
+	6:  "user_validated",
+	7:  "dangerous_host",
+	8:  "potentially_unwanted",
+	9:  "allowlisted_by_policy",
+	10: "async_scanning",
+	11: "blocked_password_protected",
+	12: "blocked_too_large",
+	13: "sensitive_content_warning",
+	14: "sensitive_content_block",
+	15: "deep_scanned_safe",
+	16: "deep_scanned_opened_dangerous",
+	17: "prompt_for_scanning",
+}
+
+var chromiumDownloadStates = map[int64]string{
+	0: "in_progress",
+	1: "complete",
+	2: "cancelled",
+	3: "interrupted",
+	4: "interrupted",
+}
+
+var firefoxVisitTypes = map[int64]string{
+	1: "link",
+	2: "typed",
+	3: "bookmark",
+	4: "embed",
+	5: "redirect_permanent",
+	6: "redirect_temporary",
+	7: "download",
+	8: "framed_link",
+	9: "reload",
+}
+
+func (p *BrowserParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "browser-profile-parser",
+		Version:   "1.0.0",
+		Type:      "parser",
+		Platforms: []string{"windows", "linux", "darwin"},
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "application/vnd.sqlite3",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "browser",
+		},
+	}
+}
+
+func (p *BrowserParser) CanParse(path string, header []byte) bool {
+	if !strings.HasPrefix(string(header), "SQLite format 3") {
+		return false
+	}
+	_, ok := browserDBKinds[strings.ToLower(filepath.Base(path))]
+	return ok
+}
+
+func (p *BrowserParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	orig := originalPath(in)
+	src := identifyBrowser(orig)
+	src.User = hiveOwner(in)
+	src.SourcePath = in.EvidencePath
+
+	var events []model.TimelineEvent
+	emit := func(evt model.TimelineEvent) {
+		if in.StreamCallback != nil {
+			in.StreamCallback(evt)
+		} else {
+			events = append(events, evt)
+		}
+	}
+	if err := readBrowserFile(ctx, filepath.Base(orig), in.EvidencePath, src, emit); err != nil {
+		return nil, err
+	}
+	return &pluginsdk.ParseResponse{
+		Events: events,
+	}, nil
+}
+
+// readBrowserFile opens a browser database read-only and dispatches on its file name.
+// dbPath may be a temp copy of the original named name.
+func readBrowserFile(ctx context.Context, name, dbPath string, src browserSource, emit func(model.TimelineEvent)) error {
+	reader, ok := browserDBKinds[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown browser database %s", name)
+	}
+	db, err := sql.Open("sqlite", "file:"+filepath.ToSlash(dbPath)+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite DB: %w", err)
+	}
+	defer db.Close()
+	return reader(ctx, db, src, emit)
+}
+
+// identifyBrowser derives browser and profile names from the database path.
+func identifyBrowser(path string) browserSource {
+	lower := strings.ToLower(filepath.ToSlash(path))
+	src := browserSource{Browser: "Chromium"}
+	switch {
+	case strings.Contains(lower, "mozilla/firefox") || strings.Contains(lower, ".mozilla/firefox"):
+		src.Browser = "Firefox"
+	case strings.Contains(lower, "google/chrome") || strings.Contains(lower, "google-chrome"):
+		src.Browser = "Chrome"
+	case strings.Contains(lower, "microsoft/edge") || strings.Contains(lower, "microsoft edge") || strings.Contains(lower, "microsoft-edge"):
+		src.Browser = "Edge"
+	case strings.Contains(lower, "bravesoftware"):
+		src.Browser = "Brave"
+	case strings.Contains(lower, "opera software"):
+		src.Browser = "Opera"
+	case strings.Contains(lower, "vivaldi"):
+		src.Browser = "Vivaldi"
+	}
+	dir := filepath.Dir(path)
+	// Chromium moved Cookies into <profile>/Network.
+	if strings.EqualFold(filepath.Base(dir), "Network") {
+		dir = filepath.Dir(dir)
+	}
+	src.Profile = filepath.Base(dir)
+	return src
+}
+
+func (src browserSource) event(artifact, action, semantics, subject string, ts time.Time, details map[string]string) model.TimelineEvent {
+	details["Browser"] = src.Browser
+	details["Profile"] = src.Profile
+	details["User"] = src.User
+	return model.TimelineEvent{
+		ID:            fmt.Sprintf("browser-%s-%s-%s-%s-%d", src.Browser, src.Profile, action, subject, ts.UnixNano()),
+		EventTime:     ts,
+		TimeSemantics: semantics,
+		Source:        "Browser",
+		Artifact:      src.Browser + " " + artifact,
+		Action:        action,
+		Subject:       subject,
+		Details:       details,
+		EvidenceRef: model.EvidenceRef{
+			SourcePath: src.SourcePath,
+		},
+	}
+}
+
+// webkitTime converts Chromium timestamps (microseconds since 1601-01-01).
+func webkitTime(us int64) time.Time {
+	if us <= 0 {
+		return time.Time{}
+	}
+	// Subtract the 1601→1970 offset in seconds first to stay inside time.Duration's range.
+	const unixEpochOffset = 11644473600
+	return time.Unix(us/1000000-unixEpochOffset, (us%1000000)*1000).UTC()
+}
+
+// prTime converts Firefox PRTime (microseconds since the Unix epoch).
+func prTime(us int64) time.Time {
+	if us <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(us).UTC()
+}
+
+// urlHost returns the host of u for use as a compact event subject.
+func urlHost(u string) string {
+	if parsed, err := url.Parse(u); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return u
+}
+
+// chromiumTransition renders visits.transition as "typed|from_address_bar|chain_end".
+func chromiumTransition(t int64) string {
+	v := uint32(t)
+	core := int(v & 0xff)
+	parts := []string{strconv.Itoa(core)}
+	if core < len(chromiumTransitions) {
+		parts[0] = chromiumTransitions[core]
+	}
+	for _, q := range chromiumTransitionQualifiers {
+		if v&q.mask != 0 {
+			parts = append(parts, q.name)
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+// collectBrowserFiles parses live browser databases through temp copies made by copyFn,
+// since running browsers keep them locked. Files that cannot be copied or read are skipped;
+// their errors are joined into the result.
+func collectBrowserFiles(ctx context.Context, files []string, copyFn func(src, dst string) error, callback func(model.TimelineEvent)) error {
+	var errs []error
+	for _, path := range files {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := collectBrowserFile(ctx, path, copyFn, callback); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func collectBrowserFile(ctx context.Context, path string, copyFn func(src, dst string) error, callback func(model.TimelineEvent)) error {
+	tempFile := filepath.Join(os.TempDir(), fmt.Sprintf("gtrace_browser_%d.tmp", time.Now().UnixNano()))
+	// A copy that fails partway still leaves a file behind.
+	defer os.Remove(tempFile)
+	if err := copyFn(path, tempFile); err != nil {
+		return fmt.Errorf("copy %s: %w", path, err)
+	}
+	src := identifyBrowser(path)
+	src.User = profileOwner(path)
+	src.SourcePath = path
+	if err := readBrowserFile(ctx, filepath.Base(path), tempFile, src, callback); err != nil {
+		return fmt.Errorf("query %s: %w", path, err)
+	}
+	return nil
+}
+
+// globBrowserFiles expands profile directory patterns into the database files of every profile.
+func globBrowserFiles(chromiumRoots, firefoxRoots []string) []string {
+	var files []string
+	for _, root := range chromiumRoots {
+		for _, name := range []string{"History", "Cookies", filepath.Join("Network", "Cookies"), "Web Data"} {
+			matches, _ := filepath.Glob(filepath.Join(root, "*", name))
+			files = append(files, matches...)
+		}
+	}
+	for _, root := range firefoxRoots {
+		for _, name := range []string{"places.sqlite", "downloads.sqlite", "cookies.sqlite", "formhistory.sqlite"} {
+			matches, _ := filepath.Glob(filepath.Join(root, "*", name))
+			files = append(files, matches...)
+		}
+	}
+	return files
+}
+
+// nameOr returns m[k] or the number itself when unknown.
+func nameOr(m map[int64]string, k int64) string {
+	if s, ok := m[k]; ok {
+		return s
+	}
+	return strconv.FormatInt(k, 10)
+}
+
+// sectionErrors keeps going through the sections of a database and reports a failure only
+// when none of them could be read (schemas vary a lot across browser versions).
+type sectionErrors struct {
+	ok   int
+	last error
+}
+
+func (s *sectionErrors) add(err error) {
+	if err != nil {
+		s.last = err
+		return
+	}
+	s.ok++
+}
+
+func (s *sectionErrors) err() error {
+	if s.ok == 0 {
+		return s.last
+	}
+	return nil
+}
+
+func readChromiumHistory(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	var errs sectionErrors
+	errs.add(readChromiumVisits(ctx, db, src, emit))
+	errs.add(readChromiumDownloads(ctx, db, src, emit))
+	return errs.err()
+}
+
+func readChromiumVisits(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT v.id, u.url, COALESCE(u.title, ''), v.visit_time, v.transition,
+		       COALESCE(v.from_visit, 0), COALESCE(v.visit_duration, 0), COALESCE(u.visit_count, 0),
+		       COALESCE(u.typed_count, 0)
+		FROM visits v JOIN urls u ON u.id = v.url
+		ORDER BY v.visit_time
+	`)
+	if err != nil {
+		return fmt.Errorf("visits query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id, visitTime, transition, fromVisit, duration, visitCount, typedCount int64
+			u, title                                                               string
+		)
+		if err := rows.Scan(&id, &u, &title, &visitTime, &transition, &fromVisit, &duration, &visitCount, &typedCount); err != nil {
+			continue
+		}
+		ts := webkitTime(visitTime)
+		if ts.IsZero() {
+			continue
+		}
+		details := map[string]string{
+			"URL":        u,
+			"Title":      title,
+			"VisitID":    strconv.FormatInt(id, 10),
+			"FromVisit":  strconv.FormatInt(fromVisit, 10),
+			"Transition": chromiumTransition(transition),
+			"VisitCount": strconv.FormatInt(visitCount, 10),
+			"TypedCount": strconv.FormatInt(typedCount, 10),
+		}
+		if duration > 0 {
+			details["VisitDuration"] = (time.Duration(duration) * time.Microsecond).String()
+		}
+		evt := src.event("History", "Page Visit", model.TimeAccessed, urlHost(u), ts, details)
+		evt.ID = fmt.Sprintf("browser-%s-%s-visit-%d", src.Browser, src.Profile, id)
+		emit(evt)
+	}
+	return rows.Err()
+}
+
+func readChromiumDownloads(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT d.id, COALESCE(d.target_path, ''), d.start_time, COALESCE(d.end_time, 0),
+		       COALESCE(d.received_bytes, 0), COALESCE(d.total_bytes, 0), COALESCE(d.danger_type, 0),
+		       COALESCE(d.state, 0), COALESCE(d.referrer, ''), COALESCE(d.tab_url, ''),
+		       COALESCE(d.mime_type, ''),
+		       COALESCE((SELECT c.url FROM downloads_url_chains c WHERE c.id = d.id ORDER BY c.chain_index DESC LIMIT 1), '')
+		FROM downloads d
+		ORDER BY d.start_time
+	`)
+	if err != nil {
+		return fmt.Errorf("downloads query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id, start, end, received, total, danger, state int64
+			target, referrer, tabURL, mime, u              string
+		)
+		if err := rows.Scan(&id, &target, &start, &end, &received, &total, &danger, &state, &referrer, &tabURL, &mime, &u); err != nil {
+			continue
+		}
+		ts := webkitTime(start)
+		if ts.IsZero() {
+			continue
+		}
+		details := map[string]string{
+			"URL":           u,
+			"TargetPath":    target,
+			"Referrer":      referrer,
+			"TabURL":        tabURL,
+			"MimeType":      mime,
+			"ReceivedBytes": strconv.FormatInt(received, 10),
+			"TotalBytes":    strconv.FormatInt(total, 10),
+			"DangerType":    nameOr(chromiumDangerTypes, danger),
+			"State":         nameOr(chromiumDownloadStates, state),
+		}
+		if endTime := webkitTime(end); !endTime.IsZero() {
+			details["EndTime"] = endTime.Format(time.RFC3339)
+		}
+		evt := src.event("Downloads", "File Download", model.TimeCreated, filepath.Base(strings.ReplaceAll(target, `\`, "/")), ts, details)
+		evt.ID = fmt.Sprintf("browser-%s-%s-download-%d", src.Browser, src.Profile, id)
+		switch danger {
+		case 1, 2, 3, 4, 5, 7, 8, 16:
+			// The browser itself flagged the download.
+			evt.Details["_Alert"] = "Browser flagged download as " + details["DangerType"]
+			evt.Details["_AlertLevel"] = "medium"
+		}
+		emit(evt)
+	}
+	return rows.Err()
+}
+
+func readChromiumCookies(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	// Values are DPAPI/OS-keychain encrypted; only metadata is reported.
+	rows, err := db.QueryContext(ctx, `
+		SELECT host_key, name, path, creation_utc, COALESCE(last_access_utc, 0), COALESCE(expires_utc, 0),
+		       COALESCE(is_secure, 0), COALESCE(is_httponly, 0)
+		FROM cookies
+	`)
+	if err != nil {
+		return fmt.Errorf("cookies query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			host, name, path           string
+			created, accessed, expires int64
+			secure, httpOnly           int64
+		)
+		if err := rows.Scan(&host, &name, &path, &created, &accessed, &expires, &secure, &httpOnly); err != nil {
+			continue
+		}
+		emitCookie(src, emit, host, name, path, webkitTime(created), webkitTime(accessed), webkitTime(expires), secure != 0, httpOnly != 0)
+	}
+	return rows.Err()
+}
+
+// emitCookie reports creation and, when later, last access of a cookie.
+func emitCookie(src browserSource, emit func(model.TimelineEvent), host, name, path string, created, accessed, expires time.Time, secure, httpOnly bool) {
+	details := func() map[string]string {
+		d := map[string]string{
+			"Host":     host,
+			"Name":     name,
+			"Path":     path,
+			"Secure":   strconv.FormatBool(secure),
+			"HttpOnly": strconv.FormatBool(httpOnly),
+		}
+		if !created.IsZero() {
+			d["Created"] = created.Format(time.RFC3339)
+		}
+		if !accessed.IsZero() {
+			d["LastAccessed"] = accessed.Format(time.RFC3339)
+		}
+		if !expires.IsZero() {
+			d["Expires"] = expires.Format(time.RFC3339)
+		}
+		return d
+	}
+	subject := host + " " + name
+	if !created.IsZero() {
+		emit(src.event("Cookies", "Cookie Created", model.TimeCreated, subject, created, details()))
+	}
+	if accessed.After(created) {
+		emit(src.event("Cookies", "Cookie Accessed", model.TimeAccessed, subject, accessed, details()))
+	}
+}
+
+func readChromiumAutofill(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	// autofill dates are Unix seconds, unlike the rest of Chromium.
+	rows, err := db.QueryContext(ctx, `
+		SELECT name, value, COALESCE(date_created, 0), COALESCE(date_last_used, 0), COALESCE(count, 0)
+		FROM autofill
+	`)
+	if err != nil {
+		return fmt.Errorf("autofill query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			name, value              string
+			created, lastUsed, count int64
+		)
+		if err := rows.Scan(&name, &value, &created, &lastUsed, &count); err != nil {
+			continue
+		}
+		emitFormEntry(src, emit, name, value, unixTime(created), unixTime(lastUsed), count)
+	}
+	return rows.Err()
+}
+
+// emitFormEntry reports first and last use of a saved form value.
+func emitFormEntry(src browserSource, emit func(model.TimelineEvent), field, value string, first, last time.Time, count int64) {
+	details := func() map[string]string {
+		return map[string]string{
+			"Field":     field,
+			"Value":     value,
+			"TimesUsed": strconv.FormatInt(count, 10),
+		}
+	}
+	if !first.IsZero() {
+		emit(src.event("Autofill", "Form Value Saved", model.TimeCreated, field, first, details()))
+	}
+	if last.After(first) {
+		emit(src.event("Autofill", "Form Value Used", model.TimeAccessed, field, last, details()))
+	}
+}
+
+func unixTime(s int64) time.Time {
+	if s <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(s, 0).UTC()
+}
+
+func readFirefoxPlaces(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	var errs sectionErrors
+	errs.add(readFirefoxVisits(ctx, db, src, emit))
+	errs.add(readFirefoxDownloads(ctx, db, src, emit))
+	return errs.err()
+}
+
+func readFirefoxVisits(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT v.id, p.url, COALESCE(p.title, ''), v.visit_date, COALESCE(v.visit_type, 0),
+		       COALESCE(v.from_visit, 0), COALESCE(p.visit_count, 0), COALESCE(p.typed, 0)
+		FROM moz_historyvisits v JOIN moz_places p ON p.id = v.place_id
+		ORDER BY v.visit_date
+	`)
+	if err != nil {
+		return fmt.Errorf("visits query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id, visitDate, visitType, fromVisit, visitCount, typed int64
+			u, title                                               string
+		)
+		if err := rows.Scan(&id, &u, &title, &visitDate, &visitType, &fromVisit, &visitCount, &typed); err != nil {
+			continue
+		}
+		ts := prTime(visitDate)
+		if ts.IsZero() {
+			continue
+		}
+		evt := src.event("History", "Page Visit", model.TimeAccessed, urlHost(u), ts, map[string]string{
+			"URL":        u,
+			"Title":      title,
+			"VisitID":    strconv.FormatInt(id, 10),
+			"FromVisit":  strconv.FormatInt(fromVisit, 10),
+			"Transition": nameOr(firefoxVisitTypes, visitType),
+			"VisitCount": strconv.FormatInt(visitCount, 10),
+			"Typed":      strconv.FormatBool(typed != 0),
+		})
+		evt.ID = fmt.Sprintf("browser-%s-%s-visit-%d", src.Browser, src.Profile, id)
+		emit(evt)
+	}
+	return rows.Err()
+}
+
+// readFirefoxDownloads reads Firefox 26+ downloads, kept as page annotations in places.sqlite.
+func readFirefoxDownloads(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT p.url, a.content, a.dateAdded,
+		       COALESCE((SELECT m.content FROM moz_annos m JOIN moz_anno_attributes mn ON mn.id = m.anno_attribute_id
+		                 WHERE m.place_id = a.place_id AND mn.name = 'downloads/metaData'), '')
+		FROM moz_annos a
+		JOIN moz_anno_attributes n ON n.id = a.anno_attribute_id
+		JOIN moz_places p ON p.id = a.place_id
+		WHERE n.name = 'downloads/destinationFileURI'
+	`)
+	if err != nil {
+		return fmt.Errorf("downloads query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			u, dest, meta string
+			added         int64
+		)
+		if err := rows.Scan(&u, &dest, &added, &meta); err != nil {
+			continue
+		}
+		ts := prTime(added)
+		if ts.IsZero() {
+			continue
+		}
+		target := dest
+		if parsed, err := url.Parse(dest); err == nil && parsed.Scheme == "file" {
+			target = strings.TrimPrefix(parsed.Path, "/")
+			if !strings.Contains(target, ":") {
+				target = "/" + target
+			}
+		}
+		details := map[string]string{
+			"URL":        u,
+			"TargetPath": target,
+		}
+		var m struct {
+			State    int64 `json:"state"`
+			EndTime  int64 `json:"endTime"`
+			FileSize int64 `json:"fileSize"`
+			Deleted  bool  `json:"deleted"`
+		}
+		if json.Unmarshal([]byte(meta), &m) == nil && meta != "" {
+			details["State"] = strconv.FormatInt(m.State, 10)
+			details["TotalBytes"] = strconv.FormatInt(m.FileSize, 10)
+			details["Deleted"] = strconv.FormatBool(m.Deleted)
+			if m.EndTime > 0 {
+				details["EndTime"] = time.UnixMilli(m.EndTime).UTC().Format(time.RFC3339)
+			}
+		}
+		emit(src.event("Downloads", "File Download", model.TimeCreated, filepath.Base(target), ts, details))
+	}
+	return rows.Err()
+}
+
+// readFirefoxLegacyDownloads reads downloads.sqlite (Firefox 3-25).
+func readFirefoxLegacyDownloads(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, COALESCE(source, ''), COALESCE(target, ''), startTime, COALESCE(endTime, 0),
+		       COALESCE(state, 0), COALESCE(referrer, ''), COALESCE(maxBytes, 0), COALESCE(mimeType, '')
+		FROM moz_downloads
+	`)
+	if err != nil {
+		return fmt.Errorf("downloads query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id, start, end, state, size    int64
+			source, target, referrer, mime string
+		)
+		if err := rows.Scan(&id, &source, &target, &start, &end, &state, &referrer, &size, &mime); err != nil {
+			continue
+		}
+		ts := prTime(start)
+		if ts.IsZero() {
+			continue
+		}
+		details := map[string]string{
+			"URL":        source,
+			"TargetPath": target,
+			"Referrer":   referrer,
+			"MimeType":   mime,
+			"State":      strconv.FormatInt(state, 10),
+			"TotalBytes": strconv.FormatInt(size, 10),
+		}
+		if endTime := prTime(end); !endTime.IsZero() {
+			details["EndTime"] = endTime.Format(time.RFC3339)
+		}
+		evt := src.event("Downloads", "File Download", model.TimeCreated, filepath.Base(target), ts, details)
+		evt.ID = fmt.Sprintf("browser-%s-%s-download-%d", src.Browser, src.Profile, id)
+		emit(evt)
+	}
+	return rows.Err()
+}
+
+func readFirefoxCookies(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT host, name, path, creationTime, COALESCE(lastAccessed, 0), COALESCE(expiry, 0),
+		       COALESCE(isSecure, 0), COALESCE(isHttpOnly, 0)
+		FROM moz_cookies
+	`)
+	if err != nil {
+		return fmt.Errorf("cookies query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			host, name, path           string
+			created, accessed, expires int64
+			secure, httpOnly           int64
+		)
+		if err := rows.Scan(&host, &name, &path, &created, &accessed, &expires, &secure, &httpOnly); err != nil {
+			continue
+		}
+		emitCookie(src, emit, host, name, path, prTime(created), prTime(accessed), unixTime(expires), secure != 0, httpOnly != 0)
+	}
+	return rows.Err()
+}
+
+func readFirefoxFormHistory(ctx context.Context, db *sql.DB, src browserSource, emit func(model.TimelineEvent)) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT fieldname, value, COALESCE(firstUsed, 0), COALESCE(lastUsed, 0), COALESCE(timesUsed, 0)
+		FROM moz_formhistory
+	`)
+	if err != nil {
+		return fmt.Errorf("formhistory query failed: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			field, value       string
+			first, last, count int64
+		)
+		if err := rows.Scan(&field, &value, &first, &last, &count); err != nil {
+			continue
+		}
+		emitFormEntry(src, emit, field, value, prTime(first), prTime(last), count)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+func TestChromiumTransition(t *testing.T) {
+	if got := chromiumTransition(0x32000001); got != "typed|from_address_bar|chain_start|chain_end" {
+		t.Errorf("chromiumTransition = %q", got)
+	}
+}
+
+func TestBrowserParserChromiumHistory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Users", "alice", "AppData", "Local", "Google", "Chrome", "User Data", "Profile 2")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "History")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE urls (id INTEGER PRIMARY KEY, url TEXT, title TEXT, visit_count INTEGER, typed_count INTEGER);
+		CREATE TABLE visits (id INTEGER PRIMARY KEY, url INTEGER, visit_time INTEGER, from_visit INTEGER, transition INTEGER, visit_duration INTEGER);
+		CREATE TABLE downloads (id INTEGER PRIMARY KEY, target_path TEXT, start_time INTEGER, end_time INTEGER,
+			received_bytes INTEGER, total_bytes INTEGER, danger_type INTEGER, state INTEGER, referrer TEXT, tab_url TEXT, mime_type TEXT);
+		CREATE TABLE downloads_url_chains (id INTEGER, chain_index INTEGER, url TEXT);
+		INSERT INTO urls VALUES (1, 'https://evil.example/dl', 'Download', 1, 1);
+		INSERT INTO visits VALUES (1, 1, 13300000000000000, 0, 1, 0);
+		INSERT INTO downloads VALUES (1, 'C:\Users\alice\Downloads\a.exe', 13300000001000000, 13300000002000000, 10, 10, 1, 1, 'https://evil.example/', '', 'application/x-msdownload');
+		INSERT INTO downloads_url_chains VALUES (1, 0, 'https://evil.example/dl'), (1, 1, 'https://cdn.evil.example/a.exe');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := (&BrowserParser{}).Parse(context.Background(), pluginsdk.ParseRequest{EvidencePath: path})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(resp.Events) != 2 {
+		t.Fatalf("expected visit + download, got %d events", len(resp.Events))
+	}
+	visit, dl := resp.Events[0], resp.Events[1]
+	if visit.Artifact != "Chrome History" || visit.Details["Profile"] != "Profile 2" || visit.Details["User"] != "alice" {
+		t.Errorf("visit attribution: %s %v", visit.Artifact, visit.Details)
+	}
+	if visit.Details["Transition"] != "typed" {
+		t.Errorf("transition = %q", visit.Details["Transition"])
+	}
+	if dl.Details["URL"] != "https://cdn.evil.example/a.exe" || dl.Details["DangerType"] != "dangerous_file" || dl.Details["_Alert"] == "" {
+		t.Errorf("download details: %v", dl.Details)
+	}
+}
+
+func TestCollectBrowserFilesRemovesPartialCopy(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	partial := func(src, dst string) error {
+		os.WriteFile(dst, []byte("SQLite format 3\x00"), 0o644)
+		return errors.New("sharing violation")
+	}
+	err := collectBrowserFiles(context.Background(), []string{filepath.Join("Default", "History")}, partial, func(model.TimelineEvent) {})
+	if err == nil || !strings.Contains(err.Error(), "sharing violation") {
+		t.Errorf("error = %v", err)
+	}
+	if left, _ := os.ReadDir(tmp); len(left) != 0 {
+		t.Errorf("temp files left behind: %v", left)
+	}
+}
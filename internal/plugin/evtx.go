@@ -2,10 +2,13 @@ package plugin
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"gtrace/internal/noise"
 	"gtrace/pkg/model"
 	"gtrace/pkg/pluginsdk"
+	"io"
 	"log"
 	"path/filepath" // Added strconv
 	"strconv"
@@ -31,6 +34,13 @@ func extractFileName(path string) string {
 
 type EvtxParser struct{}
 
+// Limits the parser reports in Truncated: records within the days cutoff left unread because
+// the log reached max_events, or because the scan limit (50x max_events) was hit.
+const (
+	DropMaxEventsPerLog = "evtx-max-events-per-log"
+	DropScanLimit       = "evtx-scan-limit"
+)
+
 func (p *EvtxParser) Manifest() pluginsdk.Manifest {
 	return pluginsdk.Manifest{
 		Name:      "win-evtx-parser",
@@ -146,13 +156,6 @@ var interestingEvents = map[int64]string{
 	141:  "Task Deleted",
 	200:  "Task Action Started",
 
-	// --- RDP (TerminalServices-LocalSessionManager) ---
-	21: "RDP Session Logon",
-	22: "RDP Shell Start",
-	23: "RDP Logoff",
-	25: "RDP Reconnect",
-	39: "RDP Disconnect",
-
 	// --- RDP (RemoteConnectionManager) ---
 	1149: "RDP Auth Succeeded",
 
@@ -175,11 +178,87 @@ var interestingEvents = map[int64]string{
 	4826: "Boot Config Loaded",
 }
 
+// sysmonChannel is the Sysmon operational log; its event IDs overlap the Security/System ones above.
+const sysmonChannel = "Microsoft-Windows-Sysmon/Operational"
+
+// sysmonEvents names Sysmon event IDs.
+var sysmonEvents = map[int64]string{
+	1:   "Process Created (Sysmon)",
+	2:   "File Creation Time Changed",
+	3:   "Network Connection",
+	4:   "Sysmon Service State Changed",
+	5:   "Process Terminated (Sysmon)",
+	6:   "Driver Loaded",
+	7:   "Image Loaded",
+	8:   "Remote Thread Created",
+	9:   "Raw Disk Access",
+	10:  "Process Accessed",
+	11:  "File Created",
+	12:  "Registry Key Created/Deleted",
+	13:  "Registry Value Set",
+	14:  "Registry Key Renamed",
+	15:  "Alternate Data Stream Created",
+	16:  "Sysmon Config Changed",
+	17:  "Pipe Created",
+	18:  "Pipe Connected",
+	19:  "WMI Filter Registered",
+	20:  "WMI Consumer Registered",
+	21:  "WMI Consumer Bound",
+	22:  "DNS Query",
+	23:  "File Deleted (Archived)",
+	24:  "Clipboard Changed",
+	25:  "Process Tampering",
+	26:  "File Deleted",
+	27:  "Executable Blocked",
+	28:  "File Shredding Blocked",
+	29:  "Executable Detected",
+	255: "Sysmon Error",
+}
+
+// localSessionManagerChannel logs RDP/console session state; its low event IDs clash with
+// System log IDs (24 is also Kernel-General's time zone change).
+const localSessionManagerChannel = "Microsoft-Windows-TerminalServices-LocalSessionManager/Operational"
+
+var sessionManagerEvents = map[int64]string{
+	21: "RDP Session Logon",
+	22: "RDP Shell Start",
+	23: "RDP Logoff",
+	24: "RDP Disconnect",
+	25: "RDP Reconnect",
+	39: "RDP Disconnect",
+	40: "RDP Disconnect Reason",
+}
+
+// isSysmon reports whether channel is the Sysmon operational log.
+func isSysmon(channel string) bool {
+	return strings.EqualFold(channel, sysmonChannel)
+}
+
+// eventName returns the action label for an event ID in its channel.
+func eventName(channel string, eid int64) (string, bool) {
+	if isSysmon(channel) {
+		name, ok := sysmonEvents[eid]
+		return name, ok
+	}
+	if strings.EqualFold(channel, localSessionManagerChannel) {
+		name, ok := sessionManagerEvents[eid]
+		return name, ok
+	}
+	name, ok := interestingEvents[eid]
+	return name, ok
+}
+
 // Helper to determine category
-func getEventCategory(eid int64) string {
+func getEventCategory(channel string, eid int64) string {
+	if isSysmon(channel) {
+		return getSysmonCategory(eid)
+	}
+	if strings.EqualFold(channel, localSessionManagerChannel) {
+		return "Logon"
+	}
 	switch eid {
 	// Logon/Auth
-	case 4624, 4625, 4672, 4768, 4769, 21, 25, 24, 1149:
+	case 4624, 4625, 4634, 4647, 4648, 4672, 4768, 4769, 1149:
 		return "Logon"
 	// Account Management
 	case 4720, 4726, 4728, 4729, 4732, 4733, 4756:
@@ -201,6 +280,85 @@ func getEventCategory(eid int64) string {
 	}
 }
 
+func getSysmonCategory(eid int64) string {
+	switch eid {
+	case 1, 5, 25:
+		return "Process"
+	case 3, 22:
+		return "Network"
+	case 6, 7:
+		return "Module"
+	case 8, 10:
+		return "Injection"
+	case 2, 11, 15, 23, 26, 27, 28, 29:
+		return "File"
+	case 12, 13, 14:
+		return "Registry"
+	case 17, 18:
+		return "Pipe"
+	case 19, 20, 21:
+		return "Persistence"
+	default:
+		return "System"
+	}
+}
+
+// sysmonSubject picks the most telling field of a Sysmon event for the timeline subject.
+func sysmonSubject(eid int64, props map[string]string) string {
+	switch eid {
+	case 1, 5, 6, 25:
+		if img := props["Image"]; img != "" {
+			return extractFileName(img)
+		}
+		return extractFileName(props["ImageLoaded"])
+	case 3:
+		dest := props["DestinationIp"] + ":" + props["DestinationPort"]
+		if host := props["DestinationHostname"]; host != "" && host != "-" {
+			dest = host + " (" + dest + ")"
+		}
+		return extractFileName(props["Image"]) + " -> " + dest
+	case 7:
+		return extractFileName(props["ImageLoaded"])
+	case 8, 10:
+		return extractFileName(props["SourceImage"]) + " -> " + extractFileName(props["TargetImage"])
+	case 2, 11, 15, 23, 26, 27, 28, 29:
+		return props["TargetFilename"]
+	case 12, 13, 14:
+		return props["TargetObject"]
+	case 17, 18:
+		return props["PipeName"]
+	case 19, 20, 21:
+		if name := props["Name"]; name != "" {
+			return name
+		}
+		return props["Consumer"]
+	case 22:
+		return props["QueryName"]
+	}
+	return ""
+}
+
+// normalizeProcessGuids puts every *ProcessGuid field in one canonical form ("{UPPERCASE}") so
+// Sysmon events can be joined on them, and mirrors the source process GUID of 8/10 events into
+// ProcessGuid.
+func normalizeProcessGuids(props map[string]string) {
+	for k, v := range props {
+		if !strings.HasSuffix(strings.ToLower(k), "processguid") || v == "" {
+			continue
+		}
+		g := strings.ToUpper(strings.Trim(v, "{} "))
+		props[k] = "{" + g + "}"
+	}
+	if props["ProcessGuid"] == "" {
+		for _, k := range []string{"SourceProcessGUID", "SourceProcessGuid"} {
+			if g := props[k]; g != "" {
+				props["ProcessGuid"] = g
+				break
+			}
+		}
+	}
+}
+
 func (p *EvtxParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
 	// log.Printf("DEBUG: EVTX Parser started for %s", in.EvidencePath)
 	f, err := openFileShared(in.EvidencePath)
@@ -236,28 +394,66 @@ func (p *EvtxParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plu
 		}
 	}
 	cutoffTime := time.Now().AddDate(0, 0, -days)
-	log.Printf("EVTX Parser: MaxEvents=%d, Days=%d, Cutoff=%s", maxEvents, days, cutoffTime.Format(time.RFC3339))
+
+	// Noise-reduction profile chosen for the run; the built-in triage profile by default.
+	profile, err := noise.Lookup(noise.Builtin(), noise.DefaultProfile)
+	if enc := in.Metadata[noise.MetadataKey]; enc != "" {
+		profile, err = noise.Decode(enc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	filter := noise.NewFilter(profile)
+	log.Printf("EVTX Parser: MaxEvents=%d, Days=%d, Cutoff=%s, Noise profile=%s", maxEvents, days, cutoffTime.Format(time.RFC3339), profile.Name)
 
 	// STRATEGY: Iterate Backwards (Tail) to get the most recent events first.
 	count := 0
-	unknownCount := 0
 	totalScanned := 0
 	scanLimit := maxEvents * 50 // Don't scan more than 50x the requested limit to prevent infinite scanning on huge files
 	if scanLimit < 500000 {
 		scanLimit = 500000
 	} // Minimum scan floor
 
+	// 4104 script blocks split over several records are emitted once complete.
+	scriptBlocks := newScriptBlockAssembler()
+	emit := func(ev model.TimelineEvent) {
+		if in.StreamCallback != nil {
+			in.StreamCallback(ev)
+		} else {
+			events = append(events, ev)
+		}
+		count++
+	}
+
 	// Iterate Chunks in REVERSE order
 	// EVTX appends new chunks to the end.
 	totalChunks := len(chunks)
+	// Records left unread when a limit stops the scan are reported, not silently lost. Those
+	// older than the cutoff would not have been read anyway and are not counted.
+	dropped := filter.Dropped()
+	truncated := make(map[string]int)
+	unread := func(upTo int) int {
+		n := 0
+		for k := upTo; k >= 0; k-- {
+			newer, reachedCutoff := recordsSince(chunks[k], cutoffTime)
+			n += newer
+			if reachedCutoff {
+				break
+			}
+		}
+		return n
+	}
+chunkLoop:
 	for i := totalChunks - 1; i >= 0; i-- {
 		if count >= maxEvents {
+			truncated[DropMaxEventsPerLog] += unread(i)
 			break
 		}
 
 		// Safety Break for huge files
 		if totalScanned > scanLimit {
 			// log.Printf("DEBUG: Hit scan limit of %d events. Stopping.", scanLimit)
+			truncated[DropScanLimit] += unread(i)
 			break
 		}
 
@@ -298,10 +494,13 @@ func (p *EvtxParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plu
 				// Optimization: If we hit a log older than cutoff, and we are iterating backwards,
 				// then all remaining logs in this chunk (and previous chunks) are also too old.
 				// We can stop everything.
-				return &pluginsdk.ParseResponse{Events: events}, nil
+				break chunkLoop
 			}
 
 			if count >= maxEvents {
+				for k := j; k >= 0 && !windowsFiletimeToGo(records[k].Header.FileTime).Before(cutoffTime); k-- {
+					truncated[DropMaxEventsPerLog]++
+				}
 				break
 			}
 
@@ -434,12 +633,13 @@ func (p *EvtxParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plu
 
 			// Details
 			props := make(map[string]string)
-			props["Category"] = getEventCategory(eid)
 
 			// Extract Channel/Computer from System dict
-			if ch, ok := sysDict.GetString("Channel"); ok {
-				props["Channel"] = ch
+			channel, _ := sysDict.GetString("Channel")
+			if channel != "" {
+				props["Channel"] = channel
 			}
+			props["Category"] = getEventCategory(channel, eid)
 			if comp, ok := sysDict.GetString("Computer"); ok {
 				props["Computer"] = comp
 			}
@@ -480,37 +680,28 @@ func (p *EvtxParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plu
 				}
 			}
 
-			// --- INTELLIGENT NOISE REDUCTION ---
-			// This must happen AFTER props are populated.
-			if eid == 4624 || eid == 4625 {
-				user, _ := props["TargetUserName"]
-
-				// 1. Filter Machine Accounts (ending in $)
-				if strings.HasSuffix(user, "$") {
-					continue
-				}
-				// 2. Filter System Accounts - aggressive filter for Triage
-				if user == "SYSTEM" || user == "NETWORK SERVICE" || user == "LOCAL SERVICE" || user == "DWM-1" || user == "UMFD-0" {
-					continue
-				}
+			if isSysmon(channel) {
+				normalizeProcessGuids(props)
 			}
 
-			// NOISE REDUCTION: 4907 (Audit Settings)
-			// Windows Updates (TiWorker) generate thousands of these.
-			if eid == 4907 {
-				procName, _ := props["ProcessName"]
-				if strings.Contains(strings.ToLower(procName), "tiworker.exe") || strings.Contains(strings.ToLower(procName), "trustedinstaller") {
-					continue
+			// Surface -EncodedCommand payloads (4688, 7045, Sysmon 1, ...) as plain text.
+			annotateEncodedCommand(props, "CommandLine", "ImagePath", "ServiceFileName", "HostApplication", "ParentCommandLine")
+
+			if eid == 4104 {
+				block, complete := scriptBlocks.Add(scriptBlockPart{Time: evtTime, RecordID: record.Header.RecordID, Details: props})
+				if complete {
+					emit(scriptBlockEvent(block, in.EvidencePath))
 				}
+				continue
 			}
 
-			desc, interesting := interestingEvents[eid]
+			// Noise reduction runs after props are populated; every drop is counted per rule.
+			desc, interesting := eventName(channel, eid)
+			if filter.Drop(eid, channel, interesting, props) {
+				continue
+			}
 			if !interesting {
-				if unknownCount >= 1000 {
-					continue
-				}
 				desc = "Unknown" // Keep Action clean (Event ID is in Details)
-				unknownCount++
 			}
 
 			// --- KERBEROS ENRICHMENT ---
@@ -527,6 +718,16 @@ func (p *EvtxParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plu
 				if userDataDict, ok := userDataRaw.(*ordereddict.Dict); ok {
 					for _, k := range userDataDict.Keys() {
 						val, _ := userDataDict.Get(k)
+						// TerminalServices wraps its fields in <EventXML>; lift them up like EventData.
+						if inner, ok := val.(*ordereddict.Dict); ok {
+							for _, ik := range inner.Keys() {
+								if _, exists := props[ik]; !exists {
+									iv, _ := inner.Get(ik)
+									props[ik] = fmt.Sprintf("%v", iv)
+								}
+							}
+							continue
+						}
 						props["UserData."+k] = fmt.Sprintf("%v", val)
 					}
 				}
@@ -535,6 +736,18 @@ func (p *EvtxParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plu
 			// Heuristic Subject - Make it meaningful for each event type
 			subject := ""
 
+			if isSysmon(channel) {
+				subject = sysmonSubject(eid, props)
+				if eid == 1 {
+					if cmd := props["CommandLine"]; cmd != "" {
+						props["_CommandLine"] = cmd
+					}
+					if parent := props["ParentImage"]; parent != "" {
+						props["_ParentProcess"] = extractFileName(parent)
+					}
+				}
+			}
+
 			// 4688: Process Creation - Show the process name
 			if eid == 4688 {
 				if s, ok := props["NewProcessName"]; ok && s != "" {
@@ -551,7 +764,7 @@ func (p *EvtxParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plu
 			}
 
 			// 4689: Process Termination
-			if eid == 4689 {
+			if eid == 4689 && subject == "" {
 				if s, ok := props["ProcessName"]; ok && s != "" {
 					subject = filepath.Base(s)
 				}
@@ -593,29 +806,64 @@ func (p *EvtxParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plu
 				}
 			}
 
+			// Two records with the same content are still two events; the record number
+			// tells them apart and is what makes the event fingerprint unique.
+			if _, ok := props["EventRecordID"]; !ok {
+				props["EventRecordID"] = strconv.FormatUint(record.Header.RecordID, 10)
+			}
+
 			ev := model.TimelineEvent{
-				ID:        fmt.Sprintf("evtx-%d-%d", eid, record.Header.RecordID),
-				EventTime: evtTime,
-				Source:    "EventLog",
-				Artifact:  filepath.Base(in.EvidencePath),
-				Action:    desc,
-				Subject:   subject,
-				Details:   props,
+				ID:            fmt.Sprintf("evtx-%d-%d", eid, record.Header.RecordID),
+				EventTime:     evtTime,
+				TimeSemantics: model.TimeLogged,
+				Source:        "EventLog",
+				Artifact:      filepath.Base(in.EvidencePath),
+				Action:        desc,
+				Subject:       subject,
+				Details:       props,
 				EvidenceRef: model.EvidenceRef{
 					SourcePath: in.EvidencePath,
 				},
 			}
 
-			if in.StreamCallback != nil {
-				in.StreamCallback(ev)
-			} else {
-				events = append(events, ev)
-			}
-			count++
+			emit(ev)
 		}
 	}
 
+	// Blocks whose other fragments were outside the scanned range.
+	for _, block := range scriptBlocks.Flush() {
+		emit(scriptBlockEvent(block, in.EvidencePath))
+	}
+
 	return &pluginsdk.ParseResponse{
-		Events: events,
+		Events:    events,
+		Dropped:   dropped,
+		Truncated: truncated,
 	}, nil
 }
+
+// recordsSince counts the records of a chunk written at or after cutoff from their headers,
+// without parsing the events, and reports whether the chunk holds an older record.
+func recordsSince(chunk *evtx.Chunk, cutoff time.Time) (int, bool) {
+	buf := make([]byte, evtx.EVTX_CHUNK_SIZE)
+	if _, err := chunk.Fd.Seek(chunk.Offset, io.SeekStart); err != nil {
+		return 0, false
+	}
+	if _, err := io.ReadFull(chunk.Fd, buf); err != nil {
+		return 0, false
+	}
+	n, older := 0, false
+	for off := evtx.EVTX_CHUNK_HEADER_SIZE; off+evtx.EVTX_EVENT_RECORD_SIZE <= len(buf) && string(buf[off:off+4]) == evtx.EVTX_EVENT_RECORD_MAGIC; {
+		size := int(binary.LittleEndian.Uint32(buf[off+4:]))
+		if windowsFiletimeToGo(binary.LittleEndian.Uint64(buf[off+16:])).Before(cutoff) {
+			older = true
+		} else {
+			n++
+		}
+		if size < evtx.EVTX_EVENT_RECORD_SIZE {
+			break
+		}
+		off += size
+	}
+	return n, older
+}
@@ -1,10 +1,15 @@
 package plugin
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"gtrace/pkg/pluginsdk"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"www.velocidex.com/golang/evtx"
 )
 
 func TestEvtxParser_Parse(t *testing.T) {
@@ -38,3 +43,61 @@ func TestEvtxParser_Parse(t *testing.T) {
 		t.Logf("Event: %+v", e)
 	}
 }
+
+func TestEventNameByChannel(t *testing.T) {
+	if name, _ := eventName(sysmonChannel, 1); name != "Process Created (Sysmon)" {
+		t.Errorf("Sysmon 1 = %q", name)
+	}
+	if name, _ := eventName("System", 1); name != "System Time Changed" {
+		t.Errorf("System 1 = %q", name)
+	}
+	if cat := getEventCategory(sysmonChannel, 13); cat != "Registry" {
+		t.Errorf("Sysmon 13 category = %q", cat)
+	}
+	if cat := getEventCategory("System", 13); cat != "System" {
+		t.Errorf("System 13 category = %q", cat)
+	}
+	if name, _ := eventName(localSessionManagerChannel, 24); name != "RDP Disconnect" {
+		t.Errorf("LocalSessionManager 24 = %q", name)
+	}
+	if name, _ := eventName("System", 24); name != "Time Zone Changed" {
+		t.Errorf("System 24 = %q", name)
+	}
+}
+
+func TestNormalizeProcessGuids(t *testing.T) {
+	props := map[string]string{
+		"SourceProcessGUID": "{0197231e-61ef-693a-c812-000000000800}",
+		"TargetProcessGUID": "0197231E-BDEA-6937-AB0C-000000000800",
+	}
+	normalizeProcessGuids(props)
+	if props["ProcessGuid"] != "{0197231E-61EF-693A-C812-000000000800}" {
+		t.Errorf("ProcessGuid = %q", props["ProcessGuid"])
+	}
+	if props["TargetProcessGUID"] != "{0197231E-BDEA-6937-AB0C-000000000800}" {
+		t.Errorf("TargetProcessGUID = %q", props["TargetProcessGUID"])
+	}
+}
+
+func TestRecordsSince(t *testing.T) {
+	cutoff := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	chunk := make([]byte, evtx.EVTX_CHUNK_SIZE)
+	off := evtx.EVTX_CHUNK_HEADER_SIZE
+	for i, at := range []time.Time{cutoff.AddDate(0, 0, -2), cutoff.AddDate(0, 0, -1), cutoff, cutoff.AddDate(0, 0, 1)} {
+		const size = 64
+		copy(chunk[off:], evtx.EVTX_EVENT_RECORD_MAGIC)
+		binary.LittleEndian.PutUint32(chunk[off+4:], size)
+		binary.LittleEndian.PutUint64(chunk[off+8:], uint64(i+1))
+		copy(chunk[off+16:], filetimeBytes(at))
+		off += size
+	}
+
+	n, older := recordsSince(&evtx.Chunk{Fd: bytes.NewReader(chunk)}, cutoff)
+	if n != 2 || !older {
+		t.Errorf("recordsSince = %d, %v; want 2 records at or after the cutoff, and older ones", n, older)
+	}
+	n, older = recordsSince(&evtx.Chunk{Fd: bytes.NewReader(chunk)}, cutoff.AddDate(-1, 0, 0))
+	if n != 4 || older {
+		t.Errorf("recordsSince = %d, %v; want all 4 records", n, older)
+	}
+}
@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"www.velocidex.com/golang/regparser"
+)
+
+// testKey and testValue describe a registry tree for buildHive.
+type testKey struct {
+	name    string
+	written time.Time
+	values  []testValue
+	subkeys []*testKey
+}
+
+type testValue struct {
+	name string
+	typ  uint32
+	data []byte
+}
+
+func szValue(name, s string) testValue {
+	return testValue{name: name, typ: 1, data: utf16z(s)}
+}
+
+func binValue(name string, data []byte) testValue {
+	return testValue{name: name, typ: 3, data: data}
+}
+
+func utf16z(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return append(b, 0, 0)
+}
+
+func filetimeBytes(t time.Time) []byte {
+	return binary.LittleEndian.AppendUint64(nil, uint64(t.UnixNano()/100+116444736000000000))
+}
+
+// hiveWriter lays out cells in a single hive bin. Offsets are relative to the first bin.
+type hiveWriter struct {
+	bin []byte
+}
+
+func (w *hiveWriter) cell(payload []byte) uint32 {
+	off := uint32(len(w.bin))
+	size := (len(payload) + 4 + 7) &^ 7
+	w.bin = binary.LittleEndian.AppendUint32(w.bin, uint32(-int32(size)))
+	w.bin = append(w.bin, payload...)
+	w.bin = append(w.bin, make([]byte, size-4-len(payload))...)
+	return off
+}
+
+func (w *hiveWriter) key(k *testKey) uint32 {
+	const none = 0xFFFFFFFF
+	subList, valList := uint32(none), uint32(none)
+	if len(k.subkeys) > 0 {
+		lf := []byte("lf")
+		lf = binary.LittleEndian.AppendUint16(lf, uint16(len(k.subkeys)))
+		for _, sub := range k.subkeys {
+			lf = binary.LittleEndian.AppendUint32(lf, w.key(sub))
+			lf = append(lf, sub.name[:min(4, len(sub.name))]...)
+			lf = append(lf, make([]byte, 4-min(4, len(sub.name)))...)
+		}
+		subList = w.cell(lf)
+	}
+	if len(k.values) > 0 {
+		var list []byte
+		for _, v := range k.values {
+			vk := []byte("vk")
+			vk = binary.LittleEndian.AppendUint16(vk, uint16(len(v.name)))
+			if len(v.data) <= 4 {
+				vk = binary.LittleEndian.AppendUint32(vk, uint32(len(v.data))|0x80000000)
+				vk = append(vk, v.data...)
+				vk = append(vk, make([]byte, 4-len(v.data))...)
+			} else {
+				vk = binary.LittleEndian.AppendUint32(vk, uint32(len(v.data)))
+				vk = binary.LittleEndian.AppendUint32(vk, w.cell(v.data))
+			}
+			vk = binary.LittleEndian.AppendUint32(vk, v.typ)
+			vk = binary.LittleEndian.AppendUint16(vk, 1) // ASCII name
+			vk = append(vk, 0, 0)
+			vk = append(vk, v.name...)
+			list = binary.LittleEndian.AppendUint32(list, w.cell(vk))
+		}
+		valList = w.cell(list)
+	}
+	nk := make([]byte, 0x4C, 0x4C+len(k.name))
+	copy(nk, "nk")
+	binary.LittleEndian.PutUint16(nk[0x02:], 0x20) // ASCII name
+	if !k.written.IsZero() {
+		copy(nk[0x04:], filetimeBytes(k.written))
+	}
+	binary.LittleEndian.PutUint32(nk[0x14:], uint32(len(k.subkeys)))
+	binary.LittleEndian.PutUint32(nk[0x1C:], subList)
+	binary.LittleEndian.PutUint32(nk[0x20:], none)
+	binary.LittleEndian.PutUint32(nk[0x24:], uint32(len(k.values)))
+	binary.LittleEndian.PutUint32(nk[0x28:], valList)
+	binary.LittleEndian.PutUint32(nk[0x2C:], none)
+	binary.LittleEndian.PutUint32(nk[0x30:], none)
+	binary.LittleEndian.PutUint16(nk[0x48:], uint16(len(k.name)))
+	return w.cell(append(nk, k.name...))
+}
+
+// buildHive writes a minimal regf hive holding root and returns its path.
+func buildHive(t *testing.T, name string, root *testKey) string {
+	t.Helper()
+	w := &hiveWriter{bin: make([]byte, 0x20)}
+	rootOff := w.key(root)
+	for len(w.bin)%0x1000 != 0 {
+		w.bin = append(w.bin, 0)
+	}
+	copy(w.bin, "hbin")
+	binary.LittleEndian.PutUint32(w.bin[8:], uint32(len(w.bin)))
+
+	base := make([]byte, 0x1000)
+	copy(base, "regf")
+	binary.LittleEndian.PutUint32(base[0x14:], 1)
+	binary.LittleEndian.PutUint32(base[0x18:], 5)
+	binary.LittleEndian.PutUint32(base[0x20:], 1)
+	binary.LittleEndian.PutUint32(base[0x24:], rootOff)
+	binary.LittleEndian.PutUint32(base[0x28:], uint32(len(w.bin)))
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, append(base, w.bin...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// openTestHive builds a hive and opens the key at path in it.
+func openTestHive(t *testing.T, root *testKey, path string) *regparser.CM_KEY_NODE {
+	t.Helper()
+	data, err := os.ReadFile(buildHive(t, "hive", root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg, err := regparser.NewRegistry(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := reg.OpenKey(path)
+	if key == nil {
+		t.Fatalf("key %s not found", path)
+	}
+	return key
+}
@@ -110,12 +110,13 @@ func (p *JumplistParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (
 
 		if !modTime.IsZero() && targetPath != "" {
 			events = append(events, model.TimelineEvent{
-				ID:        fmt.Sprintf("jump-%s-%d", dir.Name, modTime.UnixNano()),
-				EventTime: modTime,
-				Source:    "Jumplist",
-				Artifact:  "AutomaticDestinations",
-				Action:    "Access",
-				Subject:   targetPath,
+				ID:            fmt.Sprintf("jump-%s-%d", dir.Name, modTime.UnixNano()),
+				EventTime:     modTime,
+				TimeSemantics: model.TimeModified,
+				Source:        "Jumplist",
+				Artifact:      "AutomaticDestinations",
+				Action:        "Access",
+				Subject:       targetPath,
 				Details: map[string]string{
 					"args":          args,
 					"app_id_file":   filepath.Base(in.EvidencePath),
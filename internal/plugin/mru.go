@@ -0,0 +1,359 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+
+	"www.velocidex.com/golang/regparser"
+)
+
+// UserActivityParser extracts Explorer MRU lists from NTUSER.DAT: RecentDocs, OpenSave/LastVisited
+// dialogs, TypedPaths, RunMRU, WordWheelQuery and TypedURLs.
+//
+// Only the most recent entry of an MRU list carries an exact time (the key's LastWriteTime);
+// older entries are emitted at the same time with low confidence and their MRU position so the
+// ordering survives into the timeline.
+type UserActivityParser struct{}
+
+func (p *UserActivityParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "win-user-activity-parser",
+		Version:   "1.0.0",
+		Type:      "parser",
+		Platforms: []string{"windows"},
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "application/octet-stream",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "user_activity",
+		},
+	}
+}
+
+func (p *UserActivityParser) CanParse(path string, header []byte) bool {
+	return isHiveHeader(header) && hiveKind(path) == "NTUSER"
+}
+
+// mruEntry is one decoded MRU value.
+type mruEntry struct {
+	Position int
+	Value    string
+	Extra    map[string]string
+}
+
+const explorerKey = `Software\Microsoft\Windows\CurrentVersion\Explorer`
+
+func (p *UserActivityParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	f, reg, err := openHive(in.EvidencePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	owner := hiveOwner(in)
+	var events []model.TimelineEvent
+	emit := func(artifact, action string, key *regparser.CM_KEY_NODE, keyPath string, entries []mruEntry) {
+		ts := keyLastWrite(key)
+		if ts.IsZero() {
+			return
+		}
+		for _, e := range entries {
+			if e.Value == "" {
+				continue
+			}
+			confidence := "high"
+			if e.Position > 0 {
+				confidence = "low" // at or before the key write time
+			}
+			details := map[string]string{
+				"User":        owner,
+				"Key":         keyPath,
+				"MRUPosition": strconv.Itoa(e.Position),
+			}
+			for k, v := range e.Extra {
+				details[k] = v
+			}
+			evt := model.TimelineEvent{
+				ID:            fmt.Sprintf("mru-%s-%s-%d-%d", owner, keyPath, e.Position, ts.UnixNano()),
+				EventTime:     ts,
+				TimeSemantics: model.TimeKeyWrite,
+				Source:        "Registry",
+				Artifact:      artifact,
+				Action:        action,
+				Subject:       e.Value,
+				Details:       details,
+				Confidence:    confidence,
+				EvidenceRef:   model.EvidenceRef{SourcePath: in.EvidencePath},
+			}
+			if in.StreamCallback != nil {
+				in.StreamCallback(evt)
+			} else {
+				events = append(events, evt)
+			}
+		}
+	}
+
+	// RecentDocs: root list plus one list per extension.
+	if rd := reg.OpenKey(explorerKey + `\RecentDocs`); rd != nil {
+		emit("RecentDocs", "File Opened", rd, explorerKey+`\RecentDocs`, readMRU(rd, decodeRecentDoc))
+		for _, ext := range rd.Subkeys() {
+			path := explorerKey + `\RecentDocs\` + ext.Name()
+			emit("RecentDocs", "File Opened", ext, path, readMRU(ext, decodeRecentDoc))
+		}
+	}
+
+	// OpenSave / LastVisited common dialog MRUs (Vista+ PIDL variants and XP string variants).
+	for _, name := range []string{"OpenSavePidlMRU", "OpenSaveMRU"} {
+		base := explorerKey + `\ComDlg32\` + name
+		if key := reg.OpenKey(base); key != nil {
+			for _, ext := range key.Subkeys() {
+				emit("OpenSaveMRU", "File Dialog Open/Save", ext, base+`\`+ext.Name(), readMRU(ext, decodeShellItemPath))
+			}
+		}
+	}
+	for _, name := range []string{"LastVisitedPidlMRU", "LastVisitedMRU"} {
+		path := explorerKey + `\ComDlg32\` + name
+		if key := reg.OpenKey(path); key != nil {
+			emit("LastVisitedMRU", "File Dialog Folder", key, path, readMRU(key, decodeLastVisited))
+		}
+	}
+
+	if key := reg.OpenKey(explorerKey + `\TypedPaths`); key != nil {
+		emit("TypedPaths", "Path Typed", key, explorerKey+`\TypedPaths`, readURLList(key))
+	}
+	if key := reg.OpenKey(explorerKey + `\RunMRU`); key != nil {
+		entries := readMRU(key, decodeRegString)
+		for i := range entries {
+			entries[i].Value = strings.TrimSuffix(entries[i].Value, `\1`)
+		}
+		emit("RunMRU", "Run Dialog Command", key, explorerKey+`\RunMRU`, entries)
+	}
+	if key := reg.OpenKey(explorerKey + `\WordWheelQuery`); key != nil {
+		emit("WordWheelQuery", "Explorer Search", key, explorerKey+`\WordWheelQuery`, readMRU(key, decodeRecentDoc))
+	}
+
+	// TypedURLs has exact per-entry times in TypedURLsTime (Win8+).
+	typedURLsPath := `Software\Microsoft\Internet Explorer\TypedURLs`
+	if key := reg.OpenKey(typedURLsPath); key != nil {
+		entries := readURLList(key)
+		times := reg.OpenKey(`Software\Microsoft\Internet Explorer\TypedURLsTime`)
+		var fallback []mruEntry
+		for _, e := range entries {
+			ts := regFiletime(regBinary(times, fmt.Sprintf("url%d", e.Position+1)))
+			if ts.IsZero() {
+				fallback = append(fallback, e)
+				continue
+			}
+			evt := model.TimelineEvent{
+				ID:            fmt.Sprintf("typedurl-%s-%d-%d", owner, e.Position, ts.UnixNano()),
+				EventTime:     ts,
+				TimeSemantics: model.TimeLogged,
+				Source:        "Registry",
+				Artifact:      "TypedURLs",
+				Action:        "URL Typed",
+				Subject:       e.Value,
+				Confidence:    "high",
+				Details: map[string]string{
+					"User":        owner,
+					"URL":         e.Value,
+					"Key":         typedURLsPath,
+					"MRUPosition": strconv.Itoa(e.Position),
+				},
+				EvidenceRef: model.EvidenceRef{SourcePath: in.EvidencePath},
+			}
+			if in.StreamCallback != nil {
+				in.StreamCallback(evt)
+			} else {
+				events = append(events, evt)
+			}
+		}
+		emit("TypedURLs", "URL Typed", key, typedURLsPath, fallback)
+	}
+
+	return &pluginsdk.ParseResponse{
+		Events: events,
+	}, nil
+}
+
+// readMRU returns the values of an MRUListEx/MRUList key in most-recent-first order.
+func readMRU(key *regparser.CM_KEY_NODE, decode func(*regparser.ValueData) mruEntry) []mruEntry {
+	var order []string
+	if data := regBinary(key, "MRUListEx"); len(data) >= 4 {
+		for i := 0; i+4 <= len(data); i += 4 {
+			idx := binary.LittleEndian.Uint32(data[i : i+4])
+			if idx == 0xFFFFFFFF {
+				break
+			}
+			order = append(order, strconv.FormatUint(uint64(idx), 10))
+		}
+	} else if list := regString(key, "MRUList"); list != "" {
+		for _, r := range list {
+			order = append(order, string(r))
+		}
+	}
+
+	var out []mruEntry
+	for pos, name := range order {
+		vd := regValue(key, name)
+		if vd == nil {
+			continue
+		}
+		e := decode(vd)
+		e.Position = pos
+		out = append(out, e)
+	}
+	return out
+}
+
+// readURLList handles url1..urlN lists where url1 is the most recent.
+func readURLList(key *regparser.CM_KEY_NODE) []mruEntry {
+	type item struct {
+		n     int
+		value string
+	}
+	var items []item
+	for _, v := range key.Values() {
+		name := strings.ToLower(v.ValueName())
+		if !strings.HasPrefix(name, "url") {
+			continue
+		}
+		n, err := strconv.Atoi(name[3:])
+		if err != nil {
+			continue
+		}
+		if vd := v.ValueData(); vd != nil {
+			items = append(items, item{n: n, value: CleanString(vd.String)})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].n < items[j].n })
+	out := make([]mruEntry, 0, len(items))
+	for _, it := range items {
+		out = append(out, mruEntry{Position: it.n - 1, Value: it.value})
+	}
+	return out
+}
+
+func decodeRegString(vd *regparser.ValueData) mruEntry {
+	if vd.String != "" {
+		return mruEntry{Value: CleanString(vd.String)}
+	}
+	return mruEntry{Value: cleanupUTF16(vd.Data)}
+}
+
+// decodeRecentDoc reads the leading NUL-terminated UTF-16 name (a shell item follows it).
+func decodeRecentDoc(vd *regparser.ValueData) mruEntry {
+	return mruEntry{Value: CleanString(cleanupUTF16(evenPrefix(vd.Data)))}
+}
+
+// decodeLastVisited reads "<exe>\0<PIDL of the folder>".
+func decodeLastVisited(vd *regparser.ValueData) mruEntry {
+	if vd.String != "" {
+		return decodeRegString(vd)
+	}
+	exe := cleanupUTF16(evenPrefix(vd.Data))
+	folder := ""
+	if end := utf16End(vd.Data); end > 0 && end < len(vd.Data) {
+		folder = shellItemListPath(vd.Data[end:])
+	}
+	return mruEntry{
+		Value: exe,
+		Extra: map[string]string{"Application": exe, "Folder": folder},
+	}
+}
+
+func decodeShellItemPath(vd *regparser.ValueData) mruEntry {
+	if vd.String != "" {
+		return decodeRegString(vd)
+	}
+	return mruEntry{Value: shellItemListPath(vd.Data)}
+}
+
+// evenPrefix trims data to an even length so it can be decoded as UTF-16.
+func evenPrefix(data []byte) []byte {
+	return data[:len(data)&^1]
+}
+
+// utf16End returns the offset just past the first UTF-16 NUL terminator.
+func utf16End(data []byte) int {
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 && data[i+1] == 0 {
+			return i + 2
+		}
+	}
+	return -1
+}
+
+// shellItemListPath builds a best-effort path from an ITEMIDLIST.
+// Each item is a uint16 size followed by a type-specific payload. Only the classes seen in
+// dialog MRUs are decoded: volumes ("C:\") and file entries, whose Unicode long name lives in
+// the BEEF0004 extension block with the 8.3 ASCII name as fallback.
+func shellItemListPath(data []byte) string {
+	var parts []string
+	for off := 0; off+2 <= len(data); {
+		size := int(binary.LittleEndian.Uint16(data[off:]))
+		if size < 3 || off+size > len(data) {
+			break
+		}
+		item := data[off+2 : off+size]
+		off += size
+		switch {
+		case item[0] == 0x1F: // root folder (My Computer etc.), carries only a class GUID
+		case item[0]&0x70 == 0x20: // volume: "C:\"
+			parts = append(parts, strings.TrimRight(asciiRun(item[1:]), `\`))
+		case item[0]&0x70 == 0x30: // file entry
+			if name := beef0004Name(item); name != "" {
+				parts = append(parts, name)
+			} else if len(item) > 12 {
+				parts = append(parts, asciiRun(item[12:]))
+			}
+		}
+	}
+	return strings.Join(parts, `\`)
+}
+
+// beef0004Name extracts the long name from a file entry's BEEF0004 extension block.
+// The name follows the version-dependent fields: v3 has only the name size at 0x12, v7 adds
+// the MFT reference and reserved bytes, and v8 and v9 each add another 32-bit field.
+func beef0004Name(item []byte) string {
+	idx := bytes.Index(item, []byte{0x04, 0x00, 0xEF, 0xBE})
+	if idx < 4 {
+		return ""
+	}
+	block := item[idx-4:]
+	version := binary.LittleEndian.Uint16(block[2:4])
+	var nameOff int
+	switch {
+	case version >= 9:
+		nameOff = 0x2E
+	case version == 8:
+		nameOff = 0x2A
+	case version == 7:
+		nameOff = 0x26
+	case version >= 3:
+		nameOff = 0x14
+	default:
+		return ""
+	}
+	if nameOff >= len(block) {
+		return ""
+	}
+	return cleanupUTF16(evenPrefix(block[nameOff:]))
+}
+
+// asciiRun returns the leading printable ASCII string of b.
+func asciiRun(b []byte) string {
+	end := 0
+	for end < len(b) && b[end] >= 0x20 && b[end] < 0x7F {
+		end++
+	}
+	return string(b[:end])
+}
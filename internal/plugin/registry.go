@@ -20,8 +20,20 @@ func NewDefaultRegistry() *Registry {
 			&WintriProcessParser{},
 			&PrefetchParser{},
 			&ShimCacheParser{},
+			&USBDeviceParser{},
 			&AmcacheParser{},
 			&UserAssistParser{},
+			&UserActivityParser{},
+			&BAMParser{},
+			&RunKeyParser{},
+			&ServicesParser{},
+			&TimezoneParser{},
+			&SRUMParser{},
+			&ActivitiesCacheParser{},
+			&RecycleBinParser{},
+			&BrowserParser{},
+			&PSHistoryParser{},
+			&PSTranscriptParser{},
 			&JumplistParser{},
 			&TaskXMLParser{},
 			&EvtxParser{},
@@ -30,6 +42,8 @@ func NewDefaultRegistry() *Registry {
 		analyzers: []pluginsdk.AnalyzerPlugin{
 			&analyzers.TempExecutionAnalyzer{},
 			&analyzers.ExecutionAnomalyAnalyzer{},
+			&analyzers.LogonSessionAnalyzer{},
+			&analyzers.HostStackingAnalyzer{},
 		},
 	}
 }
@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gtrace/pkg/pluginsdk"
+
+	"www.velocidex.com/golang/regparser"
+)
+
+// openHive opens a registry hive file. The caller must close the returned file.
+func openHive(path string) (*os.File, *regparser.Registry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	reg, err := regparser.NewRegistry(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("open hive: %w", err)
+	}
+	return f, reg, nil
+}
+
+// isHiveHeader reports whether header starts with the "regf" magic.
+func isHiveHeader(header []byte) bool {
+	return len(header) >= 4 && string(header[:4]) == "regf"
+}
+
+// hiveKind maps a hive file name (SYSTEM, SOFTWARE.bak, NTUSER.DAT ...) to its canonical kind.
+// Returns "" for files that are not a known hive.
+func hiveKind(path string) string {
+	base := strings.ToUpper(filepath.Base(path))
+	switch {
+	case strings.HasPrefix(base, "NTUSER.DAT"):
+		return "NTUSER"
+	case strings.HasPrefix(base, "USRCLASS.DAT"):
+		return "USRCLASS"
+	case strings.HasPrefix(base, "SYSTEM"):
+		return "SYSTEM"
+	case strings.HasPrefix(base, "SOFTWARE"):
+		return "SOFTWARE"
+	}
+	return ""
+}
+
+// currentControlSet resolves the control set referenced by Select\Current.
+// Falls back to ControlSet001 when the Select key is missing.
+func currentControlSet(reg *regparser.Registry) string {
+	if sel := reg.OpenKey("Select"); sel != nil {
+		if cur, ok := regUint(sel, "Current"); ok && cur > 0 {
+			name := fmt.Sprintf("ControlSet%03d", cur)
+			if reg.OpenKey(name) != nil {
+				return name
+			}
+		}
+	}
+	return "ControlSet001"
+}
+
+// regValue finds a value by name (case-insensitive). An empty name selects the default value.
+func regValue(key *regparser.CM_KEY_NODE, name string) *regparser.ValueData {
+	if key == nil {
+		return nil
+	}
+	for _, v := range key.Values() {
+		if strings.EqualFold(v.ValueName(), name) {
+			vd := v.ValueData()
+			if vd == nil || vd.Error != nil {
+				return nil
+			}
+			return vd
+		}
+	}
+	return nil
+}
+
+// regString returns a REG_SZ/REG_EXPAND_SZ value, or the joined REG_MULTI_SZ entries.
+func regString(key *regparser.CM_KEY_NODE, name string) string {
+	vd := regValue(key, name)
+	if vd == nil {
+		return ""
+	}
+	if len(vd.MultiSz) > 0 {
+		return CleanString(strings.Join(vd.MultiSz, ";"))
+	}
+	return CleanString(vd.String)
+}
+
+// regUint returns an integer value (REG_DWORD/REG_QWORD).
+func regUint(key *regparser.CM_KEY_NODE, name string) (uint64, bool) {
+	vd := regValue(key, name)
+	if vd == nil {
+		return 0, false
+	}
+	switch vd.Type {
+	case regparser.REG_DWORD, regparser.REG_DWORD_BIG_ENDIAN, regparser.REG_QWORD:
+		return vd.Uint64, true
+	}
+	return 0, false
+}
+
+// regBinary returns the raw bytes of a value.
+func regBinary(key *regparser.CM_KEY_NODE, name string) []byte {
+	vd := regValue(key, name)
+	if vd == nil {
+		return nil
+	}
+	return vd.Data
+}
+
+// regSubkey returns the direct child named name (case-insensitive).
+func regSubkey(key *regparser.CM_KEY_NODE, name string) *regparser.CM_KEY_NODE {
+	if key == nil {
+		return nil
+	}
+	for _, sub := range key.Subkeys() {
+		if strings.EqualFold(sub.Name(), name) {
+			return sub
+		}
+	}
+	return nil
+}
+
+// regFiletime decodes an 8-byte FILETIME stored in a binary value.
+func regFiletime(data []byte) time.Time {
+	if len(data) < 8 {
+		return time.Time{}
+	}
+	return windowsFiletimeToGo(binary.LittleEndian.Uint64(data[:8]))
+}
+
+// keyLastWrite returns the key's LastWriteTime in UTC.
+func keyLastWrite(key *regparser.CM_KEY_NODE) time.Time {
+	if key == nil {
+		return time.Time{}
+	}
+	return key.LastWriteTime().Time.UTC()
+}
+
+// originalPath returns the evidence path as the analyst supplied it.
+// The pipeline may hand parsers a temporary dump; the original is carried in Metadata.
+func originalPath(in pluginsdk.ParseRequest) string {
+	if p := in.Metadata["original_path"]; p != "" {
+		return p
+	}
+	return in.EvidencePath
+}
+
+// hiveOwner derives the profile name owning a user hive (…\Users\<name>\NTUSER.DAT).
+func hiveOwner(in pluginsdk.ParseRequest) string {
+	if owner := in.Metadata["hive_owner"]; owner != "" {
+		return owner
+	}
+	return profileOwner(originalPath(in))
+}
+
+// profileOwner returns the <name> segment of a ...\Users\<name>\... path.
+func profileOwner(path string) string {
+	parts := strings.FieldsFunc(path, func(r rune) bool { return r == '\\' || r == '/' })
+	for i := len(parts) - 2; i >= 1; i-- {
+		if strings.EqualFold(parts[i-1], "Users") || strings.EqualFold(parts[i-1], "Documents and Settings") {
+			return parts[i]
+		}
+	}
+	return ""
+}
+
+// findSibling resolves a path relative to dir, matching each component case-insensitively.
+// Offline evidence trees copied from NTFS frequently change the case of directory names.
+func findSibling(dir string, components ...string) string {
+	current := dir
+	for _, comp := range components {
+		if comp == ".." {
+			current = filepath.Dir(current)
+			continue
+		}
+		exact := filepath.Join(current, comp)
+		if _, err := os.Stat(exact); err == nil {
+			current = exact
+			continue
+		}
+		entries, err := os.ReadDir(current)
+		if err != nil {
+			return ""
+		}
+		found := ""
+		for _, e := range entries {
+			if strings.EqualFold(e.Name(), comp) {
+				found = filepath.Join(current, e.Name())
+				break
+			}
+		}
+		if found == "" {
+			return ""
+		}
+		current = found
+	}
+	return current
+}
@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+// RunKeyParser lists the Run and RunOnce autostart entries of the SOFTWARE hive and of user
+// NTUSER.DAT hives. Each value becomes an event at the key's last write time, named the way
+// Sysmon names registry objects (HKLM\SOFTWARE\..., HKCU\Software\...).
+type RunKeyParser struct{}
+
+// runKeyPaths are the autostart keys read from each hive kind, relative to the hive root.
+var runKeyPaths = map[string][]string{
+	"SOFTWARE": {
+		`Microsoft\Windows\CurrentVersion\Run`,
+		`Microsoft\Windows\CurrentVersion\RunOnce`,
+		`Wow6432Node\Microsoft\Windows\CurrentVersion\Run`,
+		`Wow6432Node\Microsoft\Windows\CurrentVersion\RunOnce`,
+	},
+	"NTUSER": {
+		`Software\Microsoft\Windows\CurrentVersion\Run`,
+		`Software\Microsoft\Windows\CurrentVersion\RunOnce`,
+	},
+}
+
+var runKeyRoots = map[string]string{"SOFTWARE": `HKLM\SOFTWARE\`, "NTUSER": `HKCU\`}
+
+func (p *RunKeyParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "win-runkey-parser",
+		Version:   "1.0.0",
+		Type:      "parser",
+		Platforms: []string{"windows"},
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "application/octet-stream",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "runkey",
+		},
+	}
+}
+
+func (p *RunKeyParser) CanParse(path string, header []byte) bool {
+	_, ok := runKeyPaths[hiveKind(path)]
+	return ok && isHiveHeader(header)
+}
+
+func (p *RunKeyParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	kind := hiveKind(originalPath(in))
+	f, reg, err := openHive(in.EvidencePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	user := ""
+	if kind == "NTUSER" {
+		user = hiveOwner(in)
+	}
+
+	resp := &pluginsdk.ParseResponse{}
+	for _, keyPath := range runKeyPaths[kind] {
+		key := reg.OpenKey(keyPath)
+		if key == nil {
+			continue
+		}
+		lastWrite := keyLastWrite(key)
+		name := runKeyRoots[kind] + keyPath
+		for _, v := range key.Values() {
+			command := regString(key, v.ValueName())
+			if command == "" {
+				continue
+			}
+			details := map[string]string{
+				"Key":          name,
+				"ValueName":    v.ValueName(),
+				"Command":      command,
+				"TargetObject": name + `\` + v.ValueName(),
+			}
+			if user != "" {
+				details["User"] = user
+			}
+			evt := model.TimelineEvent{
+				ID:            fmt.Sprintf("runkey-%s-%s-%d", strings.ToLower(name), v.ValueName(), lastWrite.UnixNano()),
+				EventTime:     lastWrite,
+				TimeSemantics: model.TimeKeyWrite,
+				Source:        "Registry",
+				Artifact:      "RunKey",
+				Action:        "Autostart Entry",
+				Subject:       v.ValueName(),
+				Details:       details,
+				EvidenceRef:   model.EvidenceRef{SourcePath: in.EvidencePath},
+			}
+			if in.StreamCallback != nil {
+				in.StreamCallback(evt)
+			} else {
+				resp.Events = append(resp.Events, evt)
+			}
+		}
+	}
+	return resp, nil
+}
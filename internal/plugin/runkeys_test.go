@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gtrace/pkg/pluginsdk"
+)
+
+func TestRunKeyParserParse(t *testing.T) {
+	written := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	runKeys := func(values ...testValue) *testKey {
+		return &testKey{name: "Microsoft", subkeys: []*testKey{{name: "Windows", subkeys: []*testKey{{
+			name: "CurrentVersion",
+			subkeys: []*testKey{
+				{name: "Run", written: written, values: values},
+				{name: "RunOnce"},
+			},
+		}}}}}
+	}
+
+	software := buildHive(t, "SOFTWARE", &testKey{name: "ROOT", subkeys: []*testKey{
+		runKeys(szValue("SecurityHealth", `%windir%\system32\SecurityHealthSystray.exe`)),
+		{name: "Wow6432Node", subkeys: []*testKey{runKeys(szValue("Sync", `C:\Users\Public\sync.exe`))}},
+	}})
+	ntuser := buildHive(t, "NTUSER.DAT", &testKey{name: "ROOT", subkeys: []*testKey{
+		{name: "Software", subkeys: []*testKey{runKeys(szValue("OneDrive", `C:\Users\bob\AppData\Local\OneDrive.exe /background`))}},
+	}})
+
+	p := &RunKeyParser{}
+	if !p.CanParse(software, []byte("regf")) || !p.CanParse(ntuser, []byte("regf")) || p.CanParse(filepath.Join(filepath.Dir(software), "SYSTEM"), []byte("regf")) {
+		t.Fatal("CanParse accepts the wrong hives")
+	}
+
+	parse := func(path string, meta map[string]string) map[string]map[string]string {
+		t.Helper()
+		resp, err := p.Parse(context.Background(), pluginsdk.ParseRequest{EvidencePath: path, Metadata: meta})
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		out := make(map[string]map[string]string)
+		for _, ev := range resp.Events {
+			if !ev.EventTime.Equal(written) || ev.Artifact != "RunKey" {
+				t.Errorf("event = %s %s", ev.EventTime, ev.Artifact)
+			}
+			out[ev.Details["TargetObject"]] = ev.Details
+		}
+		return out
+	}
+
+	got := parse(software, nil)
+	if len(got) != 2 {
+		t.Fatalf("SOFTWARE events = %v", got)
+	}
+	if d := got[`HKLM\SOFTWARE\Wow6432Node\Microsoft\Windows\CurrentVersion\Run\Sync`]; d["Command"] != `C:\Users\Public\sync.exe` {
+		t.Errorf("Wow6432Node entry = %v", d)
+	}
+	if _, ok := got[`HKLM\SOFTWARE\Microsoft\Windows\CurrentVersion\Run\SecurityHealth`]; !ok {
+		t.Errorf("Run entry missing: %v", got)
+	}
+
+	got = parse(ntuser, map[string]string{"hive_owner": "bob"})
+	d := got[`HKCU\Software\Microsoft\Windows\CurrentVersion\Run\OneDrive`]
+	if len(got) != 1 || d["User"] != "bob" || d["ValueName"] != "OneDrive" {
+		t.Errorf("NTUSER events = %v", got)
+	}
+}
@@ -34,19 +34,8 @@ func (p *SAMParser) Manifest() pluginsdk.Manifest {
 }
 
 func (p *SAMParser) CanParse(path string, header []byte) bool {
-	// Robust checks for SAM hive
-	base := strings.ToUpper(filepath.Base(path))
-	// Check filename or header magic
-	if strings.Contains(base, "SAM") || base == "SAM" {
-		if len(header) >= 4 && string(header[:4]) == "regf" {
-			return true
-		}
-	}
-	// Fallback for generic dump names containing SAM
-	if strings.Contains(strings.ToUpper(path), "SAM") && len(header) >= 4 && string(header[:4]) == "regf" {
-		return true
-	}
-	return false
+	// Only the hive itself: names such as SAMPLE.DAT or the SAM.LOG1 transaction log are not SAM.
+	return isHiveHeader(header) && strings.ToUpper(filepath.Base(path)) == "SAM"
 }
 
 func (p *SAMParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
@@ -208,9 +197,11 @@ func (p *SAMParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plug
 
 		evtTime := lastLogon
 		action := "User Last Logon"
+		semantics := model.TimeLastLogon
 		if evtTime.IsZero() || evtTime.Year() < 1970 {
 			evtTime = pwdLastSet
 			action = "User Password Set"
+			semantics = model.TimeModified
 		}
 
 		// Ensure non-zero time for timeline visibility
@@ -224,13 +215,14 @@ func (p *SAMParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*plug
 		}
 
 		evt := model.TimelineEvent{
-			ID:        fmt.Sprintf("user-%s-%d", ridName, evtTime.UnixNano()),
-			EventTime: evtTime,
-			Source:    "SAM",
-			Artifact:  "UserAccount",
-			Action:    action,
-			Subject:   username,
-			Details:   props,
+			ID:            fmt.Sprintf("user-%s-%d", ridName, evtTime.UnixNano()),
+			EventTime:     evtTime,
+			TimeSemantics: semantics,
+			Source:        "SAM",
+			Artifact:      "UserAccount",
+			Action:        action,
+			Subject:       username,
+			Details:       props,
 			EvidenceRef: model.EvidenceRef{
 				SourcePath: in.EvidencePath,
 			},
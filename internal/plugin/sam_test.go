@@ -0,0 +1,25 @@
+package plugin
+
+import "testing"
+
+func TestSAMParserCanParse(t *testing.T) {
+	p := &SAMParser{}
+	regf := []byte("regf\x00\x00\x00\x00")
+	cases := []struct {
+		path   string
+		header []byte
+		want   bool
+	}{
+		{"/mnt/image/Windows/System32/config/SAM", regf, true},
+		{"/evidence/config/sam", regf, true},
+		{"/evidence/config/SAM.LOG1", regf, false},
+		{"/evidence/SAMPLE.DAT", regf, false},
+		{"/evidence/SAM/SYSTEM", regf, false},
+		{"/evidence/config/SAM", []byte("MZ\x90\x00"), false},
+	}
+	for _, c := range cases {
+		if got := p.CanParse(c.path, c.header); got != c.want {
+			t.Errorf("CanParse(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
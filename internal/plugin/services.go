@@ -0,0 +1,286 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+// ServicesParser enumerates CurrentControlSet\Services from the SYSTEM hive.
+// Unlike EVTX 7045 it sees every installed service, regardless of log retention.
+type ServicesParser struct{}
+
+// ServiceEntry is one service key.
+type ServiceEntry struct {
+	Name        string
+	DisplayName string
+	ImagePath   string
+	StartType   string
+	ServiceType uint64
+	ObjectName  string
+	ServiceDll  string
+	Description string
+	LastWrite   time.Time
+}
+
+// serviceFlag is a single attacker trait found on a service.
+type serviceFlag struct {
+	Rule     string
+	Severity string
+	Reason   string
+}
+
+var serviceStartTypes = map[uint64]string{
+	0: "Boot",
+	1: "System",
+	2: "Automatic",
+	3: "Manual",
+	4: "Disabled",
+}
+
+func (p *ServicesParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "win-services-parser",
+		Version:   "1.0.0",
+		Type:      "parser",
+		Platforms: []string{"windows"},
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "application/octet-stream",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "service",
+		},
+	}
+}
+
+func (p *ServicesParser) CanParse(path string, header []byte) bool {
+	return isHiveHeader(header) && hiveKind(path) == "SYSTEM"
+}
+
+func (p *ServicesParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	f, reg, err := openHive(in.EvidencePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ccs := currentControlSet(reg)
+	services := reg.OpenKey(ccs + `\Services`)
+	if services == nil {
+		return nil, fmt.Errorf("Services key not found")
+	}
+
+	resp := &pluginsdk.ParseResponse{}
+	ref := model.EvidenceRef{SourcePath: in.EvidencePath}
+
+	// Finding IDs name the host, so the same service flagged on several hosts of a case
+	// yields a finding for each rather than one replacing the others.
+	scope := strings.ToLower(hiveComputerName(reg))
+	if scope == "" {
+		scope = originalPath(in)
+	}
+	scopeSum := sha1.Sum([]byte(scope))
+	scopeID := hex.EncodeToString(scopeSum[:6])
+
+	for _, key := range services.Subkeys() {
+		svc := ServiceEntry{
+			Name:        key.Name(),
+			DisplayName: regString(key, "DisplayName"),
+			ImagePath:   regString(key, "ImagePath"),
+			ObjectName:  regString(key, "ObjectName"),
+			Description: regString(key, "Description"),
+			ServiceDll:  regString(regSubkey(key, "Parameters"), "ServiceDll"),
+			LastWrite:   keyLastWrite(key),
+		}
+		if svc.ServiceDll == "" {
+			svc.ServiceDll = regString(key, "ServiceDll")
+		}
+		if start, ok := regUint(key, "Start"); ok {
+			svc.StartType = serviceStartTypes[start]
+		}
+		svc.ServiceType, _ = regUint(key, "Type")
+
+		// Keys without an image (e.g. driver groups, parameter-only keys) are not services.
+		if svc.ImagePath == "" && svc.ServiceDll == "" {
+			continue
+		}
+
+		flags := serviceAnomalies(svc)
+		var flagRules []string
+		for _, fl := range flags {
+			flagRules = append(flagRules, fl.Rule)
+		}
+
+		details := map[string]string{
+			"ServiceName": svc.Name,
+			"DisplayName": svc.DisplayName,
+			"ImagePath":   svc.ImagePath,
+			"StartType":   svc.StartType,
+			"ServiceType": fmt.Sprintf("0x%x", svc.ServiceType),
+			"ObjectName":  svc.ObjectName,
+			"ServiceDll":  svc.ServiceDll,
+			"Description": svc.Description,
+			"Flags":       strings.Join(flagRules, ", "),
+		}
+
+		lastWrite := svc.LastWrite
+		resp.Artifacts = append(resp.Artifacts, model.Artifact{
+			ID:          "svc-" + svc.Name,
+			Type:        "service",
+			Source:      "Registry",
+			Path:        svc.ImagePath,
+			Modified:    &lastWrite,
+			Metadata:    details,
+			EvidenceRef: ref,
+		})
+
+		// The event gets its own copy: alert fields do not belong in the artifact's metadata.
+		evtDetails := make(map[string]string, len(details)+2)
+		for k, v := range details {
+			evtDetails[k] = v
+		}
+		evt := model.TimelineEvent{
+			ID:            fmt.Sprintf("svc-%s-%d", svc.Name, lastWrite.UnixNano()),
+			EventTime:     lastWrite,
+			TimeSemantics: model.TimeKeyWrite,
+			Source:        "Registry",
+			Artifact:      "Service",
+			Action:        "Service Key Modified",
+			Subject:       svc.Name,
+			Details:       evtDetails,
+			EvidenceRef:   ref,
+		}
+		if len(flags) > 0 {
+			evt.Details["_Alert"] = flags[0].Reason
+			evt.Details["_AlertLevel"] = flags[0].Severity
+		}
+		if in.StreamCallback != nil {
+			in.StreamCallback(evt)
+		} else {
+			resp.Events = append(resp.Events, evt)
+		}
+
+		for _, fl := range flags {
+			resp.Findings = append(resp.Findings, model.Finding{
+				ID:       fmt.Sprintf("%s-%s-%s", fl.Rule, svc.Name, scopeID),
+				Severity: fl.Severity,
+				Title:    fmt.Sprintf("Suspicious service %q: %s", svc.Name, fl.Reason),
+				Description: fmt.Sprintf("ImagePath: %s; ServiceDll: %s; Start: %s; Account: %s; key last written %s",
+					svc.ImagePath, svc.ServiceDll, svc.StartType, svc.ObjectName, lastWrite.Format(time.RFC3339)),
+				RuleID:       fl.Rule,
+				EvidenceRefs: []model.EvidenceRef{ref},
+				EventIDs:     []string{evt.ID},
+			})
+		}
+	}
+
+	return resp, nil
+}
+
+// userWritableDirs are path fragments regular users can write to.
+var userWritableDirs = []string{
+	`\users\`, `\programdata\`, `\windows\temp\`, `\temp\`, `\appdata\`,
+	`\perflogs\`, `\$recycle.bin\`, `\windows\tasks\`, `\windows\tracing\`,
+}
+
+// protectedSubtrees are locked-down vendor directories inside userWritableDirs that stock
+// services run from, such as Defender's platform updates under ProgramData.
+var protectedSubtrees = []string{
+	`\programdata\microsoft\windows defender\`,
+	`\programdata\microsoft\windows defender advanced threat protection\`,
+}
+
+// serviceAnomalies returns the attacker traits present on svc, most severe first.
+func serviceAnomalies(svc ServiceEntry) []serviceFlag {
+	var flags []serviceFlag
+	image := strings.ToLower(svc.ImagePath)
+	dll := strings.ToLower(svc.ServiceDll)
+
+	for _, marker := range []string{"cmd /c", "cmd.exe /c", "%comspec%", "powershell", "pwsh", "mshta", "-encodedcommand", " -enc "} {
+		if strings.Contains(image, marker) {
+			flags = append(flags, serviceFlag{Rule: "svc-shell-imagepath", Severity: "high", Reason: "shell interpreter in ImagePath"})
+			break
+		}
+	}
+	for _, target := range []string{image, dll} {
+		if target == "" {
+			continue
+		}
+		if hasUserWritableDir(target) {
+			flags = append(flags, serviceFlag{Rule: "svc-user-writable-path", Severity: "high", Reason: "binary in user-writable directory"})
+			break
+		}
+	}
+	if dll != "" && !strings.Contains(dll, `\system32\`) && !strings.Contains(dll, `\syswow64\`) {
+		flags = append(flags, serviceFlag{Rule: "svc-servicedll-outside-system32", Severity: "medium", Reason: "ServiceDll outside System32"})
+	}
+	// Drivers have terse vendor names (e1i63x64); attackers install Win32 services.
+	if svc.ServiceType&0x30 != 0 && looksRandomName(svc.Name) {
+		flags = append(flags, serviceFlag{Rule: "svc-random-name", Severity: "medium", Reason: "random-looking service name"})
+	}
+	return flags
+}
+
+func hasUserWritableDir(path string) bool {
+	for _, dir := range protectedSubtrees {
+		if strings.Contains(path, dir) {
+			return false
+		}
+	}
+	for _, dir := range userWritableDirs {
+		if strings.Contains(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksRandomName flags generated names such as Metasploit's 16 mixed-case letters or
+// Cobalt Strike's 7 hex/alnum characters. Terse vendor names ("mpssvc", "W32Time") and
+// CamelCase names ("NetTcpPortSharing") keep long lowercase runs and are not flagged.
+func looksRandomName(name string) bool {
+	if len(name) < 7 {
+		return false
+	}
+	var letters, digits, classSwitches int
+	var caseRuns, lowerRuns, lowerLen int
+	var prev rune
+	for i, r := range name {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			letters++
+			if unicode.IsLower(r) {
+				lowerLen++
+			}
+		case unicode.IsDigit(r):
+			digits++
+		default:
+			return false
+		}
+		if i > 0 && unicode.IsDigit(r) != unicode.IsDigit(prev) {
+			classSwitches++
+		}
+		if unicode.IsLetter(r) && (i == 0 || !unicode.IsLetter(prev) || unicode.IsUpper(r) != unicode.IsUpper(prev)) {
+			caseRuns++
+			if unicode.IsLower(r) {
+				lowerRuns++
+			}
+		}
+		prev = r
+	}
+	if digits >= 2 && letters >= 2 && classSwitches >= 3 {
+		return true
+	}
+	if len(name) < 10 || digits > 0 || lowerRuns == 0 {
+		return false
+	}
+	return caseRuns*2 >= len(name) && float64(lowerLen)/float64(lowerRuns) < 2
+}
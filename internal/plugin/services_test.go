@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"gtrace/pkg/pluginsdk"
+)
+
+func TestServiceAnomalies(t *testing.T) {
+	cases := []struct {
+		svc  ServiceEntry
+		want []string
+	}{
+		{
+			svc:  ServiceEntry{Name: "Dnscache", ServiceType: 0x20, ImagePath: `%SystemRoot%\system32\svchost.exe -k NetworkService`, ServiceDll: `%SystemRoot%\System32\dnsrslvr.dll`},
+			want: nil,
+		},
+		{
+			svc:  ServiceEntry{Name: "NetTcpPortSharing", ServiceType: 0x20, ImagePath: `C:\Windows\Microsoft.NET\Framework64\v4.0.30319\SMSvcHost.exe`},
+			want: nil,
+		},
+		{
+			svc:  ServiceEntry{Name: "e1i63x64", ServiceType: 0x1, ImagePath: `\SystemRoot\System32\drivers\e1i63x64.sys`},
+			want: nil,
+		},
+		{
+			svc:  ServiceEntry{Name: "rPjTsgEhQIYKzRdM", ServiceType: 0x10, ImagePath: `%COMSPEC% /b /c start /b /min powershell -nop -w hidden -encodedcommand JABzAD0A`},
+			want: []string{"svc-shell-imagepath", "svc-random-name"},
+		},
+		{
+			svc:  ServiceEntry{Name: "4f3c9a2", ServiceType: 0x10, ImagePath: `\\127.0.0.1\ADMIN$\4f3c9a2.exe`},
+			want: []string{"svc-random-name"},
+		},
+		{
+			svc:  ServiceEntry{Name: "WinDefend", ServiceType: 0x10, ImagePath: `"C:\ProgramData\Microsoft\Windows Defender\Platform\4.18.24090.11-0\MsMpEng.exe"`},
+			want: nil,
+		},
+		{
+			svc:  ServiceEntry{Name: "SyncAgent", ServiceType: 0x10, ImagePath: `C:\ProgramData\SyncAgent\agent.exe`},
+			want: []string{"svc-user-writable-path"},
+		},
+		{
+			svc:  ServiceEntry{Name: "UpdaterSvc", ServiceType: 0x20, ImagePath: `C:\Windows\system32\svchost.exe -k netsvcs`, ServiceDll: `C:\Users\Public\upd.dll`},
+			want: []string{"svc-user-writable-path", "svc-servicedll-outside-system32"},
+		},
+	}
+	for _, tc := range cases {
+		flags := serviceAnomalies(tc.svc)
+		var got []string
+		for _, f := range flags {
+			got = append(got, f.Rule)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.svc.Name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: got %v, want %v", tc.svc.Name, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestServiceFindingsPerHost(t *testing.T) {
+	system := func(computer string) string {
+		return buildHive(t, "SYSTEM", &testKey{name: "ROOT", subkeys: []*testKey{{
+			name: "ControlSet001",
+			subkeys: []*testKey{
+				{name: "Control", subkeys: []*testKey{{name: "ComputerName", subkeys: []*testKey{{
+					name:   "ComputerName",
+					values: []testValue{szValue("ComputerName", computer)},
+				}}}}},
+				{name: "Services", subkeys: []*testKey{{
+					name: "4f3c9a2",
+					values: []testValue{
+						{name: "Type", typ: 4, data: []byte{0x10, 0, 0, 0}},
+						szValue("ImagePath", `\\127.0.0.1\ADMIN$\4f3c9a2.exe`),
+					},
+				}}},
+			},
+		}}})
+	}
+
+	ids := make(map[string]bool)
+	for _, host := range []string{"WS01", "WS02", "WS01"} {
+		resp, err := (&ServicesParser{}).Parse(context.Background(), pluginsdk.ParseRequest{EvidencePath: system(host)})
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if len(resp.Findings) != 1 {
+			t.Fatalf("%s: %d findings, want 1", host, len(resp.Findings))
+		}
+		ids[resp.Findings[0].ID] = true
+		if _, ok := resp.Artifacts[0].Metadata["_Alert"]; ok || resp.Events[0].Details["_Alert"] == "" {
+			t.Errorf("%s: alert on artifact %v / event %v", host, resp.Artifacts[0].Metadata, resp.Events[0].Details)
+		}
+	}
+	// The same hive collected twice keeps its ID; another host gets its own.
+	if len(ids) != 2 {
+		t.Errorf("finding IDs = %v, want one per host", ids)
+	}
+}
@@ -90,12 +90,13 @@ func (p *ShimCacheParser) Parse(ctx context.Context, in pluginsdk.ParseRequest)
 		}
 
 		evt := model.TimelineEvent{
-			ID:        fmt.Sprintf("shim-%s-%d", filepath.Base(entry.Name), entry.Time.UnixNano()),
-			EventTime: entry.Time,
-			Source:    "shimcache",
-			Artifact:  "shimcache",
-			Action:    "FILE_MODIFIED",
-			Subject:   entry.Name,
+			ID:            fmt.Sprintf("shim-%s-%d", filepath.Base(entry.Name), entry.Time.UnixNano()),
+			EventTime:     entry.Time.UTC(),
+			TimeSemantics: model.TimeModified, // the file's last modification, not its execution
+			Source:        "shimcache",
+			Artifact:      "shimcache",
+			Action:        "FILE_MODIFIED",
+			Subject:       entry.Name,
 			Details: map[string]string{
 				"path": entry.Name,
 				"key":  "AppCompatCache",
@@ -0,0 +1,253 @@
+package plugin
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"gtrace/internal/ese"
+	"gtrace/pkg/pluginsdk"
+)
+
+func TestSIDString(t *testing.T) {
+	sid := []byte{
+		0x01, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05,
+		0x15, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x00,
+		0x03, 0x00, 0x00, 0x00,
+		0xe9, 0x03, 0x00, 0x00,
+	}
+	if got := sidString(sid); got != "S-1-5-21-1-2-3-1001" {
+		t.Errorf("sidString = %q", got)
+	}
+	if got := sidString(sid[:10]); got != "" {
+		t.Errorf("truncated SID should be empty, got %q", got)
+	}
+}
+
+const srumPageSize = 4096
+
+// eseEntry is a leaf entry of a test ESE page.
+type eseEntry struct {
+	key  []byte
+	data []byte
+}
+
+// esePage lays out a small-page (40 byte header) leaf page with an empty common key prefix.
+func esePage(flags uint32, entries []eseEntry) []byte {
+	p := make([]byte, srumPageSize)
+	values := [][]byte{nil}
+	for _, e := range entries {
+		v := binary.LittleEndian.AppendUint16(nil, uint16(len(e.key)))
+		v = append(v, e.key...)
+		values = append(values, append(v, e.data...))
+	}
+	off := 0
+	for i, v := range values {
+		copy(p[40+off:], v)
+		pos := len(p) - 4*(i+1)
+		binary.LittleEndian.PutUint16(p[pos:], uint16(len(v)))
+		binary.LittleEndian.PutUint16(p[pos+2:], uint16(off))
+		off += len(v)
+	}
+	binary.LittleEndian.PutUint16(p[34:], uint16(len(values)))
+	binary.LittleEndian.PutUint32(p[36:], flags)
+	return p
+}
+
+// eseTagged is a tagged column value; flag 0x04 marks a long value reference.
+type eseTagged struct {
+	id    uint16
+	flag  byte
+	value []byte
+}
+
+// eseRecord encodes a record with fixed columns 1..len(fixed), no variable columns and the
+// given tagged columns.
+func eseRecord(fixed [][]byte, tagged ...eseTagged) []byte {
+	rec := []byte{byte(len(fixed)), 0, 0, 0}
+	for _, f := range fixed {
+		rec = append(rec, f...)
+	}
+	binary.LittleEndian.PutUint16(rec[2:], uint16(len(rec)))
+	off := 4 * len(tagged)
+	var body []byte
+	for _, t := range tagged {
+		rec = binary.LittleEndian.AppendUint16(rec, t.id)
+		rec = binary.LittleEndian.AppendUint16(rec, uint16(off)|0x4000)
+		body = append(body, t.flag)
+		body = append(body, t.value...)
+		off += 1 + len(t.value)
+	}
+	return append(rec, body...)
+}
+
+func le32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+func le64(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+
+func utf16le(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+func jetDate(t time.Time) []byte {
+	days := t.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	return le64(math.Float64bits(days))
+}
+
+// srumCatalogRow encodes an MSysObjects row: table (1), column (2) or long value tree (4).
+func srumCatalogRow(objID uint32, typ uint16, id, coltypOrFDP, size uint32, name string) []byte {
+	fixed := [][]byte{le32(objID), binary.LittleEndian.AppendUint16(nil, typ), le32(id), le32(coltypOrFDP), le32(size), le32(0), le32(0)}
+	rec := eseRecord(fixed)
+	rec[1] = 128 // one variable column: Name
+	rec = binary.LittleEndian.AppendUint16(rec, uint16(len(name)))
+	return append(rec, name...)
+}
+
+type srumColumn struct {
+	name string
+	typ  uint32
+	size uint32
+}
+
+// buildSRUDB writes a SRUDB.dat with SruDbIdMapTable, the network usage table and the
+// application resource usage table, each with one row per test record. The application path
+// is stored as a separated long value, as SRUM does for long IdBlobs.
+func buildSRUDB(t *testing.T, path string, when time.Time) {
+	t.Helper()
+	const (
+		idMapPage, idMapLVPage = 10, 11
+		networkPage, appPage   = 12, 13
+		lid                    = 0x51
+	)
+	usageColumns := []srumColumn{
+		{"AutoIncId", ese.ColumnTypeLong, 4},
+		{"TimeStamp", ese.ColumnTypeDateTime, 8},
+		{"AppId", ese.ColumnTypeLong, 4},
+		{"UserId", ese.ColumnTypeLong, 4},
+	}
+	tables := []struct {
+		name    string
+		page    uint32
+		columns []srumColumn
+	}{
+		{"SruDbIdMapTable", idMapPage, []srumColumn{{"IdType", ese.ColumnTypeUnsignedByte, 1}, {"IdIndex", ese.ColumnTypeLong, 4}}},
+		{"{973F5D5C-1D90-4944-BE8E-24B94231A174}", networkPage, append(usageColumns[:4:4],
+			srumColumn{"InterfaceLuid", ese.ColumnTypeLongLong, 8},
+			srumColumn{"L2ProfileId", ese.ColumnTypeLong, 4},
+			srumColumn{"BytesSent", ese.ColumnTypeLongLong, 8},
+			srumColumn{"BytesRecvd", ese.ColumnTypeLongLong, 8})},
+		{"{D10CA2FE-6FCF-4F6D-848E-B2E99266FA89}", appPage, append(usageColumns[:4:4],
+			srumColumn{"ForegroundCycleTime", ese.ColumnTypeLongLong, 8},
+			srumColumn{"BackgroundCycleTime", ese.ColumnTypeLongLong, 8},
+			srumColumn{"ForegroundBytesRead", ese.ColumnTypeLongLong, 8})},
+	}
+	var catalog []eseEntry
+	key := func() []byte { return []byte{byte(len(catalog) >> 8), byte(len(catalog))} }
+	for i, tbl := range tables {
+		objID := uint32(i + 2)
+		catalog = append(catalog, eseEntry{key(), srumCatalogRow(objID, 1, objID, tbl.page, 0, tbl.name)})
+		for j, c := range tbl.columns {
+			catalog = append(catalog, eseEntry{key(), srumCatalogRow(objID, 2, uint32(j+1), c.typ, c.size, c.name)})
+		}
+	}
+	catalog = append(catalog,
+		eseEntry{key(), srumCatalogRow(2, 2, 256, ese.ColumnTypeLongBinary, 0, "IdBlob")},
+		eseEntry{key(), srumCatalogRow(2, 4, 0, idMapLVPage, 0, "LV")})
+
+	sid := []byte{1, 5, 0, 0, 0, 0, 0, 5, 0x15, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 0xe9, 3, 0, 0}
+	app := utf16le(`\Device\HarddiskVolume2\Users\bob\AppData\Local\Temp\rclone.exe`)
+	pages := map[uint32][]byte{
+		4: esePage(0x2, catalog),
+		idMapPage: esePage(0x2, []eseEntry{
+			{[]byte{1}, eseRecord([][]byte{{0}, le32(1)}, eseTagged{256, 0x04, le32(lid)})},
+			{[]byte{2}, eseRecord([][]byte{{3}, le32(2)}, eseTagged{256, 0, sid})},
+		}),
+		// LV tree: the LID key holds the header, (LID, offset) keys the chunks.
+		idMapLVPage: esePage(0x2|0x80, []eseEntry{
+			{binary.BigEndian.AppendUint32(nil, lid), le32(uint32(len(app)))},
+			{append(binary.BigEndian.AppendUint32(nil, lid), 0, 0, 0, 40), app[40:]},
+			{append(binary.BigEndian.AppendUint32(nil, lid), 0, 0, 0, 0), app[:40]},
+		}),
+		networkPage: esePage(0x2, []eseEntry{
+			{[]byte{1}, eseRecord([][]byte{le32(100), jetDate(when), le32(1), le32(2), le64(0x6008000), le32(0), le64(734003200), le64(1048576)})},
+		}),
+		appPage: esePage(0x2, []eseEntry{
+			{[]byte{1}, eseRecord([][]byte{le32(200), jetDate(when), le32(1), le32(2), le64(123456789), le64(42), le64(8192)})},
+		}),
+	}
+
+	db := make([]byte, (appPage+2)*srumPageSize)
+	binary.LittleEndian.PutUint32(db[4:], 0x89ABCDEF)
+	binary.LittleEndian.PutUint32(db[8:], 0x620)
+	binary.LittleEndian.PutUint32(db[232:], 0x11)
+	binary.LittleEndian.PutUint32(db[236:], srumPageSize)
+	for n, p := range pages {
+		copy(db[int(n+1)*srumPageSize:], p)
+	}
+	if err := os.WriteFile(path, db, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSRUMParserParse(t *testing.T) {
+	root := t.TempDir()
+	sru := filepath.Join(root, "Windows", "System32", "sru")
+	config := filepath.Join(root, "Windows", "System32", "config")
+	os.MkdirAll(sru, 0o755)
+	os.MkdirAll(config, 0o755)
+	when := time.Date(2024, 3, 9, 14, 0, 0, 0, time.UTC)
+	dbPath := filepath.Join(sru, "SRUDB.dat")
+	buildSRUDB(t, dbPath, when)
+
+	software, err := os.ReadFile(buildHive(t, "SOFTWARE", &testKey{name: "ROOT", subkeys: []*testKey{{
+		name: "Microsoft", subkeys: []*testKey{{name: "Windows NT", subkeys: []*testKey{{name: "CurrentVersion", subkeys: []*testKey{{
+			name: "ProfileList", subkeys: []*testKey{{
+				name:   "S-1-5-21-1-2-3-1001",
+				values: []testValue{szValue("ProfileImagePath", `C:\Users\bob`)},
+			}},
+		}}}}}},
+	}}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(config, "SOFTWARE"), software, 0o644)
+
+	header, _ := os.ReadFile(dbPath)
+	p := &SRUMParser{}
+	if !p.CanParse(dbPath, header[:8]) {
+		t.Fatal("CanParse rejected SRUDB.dat")
+	}
+	resp, err := p.Parse(context.Background(), pluginsdk.ParseRequest{EvidencePath: dbPath})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(resp.Events) != 2 {
+		t.Fatalf("events = %+v", resp.Events)
+	}
+	byArtifact := make(map[string]map[string]string)
+	for _, ev := range resp.Events {
+		if !ev.EventTime.Equal(when) || ev.Subject != `\Device\HarddiskVolume2\Users\bob\AppData\Local\Temp\rclone.exe` {
+			t.Errorf("%s: time %s, subject %q", ev.Artifact, ev.EventTime, ev.Subject)
+		}
+		if ev.Details["UserSID"] != "S-1-5-21-1-2-3-1001" || ev.Details["User"] != "bob" {
+			t.Errorf("%s: user %q / %q", ev.Artifact, ev.Details["UserSID"], ev.Details["User"])
+		}
+		byArtifact[ev.Artifact] = ev.Details
+	}
+	if d := byArtifact["SRUM Network Usage"]; d["BytesSent"] != "734003200" || d["BytesRecvd"] != "1048576" {
+		t.Errorf("network usage = %v", d)
+	}
+	if d := byArtifact["SRUM Application Usage"]; d["ForegroundCycleTime"] != "123456789" || d["ForegroundBytesRead"] != "8192" {
+		t.Errorf("application usage = %v", d)
+	}
+}
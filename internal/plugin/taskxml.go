@@ -1,9 +1,11 @@
 package plugin
 
 import (
+	"bytes"
 	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -89,45 +91,64 @@ func (p *TaskXMLParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*
 
 	// Convert UTF-16 LE to UTF-8 if needed
 	if len(content) > 2 && content[0] == 0xFF && content[1] == 0xFE {
-		content = []byte(cleanupUTF16(content))
-		// Note cleanupUTF16 from utils.go works on bytes -> string.
-		// We can cast back.
+		content = []byte(cleanupUTF16(content[2:]))
 	}
 
+	// The declaration still says encoding="UTF-16"; the content is UTF-8 by now.
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.HasPrefix(strings.ToLower(charset), "utf-16") {
+			return input, nil
+		}
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
 	var task Task
-	if err := xml.Unmarshal(content, &task); err != nil {
+	if err := dec.Decode(&task); err != nil {
 		return nil, fmt.Errorf("xml parse failed: %w", err)
 	}
 
 	var events []model.TimelineEvent
 
-	// Create Event
-	// Use File Mod time as "Task Modified" time
-	info, _ := os.Stat(in.EvidencePath)
-	modTime := time.Now()
-	if info != nil {
-		modTime = info.ModTime()
+	// The task was registered at RegistrationInfo/Date. It is usually written in host-local
+	// time without an offset: convert it with the collection's timezone when the SYSTEM hive is
+	// at hand, else leave it to the case's host clock settings. Tasks without a date fall back
+	// to the file's modification time.
+	eventTime, semantics, timeBase := taskRegistrationTime(task.RegistrationInfo.Date)
+	if eventTime.IsZero() {
+		if info, err := os.Stat(in.EvidencePath); err == nil {
+			eventTime, semantics, timeBase = info.ModTime().UTC(), model.TimeModified, ""
+		}
+	} else if timeBase == "host-local" {
+		if tz, ok := siblingTimezone(originalPath(in)); ok {
+			eventTime, timeBase = tz.ToUTC(eventTime), "converted-from-host-local"
+		}
 	}
 
 	// Collect Commands
-	for _, exec := range task.Actions.Exec {
+	for i, exec := range task.Actions.Exec {
 		cmd := exec.Command
 		args := exec.Arguments
 
+		details := map[string]string{
+			"arguments":         args,
+			"task_name":         filepath.Base(in.EvidencePath),
+			"author":            task.RegistrationInfo.Author,
+			"description":       task.RegistrationInfo.Description,
+			"registration_date": task.RegistrationInfo.Date,
+			"triggers":          simplifyTriggers(task.Triggers.Raw),
+		}
+		if timeBase != "" {
+			details["TimeBase"] = timeBase
+		}
 		events = append(events, model.TimelineEvent{
-			ID:        fmt.Sprintf("task-%s-%d", filepath.Base(in.EvidencePath), modTime.UnixNano()),
-			EventTime: modTime, // Task Modification Time
-			Source:    "TaskScheduler",
-			Artifact:  "ScheduledTask",
-			Action:    "Persistence Configured",
-			Subject:   cmd, // The malicious binary
-			Details: map[string]string{
-				"arguments":   args,
-				"task_name":   filepath.Base(in.EvidencePath),
-				"author":      task.RegistrationInfo.Author,
-				"description": task.RegistrationInfo.Description,
-				"triggers":    simplifyTriggers(task.Triggers.Raw),
-			},
+			ID:            fmt.Sprintf("task-%s-%d-%d", filepath.Base(in.EvidencePath), eventTime.UnixNano(), i),
+			EventTime:     eventTime,
+			TimeSemantics: semantics,
+			Source:        "TaskScheduler",
+			Artifact:      "ScheduledTask",
+			Action:        "Persistence Configured",
+			Subject:       cmd, // The malicious binary
+			Details:       details,
 			EvidenceRef: model.EvidenceRef{
 				SourcePath: in.EvidencePath,
 			},
@@ -139,6 +160,22 @@ func (p *TaskXMLParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*
 	}, nil
 }
 
+// taskRegistrationTime parses RegistrationInfo/Date ("2024-03-09T14:00:00", optionally with
+// fractional seconds or an offset). A date without an offset is host-local.
+func taskRegistrationTime(date string) (time.Time, string, string) {
+	date = strings.TrimSpace(date)
+	if date == "" {
+		return time.Time{}, "", ""
+	}
+	if t, err := time.Parse(time.RFC3339Nano, date); err == nil {
+		return t.UTC(), model.TimeCreated, ""
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05.999999999", date); err == nil {
+		return t, model.TimeCreated, "host-local"
+	}
+	return time.Time{}, "", ""
+}
+
 func simplifyTriggers(raw string) string {
 	// Very basic summary
 	summary := []string{}
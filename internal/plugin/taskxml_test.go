@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+// taskXML is a task file as Task Scheduler writes it: UTF-16 LE with a byte order mark.
+func taskXML(date string) []byte {
+	return append([]byte{0xFF, 0xFE}, utf16le(`<?xml version="1.0" encoding="UTF-16"?>
+<Task version="1.2" xmlns="http://schemas.microsoft.com/windows/2004/02/mit/task">
+  <RegistrationInfo><Date>`+date+`</Date><Author>CORP\admin</Author></RegistrationInfo>
+  <Triggers><LogonTrigger><Enabled>true</Enabled></LogonTrigger></Triggers>
+  <Actions Context="Author"><Exec><Command>C:\Users\Public\updater.exe</Command><Arguments>-q</Arguments></Exec></Actions>
+</Task>`)...)
+}
+
+func TestTaskXMLParserRegistrationDate(t *testing.T) {
+	root := t.TempDir()
+	tasks := filepath.Join(root, "Windows", "System32", "Tasks")
+	os.MkdirAll(tasks, 0o755)
+	path := filepath.Join(tasks, "Updater")
+	mtime := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	parse := func(date string) model.TimelineEvent {
+		t.Helper()
+		if err := os.WriteFile(path, taskXML(date), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, mtime, mtime)
+		resp, err := (&TaskXMLParser{}).Parse(context.Background(), pluginsdk.ParseRequest{EvidencePath: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Events) != 1 {
+			t.Fatalf("events = %+v", resp.Events)
+		}
+		return resp.Events[0]
+	}
+
+	// Without the SYSTEM hive the local date is left for the case's host settings to convert.
+	ev := parse("2024-07-01T14:00:00")
+	if !ev.EventTime.Equal(time.Date(2024, 7, 1, 14, 0, 0, 0, time.UTC)) || ev.TimeSemantics != model.TimeCreated || ev.Details["TimeBase"] != "host-local" {
+		t.Errorf("no hive: %s %s %q", ev.EventTime, ev.TimeSemantics, ev.Details["TimeBase"])
+	}
+	if ev := parse("2024-07-01T14:00:00.1234567+02:00"); !ev.EventTime.Equal(time.Date(2024, 7, 1, 12, 0, 0, 123456700, time.UTC)) || ev.Details["TimeBase"] != "" {
+		t.Errorf("date with offset: %s %q", ev.EventTime, ev.Details["TimeBase"])
+	}
+	if ev = parse(""); !ev.EventTime.Equal(mtime) || ev.TimeSemantics != model.TimeModified {
+		t.Errorf("no date: %s %s", ev.EventTime, ev.TimeSemantics)
+	}
+
+	// W. Europe Standard Time in the collection's SYSTEM hive.
+	minus60 := binary.LittleEndian.AppendUint32(nil, uint32(0xFFFFFFC4))
+	system, err := os.ReadFile(buildHive(t, "SYSTEM", &testKey{name: "ROOT", subkeys: []*testKey{
+		{name: "Select", values: []testValue{{name: "Current", typ: 4, data: []byte{1, 0, 0, 0}}}},
+		{name: "ControlSet001", subkeys: []*testKey{{name: "Control", subkeys: []*testKey{{
+			name: "TimeZoneInformation",
+			values: []testValue{
+				szValue("TimeZoneKeyName", "W. Europe Standard Time"),
+				{name: "Bias", typ: 4, data: minus60},
+				{name: "DaylightBias", typ: 4, data: minus60},
+				binValue("StandardStart", systemTime(10, 0, 5, 3)),
+				binValue("DaylightStart", systemTime(3, 0, 5, 2)),
+			},
+		}}}}},
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(root, "Windows", "System32", "config")
+	os.MkdirAll(config, 0o755)
+	os.WriteFile(filepath.Join(config, "SYSTEM"), system, 0o644)
+	if ev := parse("2024-07-01T14:00:00"); !ev.EventTime.Equal(time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)) || ev.Details["TimeBase"] != "converted-from-host-local" {
+		t.Errorf("with hive: %s %q", ev.EventTime, ev.Details["TimeBase"])
+	}
+}
@@ -0,0 +1,188 @@
+package plugin
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+
+	"www.velocidex.com/golang/regparser"
+)
+
+// TimezoneParser reads the host name and Control\TimeZoneInformation from the SYSTEM hive.
+// The pipeline records the result per host so that local-time artifacts can be converted
+// and events displayed in host time.
+type TimezoneParser struct{}
+
+// HostTimezoneArtifact is the artifact type the parser emits.
+const HostTimezoneArtifact = "host_timezone"
+
+func (p *TimezoneParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "win-timezone-parser",
+		Version:   "1.0.0",
+		Type:      "parser",
+		Platforms: []string{"windows"},
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "application/octet-stream",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: HostTimezoneArtifact,
+		},
+	}
+}
+
+func (p *TimezoneParser) CanParse(path string, header []byte) bool {
+	return isHiveHeader(header) && hiveKind(path) == "SYSTEM"
+}
+
+func (p *TimezoneParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	f, reg, err := openHive(in.EvidencePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ccs := currentControlSet(reg)
+	key := reg.OpenKey(ccs + `\Control\TimeZoneInformation`)
+	tz, ok := hostTimezone(reg)
+	if !ok {
+		return nil, fmt.Errorf("TimeZoneInformation key not found")
+	}
+	host := hiveComputerName(reg)
+	domain := regString(reg.OpenKey(ccs+`\Services\Tcpip\Parameters`), "Domain")
+
+	meta := tz.Metadata()
+	meta["Hostname"] = host
+	meta["Domain"] = domain
+	if v, ok := regUint(key, "ActiveTimeBias"); ok {
+		meta["ActiveTimeBias"] = fmt.Sprint(int32(v))
+	}
+	if v, ok := regUint(key, "RealTimeIsUniversal"); ok && v != 0 {
+		meta["RealTimeIsUniversal"] = "1"
+	}
+	ref := model.EvidenceRef{SourcePath: in.EvidencePath}
+	lastWrite := keyLastWrite(key)
+
+	resp := &pluginsdk.ParseResponse{
+		Artifacts: []model.Artifact{{
+			ID:          "tz-" + strings.ToLower(host),
+			Host:        host,
+			Type:        HostTimezoneArtifact,
+			Source:      "Registry",
+			Path:        ccs + `\Control\TimeZoneInformation`,
+			Modified:    &lastWrite,
+			Metadata:    meta,
+			EvidenceRef: ref,
+		}},
+	}
+	evt := model.TimelineEvent{
+		ID:            "tz-" + strings.ToLower(host),
+		EventTime:     lastWrite,
+		TimeSemantics: model.TimeKeyWrite,
+		Source:        "Registry",
+		Artifact:      "TimeZoneInformation",
+		Action:        "Time Zone Configured",
+		Subject:       tz.Name,
+		Details:       meta,
+		EvidenceRef:   ref,
+	}
+	if in.StreamCallback != nil {
+		in.StreamCallback(evt)
+	} else {
+		resp.Events = append(resp.Events, evt)
+	}
+	return resp, nil
+}
+
+// hostTimezone reads Control\TimeZoneInformation of the current control set.
+func hostTimezone(reg *regparser.Registry) (model.HostTimezone, bool) {
+	key := reg.OpenKey(currentControlSet(reg) + `\Control\TimeZoneInformation`)
+	if key == nil {
+		return model.HostTimezone{}, false
+	}
+	bias, ok := regUint(key, "Bias")
+	if !ok {
+		return model.HostTimezone{}, false
+	}
+	tz := model.HostTimezone{
+		Name:         regString(key, "TimeZoneKeyName"),
+		StandardName: regString(key, "StandardName"),
+		DaylightName: regString(key, "DaylightName"),
+		Bias:         int(int32(bias)),
+	}
+	if v, ok := regUint(key, "StandardBias"); ok {
+		tz.StandardBias = int(int32(v))
+	}
+	if v, ok := regUint(key, "DaylightBias"); ok {
+		tz.DaylightBias = int(int32(v))
+	}
+	std, okStd := systemTimeRule(regBinary(key, "StandardStart"))
+	day, okDay := systemTimeRule(regBinary(key, "DaylightStart"))
+	if okStd && okDay {
+		tz.StandardStart, tz.DaylightStart = &std, &day
+	}
+	if tz.Name == "" {
+		tz.Name = tz.StandardName
+	}
+	return tz, true
+}
+
+// systemTimeRule decodes a SYSTEMTIME transition date. A zero month means the zone has no
+// daylight saving time.
+func systemTimeRule(data []byte) (model.TZRule, bool) {
+	if len(data) < 16 {
+		return model.TZRule{}, false
+	}
+	word := func(i int) int { return int(binary.LittleEndian.Uint16(data[i*2:])) }
+	r := model.TZRule{Month: word(1), Weekday: word(2), Week: word(3), Hour: word(4), Minute: word(5)}
+	if r.Month < 1 || r.Month > 12 || r.Week < 1 || r.Week > 5 || r.Weekday > 6 {
+		return model.TZRule{}, false
+	}
+	return r, true
+}
+
+// siblingTimezone looks for the SYSTEM hive of the Windows installation path was collected
+// from, walking up from its directory, and returns the host timezone. It lets parsers of
+// logs written in local time (setupapi.dev.log, PowerShell transcripts) emit UTC.
+func siblingTimezone(path string) (model.HostTimezone, bool) {
+	dir := filepath.Dir(path)
+	for i := 0; i < 8; i++ {
+		for _, rel := range [][]string{{"System32", "config", "SYSTEM"}, {"Windows", "System32", "config", "SYSTEM"}} {
+			if hive := findSibling(dir, rel...); hive != "" {
+				f, reg, err := openHive(hive)
+				if err != nil {
+					return model.HostTimezone{}, false
+				}
+				defer f.Close()
+				return hostTimezone(reg)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return model.HostTimezone{}, false
+}
+
+// HiveComputerName returns the ComputerName recorded in a SYSTEM hive, or "" when the file
+// cannot be read as one.
+func HiveComputerName(path string) string {
+	f, reg, err := openHive(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return hiveComputerName(reg)
+}
+
+func hiveComputerName(reg *regparser.Registry) string {
+	return regString(reg.OpenKey(currentControlSet(reg)+`\Control\ComputerName\ComputerName`), "ComputerName")
+}
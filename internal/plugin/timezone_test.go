@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+func systemTime(month, weekday, week, hour int) []byte {
+	b := make([]byte, 16)
+	for i, v := range []int{0, month, weekday, week, hour} {
+		binary.LittleEndian.PutUint16(b[i*2:], uint16(v))
+	}
+	return b
+}
+
+func TestHostTimezoneOffsets(t *testing.T) {
+	// W. Europe Standard Time: UTC+1, DST from the last Sunday of March 02:00 to the last
+	// Sunday of October 03:00.
+	std, ok := systemTimeRule(systemTime(10, 0, 5, 3))
+	if !ok {
+		t.Fatal("StandardStart not decoded")
+	}
+	day, ok := systemTimeRule(systemTime(3, 0, 5, 2))
+	if !ok {
+		t.Fatal("DaylightStart not decoded")
+	}
+	if _, ok := systemTimeRule(make([]byte, 16)); ok {
+		t.Error("zero SYSTEMTIME decoded as a transition")
+	}
+	tz := model.HostTimezone{Name: "W. Europe Standard Time", Bias: -60, DaylightBias: -60, StandardStart: &std, DaylightStart: &day}
+
+	for _, tc := range []struct {
+		utc  string
+		want int
+	}{
+		{"2024-01-15T12:00:00Z", 60},
+		{"2024-03-31T00:59:59Z", 60},
+		{"2024-03-31T01:00:00Z", 120}, // 02:00 local standard time
+		{"2024-07-01T12:00:00Z", 120},
+		{"2024-10-27T00:59:59Z", 120},
+		{"2024-10-27T01:00:00Z", 60}, // 03:00 local daylight time
+	} {
+		ts, _ := time.Parse(time.RFC3339, tc.utc)
+		if got := tz.OffsetAt(ts); got != tc.want {
+			t.Errorf("OffsetAt(%s) = %d, want %d", tc.utc, got, tc.want)
+		}
+	}
+
+	local := time.Date(2019, 3, 8, 14, 33, 47, 0, time.UTC) // setupapi.dev.log reading
+	if got := tz.ToUTC(local).Format(time.RFC3339); got != "2019-03-08T13:33:47Z" {
+		t.Errorf("ToUTC winter = %s", got)
+	}
+	local = time.Date(2019, 7, 8, 14, 33, 47, 0, time.UTC)
+	if got := tz.ToUTC(local).Format(time.RFC3339); got != "2019-07-08T12:33:47Z" {
+		t.Errorf("ToUTC summer = %s", got)
+	}
+
+	back, err := model.ParseHostTimezone(tz.Metadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Bias != tz.Bias || back.DaylightStart == nil || *back.DaylightStart != day || *back.StandardStart != std {
+		t.Errorf("metadata round trip = %+v", back)
+	}
+}
+
+func TestParseWMIDate(t *testing.T) {
+	ts, err := parseWMIDate("20250114120000.000000+060")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ts.Format(time.RFC3339); got != "2025-01-14T11:00:00Z" {
+		t.Errorf("parseWMIDate = %s", got)
+	}
+	if _, err := parseWMIDate("20250114120000"); err == nil {
+		t.Error("truncated date accepted")
+	}
+}
@@ -0,0 +1,619 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+
+	"www.velocidex.com/golang/regparser"
+)
+
+// devicePropertyGUID is the DEVPKEY_Device_* property set holding install/arrival/removal FILETIMEs.
+const devicePropertyGUID = "{83da6326-97a6-4088-9453-a1923f573b29}"
+
+// USBDeviceParser builds a USB device inventory and connect/removal timeline.
+// SYSTEM is the anchor hive (USBSTOR, USB, MountedDevices); SOFTWARE, setupapi.dev.log and
+// per-user MountPoints2 are correlated when found next to it in the evidence tree,
+// and also parsed on their own when fed individually.
+type USBDeviceParser struct{}
+
+// USBDevice is one mass storage device reconstructed from SYSTEM\...\Enum\USBSTOR.
+type USBDevice struct {
+	Class        string
+	Vendor       string
+	Product      string
+	Revision     string
+	Serial       string
+	FriendlyName string
+	VID          string
+	PID          string
+	DriveLetter  string
+	VolumeGUID   string
+	VolumeLabel  string
+	Users        []string
+	FirstInstall time.Time
+	LastConnect  time.Time
+	LastRemoval  time.Time
+	KeyWritten   time.Time
+}
+
+func (p *USBDeviceParser) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:        "win-usb-parser",
+		Version:     "1.0.0",
+		Type:        "parser",
+		Platforms:   []string{"windows"},
+		Description: "USB device inventory from SYSTEM/SOFTWARE/NTUSER hives and setupapi.dev.log",
+		Input: pluginsdk.IODecl{
+			Kind: "file",
+			MIME: "application/octet-stream",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "usb_device",
+		},
+	}
+}
+
+func (p *USBDeviceParser) CanParse(path string, header []byte) bool {
+	if strings.EqualFold(filepath.Base(path), "setupapi.dev.log") {
+		return true
+	}
+	if !isHiveHeader(header) {
+		return false
+	}
+	switch hiveKind(path) {
+	case "SYSTEM", "SOFTWARE", "NTUSER":
+		return true
+	}
+	return false
+}
+
+func (p *USBDeviceParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	if strings.EqualFold(filepath.Base(originalPath(in)), "setupapi.dev.log") {
+		return p.parseSetupAPI(in)
+	}
+
+	f, reg, err := openHive(in.EvidencePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch hiveKind(originalPath(in)) {
+	case "SYSTEM":
+		return p.parseSystem(in, reg)
+	case "SOFTWARE":
+		return p.parseSoftware(in, reg)
+	case "NTUSER":
+		return p.parseMountPoints(in, reg)
+	}
+	return &pluginsdk.ParseResponse{}, nil
+}
+
+// parseSystem enumerates USBSTOR and enriches devices from the sibling hives and logs.
+func (p *USBDeviceParser) parseSystem(in pluginsdk.ParseRequest, reg *regparser.Registry) (*pluginsdk.ParseResponse, error) {
+	ccs := currentControlSet(reg)
+	usbstor := reg.OpenKey(ccs + `\Enum\USBSTOR`)
+	if usbstor == nil {
+		return &pluginsdk.ParseResponse{}, nil
+	}
+
+	devices := collectUSBStor(usbstor)
+	if len(devices) == 0 {
+		return &pluginsdk.ParseResponse{}, nil
+	}
+	applyUSBEnum(reg.OpenKey(ccs+`\Enum\USB`), devices)
+	applyMountedDevices(reg.OpenKey("MountedDevices"), devices)
+
+	// Correlate with sibling evidence: ...\System32\config\SOFTWARE, ...\Windows\INF\setupapi.dev.log,
+	// ...\Users\*\NTUSER.DAT. Locked or missing files are simply skipped.
+	configDir := filepath.Dir(originalPath(in))
+	if sw := findSibling(configDir, "SOFTWARE"); sw != "" {
+		if f, swReg, err := openHive(sw); err == nil {
+			applyPortableDevices(swReg, devices)
+			f.Close()
+		}
+	}
+	if logPath := findSibling(configDir, "..", "..", "INF", "setupapi.dev.log"); logPath != "" {
+		if installs, err := readSetupAPIInstalls(logPath); err == nil {
+			tz, hasTZ := hostTimezone(reg)
+			for _, inst := range installs {
+				if hasTZ {
+					inst.Time = tz.ToUTC(inst.Time)
+				}
+				for _, d := range devices {
+					if strings.EqualFold(usbSerialRoot(d.Serial), inst.Serial) && (d.FirstInstall.IsZero() || inst.Time.Before(d.FirstInstall)) {
+						d.FirstInstall = inst.Time
+					}
+				}
+			}
+		}
+	}
+	if usersDir := findSibling(configDir, "..", "..", "..", "Users"); usersDir != "" {
+		applyUserMountPoints(usersDir, devices)
+	}
+
+	resp := &pluginsdk.ParseResponse{}
+	for _, d := range devices {
+		resp.Artifacts = append(resp.Artifacts, d.artifact(in.EvidencePath))
+
+		emit := func(ts time.Time, action, semantics string) {
+			if ts.IsZero() {
+				return
+			}
+			evt := d.event(ts, action, semantics, in.EvidencePath)
+			if in.StreamCallback != nil {
+				in.StreamCallback(evt)
+			} else {
+				resp.Events = append(resp.Events, evt)
+			}
+		}
+		emit(d.FirstInstall, "USB First Connected", model.TimeConnected)
+		if d.LastConnect.IsZero() {
+			// Pre-Win8 hives lack the arrival property; the serial key write time is the best proxy.
+			emit(d.KeyWritten, "USB Last Connected (key write)", model.TimeKeyWrite)
+		} else {
+			emit(d.LastConnect, "USB Last Connected", model.TimeConnected)
+		}
+		emit(d.LastRemoval, "USB Last Removed", model.TimeDisconnected)
+	}
+	return resp, nil
+}
+
+// collectUSBStor walks Enum\USBSTOR\<Disk&Ven_&Prod_&Rev_>\<Serial>.
+func collectUSBStor(usbstor *regparser.CM_KEY_NODE) []*USBDevice {
+	var devices []*USBDevice
+	for _, devClass := range usbstor.Subkeys() {
+		class, vendor, product, rev := parseUSBStorID(devClass.Name())
+		for _, inst := range devClass.Subkeys() {
+			d := &USBDevice{
+				Class:        class,
+				Vendor:       vendor,
+				Product:      product,
+				Revision:     rev,
+				Serial:       inst.Name(),
+				FriendlyName: regString(inst, "FriendlyName"),
+				KeyWritten:   keyLastWrite(inst),
+			}
+			if props := regSubkey(regSubkey(inst, "Properties"), devicePropertyGUID); props != nil {
+				d.FirstInstall = devicePropertyTime(props, "0065")
+				d.LastConnect = devicePropertyTime(props, "0066")
+				d.LastRemoval = devicePropertyTime(props, "0067")
+			}
+			devices = append(devices, d)
+		}
+	}
+	return devices
+}
+
+// devicePropertyTime reads a FILETIME property. Win10 stores it as the default value of the
+// property key, Win7/8 under a 00000000 subkey.
+func devicePropertyTime(props *regparser.CM_KEY_NODE, id string) time.Time {
+	key := regSubkey(props, id)
+	if key == nil {
+		return time.Time{}
+	}
+	for _, k := range []*regparser.CM_KEY_NODE{key, regSubkey(key, "00000000")} {
+		if k == nil {
+			continue
+		}
+		for _, v := range k.Values() {
+			vd := v.ValueData()
+			if vd != nil && len(vd.Data) == 8 {
+				if ts := regFiletime(vd.Data); !ts.IsZero() {
+					return ts
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// parseUSBStorID splits "Disk&Ven_SanDisk&Prod_Cruzer_Blade&Rev_1.00".
+func parseUSBStorID(id string) (class, vendor, product, rev string) {
+	for i, part := range strings.Split(id, "&") {
+		switch {
+		case i == 0:
+			class = part
+		case strings.HasPrefix(strings.ToLower(part), "ven_"):
+			vendor = strings.ReplaceAll(part[4:], "_", " ")
+		case strings.HasPrefix(strings.ToLower(part), "prod_"):
+			product = strings.ReplaceAll(part[5:], "_", " ")
+		case strings.HasPrefix(strings.ToLower(part), "rev_"):
+			rev = part[4:]
+		}
+	}
+	return
+}
+
+// usbSerialRoot strips the "&0" LUN suffix Windows appends to USBSTOR instance names.
+// Serials whose second character is '&' were generated by Windows and are kept as-is.
+func usbSerialRoot(serial string) string {
+	if len(serial) > 1 && serial[1] == '&' {
+		return serial
+	}
+	if idx := strings.LastIndex(serial, "&"); idx > 0 {
+		return serial[:idx]
+	}
+	return serial
+}
+
+// applyUSBEnum fills VID/PID from Enum\USB\VID_xxxx&PID_yyyy\<Serial>.
+func applyUSBEnum(usb *regparser.CM_KEY_NODE, devices []*USBDevice) {
+	if usb == nil {
+		return
+	}
+	bySerial := make(map[string]*USBDevice)
+	for _, d := range devices {
+		bySerial[strings.ToUpper(usbSerialRoot(d.Serial))] = d
+	}
+	for _, vidpid := range usb.Subkeys() {
+		for _, inst := range vidpid.Subkeys() {
+			d, ok := bySerial[strings.ToUpper(inst.Name())]
+			if !ok {
+				continue
+			}
+			for _, part := range strings.Split(vidpid.Name(), "&") {
+				switch {
+				case strings.HasPrefix(strings.ToUpper(part), "VID_"):
+					d.VID = part[4:]
+				case strings.HasPrefix(strings.ToUpper(part), "PID_"):
+					d.PID = part[4:]
+				}
+			}
+		}
+	}
+}
+
+// applyMountedDevices maps \DosDevices\X: and \??\Volume{GUID} entries to devices.
+// USB volumes store a UTF-16 device path containing "#<serial>#".
+func applyMountedDevices(md *regparser.CM_KEY_NODE, devices []*USBDevice) {
+	if md == nil {
+		return
+	}
+	for _, v := range md.Values() {
+		vd := v.ValueData()
+		if vd == nil || len(vd.Data) < 8 {
+			continue
+		}
+		target := strings.ToUpper(cleanupUTF16(vd.Data))
+		if !strings.Contains(target, "USBSTOR") {
+			continue
+		}
+		name := v.ValueName()
+		for _, d := range devices {
+			if !strings.Contains(target, "#"+strings.ToUpper(d.Serial)+"#") {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(name, `\DosDevices\`):
+				d.DriveLetter = strings.TrimPrefix(name, `\DosDevices\`)
+			case strings.HasPrefix(name, `\??\Volume`):
+				d.VolumeGUID = strings.TrimPrefix(name, `\??\Volume`)
+			}
+		}
+	}
+}
+
+// portableDeviceKeys are the SOFTWARE locations holding WPD friendly names (volume labels).
+var portableDeviceKeys = []string{
+	`Microsoft\Windows Portable Devices\Devices`,
+	`Microsoft\Windows NT\CurrentVersion\Portable Devices\Devices`,
+}
+
+// applyPortableDevices sets the volume label from the WPD device entry matching the serial.
+func applyPortableDevices(reg *regparser.Registry, devices []*USBDevice) {
+	for _, path := range portableDeviceKeys {
+		key := reg.OpenKey(path)
+		if key == nil {
+			continue
+		}
+		for _, dev := range key.Subkeys() {
+			name := strings.ToUpper(dev.Name())
+			for _, d := range devices {
+				if strings.Contains(name, "#"+strings.ToUpper(d.Serial)+"#") {
+					if label := regString(dev, "FriendlyName"); label != "" {
+						d.VolumeLabel = label
+					}
+				}
+			}
+		}
+	}
+}
+
+// applyUserMountPoints attributes devices to profiles whose MountPoints2 lists the volume GUID.
+func applyUserMountPoints(usersDir string, devices []*USBDevice) {
+	entries, err := os.ReadDir(usersDir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		hive := findSibling(filepath.Join(usersDir, e.Name()), "NTUSER.DAT")
+		if hive == "" {
+			continue
+		}
+		f, reg, err := openHive(hive)
+		if err != nil {
+			continue
+		}
+		mp := reg.OpenKey(`Software\Microsoft\Windows\CurrentVersion\Explorer\MountPoints2`)
+		if mp != nil {
+			for _, sub := range mp.Subkeys() {
+				for _, d := range devices {
+					if d.VolumeGUID != "" && strings.EqualFold(sub.Name(), d.VolumeGUID) {
+						d.Users = append(d.Users, e.Name())
+					}
+				}
+			}
+		}
+		f.Close()
+	}
+}
+
+func (d *USBDevice) artifact(source string) model.Artifact {
+	meta := map[string]string{
+		"class":         d.Class,
+		"vendor":        d.Vendor,
+		"product":       d.Product,
+		"revision":      d.Revision,
+		"serial":        d.Serial,
+		"friendly_name": d.FriendlyName,
+		"vid":           d.VID,
+		"pid":           d.PID,
+		"drive_letter":  d.DriveLetter,
+		"volume_guid":   d.VolumeGUID,
+		"volume_label":  d.VolumeLabel,
+		"users":         strings.Join(d.Users, ", "),
+	}
+	for k, ts := range map[string]time.Time{"first_connect": d.FirstInstall, "last_connect": d.LastConnect, "last_removal": d.LastRemoval} {
+		if !ts.IsZero() {
+			meta[k] = ts.Format(time.RFC3339)
+		}
+	}
+	a := model.Artifact{
+		ID:       "usb-" + d.Serial,
+		User:     strings.Join(d.Users, ", "),
+		Type:     "usb_device",
+		Source:   "USB",
+		Path:     d.DriveLetter,
+		Metadata: meta,
+		EvidenceRef: model.EvidenceRef{
+			SourcePath: source,
+		},
+	}
+	if !d.FirstInstall.IsZero() {
+		created := d.FirstInstall
+		a.Created = &created
+	}
+	if !d.LastConnect.IsZero() {
+		accessed := d.LastConnect
+		a.Accessed = &accessed
+	}
+	return a
+}
+
+func (d *USBDevice) event(ts time.Time, action, semantics, source string) model.TimelineEvent {
+	subject := d.FriendlyName
+	if subject == "" {
+		subject = strings.TrimSpace(d.Vendor + " " + d.Product)
+	}
+	return model.TimelineEvent{
+		ID:            fmt.Sprintf("usb-%s-%s-%d", strings.ReplaceAll(strings.ToLower(action), " ", "_"), d.Serial, ts.UnixNano()),
+		EventTime:     ts,
+		TimeSemantics: semantics,
+		Source:        "USB",
+		Artifact:      "USBDevice",
+		Action:        action,
+		Subject:       subject,
+		Details: map[string]string{
+			"Vendor":      d.Vendor,
+			"Product":     d.Product,
+			"Serial":      d.Serial,
+			"VID":         d.VID,
+			"PID":         d.PID,
+			"DriveLetter": d.DriveLetter,
+			"VolumeGUID":  d.VolumeGUID,
+			"VolumeLabel": d.VolumeLabel,
+			"Users":       strings.Join(d.Users, ", "),
+		},
+		EvidenceRef: model.EvidenceRef{
+			SourcePath: source,
+		},
+	}
+}
+
+// parseSoftware emits Windows Portable Devices registrations (label + key write time).
+func (p *USBDeviceParser) parseSoftware(in pluginsdk.ParseRequest, reg *regparser.Registry) (*pluginsdk.ParseResponse, error) {
+	resp := &pluginsdk.ParseResponse{}
+	for _, path := range portableDeviceKeys {
+		key := reg.OpenKey(path)
+		if key == nil {
+			continue
+		}
+		for _, dev := range key.Subkeys() {
+			ts := keyLastWrite(dev)
+			if ts.IsZero() {
+				continue
+			}
+			label := regString(dev, "FriendlyName")
+			evt := model.TimelineEvent{
+				ID:            fmt.Sprintf("wpd-%s-%d", dev.Name(), ts.UnixNano()),
+				EventTime:     ts,
+				TimeSemantics: model.TimeKeyWrite,
+				Source:        "USB",
+				Artifact:      "PortableDevice",
+				Action:        "Portable Device Registered",
+				Subject:       label,
+				Details: map[string]string{
+					"DeviceKey":   dev.Name(),
+					"VolumeLabel": label,
+				},
+				EvidenceRef: model.EvidenceRef{
+					SourcePath: in.EvidencePath,
+				},
+			}
+			if in.StreamCallback != nil {
+				in.StreamCallback(evt)
+			} else {
+				resp.Events = append(resp.Events, evt)
+			}
+		}
+	}
+	return resp, nil
+}
+
+// parseMountPoints emits one event per volume the hive owner mounted (MountPoints2 key write).
+func (p *USBDeviceParser) parseMountPoints(in pluginsdk.ParseRequest, reg *regparser.Registry) (*pluginsdk.ParseResponse, error) {
+	mp := reg.OpenKey(`Software\Microsoft\Windows\CurrentVersion\Explorer\MountPoints2`)
+	if mp == nil {
+		return &pluginsdk.ParseResponse{}, nil
+	}
+	owner := hiveOwner(in)
+	resp := &pluginsdk.ParseResponse{}
+	for _, sub := range mp.Subkeys() {
+		name := sub.Name()
+		if !strings.HasPrefix(name, "{") {
+			continue // drive letters and ##server#share entries are not volumes
+		}
+		ts := keyLastWrite(sub)
+		if ts.IsZero() {
+			continue
+		}
+		evt := model.TimelineEvent{
+			ID:            fmt.Sprintf("mp2-%s-%s-%d", owner, name, ts.UnixNano()),
+			EventTime:     ts,
+			TimeSemantics: model.TimeKeyWrite,
+			Source:        "USB",
+			Artifact:      "MountPoints2",
+			Action:        "Volume Mounted by User",
+			Subject:       name,
+			Details: map[string]string{
+				"VolumeGUID": name,
+				"User":       owner,
+			},
+			EvidenceRef: model.EvidenceRef{
+				SourcePath: in.EvidencePath,
+			},
+		}
+		if in.StreamCallback != nil {
+			in.StreamCallback(evt)
+		} else {
+			resp.Events = append(resp.Events, evt)
+		}
+	}
+	return resp, nil
+}
+
+// setupAPIInstall is a device install section from setupapi.dev.log.
+type setupAPIInstall struct {
+	DeviceID string
+	Serial   string
+	Time     time.Time
+}
+
+// readSetupAPIInstalls extracts USB/USBSTOR device install sections:
+//
+//	>>>  [Device Install (Hardware initiated) - USBSTOR\Disk&Ven_X&Prod_Y&Rev_1.0\SERIAL&0]
+//	>>>  Section start 2019/03/08 14:33:47.406
+//
+// Section timestamps are written in the host's local time.
+func readSetupAPIInstalls(path string) ([]setupAPIInstall, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []setupAPIInstall
+	pending := ""
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(BytesToString(scanner.Bytes()))
+		if strings.HasPrefix(line, ">>>  [Device Install") {
+			pending = ""
+			if idx := strings.Index(line, " - "); idx > 0 {
+				id := strings.TrimSuffix(line[idx+3:], "]")
+				upper := strings.ToUpper(id)
+				if strings.HasPrefix(upper, `USBSTOR\`) || strings.HasPrefix(upper, `USB\`) {
+					pending = id
+				}
+			}
+			continue
+		}
+		if pending != "" && strings.HasPrefix(line, ">>>  Section start") {
+			stamp := strings.TrimSpace(strings.TrimPrefix(line, ">>>  Section start"))
+			if ts, err := time.Parse("2006/01/02 15:04:05.000", stamp); err == nil {
+				parts := strings.Split(pending, `\`)
+				out = append(out, setupAPIInstall{
+					DeviceID: pending,
+					Serial:   usbSerialRoot(parts[len(parts)-1]),
+					Time:     ts,
+				})
+			}
+			pending = ""
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, scanner.Err()
+}
+
+// parseSetupAPI emits first-install events for USB devices.
+func (p *USBDeviceParser) parseSetupAPI(in pluginsdk.ParseRequest) (*pluginsdk.ParseResponse, error) {
+	installs, err := readSetupAPIInstalls(in.EvidencePath)
+	if err != nil {
+		return nil, err
+	}
+	tz, hasTZ := siblingTimezone(originalPath(in))
+	resp := &pluginsdk.ParseResponse{}
+	for _, inst := range installs {
+		var vendor, product string
+		if parts := strings.Split(inst.DeviceID, `\`); len(parts) >= 2 {
+			_, vendor, product, _ = parseUSBStorID(parts[1])
+		}
+		subject := strings.TrimSpace(vendor + " " + product)
+		if subject == "" {
+			subject = inst.DeviceID
+		}
+		timeBase := "host-local"
+		if hasTZ {
+			inst.Time, timeBase = tz.ToUTC(inst.Time), "converted-from-host-local"
+		}
+		evt := model.TimelineEvent{
+			ID:            fmt.Sprintf("setupapi-%s-%d", inst.Serial, inst.Time.UnixNano()),
+			EventTime:     inst.Time,
+			TimeSemantics: model.TimeConnected,
+			Source:        "USB",
+			Artifact:      "setupapi.dev.log",
+			Action:        "USB Device First Install",
+			Subject:       subject,
+			Details: map[string]string{
+				"DeviceID": inst.DeviceID,
+				"Serial":   inst.Serial,
+				"Vendor":   vendor,
+				"Product":  product,
+				"TimeBase": timeBase,
+			},
+			EvidenceRef: model.EvidenceRef{
+				SourcePath: in.EvidencePath,
+			},
+		}
+		if in.StreamCallback != nil {
+			in.StreamCallback(evt)
+		} else {
+			resp.Events = append(resp.Events, evt)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadSetupAPIInstalls(t *testing.T) {
+	log := `[Device Install Log]
+>>>  [Device Install (Hardware initiated) - SWD\WPDBUSENUM\_??_USBSTOR#Disk&Ven_SanDisk&Prod_Cruzer&Rev_1.26#4C530012450531101593&0#{53f56307-b6bf-11d0-94f2-00a0c91efb8b}]
+>>>  Section start 2019/03/08 14:33:48.001
+<<<  Section end 2019/03/08 14:33:49.000
+>>>  [Device Install (Hardware initiated) - USBSTOR\Disk&Ven_SanDisk&Prod_Cruzer&Rev_1.26\4C530012450531101593&0]
+>>>  Section start 2019/03/08 14:33:47.406
+<<<  Section end 2019/03/08 14:33:48.000
+`
+	path := filepath.Join(t.TempDir(), "setupapi.dev.log")
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	installs, err := readSetupAPIInstalls(path)
+	if err != nil {
+		t.Fatalf("readSetupAPIInstalls: %v", err)
+	}
+	if len(installs) != 1 {
+		t.Fatalf("expected 1 USB install, got %d", len(installs))
+	}
+	if installs[0].Serial != "4C530012450531101593" {
+		t.Errorf("serial = %q", installs[0].Serial)
+	}
+	if got := installs[0].Time.Format("2006-01-02 15:04:05"); got != "2019-03-08 14:33:47" {
+		t.Errorf("time = %s", got)
+	}
+}
+
+func TestParseUSBStorID(t *testing.T) {
+	class, vendor, product, rev := parseUSBStorID("Disk&Ven_SanDisk&Prod_Cruzer_Blade&Rev_1.00")
+	if class != "Disk" || vendor != "SanDisk" || product != "Cruzer Blade" || rev != "1.00" {
+		t.Errorf("got %q %q %q %q", class, vendor, product, rev)
+	}
+	if got := usbSerialRoot("4C530012450531101593&0"); got != "4C530012450531101593" {
+		t.Errorf("usbSerialRoot = %q", got)
+	}
+	if got := usbSerialRoot("7&2a8b3c4d&0"); got != "7&2a8b3c4d&0" {
+		t.Errorf("generated serial should be kept, got %q", got)
+	}
+}
+
+func TestCollectUSBStorPropertyTimes(t *testing.T) {
+	install := time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC)
+	firstInstall := time.Date(2022, 1, 10, 8, 30, 0, 0, time.UTC)
+	arrival := time.Date(2023, 6, 2, 10, 15, 0, 0, time.UTC)
+	prop := func(id string, ts time.Time) *testKey {
+		return &testKey{name: id, values: []testValue{binValue("", filetimeBytes(ts))}}
+	}
+	root := &testKey{name: "ROOT", subkeys: []*testKey{{
+		name: "USBSTOR",
+		subkeys: []*testKey{{
+			name: "Disk&Ven_SanDisk&Prod_Cruzer_Blade&Rev_1.00",
+			subkeys: []*testKey{{
+				name:   "4C530012450531101593&0",
+				values: []testValue{szValue("FriendlyName", "SanDisk Cruzer Blade USB Device")},
+				subkeys: []*testKey{{
+					name: "Properties",
+					subkeys: []*testKey{{
+						name: devicePropertyGUID,
+						subkeys: []*testKey{
+							prop("0064", install),
+							prop("0065", firstInstall),
+							{name: "0066", subkeys: []*testKey{prop("00000000", arrival)}},
+						},
+					}},
+				}},
+			}},
+		}},
+	}}}
+
+	devices := collectUSBStor(openTestHive(t, root, "USBSTOR"))
+	if len(devices) != 1 {
+		t.Fatalf("expected 1 device, got %d", len(devices))
+	}
+	d := devices[0]
+	if d.FriendlyName != "SanDisk Cruzer Blade USB Device" {
+		t.Errorf("friendly name = %q", d.FriendlyName)
+	}
+	if !d.FirstInstall.Equal(firstInstall) {
+		t.Errorf("first install = %s, want the 0065 FirstInstallDate %s", d.FirstInstall, firstInstall)
+	}
+	if !d.LastConnect.Equal(arrival) {
+		t.Errorf("last connect = %s, want %s", d.LastConnect, arrival)
+	}
+	if !d.LastRemoval.IsZero() {
+		t.Errorf("last removal = %s, want zero", d.LastRemoval)
+	}
+}
@@ -109,12 +109,13 @@ func (p *UserAssistParser) Parse(ctx context.Context, in pluginsdk.ParseRequest)
 
 			if runCount > 0 && !lastExec.IsZero() {
 				events = append(events, model.TimelineEvent{
-					ID:        fmt.Sprintf("ua-%d-%s", lastExec.UnixNano(), path),
-					EventTime: lastExec,
-					Source:    "UserAssist",
-					Artifact:  "UserAssist",
-					Action:    "Execution",
-					Subject:   path,
+					ID:            fmt.Sprintf("ua-%d-%s", lastExec.UnixNano(), path),
+					EventTime:     lastExec,
+					TimeSemantics: model.TimeLastRun,
+					Source:        "UserAssist",
+					Artifact:      "UserAssist",
+					Action:        "Execution",
+					Subject:       path,
 					Details: map[string]string{
 						"run_count": fmt.Sprintf("%d", runCount),
 						"rot13_raw": value.Name(),
@@ -2,7 +2,9 @@ package plugin
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf16"
@@ -64,6 +66,27 @@ func CleanString(s string) string {
 	return strings.TrimSpace(strings.Trim(s, "\x00"))
 }
 
+// parseWMIDate parses a CIM_DATETIME ("20250114120000.000000+060") into UTC. The suffix is
+// the offset from UTC in minutes, not hours.
+func parseWMIDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if len(s) != 25 || (s[21] != '+' && s[21] != '-') {
+		return time.Time{}, fmt.Errorf("invalid WMI date %q", s)
+	}
+	t, err := time.Parse("20060102150405.000000", s[:21])
+	if err != nil {
+		return time.Time{}, err
+	}
+	minutes, err := strconv.Atoi(s[22:])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid WMI date offset %q", s)
+	}
+	if s[21] == '-' {
+		minutes = -minutes
+	}
+	return t.Add(-time.Duration(minutes) * time.Minute), nil
+}
+
 // windowsFiletimeToGo converts a Windows FILETIME (uint64) to a Go time.Time.
 func windowsFiletimeToGo(ft uint64) time.Time {
 	// 100-nanosecond intervals since January 1, 1601 (UTC)
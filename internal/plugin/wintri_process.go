@@ -3,10 +3,10 @@ package plugin
 import (
 	"context"
 	"encoding/csv"
+	"fmt"
 	"io"
 	"os"
 	"strings"
-	"time"
 
 	"gtrace/pkg/model"
 	"gtrace/pkg/pluginsdk"
@@ -57,10 +57,7 @@ func (p *WintriProcessParser) Parse(ctx context.Context, in pluginsdk.ParseReque
 
 	var events []model.TimelineEvent
 
-	// CreationDate format example: 20250114000305.123456+000
-	const timeLayout = "20060102150405.000000-070"
-
-	for {
+	for row := 1; ; row++ {
 		record, err := reader.Read()
 		if err == io.EOF {
 			break
@@ -74,28 +71,17 @@ func (p *WintriProcessParser) Parse(ctx context.Context, in pluginsdk.ParseReque
 		cmdline := getCol(record, colMap, "commandline")
 		dateStr := getCol(record, colMap, "creationdate")
 
-		var ts time.Time
-		// Try parsing time
-		// WMI often returns dates like 20250114120000.000000+000
-		if len(dateStr) > 21 {
-			// Simplistic trim just to parse main part if +000 format varies
-			// But let's try standard WMI layout first
-			t, err := time.Parse("20060102150405.000000-070", dateStr)
-			if err == nil {
-				ts = t
-			} else {
-				// Fallback or ignore
-				ts = time.Now()
-			}
-		}
+		// An unparsable date leaves the time zero rather than inventing one.
+		ts, _ := parseWMIDate(dateStr)
 
 		evt := model.TimelineEvent{
-			ID:        "proc-" + pid + "-" + dateStr, // not unique, but MVP ok
-			EventTime: ts,
-			Source:    "wintri-process",
-			Artifact:  "process",
-			Action:    "EXECUTION",
-			Subject:   name,
+			ID:            fmt.Sprintf("proc-%d-%s", row, pid), // the row keeps PIDs without a creation date apart
+			EventTime:     ts,
+			TimeSemantics: model.TimeCreated,
+			Source:        "wintri-process",
+			Artifact:      "process",
+			Action:        "EXECUTION",
+			Subject:       name,
 			Details: map[string]string{
 				"pid":     pid,
 				"cmdline": cmdline,
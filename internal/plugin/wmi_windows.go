@@ -88,7 +88,7 @@ func queryWMI(service *ole.IDispatch, query, artifactName string, callback func(
 	qenum := (*ole.IEnumVARIANT)(enum)
 	defer qenum.Release()
 
-	now := time.Now()
+	now := time.Now().UTC()
 
 	for {
 		variant, fetched, err := qenum.Next(1)
@@ -140,12 +140,13 @@ func processItem(item *ole.IDispatch, artifactName string, callback func(model.T
 	}
 
 	callback(model.TimelineEvent{
-		EventTime: now,
-		Source:    "WMI",
-		Artifact:  artifactName,
-		Action:    "Persistence",
-		Subject:   subject,
-		Details:   details,
+		EventTime:     now,
+		TimeSemantics: model.TimeCollected,
+		Source:        "WMI",
+		Artifact:      artifactName,
+		Action:        "Persistence",
+		Subject:       subject,
+		Details:       details,
 		EvidenceRef: model.EvidenceRef{
 			SourcePath: "WMI Namespace: root\\subscription",
 		},
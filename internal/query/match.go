@@ -0,0 +1,441 @@
+package query
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+// Match reports whether ev satisfies the query. Annotation fields (tag, note, verdict, is)
+// read ev.Annotations, so attach them before matching.
+func (q *Query) Match(ev *model.TimelineEvent) bool {
+	return q.root == nil || q.root.match(ev)
+}
+
+// Literals returns lower-case strings that occur in the JSON encoding of every matching event.
+// Callers scanning stored JSON can skip lines missing any of them before decoding.
+func (q *Query) Literals() []string {
+	if q.root == nil {
+		return nil
+	}
+	return literals(q.root)
+}
+
+func literals(n node) []string {
+	switch n := n.(type) {
+	case andNode:
+		return append(literals(n.a), literals(n.b)...)
+	case textNode:
+		// Free text also matches annotation values, which are not in the stored event.
+		return nil
+	case fieldNode:
+		if !n.f.stored {
+			return nil
+		}
+		switch m := n.m.(type) {
+		case equalMatcher:
+			if jsonSafe(string(m)) {
+				return []string{strings.ToLower(string(m))}
+			}
+		case containsMatcher:
+			if jsonSafe(string(m)) {
+				return []string{string(m)}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonSafe reports whether s is encoded verbatim by encoding/json (which escapes quotes,
+// backslashes, control characters and <, >, &) and lower-cases the same way as bytes.ToLower.
+func jsonSafe(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= 0x7f || strings.IndexByte(`"\<>&`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type node interface {
+	match(ev *model.TimelineEvent) bool
+	String() string
+}
+
+type andNode struct{ a, b node }
+
+func (n andNode) match(ev *model.TimelineEvent) bool { return n.a.match(ev) && n.b.match(ev) }
+func (n andNode) String() string                     { return "(" + n.a.String() + " AND " + n.b.String() + ")" }
+
+type orNode struct{ a, b node }
+
+func (n orNode) match(ev *model.TimelineEvent) bool { return n.a.match(ev) || n.b.match(ev) }
+func (n orNode) String() string                     { return "(" + n.a.String() + " OR " + n.b.String() + ")" }
+
+type notNode struct{ n node }
+
+func (n notNode) match(ev *model.TimelineEvent) bool { return !n.n.match(ev) }
+func (n notNode) String() string                     { return "NOT " + n.n.String() }
+
+// textNode is a bare word, phrase or regex searched across the event's text.
+type textNode struct {
+	m   matcher
+	raw string
+}
+
+func (n textNode) match(ev *model.TimelineEvent) bool {
+	for _, v := range []string{ev.ID, ev.Source, ev.Artifact, ev.Action, ev.Subject, ev.EvidenceRef.SourcePath} {
+		if n.m.match(v) {
+			return true
+		}
+	}
+	for _, v := range ev.Details {
+		if n.m.match(v) {
+			return true
+		}
+	}
+	for _, a := range ev.Annotations {
+		if n.m.match(a.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n textNode) String() string { return n.raw }
+
+// fieldNode matches when any value of a field satisfies m.
+type fieldNode struct {
+	f   field
+	m   matcher
+	raw string
+}
+
+func (n fieldNode) match(ev *model.TimelineEvent) bool {
+	for _, v := range n.f.get(ev) {
+		if n.m.match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n fieldNode) String() string {
+	if c, ok := n.m.(compareMatcher); ok {
+		return n.f.name + c.op + c.v
+	}
+	return n.f.name + ":" + n.raw
+}
+
+// timeNode compares the event time. Events without a time never match a comparison.
+type timeNode struct {
+	f  field
+	op string
+	t  time.Time
+}
+
+func (n timeNode) match(ev *model.TimelineEvent) bool {
+	if ev.EventTime.IsZero() {
+		return false
+	}
+	return compareOrdered(ev.EventTime.Compare(n.t), n.op)
+}
+
+func (n timeNode) String() string { return n.f.name + n.op + n.t.Format(time.RFC3339) }
+
+func compareOrdered(c int, op string) bool {
+	switch op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return c == 0
+}
+
+type matcher interface {
+	match(v string) bool
+}
+
+// containsMatcher holds a lower-cased needle.
+type containsMatcher string
+
+func (m containsMatcher) match(v string) bool {
+	return m != "" && strings.Contains(strings.ToLower(v), string(m))
+}
+
+func newContains(s string) containsMatcher { return containsMatcher(strings.ToLower(s)) }
+
+type equalMatcher string
+
+func (m equalMatcher) match(v string) bool { return strings.EqualFold(v, string(m)) }
+
+type existsMatcher struct{}
+
+func (existsMatcher) match(v string) bool { return v != "" }
+
+type regexMatcher struct{ re *regexp.Regexp }
+
+func (m regexMatcher) match(v string) bool { return m.re.MatchString(v) }
+
+// globMatcher compiles * and ? wildcards. Anchored patterns must cover the whole value.
+func globMatcher(pattern string, anchored bool) matcher {
+	var b strings.Builder
+	b.WriteString("(?is)")
+	if anchored {
+		b.WriteString("^")
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern) && isEscapable(pattern[i+1]):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if anchored {
+		b.WriteString("$")
+	}
+	return regexMatcher{regexp.MustCompile(b.String())}
+}
+
+// compareMatcher orders numerically when both sides are numbers, otherwise as text.
+type compareMatcher struct{ op, v string }
+
+func (m compareMatcher) match(v string) bool {
+	if v == "" {
+		return false
+	}
+	if a, err := strconv.ParseFloat(v, 64); err == nil {
+		if b, err := strconv.ParseFloat(m.v, 64); err == nil {
+			switch {
+			case a < b:
+				return compareOrdered(-1, m.op)
+			case a > b:
+				return compareOrdered(1, m.op)
+			}
+			return compareOrdered(0, m.op)
+		}
+	}
+	return compareOrdered(strings.Compare(strings.ToLower(v), strings.ToLower(m.v)), m.op)
+}
+
+const (
+	kindText = iota
+	kindTime
+)
+
+// field is a resolved field name. stored is false for values that are not in the stored
+// event JSON as written (annotations, derived flags).
+type field struct {
+	name   string
+	kind   int
+	stored bool
+	detail bool // a Details key rather than a reserved name
+	get    func(ev *model.TimelineEvent) []string
+}
+
+func (f field) equal(v string) matcher { return equalMatcher(v) }
+
+func one(s string) []string { return []string{s} }
+
+// detailAny returns the present values of several Details keys that name the same thing,
+// in key order. "-" is how Windows logs an empty value and is skipped.
+func detailAny(keys ...string) func(ev *model.TimelineEvent) []string {
+	return func(ev *model.TimelineEvent) []string {
+		var out []string
+		for _, k := range keys {
+			if v := ev.Details[k]; v != "" && v != "-" {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+}
+
+// FieldValues returns the accessor for a field as queries resolve it: a reserved name such as
+// host, user or process, or a Details key. Multi-valued fields list the primary value first.
+func FieldValues(name string) func(ev *model.TimelineEvent) []string {
+	return resolveField(name).get
+}
+
+func detail(keys ...string) func(ev *model.TimelineEvent) []string {
+	return func(ev *model.TimelineEvent) []string {
+		for _, k := range keys {
+			if v, ok := ev.Details[k]; ok {
+				return one(v)
+			}
+		}
+		return nil
+	}
+}
+
+// Fields lists the reserved field names; anything else addresses a Details key.
+var Fields = map[string]string{
+	"id":        "event ID",
+	"time":      "event time (UTC); also event_time, @timestamp",
+	"source":    "event source (EventLog, Registry, ...)",
+	"artifact":  "artifact name",
+	"action":    "action / description",
+	"subject":   "subject",
+	"host":      "recording host (Computer or Hostname detail)",
+	"user":      "account (TargetUserName, User, Username, SubjectUserName)",
+	"process":   "process image (Image, NewProcessName, ProcessName, ExecutablePath)",
+	"remote_ip": "remote address (IpAddress, RemoteIP, SourceIp, DestinationIp)",
+	"service":   "service name",
+	"path":      "evidence path",
+	"semantics": "what the event time measures (created, last_run, ...)",
+	"level":     "Sigma alert level",
+	"alert":     "Sigma alert title",
+	"rule_id":   "Sigma rule ID",
+	"eid":       "Windows event ID",
+	"technique": "ATT&CK technique ID or name",
+	"tactic":    "ATT&CK tactic ID or name",
+	"ioc":       "IOC hit",
+	"tag":       "annotation tag",
+	"note":      "annotation note text (substring)",
+	"verdict":   "latest annotation verdict",
+	"is":        "bookmarked, alert, annotated, allowlisted, suppressed, downgraded",
+}
+
+func resolveField(name string) field {
+	lower := strings.ToLower(name)
+	stored := func(get func(ev *model.TimelineEvent) []string) field {
+		return field{name: lower, stored: true, get: get}
+	}
+	switch lower {
+	case "id":
+		return stored(func(ev *model.TimelineEvent) []string { return one(ev.ID) })
+	case "time", "event_time", "@timestamp", "timestamp":
+		return field{name: "time", kind: kindTime, get: func(ev *model.TimelineEvent) []string {
+			return one(ev.EventTime.Format(time.RFC3339Nano))
+		}}
+	case "source":
+		return stored(func(ev *model.TimelineEvent) []string { return one(ev.Source) })
+	case "artifact":
+		return stored(func(ev *model.TimelineEvent) []string { return one(ev.Artifact) })
+	case "action":
+		return stored(func(ev *model.TimelineEvent) []string { return one(ev.Action) })
+	case "subject":
+		return stored(func(ev *model.TimelineEvent) []string { return one(ev.Subject) })
+	case "confidence":
+		return stored(func(ev *model.TimelineEvent) []string { return one(ev.Confidence) })
+	case "semantics", "time_semantics":
+		return stored(func(ev *model.TimelineEvent) []string { return one(ev.TimeSemantics) })
+	case "host", "computer", "hostname":
+		f := stored(func(ev *model.TimelineEvent) []string {
+			h := ev.Host()
+			if i := strings.IndexByte(h, '.'); i > 0 {
+				return []string{h, h[:i]}
+			}
+			return one(h)
+		})
+		f.name = "host"
+		return f
+	case "user":
+		return stored(detailAny("TargetUserName", "User", "Username", "UserName", "SubjectUserName"))
+	case "process", "image":
+		f := stored(detailAny("Image", "NewProcessName", "ProcessName", "ExecutablePath"))
+		f.name = "process"
+		return f
+	case "remote_ip":
+		return stored(detailAny("IpAddress", "RemoteIP", "SourceIp", "DestinationIp"))
+	case "service":
+		return stored(detailAny("ServiceName"))
+	case "path", "source_path":
+		return stored(func(ev *model.TimelineEvent) []string { return one(ev.EvidenceRef.SourcePath) })
+	case "level", "severity":
+		return stored(detail("_AlertLevel"))
+	case "alert":
+		return stored(detail("_Alert"))
+	case "rule_id":
+		return stored(detail("_AlertRuleID"))
+	case "eid", "event_id", "eventid":
+		f := stored(detail("EventID"))
+		f.name = "eid"
+		return f
+	case "technique":
+		return stored(func(ev *model.TimelineEvent) []string {
+			var out []string
+			for _, r := range ev.Attack {
+				out = append(out, r.TechniqueID, r.Technique, r.ParentID)
+			}
+			return out
+		})
+	case "tactic":
+		return stored(func(ev *model.TimelineEvent) []string {
+			var out []string
+			for _, r := range ev.Attack {
+				out = append(out, r.TacticIDs...)
+				out = append(out, r.Tactics...)
+			}
+			return out
+		})
+	case "ioc":
+		return stored(func(ev *model.TimelineEvent) []string { return ev.IOCHits })
+	case "tag":
+		return field{name: lower, get: func(ev *model.TimelineEvent) []string {
+			return model.AnnotationValues(ev.Annotations, model.AnnotationTag)
+		}}
+	case "note":
+		return field{name: lower, get: func(ev *model.TimelineEvent) []string {
+			return model.AnnotationValues(ev.Annotations, model.AnnotationNote)
+		}}
+	case "verdict":
+		return field{name: lower, get: func(ev *model.TimelineEvent) []string {
+			return one(model.Verdict(ev.Annotations))
+		}}
+	case "is":
+		return field{name: lower, get: func(ev *model.TimelineEvent) []string {
+			var out []string
+			if model.Bookmarked(ev.Annotations) {
+				out = append(out, "bookmarked", "starred")
+			}
+			if ev.Details["_Alert"] != "" {
+				out = append(out, "alert")
+			}
+			if len(ev.Annotations) > 0 {
+				out = append(out, "annotated")
+			}
+			switch ev.Details["_AllowlistAction"] {
+			case "suppress":
+				out = append(out, "allowlisted", "suppressed")
+			case "downgrade":
+				out = append(out, "allowlisted", "downgraded")
+			}
+			return out
+		}}
+	}
+	key := name
+	if len(lower) > len("details.") && strings.HasPrefix(lower, "details.") {
+		key = name[len("details."):]
+	}
+	return field{name: key, stored: true, detail: true, get: func(ev *model.TimelineEvent) []string {
+		if v, ok := ev.Details[key]; ok {
+			return one(v)
+		}
+		for k, v := range ev.Details {
+			if strings.EqualFold(k, key) {
+				return one(v)
+			}
+		}
+		return nil
+	}}
+}
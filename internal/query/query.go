@@ -0,0 +1,550 @@
+// Package query implements the timeline search language shared by the GUI, the CLI and
+// saved searches. It is a Lucene/KQL-like syntax:
+//
+//	CommandLine:*-enc* AND host:WS01 AND time>2026-01-01
+//	(eid:4624 OR eid:4625) NOT user:"ANONYMOUS LOGON"
+//	Image:/\\(psexec|paexec)\w*\.exe$/ time:[2026-01-01 TO 2026-01-31]
+//	mimikatz
+//
+// A bare word matches anywhere in the event (case-insensitive substring); "quoted phrases"
+// may contain spaces. field:value compares the whole field case-insensitively, with * and ?
+// wildcards; field:/re/ searches with a case-insensitive regular expression; field:* tests
+// that a field is present. >, >=, < and <= and [a TO b] / {a TO b} ranges compare times,
+// numbers, or text. Terms are ANDed unless joined by OR; NOT and a leading - negate.
+//
+// Any Details key is a field (case-insensitive, or spelled details.Key). Event attributes
+// and annotations have reserved names; see Fields.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a parsed search expression.
+type Query struct {
+	src  string
+	root node
+}
+
+// Parse parses a search expression. An empty expression matches every event.
+func Parse(s string) (*Query, error) {
+	p := &parser{src: s}
+	q := &Query{src: strings.TrimSpace(s)}
+	p.skipSpace()
+	if p.eof() {
+		return q, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	q.root = root
+	return q, nil
+}
+
+// MustParse is Parse for expressions known to be valid, such as built-in hunts.
+func MustParse(s string) *Query {
+	q, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// String returns the expression as written.
+func (q *Query) String() string { return q.src }
+
+// Empty reports whether q matches everything.
+func (q *Query) Empty() bool { return q.root == nil }
+
+// Normalized renders the parsed expression with explicit operators and grouping, which is
+// useful to show how a query was understood.
+func (q *Query) Normalized() string {
+	if q.root == nil {
+		return ""
+	}
+	return q.root.String()
+}
+
+// SyntaxError reports where a query failed to parse.
+type SyntaxError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query syntax error at %d: %s", e.Pos+1, e.Msg)
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &SyntaxError{Query: p.src, Pos: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// keyword consumes an operator word (AND, OR, NOT in any case) or its symbol form.
+func (p *parser) keyword(word, symbol string) bool {
+	p.skipSpace()
+	rest := p.src[p.pos:]
+	if symbol != "" && strings.HasPrefix(rest, symbol) {
+		p.pos += len(symbol)
+		return true
+	}
+	if len(rest) >= len(word) && strings.EqualFold(rest[:len(word)], word) {
+		if len(rest) == len(word) || isBoundary(rest[len(word)]) {
+			p.pos += len(word)
+			return true
+		}
+	}
+	return false
+}
+
+func isBoundary(c byte) bool {
+	return c == '(' || c == ')' || c == '"' || unicode.IsSpace(rune(c))
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR", "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.eof() || p.peek() == ')' {
+			return left, nil
+		}
+		save := p.pos
+		if p.keyword("OR", "||") {
+			p.pos = save
+			return left, nil
+		}
+		p.keyword("AND", "&&") // optional: adjacent terms are ANDed
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	p.skipSpace()
+	if p.keyword("NOT", "!") {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	if p.peek() == '-' && p.pos+1 < len(p.src) && !unicode.IsSpace(rune(p.src[p.pos+1])) {
+		p.pos++
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	if p.peek() == '(' {
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return n, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *parser) parseTerm() (node, error) {
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("expected a search term")
+	}
+	switch p.peek() {
+	case ')':
+		return nil, p.errorf("unexpected )")
+	case '"':
+		s, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return textNode{m: newContains(s), raw: quote(s)}, nil
+	case '/':
+		re, raw, err := p.regex()
+		if err != nil {
+			return nil, err
+		}
+		return textNode{m: regexMatcher{re}, raw: raw}, nil
+	}
+
+	start := p.pos
+	for !p.eof() && !isBoundary(p.peek()) && !strings.ContainsRune(":<>=", rune(p.peek())) {
+		p.pos++
+	}
+	field := p.src[start:p.pos]
+	op := p.operator()
+	if op == "" || field == "" || (op == ":" && p.textColon(field)) {
+		p.pos = start
+		word := p.word()
+		if word == "" {
+			return nil, p.errorf("expected a search term")
+		}
+		if hasWildcard(word) {
+			return textNode{m: globMatcher(word, false), raw: word}, nil
+		}
+		return textNode{m: newContains(unescape(word)), raw: word}, nil
+	}
+	return p.parseFieldValue(field, op)
+}
+
+// textColon reports whether the colon just read is part of a search word rather than the
+// start of a field value: a drive letter ("C:\Windows"), a URL ("http://host", not field http
+// with an empty regex), or a word ending in a colon that names no reserved field.
+func (p *parser) textColon(field string) bool {
+	rest := p.src[p.pos:]
+	switch {
+	case len(field) == 1 && strings.HasPrefix(rest, `\`):
+		return true
+	case strings.HasPrefix(rest, "//"):
+		return true
+	case rest == "" || rest[0] != '"' && isBoundary(rest[0]):
+		return resolveField(field).detail
+	}
+	return false
+}
+
+// operator consumes a field operator, returning "" (and consuming nothing) if none follows.
+func (p *parser) operator() string {
+	for _, op := range []string{">=", "<=", ":", "=", ">", "<"} {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			p.pos += len(op)
+			if op == "=" {
+				return ":"
+			}
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *parser) parseFieldValue(field, op string) (node, error) {
+	f := resolveField(field)
+	if op != ":" {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return p.compareNode(f, op, v)
+	}
+	switch p.peek() {
+	case '[', '{':
+		return p.rangeTerm(f)
+	case '"':
+		s, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return fieldNode{f: f, m: f.equal(s), raw: quote(s)}, nil
+	case '/':
+		re, raw, err := p.regex()
+		if err != nil {
+			return nil, err
+		}
+		return fieldNode{f: f, m: regexMatcher{re}, raw: raw}, nil
+	}
+	v := p.word()
+	switch {
+	case v == "":
+		return nil, p.errorf("missing value for %s", field)
+	case v == "*":
+		return fieldNode{f: f, m: existsMatcher{}, raw: v}, nil
+	case hasWildcard(v):
+		return fieldNode{f: f, m: globMatcher(v, true), raw: v}, nil
+	}
+	if f.kind == kindTime {
+		return p.timeEqual(f, unescape(v))
+	}
+	if f.name == "note" {
+		return fieldNode{f: f, m: newContains(unescape(v)), raw: v}, nil
+	}
+	return fieldNode{f: f, m: f.equal(unescape(v)), raw: v}, nil
+}
+
+func (p *parser) rangeTerm(f field) (node, error) {
+	open := p.src[p.pos]
+	p.pos++
+	lo, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if !p.keyword("TO", "") {
+		return nil, p.errorf("expected TO in range")
+	}
+	hi, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	closeCh := p.peek()
+	if closeCh != ']' && closeCh != '}' {
+		return nil, p.errorf("missing ] or } closing the range")
+	}
+	p.pos++
+	loOp, hiOp := ">=", "<="
+	if open == '{' {
+		loOp = ">"
+	}
+	if closeCh == '}' {
+		hiOp = "<"
+	}
+	var parts []node
+	if lo != "*" {
+		n, err := p.compareNode(f, loOp, lo)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, n)
+	}
+	if hi != "*" {
+		n, err := p.compareNode(f, hiOp, hi)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, n)
+	}
+	switch len(parts) {
+	case 0:
+		return fieldNode{f: f, m: existsMatcher{}, raw: "*"}, nil
+	case 1:
+		return parts[0], nil
+	}
+	return andNode{parts[0], parts[1]}, nil
+}
+
+// value reads a comparison or range operand: a quoted string or a bare word.
+func (p *parser) value() (string, error) {
+	p.skipSpace()
+	if p.peek() == '"' {
+		return p.quoted()
+	}
+	start := p.pos
+	for !p.eof() && !isBoundary(p.peek()) && p.peek() != ']' && p.peek() != '}' {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("missing value")
+	}
+	return unescape(p.src[start:p.pos]), nil
+}
+
+// word reads an unquoted value up to whitespace or a parenthesis. A backslash escapes a
+// following space, parenthesis, quote, wildcard or backslash; any other backslash is
+// literal, so Windows paths can be typed as they are (C:\Windows\\* for a trailing wildcard).
+func (p *parser) word() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c == '\\' && p.pos+1 < len(p.src) && isEscapable(p.src[p.pos+1]) {
+			p.pos += 2
+			continue
+		}
+		if isBoundary(c) {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func isEscapable(c byte) bool {
+	return c == '\\' || c == ' ' || c == '(' || c == ')' || c == '"' || c == '*' || c == '?'
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isEscapable(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// hasWildcard reports an unescaped * or ?.
+func hasWildcard(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && isEscapable(s[i+1]) {
+				i++
+			}
+		case '*', '?':
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) quoted() (string, error) {
+	p.pos++ // opening quote
+	var b strings.Builder
+	for !p.eof() {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src) && (p.src[p.pos+1] == '"' || p.src[p.pos+1] == '\\'):
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated quote")
+}
+
+func (p *parser) regex() (*regexp.Regexp, string, error) {
+	start := p.pos
+	p.pos++ // opening slash
+	var b strings.Builder
+	for !p.eof() {
+		c := p.src[p.pos]
+		if c == '\\' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '/' {
+			b.WriteByte('/')
+			p.pos += 2
+			continue
+		}
+		if c == '/' {
+			p.pos++
+			if b.Len() == 0 {
+				p.pos = start
+				return nil, "", p.errorf("empty regular expression")
+			}
+			re, err := regexp.Compile("(?i)" + b.String())
+			if err != nil {
+				p.pos = start
+				return nil, "", p.errorf("bad regular expression: %v", err)
+			}
+			return re, p.src[start:p.pos], nil
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	p.pos = start
+	return nil, "", p.errorf("unterminated regular expression")
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// compareNode builds field op value for >, >=, < and <=.
+func (p *parser) compareNode(f field, op, v string) (node, error) {
+	if f.kind == kindTime {
+		t, _, err := parseTime(v, time.Now())
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		return timeNode{f: f, op: op, t: t}, nil
+	}
+	return fieldNode{f: f, m: compareMatcher{op: op, v: v}, raw: op + v}, nil
+}
+
+// timeEqual matches a time field against a day ("time:2026-01-01") or an instant to the second.
+func (p *parser) timeEqual(f field, v string) (node, error) {
+	t, dateOnly, err := parseTime(v, time.Now())
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	span := time.Second
+	if dateOnly {
+		span = 24 * time.Hour
+	}
+	return andNode{timeNode{f: f, op: ">=", t: t}, timeNode{f: f, op: "<", t: t.Add(span)}}, nil
+}
+
+var relativeTime = regexp.MustCompile(`^now(?:([+-])(\d+)([smhdw]))?$`)
+
+// parseTime reads an absolute time (RFC 3339, or a date with optional time, taken as UTC) or
+// a time relative to now ("now-24h", "now-7d").
+func parseTime(s string, now time.Time) (t time.Time, dateOnly bool, err error) {
+	s = strings.TrimSpace(s)
+	if m := relativeTime.FindStringSubmatch(strings.ToLower(s)); m != nil {
+		if m[1] == "" {
+			return now.UTC(), false, nil
+		}
+		var n int
+		fmt.Sscan(m[2], &n)
+		unit := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[m[3]]
+		d := time.Duration(n) * unit
+		if m[1] == "-" {
+			d = -d
+		}
+		return now.Add(d).UTC(), false, nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UTC(), false, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, false, nil
+		}
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid time %q", s)
+}
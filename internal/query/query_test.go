@@ -0,0 +1,99 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+func TestMatch(t *testing.T) {
+	ev := &model.TimelineEvent{
+		ID:        "ev-1",
+		EventTime: time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC),
+		Source:    "EventLog",
+		Action:    "Process Created",
+		Details: map[string]string{
+			"EventID":     "4688",
+			"Computer":    "WS01.corp.local",
+			"NewProcess":  `C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`,
+			"CommandLine": "powershell.exe -nop -enc SQBFAFgA",
+			"User":        "ANONYMOUS LOGON",
+			"Url":         "http://update.example.net/a.ps1",
+			"_AlertLevel": "high",
+		},
+		Attack:      []model.AttackRef{{TechniqueID: "T1059.001", ParentID: "T1059", Tactics: []string{"Execution"}}},
+		Annotations: []model.Annotation{{Kind: model.AnnotationTag, Value: "lateral"}, {Kind: model.AnnotationBookmark}},
+	}
+
+	for _, tc := range []struct {
+		q    string
+		want bool
+	}{
+		{"", true},
+		{"powershell", true},
+		{"mimikatz", false},
+		{"CommandLine:*-enc*", true},
+		{"commandline:*-enc*", true},
+		{"CommandLine:-enc", false}, // equality, not substring
+		{"CommandLine:*-enc* AND host:WS01 AND time>2026-01-01", true},
+		{"CommandLine:*-enc* host:WS02", false},
+		{"eid:4624 OR eid:4688", true},
+		{"(eid:4624 OR eid:4625) NOT user:\"ANONYMOUS LOGON\"", false},
+		{"eid:4688 -user:\"anonymous logon\"", false},
+		{"eid:4688 !level:low", true},
+		{`NewProcess:/\\powershell\.exe$/`, true},
+		{`NewProcess:C:\\Windows\\*`, true},
+		{"time:[2026-01-01 TO 2026-01-31]", true},
+		{"time:{2026-01-15T10:30:00Z TO 2026-02-01}", false},
+		{"time:2026-01-15", true},
+		{"time<now-1d", true},
+		{"eid>=4600 eid<4700", true},
+		{"eid>10000", false},
+		{"ParentImage:*", false},
+		{"details.User:*", true},
+		{"technique:T1059", true},
+		{"tactic:execution", true},
+		{"tag:lateral is:bookmarked", true},
+		{"verdict:malicious", false},
+		{"\"-nop -enc\"", true},
+		{"power*enc", true},
+		{`CommandLine:"powershell.exe -nop -enc SQBFAFgA"`, true},
+		// A URL is a search word, not field http with an empty regex.
+		{"http://update.example.net", true},
+		{"http://other.example.net", false},
+		{"Url:http://update.example.net/a.ps1", true},
+		{"http:", true},
+	} {
+		q, err := Parse(tc.q)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.q, err)
+			continue
+		}
+		if got := q.Match(ev); got != tc.want {
+			t.Errorf("%q (%s) = %v, want %v", tc.q, q.Normalized(), got, tc.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{"(eid:4688", "eid:", "CommandLine:/[/", "time>yesterday", "a OR", "time:[2026 TO", "//"} {
+		_, err := Parse(s)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("Parse(%q) error = %v, want SyntaxError", s, err)
+		}
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	q := MustParse(`eid:4688 AND CommandLine:*enc* AND Mimikatz AND tag:lateral AND "a<b"`)
+	if got := strings.Join(q.Literals(), ","); got != "4688" {
+		t.Errorf("Literals = %s", got)
+	}
+	if got := MustParse("eid:4624 OR eid:4625").Literals(); len(got) != 0 {
+		t.Errorf("OR literals = %v", got)
+	}
+}
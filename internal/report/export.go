@@ -0,0 +1,478 @@
+package report
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gtrace/internal/normalize"
+	"gtrace/pkg/model"
+)
+
+// Export formats understood by ExportTimeline.
+const (
+	FormatBodyfile   = "bodyfile"
+	FormatL2TCSV     = "l2t_csv"
+	FormatTimesketch = "timesketch"
+	FormatECS        = "ecs"
+	FormatOCSF       = "ocsf"
+)
+
+// ExportFormats lists the supported formats with the file extension each one writes.
+var ExportFormats = map[string]string{
+	FormatBodyfile:   ".bodyfile",
+	FormatL2TCSV:     ".l2t.csv",
+	FormatTimesketch: ".timesketch.jsonl",
+	FormatECS:        ".ecs.ndjson",
+	FormatOCSF:       ".ocsf.ndjson",
+}
+
+// TimelineScanner streams stored events; storage.FileStorage implements it.
+type TimelineScanner interface {
+	ScanTimeline(ctx context.Context, fn func(ev model.TimelineEvent) error) error
+}
+
+// ExportFilter narrows an export. Empty fields match everything; Sources and Hosts match
+// case-insensitively against the event source and recording host.
+type ExportFilter struct {
+	TimeStart *time.Time
+	TimeEnd   *time.Time
+	Sources   []string
+	Hosts     []string
+}
+
+// Match reports whether ev passes the filter.
+func (f ExportFilter) Match(ev model.TimelineEvent) bool {
+	if f.TimeStart != nil && ev.EventTime.Before(*f.TimeStart) {
+		return false
+	}
+	if f.TimeEnd != nil && ev.EventTime.After(*f.TimeEnd) {
+		return false
+	}
+	if len(f.Sources) > 0 && !containsFold(f.Sources, ev.Source) {
+		return false
+	}
+	if len(f.Hosts) > 0 && !matchHost(f.Hosts, ev.Host()) {
+		return false
+	}
+	return true
+}
+
+// matchHost compares host names case-insensitively. A short name ("WS01") matches the
+// FQDN of the same host ("ws01.corp.local") either way round; two FQDNs must be equal.
+func matchHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+		if !strings.Contains(h, ".") || !strings.Contains(host, ".") {
+			if net.ParseIP(h) == nil && net.ParseIP(host) == nil && strings.EqualFold(shortHostName(h), shortHostName(host)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func shortHostName(host string) string {
+	name, _, _ := strings.Cut(host, ".")
+	return name
+}
+
+func containsFold(list []string, v string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventWriter writes one export format a record at a time.
+type eventWriter interface {
+	Write(ev model.TimelineEvent) error
+	Flush() error
+}
+
+// ExportTimeline streams events from src to w in the given format and returns how many
+// were written. Events are written in storage order; the consuming tools (mactime, psort,
+// Timesketch, the SIEM) sort them.
+//
+// The ECS and OCSF formats use the built-in mapping table; use ExportNormalized to include
+// plugin mappings.
+func ExportTimeline(ctx context.Context, src TimelineScanner, format string, w io.Writer, filter ExportFilter) (int, error) {
+	if format == FormatECS || format == FormatOCSF {
+		table, err := normalize.NewTable(nil)
+		if err != nil {
+			return 0, err
+		}
+		return ExportNormalized(ctx, src, table, format, w, filter)
+	}
+
+	bw := bufio.NewWriter(w)
+	var out eventWriter
+	switch format {
+	case FormatBodyfile:
+		out = &bodyfileWriter{w: bw}
+	case FormatL2TCSV:
+		cw := csv.NewWriter(bw)
+		if err := cw.Write(l2tHeader); err != nil {
+			return 0, err
+		}
+		out = &l2tWriter{w: cw}
+	case FormatTimesketch:
+		out = &timesketchWriter{enc: json.NewEncoder(bw)}
+	default:
+		return 0, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	return writeEvents(ctx, src, out, bw, filter)
+}
+
+// ExportNormalized streams events as NDJSON in the "ecs" or "ocsf" schema, one document per
+// line, ready for Elasticsearch bulk or OCSF lake ingestion.
+func ExportNormalized(ctx context.Context, src TimelineScanner, table *normalize.Table, schema string, w io.Writer, filter ExportFilter) (int, error) {
+	bw := bufio.NewWriter(w)
+	out := &ndjsonWriter{enc: json.NewEncoder(bw)}
+	switch schema {
+	case FormatECS:
+		out.render = table.ECS
+	case FormatOCSF:
+		out.render = table.OCSF
+	default:
+		return 0, fmt.Errorf("unsupported schema %q", schema)
+	}
+	return writeEvents(ctx, src, out, bw, filter)
+}
+
+func writeEvents(ctx context.Context, src TimelineScanner, out eventWriter, bw *bufio.Writer, filter ExportFilter) (int, error) {
+	count := 0
+	err := src.ScanTimeline(ctx, func(ev model.TimelineEvent) error {
+		if !filter.Match(ev) {
+			return nil
+		}
+		count++
+		return out.Write(ev)
+	})
+	if err != nil {
+		return count, err
+	}
+	if err := out.Flush(); err != nil {
+		return count, err
+	}
+	return count, bw.Flush()
+}
+
+// timestampDesc says what the event time means, e.g. "Last Run" or "Logon Success".
+func timestampDesc(ev model.TimelineEvent) string {
+	if d := ev.Details["TimestampDesc"]; d != "" {
+		return d
+	}
+	if ev.Action != "" {
+		return ev.Action
+	}
+	return "Event Time"
+}
+
+// macbSemantics maps model timestamp semantics onto plaso's MACB notation.
+var macbSemantics = map[string]string{
+	model.TimeCreated:  "...B",
+	model.TimeAccessed: ".A..",
+	model.TimeLastRun:  ".A..",
+	model.TimeModified: "M...",
+	model.TimeKeyWrite: "M...",
+}
+
+// macb maps the timestamp meaning onto plaso's MACB notation, from the parser's timestamp
+// semantics or else the description. Events that are not file-system style timestamps
+// (a logon, a process start) stay "....".
+func macb(ev model.TimelineEvent) string {
+	if ev.TimeSemantics != "" {
+		if m, ok := macbSemantics[ev.TimeSemantics]; ok {
+			return m
+		}
+		return "...."
+	}
+	d := strings.ToLower(timestampDesc(ev))
+	switch {
+	case strings.Contains(d, "creation") || strings.Contains(d, "file created") || strings.Contains(d, "first run") || strings.Contains(d, "install"):
+		return "...B"
+	case strings.Contains(d, "access") || strings.Contains(d, "last run") || strings.Contains(d, "last executed"):
+		return ".A.."
+	case strings.Contains(d, "entry modif") || strings.Contains(d, "metadata") || strings.Contains(d, "mft change"):
+		return "..C."
+	case strings.Contains(d, "modif") || strings.Contains(d, "written") || strings.Contains(d, "last write"):
+		return "M..."
+	}
+	return "...."
+}
+
+// message renders the one-line human description used by every format.
+func message(ev model.TimelineEvent) string {
+	var b strings.Builder
+	b.WriteString("[")
+	b.WriteString(ev.Source)
+	if ev.Artifact != "" && ev.Artifact != ev.Source {
+		b.WriteString("/")
+		b.WriteString(ev.Artifact)
+	}
+	b.WriteString("] ")
+	b.WriteString(ev.Action)
+	if ev.Subject != "" {
+		b.WriteString(": ")
+		b.WriteString(ev.Subject)
+	}
+	return b.String()
+}
+
+// sortedDetails returns "key: value" pairs in key order, dropping empty values.
+func sortedDetails(d map[string]string) []string {
+	keys := make([]string, 0, len(d))
+	for k, v := range d {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	out := make([]string, len(keys))
+	for i, k := range keys {
+		out[i] = k + ": " + d[k]
+	}
+	return out
+}
+
+// --- mactime bodyfile ---
+
+// bodyfileWriter writes the Sleuth Kit 3.x format:
+// MD5|name|inode|mode_as_string|UID|GID|size|atime|mtime|ctime|crtime
+type bodyfileWriter struct {
+	w *bufio.Writer
+}
+
+func (b *bodyfileWriter) Write(ev model.TimelineEvent) error {
+	ts := strconv.FormatInt(ev.EventTime.Unix(), 10)
+	atime, mtime, ctime, crtime := "0", "0", "0", "0"
+	switch macb(ev) {
+	case ".A..":
+		atime = ts
+	case "..C.":
+		ctime = ts
+	case "...B":
+		crtime = ts
+	default:
+		mtime = ts
+	}
+
+	md5 := ev.Details["MD5"]
+	if md5 == "" {
+		md5 = "0"
+	}
+	size := ev.Details["Size"]
+	if _, err := strconv.ParseInt(size, 10, 64); err != nil {
+		size = "0"
+	}
+	name := message(ev) + " (" + timestampDesc(ev) + ")"
+
+	_, err := fmt.Fprintf(b.w, "%s|%s|0|0|0|0|%s|%s|%s|%s|%s\n",
+		md5, bodyfileEscape(name), size, atime, mtime, ctime, crtime)
+	return err
+}
+
+func (b *bodyfileWriter) Flush() error { return nil }
+
+// bodyfileEscape keeps names on one line and out of the field separator. mactime splits on
+// every '|' without honouring escapes, so the separator is replaced rather than escaped.
+func bodyfileEscape(s string) string {
+	return strings.NewReplacer("|", "_", "\n", " ", "\r", " ").Replace(s)
+}
+
+// --- log2timeline l2t_csv ---
+
+var l2tHeader = []string{"date", "time", "timezone", "MACB", "source", "sourcetype", "type", "user", "host", "short", "desc", "version", "filename", "inode", "notes", "format", "extra"}
+
+type l2tWriter struct {
+	w *csv.Writer
+}
+
+func (l *l2tWriter) Write(ev model.TimelineEvent) error {
+	t := ev.EventTime.UTC()
+	desc := timestampDesc(ev)
+	short := message(ev)
+	long := short
+	if details := sortedDetails(publicDetails(ev.Details)); len(details) > 0 {
+		long += " " + strings.Join(details, " ")
+	}
+	var notes []string
+	if alert := ev.Details["_Alert"]; alert != "" {
+		notes = append(notes, "Alert: "+alert)
+	}
+	if len(ev.IOCHits) > 0 {
+		notes = append(notes, "IOC: "+strings.Join(ev.IOCHits, ", "))
+	}
+	notes = append(notes, annotationNotes(ev.Annotations)...)
+	return l.w.Write([]string{
+		t.Format("01/02/2006"),
+		t.Format("15:04:05"),
+		"UTC",
+		macb(ev),
+		l2tShortSource(ev),
+		strings.TrimSpace(ev.Source + " " + ev.Artifact),
+		desc,
+		orDash(ev.User()),
+		orDash(ev.Host()),
+		short,
+		long,
+		"2",
+		orDash(ev.EvidenceRef.SourcePath),
+		"-",
+		orDash(strings.Join(notes, "; ")),
+		"gtrace",
+		"event_id: " + ev.ID,
+	})
+}
+
+// annotationNotes renders analyst annotations for free-text note columns.
+func annotationNotes(anns []model.Annotation) []string {
+	var notes []string
+	if model.Bookmarked(anns) {
+		notes = append(notes, "Bookmarked")
+	}
+	if tags := model.AnnotationValues(anns, model.AnnotationTag); len(tags) > 0 {
+		notes = append(notes, "Tags: "+strings.Join(tags, ", "))
+	}
+	if v := model.Verdict(anns); v != "" {
+		notes = append(notes, "Verdict: "+v)
+	}
+	for _, a := range anns {
+		if a.Kind == model.AnnotationNote {
+			notes = append(notes, fmt.Sprintf("Note (%s, %s): %s", a.Author, a.CreatedAt.UTC().Format(time.RFC3339), a.Value))
+		}
+	}
+	return notes
+}
+
+func (l *l2tWriter) Flush() error {
+	l.w.Flush()
+	return l.w.Error()
+}
+
+// l2tShortSource maps an event onto plaso's short source names.
+func l2tShortSource(ev model.TimelineEvent) string {
+	s := strings.ToLower(ev.Source + " " + ev.Artifact)
+	switch {
+	case strings.Contains(s, "eventlog") || strings.Contains(s, "evtx"):
+		return "EVT"
+	case strings.Contains(s, "registry") || strings.Contains(s, "shimcache") || strings.Contains(s, "userassist") || strings.Contains(s, "bam"):
+		return "REG"
+	case strings.Contains(s, "browser") || strings.Contains(s, "history"):
+		return "WEBHIST"
+	case strings.Contains(s, "prefetch") || strings.Contains(s, "lnk") || strings.Contains(s, "jumplist") || strings.Contains(s, "filesystem") || strings.Contains(s, "recyclebin"):
+		return "FILE"
+	}
+	return "LOG"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// publicDetails drops the internal "_" annotations, which are exported separately.
+func publicDetails(d map[string]string) map[string]string {
+	out := make(map[string]string, len(d))
+	for k, v := range d {
+		if !strings.HasPrefix(k, "_") {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// --- Timesketch JSONL ---
+
+type timesketchWriter struct {
+	enc *json.Encoder
+}
+
+// timesketchReserved are the attribute names Timesketch requires or manages itself.
+var timesketchReserved = map[string]bool{
+	"message": true, "datetime": true, "timestamp": true, "timestamp_desc": true,
+	"data_type": true, "tag": true, "label": true, "__ts_timeline_id": true,
+}
+
+func (t *timesketchWriter) Write(ev model.TimelineEvent) error {
+	rec := map[string]any{
+		"message":        message(ev),
+		"datetime":       ev.EventTime.UTC().Format(time.RFC3339Nano),
+		"timestamp":      ev.EventTime.UnixMicro(),
+		"timestamp_desc": timestampDesc(ev),
+		"data_type":      "gtrace:" + strings.ToLower(strings.ReplaceAll(ev.Source, " ", "_")),
+		"event_id":       ev.ID,
+		"source_short":   l2tShortSource(ev),
+		"source":         ev.Source,
+		"artifact":       ev.Artifact,
+		"action":         ev.Action,
+		"source_file":    ev.EvidenceRef.SourcePath,
+	}
+	if ev.Subject != "" {
+		rec["subject"] = ev.Subject
+	}
+	if h := ev.Host(); h != "" {
+		rec["hostname"] = h
+	}
+	if u := ev.User(); u != "" {
+		rec["username"] = u
+	}
+	if len(ev.IOCHits) > 0 {
+		rec["ioc_hits"] = ev.IOCHits
+	}
+	// Timesketch imports "tag" as event tags; the rest of the analyst state rides along.
+	if tags := model.AnnotationValues(ev.Annotations, model.AnnotationTag); len(tags) > 0 {
+		rec["tag"] = tags
+	}
+	if len(ev.Annotations) > 0 {
+		rec["gtrace_annotations"] = ev.Annotations
+	}
+	for k, v := range ev.Details {
+		if v == "" {
+			continue
+		}
+		// Sigma annotations become readable attribute names; the rest keep the parser's keys.
+		key := k
+		if strings.HasPrefix(k, "_") {
+			key = "gtrace_" + strings.ToLower(strings.TrimPrefix(k, "_"))
+		}
+		if timesketchReserved[key] {
+			key = "attr_" + key
+		}
+		if _, taken := rec[key]; !taken {
+			rec[key] = v
+		}
+	}
+	return t.enc.Encode(rec)
+}
+
+func (t *timesketchWriter) Flush() error { return nil }
+
+// --- ECS / OCSF NDJSON ---
+
+type ndjsonWriter struct {
+	enc    *json.Encoder
+	render func(ev model.TimelineEvent) map[string]any
+}
+
+func (n *ndjsonWriter) Write(ev model.TimelineEvent) error {
+	return n.enc.Encode(n.render(ev))
+}
+
+func (n *ndjsonWriter) Flush() error { return nil }
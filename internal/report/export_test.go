@@ -0,0 +1,85 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+type sliceScanner []model.TimelineEvent
+
+func (s sliceScanner) ScanTimeline(ctx context.Context, fn func(ev model.TimelineEvent) error) error {
+	for _, ev := range s {
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportTimeline(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	events := sliceScanner{
+		{ID: "a", EventTime: at, Source: "EventLog", Action: "Process Created", Subject: "cmd|x.exe",
+			Details: map[string]string{"Computer": "WS01", "SubjectUserName": "alice", "_Alert": "Suspicious"}},
+		{ID: "b", EventTime: at, Source: "Prefetch", Action: "Last Run", Subject: "EVIL.EXE"},
+		{ID: "c", EventTime: at.Add(48 * time.Hour), Source: "EventLog", Action: "Logoff", Details: map[string]string{"Computer": "WS01"}},
+	}
+	end := at.Add(time.Hour)
+
+	var buf bytes.Buffer
+	n, err := ExportTimeline(context.Background(), events, FormatBodyfile, &buf, ExportFilter{TimeEnd: &end})
+	if err != nil || n != 2 {
+		t.Fatalf("bodyfile: n=%d err=%v", n, err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != `0|[EventLog] Process Created: cmd_x.exe (Process Created)|0|0|0|0|0|0|1704164645|0|0` {
+		t.Errorf("bodyfile line = %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "|1704164645|0|0|0") {
+		t.Errorf("Last Run should land in atime: %q", lines[1])
+	}
+
+	buf.Reset()
+	n, err = ExportTimeline(context.Background(), events, FormatL2TCSV, &buf, ExportFilter{Hosts: []string{"ws01"}})
+	if err != nil || n != 2 {
+		t.Fatalf("l2t_csv: n=%d err=%v", n, err)
+	}
+	if !strings.Contains(buf.String(), "01/02/2024,03:04:05,UTC,....,EVT,EventLog,Process Created,alice,WS01,") {
+		t.Errorf("l2t_csv row missing:\n%s", buf.String())
+	}
+
+	fqdn := model.TimelineEvent{Details: map[string]string{"Computer": "WS01.corp.local"}}
+	for _, tc := range []struct {
+		hosts []string
+		want  bool
+	}{
+		{[]string{"ws01"}, true},
+		{[]string{"WS01.CORP.LOCAL"}, true},
+		{[]string{"ws01.other.local"}, false},
+		{[]string{"ws02", "ws01"}, true},
+		{[]string{"ws0"}, false},
+	} {
+		if got := (ExportFilter{Hosts: tc.hosts}).Match(fqdn); got != tc.want {
+			t.Errorf("Hosts %v matching WS01.corp.local = %v", tc.hosts, got)
+		}
+	}
+
+	buf.Reset()
+	n, err = ExportTimeline(context.Background(), events, FormatTimesketch, &buf, ExportFilter{Sources: []string{"prefetch"}})
+	if err != nil || n != 1 {
+		t.Fatalf("timesketch: n=%d err=%v", n, err)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["timestamp_desc"] != "Last Run" || rec["datetime"] != "2024-01-02T03:04:05Z" || rec["timestamp"] != float64(1704164645000000) {
+		t.Errorf("timesketch record = %v", rec)
+	}
+}
@@ -0,0 +1,536 @@
+package report
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gtrace/internal/attack"
+	"gtrace/internal/storage"
+	"gtrace/pkg/model"
+)
+
+//go:embed templates/report.html.tmpl
+var defaultHTMLTemplate string
+
+// TemplateOverrideName is looked up under <case>/templates/ so a team can ship its own layout.
+const TemplateOverrideName = "report.html.tmpl"
+
+const (
+	maxExcerptsPerFinding = 5
+	maxExcerptField       = 400
+	maxHistogramBuckets   = 120
+	maxBookmarks          = 500
+)
+
+// CaseInfo is the case metadata printed in the report header.
+type CaseInfo struct {
+	Title    string
+	Name     string
+	Path     string
+	Examiner string
+}
+
+// HTMLReport is the data handed to the report template.
+type HTMLReport struct {
+	Case        CaseInfo
+	GeneratedAt time.Time
+	Evidence    []storage.EvidenceLocation
+	Totals      ReportTotals
+	Severities  []SeverityGroup
+	Tactics     []TacticGroup
+	Hosts       []HostSummary
+	Histogram   Histogram
+	Bookmarks   []Excerpt
+}
+
+type ReportTotals struct {
+	Events     int
+	Findings   int
+	SigmaHits  int
+	Hosts      int
+	FirstEvent *time.Time
+	LastEvent  *time.Time
+}
+
+// ReportFinding is an analyzer finding or a Sigma rule with all of its hits.
+type ReportFinding struct {
+	Anchor      string
+	Kind        string // "finding" or "sigma"
+	Severity    string
+	Title       string
+	RuleID      string
+	Description string
+	Tactics     []string
+	Techniques  []string
+	Count       int
+	Evidence    []Excerpt
+	Verdict     string
+	Annotations []model.Annotation
+}
+
+// Excerpt is one piece of supporting evidence with where it came from.
+type Excerpt struct {
+	Time        *time.Time
+	SourcePath  string
+	Offset      int64
+	Summary     string
+	Fields      []Field
+	Annotations []model.Annotation
+}
+
+type Field struct {
+	Name  string
+	Value string
+}
+
+type SeverityGroup struct {
+	Severity string
+	Findings []*ReportFinding
+}
+
+type TacticGroup struct {
+	Tactic   string
+	Findings []*ReportFinding
+}
+
+type HostSummary struct {
+	Name      string
+	Events    int
+	SigmaHits int
+	First     time.Time
+	Last      time.Time
+	Sources   []Field // source → count, most frequent first
+}
+
+// Histogram is event density over time; Height is a percentage of the busiest bucket.
+type Histogram struct {
+	Unit    string
+	Buckets []Bucket
+	Max     int
+}
+
+type Bucket struct {
+	Start  time.Time
+	Count  int
+	Alerts int
+	Height int
+}
+
+var severityOrder = []string{"critical", "high", "medium", "low", "informational"}
+
+// excerptFields are shown, in order, when present on a Sigma-matched event.
+var excerptFields = []string{
+	"Computer", "User", "TargetUserName", "SubjectUserName", "Image", "NewProcessName", "CommandLine",
+	"DecodedCommand", "ParentImage", "ParentProcessName", "TargetFilename", "TargetObject",
+	"ScriptBlockText", "IpAddress", "DestinationIp", "QueryName", "ServiceName", "ImagePath", "TaskName", "URL",
+}
+
+// BuildHTMLReport aggregates the case in one streaming pass over the timeline.
+func BuildHTMLReport(ctx context.Context, src TimelineScanner, findings []model.Finding, evidence []storage.EvidenceLocation, info CaseInfo) (*HTMLReport, error) {
+	rep := &HTMLReport{Case: info, GeneratedAt: time.Now().UTC(), Evidence: dedupEvidence(evidence)}
+
+	hosts := make(map[string]*hostAgg)
+	rules := make(map[string]*ReportFinding)
+	hourly := make(map[int64][2]int) // unix hour → {events, alerts}
+
+	err := src.ScanTimeline(ctx, func(ev model.TimelineEvent) error {
+		rep.Totals.Events++
+		alert := ev.Details["_Alert"]
+
+		if !ev.EventTime.IsZero() && ev.EventTime.Year() >= 1980 {
+			t := ev.EventTime.UTC()
+			if rep.Totals.FirstEvent == nil || t.Before(*rep.Totals.FirstEvent) {
+				rep.Totals.FirstEvent = &t
+			}
+			if rep.Totals.LastEvent == nil || t.After(*rep.Totals.LastEvent) {
+				rep.Totals.LastEvent = &t
+			}
+			h := hourly[t.Unix()/3600]
+			h[0]++
+			if alert != "" {
+				h[1]++
+			}
+			hourly[t.Unix()/3600] = h
+		}
+
+		name := ev.Host()
+		if name == "" {
+			name = "(unattributed)"
+		}
+		agg := hosts[name]
+		if agg == nil {
+			agg = &hostAgg{sources: make(map[string]int)}
+			hosts[name] = agg
+		}
+		agg.add(ev, alert != "")
+
+		if model.Bookmarked(ev.Annotations) && len(rep.Bookmarks) < maxBookmarks {
+			rep.Bookmarks = append(rep.Bookmarks, eventExcerpt(ev))
+		}
+
+		if alert != "" {
+			rep.Totals.SigmaHits++
+			key := ev.Details["_AlertRuleID"]
+			if key == "" {
+				key = alert
+			}
+			rf := rules[key]
+			if rf == nil {
+				tactics, techniques := attackLabels(attack.Default().EventRefs(ev))
+				rf = &ReportFinding{
+					Kind:        "sigma",
+					Severity:    normalizeSeverity(ev.Details["_AlertLevel"]),
+					Title:       alert,
+					RuleID:      ev.Details["_AlertRuleID"],
+					Description: ev.Details["_AlertDescription"],
+					Tactics:     tactics,
+					Techniques:  techniques,
+				}
+				rules[key] = rf
+			}
+			rf.Count++
+			if len(rf.Evidence) < maxExcerptsPerFinding {
+				rf.Evidence = append(rf.Evidence, eventExcerpt(ev))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*ReportFinding
+	for _, f := range findings {
+		all = append(all, findingItem(f))
+	}
+	rep.Totals.Findings = len(findings)
+	keys := make([]string, 0, len(rules))
+	for k := range rules {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		all = append(all, rules[k])
+	}
+	for i, f := range all {
+		f.Anchor = fmt.Sprintf("f%d", i+1)
+	}
+
+	rep.Severities = groupBySeverity(all)
+	rep.Tactics = groupByTactic(all)
+	rep.Hosts = hostSummaries(hosts)
+	rep.Totals.Hosts = len(rep.Hosts)
+	rep.Histogram = buildHistogram(hourly)
+	return rep, nil
+}
+
+type hostAgg struct {
+	events, alerts int
+	first, last    time.Time
+	sources        map[string]int
+}
+
+func (h *hostAgg) add(ev model.TimelineEvent, alert bool) {
+	h.events++
+	if alert {
+		h.alerts++
+	}
+	h.sources[ev.Source]++
+	if t := ev.EventTime.UTC(); !ev.EventTime.IsZero() && t.Year() >= 1980 {
+		if h.first.IsZero() || t.Before(h.first) {
+			h.first = t
+		}
+		if t.After(h.last) {
+			h.last = t
+		}
+	}
+}
+
+func hostSummaries(hosts map[string]*hostAgg) []HostSummary {
+	out := make([]HostSummary, 0, len(hosts))
+	for name, h := range hosts {
+		s := HostSummary{Name: name, Events: h.events, SigmaHits: h.alerts, First: h.first, Last: h.last}
+		for src, n := range h.sources {
+			s.Sources = append(s.Sources, Field{Name: src, Value: fmt.Sprint(n)})
+		}
+		sort.Slice(s.Sources, func(i, j int) bool {
+			if h.sources[s.Sources[i].Name] != h.sources[s.Sources[j].Name] {
+				return h.sources[s.Sources[i].Name] > h.sources[s.Sources[j].Name]
+			}
+			return s.Sources[i].Name < s.Sources[j].Name
+		})
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].SigmaHits != out[j].SigmaHits {
+			return out[i].SigmaHits > out[j].SigmaHits
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func findingItem(f model.Finding) *ReportFinding {
+	rf := &ReportFinding{
+		Kind:        "finding",
+		Severity:    normalizeSeverity(f.Severity),
+		Title:       f.Title,
+		RuleID:      f.RuleID,
+		Description: f.Description,
+		Count:       1,
+		Verdict:     model.Verdict(f.Annotations),
+		Annotations: f.Annotations,
+	}
+	rf.Tactics, rf.Techniques = attackLabels(attack.Default().Enrich(append([]model.AttackRef(nil), f.Attack...)))
+	seen := make(map[string]bool)
+	for _, ref := range f.EvidenceRefs {
+		key := fmt.Sprintf("%s@%d", ref.SourcePath, ref.Offset)
+		if ref.SourcePath == "" || seen[key] || len(rf.Evidence) >= maxExcerptsPerFinding {
+			continue
+		}
+		seen[key] = true
+		ex := Excerpt{SourcePath: ref.SourcePath, Offset: ref.Offset}
+		if ref.SHA256 != "" {
+			ex.Fields = append(ex.Fields, Field{Name: "SHA256", Value: ref.SHA256})
+		}
+		rf.Evidence = append(rf.Evidence, ex)
+	}
+	for _, ioc := range f.IOCs {
+		if len(rf.Evidence) == 0 {
+			rf.Evidence = append(rf.Evidence, Excerpt{})
+		}
+		rf.Evidence[0].Fields = append(rf.Evidence[0].Fields, Field{Name: "IOC " + ioc.Type, Value: ioc.Value})
+	}
+	return rf
+}
+
+func eventExcerpt(ev model.TimelineEvent) Excerpt {
+	t := ev.EventTime.UTC()
+	ex := Excerpt{
+		Time:        &t,
+		SourcePath:  ev.EvidenceRef.SourcePath,
+		Offset:      ev.EvidenceRef.Offset,
+		Summary:     message(ev),
+		Annotations: ev.Annotations,
+	}
+	for _, k := range excerptFields {
+		if v := strings.TrimSpace(ev.Details[k]); v != "" && v != "-" {
+			if len(v) > maxExcerptField {
+				v = v[:maxExcerptField] + "…"
+			}
+			ex.Fields = append(ex.Fields, Field{Name: k, Value: v})
+		}
+	}
+	if len(ev.IOCHits) > 0 {
+		ex.Fields = append(ex.Fields, Field{Name: "IOC hits", Value: strings.Join(ev.IOCHits, ", ")})
+	}
+	return ex
+}
+
+func normalizeSeverity(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "info", "information":
+		return "informational"
+	case "":
+		return "informational"
+	}
+	return s
+}
+
+// attackLabels returns the tactic names and "ID Name" technique labels of refs.
+func attackLabels(refs []model.AttackRef) (tactics, techniques []string) {
+	for _, r := range refs {
+		for _, t := range r.Tactics {
+			tactics = appendUnique(tactics, t)
+		}
+		label := r.TechniqueID
+		if r.Technique != "" {
+			label += " " + r.Technique
+		}
+		techniques = appendUnique(techniques, label)
+	}
+	return tactics, techniques
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, s := range list {
+		if s == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+func groupBySeverity(all []*ReportFinding) []SeverityGroup {
+	groups := make(map[string][]*ReportFinding)
+	for _, f := range all {
+		groups[f.Severity] = append(groups[f.Severity], f)
+	}
+	var out []SeverityGroup
+	for _, sev := range severityOrder {
+		if len(groups[sev]) > 0 {
+			out = append(out, SeverityGroup{Severity: sev, Findings: groups[sev]})
+			delete(groups, sev)
+		}
+	}
+	var rest []string
+	for sev := range groups {
+		rest = append(rest, sev)
+	}
+	sort.Strings(rest)
+	for _, sev := range rest {
+		out = append(out, SeverityGroup{Severity: sev, Findings: groups[sev]})
+	}
+	return out
+}
+
+func groupByTactic(all []*ReportFinding) []TacticGroup {
+	groups := make(map[string][]*ReportFinding)
+	for _, f := range all {
+		if len(f.Tactics) == 0 {
+			groups["Unmapped"] = append(groups["Unmapped"], f)
+		}
+		for _, t := range f.Tactics {
+			groups[t] = append(groups[t], f)
+		}
+	}
+	var order []string
+	for _, t := range attack.Default().Tactics() {
+		order = append(order, t.Name)
+	}
+	var out []TacticGroup
+	for _, t := range append(order, "Unmapped") {
+		if len(groups[t]) > 0 {
+			out = append(out, TacticGroup{Tactic: t, Findings: groups[t]})
+		}
+	}
+	return out
+}
+
+// buildHistogram folds hourly counts into at most maxHistogramBuckets contiguous buckets.
+func buildHistogram(hourly map[int64][2]int) Histogram {
+	if len(hourly) == 0 {
+		return Histogram{}
+	}
+	var lo, hi int64 = 1 << 62, -1 << 62
+	for h := range hourly {
+		if h < lo {
+			lo = h
+		}
+		if h > hi {
+			hi = h
+		}
+	}
+
+	width, unit := int64(1), "hour"
+	for _, step := range []struct {
+		hours int64
+		unit  string
+	}{{6, "6 hours"}, {24, "day"}, {24 * 7, "week"}, {24 * 30, "30 days"}, {24 * 365, "year"}} {
+		if (hi-lo)/width < maxHistogramBuckets {
+			break
+		}
+		width, unit = step.hours, step.unit
+	}
+	lo -= lo % width
+
+	n := (hi-lo)/width + 1
+	hist := Histogram{Unit: unit, Buckets: make([]Bucket, n)}
+	for i := range hist.Buckets {
+		hist.Buckets[i].Start = time.Unix((lo+int64(i)*width)*3600, 0).UTC()
+	}
+	for h, c := range hourly {
+		b := &hist.Buckets[(h-lo)/width]
+		b.Count += c[0]
+		b.Alerts += c[1]
+	}
+	for _, b := range hist.Buckets {
+		if b.Count > hist.Max {
+			hist.Max = b.Count
+		}
+	}
+	for i := range hist.Buckets {
+		if hist.Max > 0 && hist.Buckets[i].Count > 0 {
+			hist.Buckets[i].Height = 1 + hist.Buckets[i].Count*99/hist.Max
+		}
+	}
+	return hist
+}
+
+func dedupEvidence(in []storage.EvidenceLocation) []storage.EvidenceLocation {
+	seen := make(map[string]bool)
+	var out []storage.EvidenceLocation
+	for _, e := range in {
+		key := e.Path + "|" + e.SHA256
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, e)
+	}
+	return out
+}
+
+var templateFuncs = template.FuncMap{
+	"fmtTime": func(t any) string {
+		switch v := t.(type) {
+		case time.Time:
+			if v.IsZero() {
+				return "-"
+			}
+			return v.UTC().Format("2006-01-02 15:04:05Z")
+		case *time.Time:
+			if v == nil || v.IsZero() {
+				return "-"
+			}
+			return v.UTC().Format("2006-01-02 15:04:05Z")
+		}
+		return "-"
+	},
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"bytes": func(n int64) string {
+		const unit = 1024
+		if n < unit {
+			return fmt.Sprintf("%d B", n)
+		}
+		div, exp := int64(unit), 0
+		for m := n / unit; m >= unit; m /= unit {
+			div *= unit
+			exp++
+		}
+		return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+	},
+}
+
+// LoadTemplate parses the report template at path, or the built-in one when path is empty.
+func LoadTemplate(path string) (*template.Template, error) {
+	text := defaultHTMLTemplate
+	name := "report"
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read template: %w", err)
+		}
+		text = string(data)
+		name = path
+	}
+	t, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// RenderHTML writes rep through tmpl as a single offline HTML file.
+func RenderHTML(w io.Writer, rep *HTMLReport, tmpl *template.Template) error {
+	return tmpl.Execute(w, rep)
+}
@@ -0,0 +1,78 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gtrace/internal/storage"
+	"gtrace/pkg/model"
+)
+
+func TestHTMLReport(t *testing.T) {
+	at := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	events := sliceScanner{
+		{ID: "a", EventTime: at, Source: "EventLog", Action: "Process Created", Subject: "whoami.exe",
+			EvidenceRef: model.EvidenceRef{SourcePath: `C:\cases\Security.evtx`, Offset: 42},
+			Details: map[string]string{"Computer": "WS01", "CommandLine": "whoami /all", "_Alert": "Whoami Execution",
+				"_AlertLevel": "medium", "_AlertRuleID": "r-1", "_Mitre": "attack.discovery, attack.t1033"},
+			Annotations: []model.Annotation{
+				{Kind: model.AnnotationBookmark, Author: "jdoe", CreatedAt: at},
+				{Kind: model.AnnotationTag, Value: "recon", Author: "jdoe", CreatedAt: at},
+			}},
+		{ID: "b", EventTime: at.Add(2 * time.Hour), Source: "Prefetch", Action: "Last Run", Subject: "EVIL.EXE",
+			Details: map[string]string{"Computer": "WS01"}},
+		{ID: "c", EventTime: at.Add(3 * time.Hour), Source: "EventLog", Action: "Logoff",
+			Details: map[string]string{"Computer": "SRV02"}},
+	}
+	findings := []model.Finding{{ID: "f1", Severity: "high", Title: "Execution from Temp", RuleID: "temp-exec",
+		EvidenceRefs: []model.EvidenceRef{{SourcePath: `C:\cases\SYSTEM`}, {SourcePath: `C:\cases\SYSTEM`}},
+		Annotations:  []model.Annotation{{Kind: model.AnnotationVerdict, Value: model.VerdictFalsePositive, Author: "jdoe", CreatedAt: at}}}}
+	evidence := []storage.EvidenceLocation{
+		{Path: `C:\cases\Security.evtx`, SHA256: "abc123", SizeBytes: 2048},
+		{Path: `C:\cases\Security.evtx`, SHA256: "abc123", SizeBytes: 2048},
+	}
+
+	rep, err := BuildHTMLReport(context.Background(), events, findings, evidence, CaseInfo{Title: "IR-7", Examiner: "jdoe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Totals.Events != 3 || rep.Totals.SigmaHits != 1 || rep.Totals.Hosts != 2 || len(rep.Evidence) != 1 {
+		t.Errorf("totals = %+v, evidence = %d", rep.Totals, len(rep.Evidence))
+	}
+	if len(rep.Bookmarks) != 1 || rep.Severities[0].Findings[0].Verdict != model.VerdictFalsePositive {
+		t.Errorf("bookmarks = %d, verdict = %q", len(rep.Bookmarks), rep.Severities[0].Findings[0].Verdict)
+	}
+	if rep.Hosts[0].Name != "WS01" || rep.Hosts[0].Events != 2 {
+		t.Errorf("hosts = %+v", rep.Hosts)
+	}
+	if len(rep.Severities) != 2 || rep.Severities[0].Severity != "high" || len(rep.Severities[0].Findings[0].Evidence) != 1 {
+		t.Errorf("severities = %+v", rep.Severities)
+	}
+	if rep.Tactics[0].Tactic != "Discovery" || rep.Tactics[1].Tactic != "Unmapped" {
+		t.Errorf("tactics = %+v", rep.Tactics)
+	}
+	if h := rep.Histogram; h.Unit != "hour" || len(h.Buckets) != 4 || h.Buckets[0].Alerts != 1 || h.Buckets[1].Height != 0 {
+		t.Errorf("histogram = %+v", h)
+	}
+
+	tmpl, err := LoadTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, rep, tmpl); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"IR-7", "jdoe", "abc123", "T1033", `C:\cases\Security.evtx`, "whoami /all", "#recon", "false_positive"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(out, "http://") || strings.Contains(out, "https://") {
+		t.Error("report must not reference external resources")
+	}
+}
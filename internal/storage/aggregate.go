@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gtrace/internal/query"
+	"gtrace/pkg/model"
+)
+
+// Aggregation kinds.
+const (
+	AggHistogram = "histogram" // event counts per time bucket
+	AggTerms     = "terms"     // most common values of a field
+	AggRare      = "rare"      // least common values first: frequency-of-occurrence stacking
+)
+
+// maxHistogramBuckets bounds an explicit histogram interval over a long time range.
+const maxHistogramBuckets = 10000
+
+// AggregateRequest describes one aggregation over the events matching Filter. Field and
+// GroupBy take the names the query language uses: host, user, process, source, any Details
+// key, and so on.
+type AggregateRequest struct {
+	Kind     string               `json:"kind"`
+	Field    string               `json:"field,omitempty"`    // terms and rare
+	GroupBy  string               `json:"group_by,omitempty"` // split every bucket by this field
+	Interval string               `json:"interval,omitempty"` // histogram bucket: 30s, 5m, 1h, 1d, 1w; empty picks one
+	Size     int                  `json:"size,omitempty"`     // terms and rare: buckets returned (default 10)
+	Filter   model.TimelineFilter `json:"filter"`
+}
+
+// AggBucket is one value (or time bucket) and how many events fall in it.
+type AggBucket struct {
+	Key      string      `json:"key"`
+	Time     *time.Time  `json:"time,omitempty"` // histogram bucket start
+	Count    int         `json:"count"`
+	SampleID string      `json:"sample_id,omitempty"` // first matching event, to pivot from a rare value
+	Groups   []AggBucket `json:"groups,omitempty"`
+}
+
+// AggregateResult holds the buckets of an aggregation.
+type AggregateResult struct {
+	Kind     string      `json:"kind"`
+	Field    string      `json:"field,omitempty"`
+	GroupBy  string      `json:"group_by,omitempty"`
+	Interval string      `json:"interval,omitempty"`
+	Total    int         `json:"total"`    // events matching the filter
+	Missing  int         `json:"missing"`  // matching events without the field (or a plausible time)
+	Distinct int         `json:"distinct"` // distinct values, before Size is applied
+	Buckets  []AggBucket `json:"buckets"`
+}
+
+// Aggregate computes a histogram, top-N or rare-value stacking over the timeline, with the
+// same filters as SearchTimeline.
+func (f *FileStorage) Aggregate(ctx context.Context, req AggregateRequest) (*AggregateResult, error) {
+	res := &AggregateResult{Kind: req.Kind, Field: req.Field, GroupBy: req.GroupBy, Buckets: []AggBucket{}}
+	var group func(ev *model.TimelineEvent) []string
+	if req.GroupBy != "" {
+		group = query.FieldValues(req.GroupBy)
+	}
+	size := req.Size
+	if size <= 0 {
+		size = 10
+	}
+
+	switch req.Kind {
+	case AggHistogram:
+		var step int64 // seconds
+		if req.Interval != "" {
+			d, err := parseInterval(req.Interval)
+			if err != nil {
+				return nil, err
+			}
+			step = int64(d / time.Second)
+		}
+		type point struct {
+			t     int64
+			group string
+		}
+		var points []point
+		var lo, hi int64
+		err := f.scanFiltered(ctx, &req.Filter, func(ev *model.TimelineEvent) bool {
+			res.Total++
+			// Like the report, times before 1980 are unset or corrupt fields, not activity.
+			if ev.EventTime.IsZero() || ev.EventTime.Year() < 1980 {
+				res.Missing++
+				return true
+			}
+			p := point{t: ev.EventTime.Unix()}
+			if group != nil {
+				p.group = first(group(ev))
+			}
+			if len(points) == 0 || p.t < lo {
+				lo = p.t
+			}
+			if len(points) == 0 || p.t > hi {
+				hi = p.t
+			}
+			points = append(points, p)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(points) == 0 {
+			return res, nil
+		}
+		if step == 0 {
+			step = autoInterval(hi - lo)
+		}
+		start := lo - lo%step
+		if start > lo { // times before 1970
+			start -= step
+		}
+		n := int((hi-start)/step) + 1
+		if n > maxHistogramBuckets {
+			return nil, fmt.Errorf("interval %s gives %d buckets; use a larger one", formatInterval(step), n)
+		}
+		res.Interval = formatInterval(step)
+		counts := make([]int, n)
+		var groups []map[string]int
+		if group != nil {
+			groups = make([]map[string]int, n)
+		}
+		for _, p := range points {
+			i := int((p.t - start) / step)
+			counts[i]++
+			if groups != nil {
+				if groups[i] == nil {
+					groups[i] = make(map[string]int)
+				}
+				groups[i][p.group]++
+			}
+		}
+		for i, c := range counts {
+			t := time.Unix(start+int64(i)*step, 0).UTC()
+			b := AggBucket{Key: t.Format(time.RFC3339), Time: &t, Count: c}
+			if groups != nil {
+				b.Groups = sortedBuckets(groups[i], false, 0)
+			}
+			res.Buckets = append(res.Buckets, b)
+		}
+		return res, nil
+
+	case AggTerms, AggRare:
+		if req.Field == "" {
+			return nil, fmt.Errorf("%s aggregation needs a field", req.Kind)
+		}
+		values := query.FieldValues(req.Field)
+		counts := make(map[string]int)
+		samples := make(map[string]string)
+		display := make(map[string]string) // first spelling of a case-folded value
+		var groups map[string]map[string]int
+		if group != nil {
+			groups = make(map[string]map[string]int)
+		}
+		err := f.scanFiltered(ctx, &req.Filter, func(ev *model.TimelineEvent) bool {
+			res.Total++
+			v := first(values(ev))
+			if v == "" {
+				res.Missing++
+				return true
+			}
+			k := strings.ToLower(v)
+			if _, ok := display[k]; !ok {
+				display[k] = v
+				samples[k] = ev.ID
+			}
+			counts[k]++
+			if groups != nil {
+				if groups[k] == nil {
+					groups[k] = make(map[string]int)
+				}
+				groups[k][first(group(ev))]++
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		res.Distinct = len(counts)
+		for _, b := range sortedBuckets(counts, req.Kind == AggRare, size) {
+			k := b.Key
+			b.Key = display[k]
+			b.SampleID = samples[k]
+			if groups != nil {
+				b.Groups = sortedBuckets(groups[k], false, 0)
+			}
+			res.Buckets = append(res.Buckets, b)
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("unknown aggregation %q", req.Kind)
+}
+
+// sortedBuckets orders counts by count (descending, or ascending when rare) and then key,
+// keeping at most size buckets when size > 0.
+func sortedBuckets(counts map[string]int, rare bool, size int) []AggBucket {
+	out := make([]AggBucket, 0, len(counts))
+	for k, c := range counts {
+		out = append(out, AggBucket{Key: k, Count: c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return (out[i].Count < out[j].Count) == rare
+		}
+		return out[i].Key < out[j].Key
+	})
+	if size > 0 && len(out) > size {
+		out = out[:size]
+	}
+	return out
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// parseInterval accepts Go durations plus d (day) and w (week) units.
+func parseInterval(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	var d time.Duration
+	var err error
+	switch {
+	case strings.HasSuffix(s, "d") || strings.HasSuffix(s, "w"):
+		var n int
+		n, err = strconv.Atoi(s[:len(s)-1])
+		d = time.Duration(n) * 24 * time.Hour
+		if s[len(s)-1] == 'w' {
+			d *= 7
+		}
+	default:
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+	return d.Truncate(time.Second), nil
+}
+
+// formatInterval writes an interval of secs seconds in the units parseInterval accepts.
+func formatInterval(secs int64) string {
+	const minute, hour, day = 60, 3600, 86400
+	switch {
+	case secs%(7*day) == 0:
+		return fmt.Sprintf("%dw", secs/(7*day))
+	case secs%day == 0:
+		return fmt.Sprintf("%dd", secs/day)
+	case secs%hour == 0:
+		return fmt.Sprintf("%dh", secs/hour)
+	case secs%minute == 0:
+		return fmt.Sprintf("%dm", secs/minute)
+	}
+	return fmt.Sprintf("%ds", secs)
+}
+
+// autoInterval picks the smallest round interval, in seconds, that covers a span of secs
+// seconds in about 100 buckets. Spans of centuries, from a bogus far-future timestamp, get
+// intervals of whole years. Seconds rather than a time.Duration, which overflows at 292 years.
+func autoInterval(secs int64) int64 {
+	for _, d := range []time.Duration{
+		time.Second, 10 * time.Second, time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour,
+		3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour,
+		30 * 24 * time.Hour,
+	} {
+		if step := int64(d / time.Second); secs/step <= 100 {
+			return step
+		}
+	}
+	const year = 365 * 86400
+	return (secs/(100*year) + 1) * year
+}
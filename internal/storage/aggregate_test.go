@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2026, 1, 10, 8, 0, 0, 0, time.UTC)
+	proc := func(id, host, image string, at time.Duration) model.TimelineEvent {
+		return model.TimelineEvent{ID: id, EventTime: base.Add(at), Source: "EventLog",
+			Details: map[string]string{"EventID": "4688", "Computer": host, "NewProcessName": image}}
+	}
+	if err := s.SaveTimeline(ctx, []model.TimelineEvent{
+		proc("ev-1", "WS01", `C:\Windows\System32\svchost.exe`, 0),
+		proc("ev-2", "WS02", `C:\Windows\System32\svchost.exe`, 10*time.Minute),
+		proc("ev-3", "WS01", `C:\Windows\System32\SVCHOST.EXE`, 70*time.Minute),
+		proc("ev-4", "WS02", `C:\Users\Public\evil.exe`, 150*time.Minute),
+		{ID: "ev-5", EventTime: base, Source: "Registry", Details: map[string]string{"Computer": "WS01"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := s.Aggregate(ctx, AggregateRequest{Kind: AggTerms, Field: "process", GroupBy: "host", Filter: model.TimelineFilter{Source: "EventLog"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top.Total != 4 || top.Missing != 0 || top.Distinct != 2 || len(top.Buckets) != 2 {
+		t.Fatalf("terms = %+v", top)
+	}
+	if b := top.Buckets[0]; b.Key != `C:\Windows\System32\svchost.exe` || b.Count != 3 || len(b.Groups) != 2 || b.Groups[0].Key != "WS01" || b.Groups[0].Count != 2 {
+		t.Errorf("top bucket = %+v", b)
+	}
+
+	rare, err := s.Aggregate(ctx, AggregateRequest{Kind: AggRare, Field: "process", Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rare.Missing != 1 || len(rare.Buckets) != 1 || rare.Buckets[0].Key != `C:\Users\Public\evil.exe` || rare.Buckets[0].SampleID != "ev-4" {
+		t.Errorf("rare = %+v", rare)
+	}
+
+	hist, err := s.Aggregate(ctx, AggregateRequest{Kind: AggHistogram, Interval: "1h", Filter: model.TimelineFilter{SearchTerm: "eid:4688"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counts []int
+	for _, b := range hist.Buckets {
+		counts = append(counts, b.Count)
+	}
+	if hist.Interval != "1h" || len(counts) != 3 || counts[0] != 2 || counts[1] != 1 || counts[2] != 1 || !hist.Buckets[0].Time.Equal(base) {
+		t.Errorf("histogram = %s %v", hist.Interval, counts)
+	}
+
+	// A corrupt far-future time widens the automatic interval instead
+	// of failing; a time before 1980 is not plotted at all.
+	if err := s.SaveTimeline(ctx, []model.TimelineEvent{
+		{ID: "ev-6", EventTime: time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC), Source: "Registry"},
+		{ID: "ev-7", EventTime: time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC), Source: "Registry"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	wide, err := s.Aggregate(ctx, AggregateRequest{Kind: AggHistogram})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wide.Total != 7 || wide.Missing != 1 || len(wide.Buckets) > 101 || !strings.HasSuffix(wide.Interval, "d") {
+		t.Errorf("histogram over a bogus time = %s, %d buckets, total %d, missing %d", wide.Interval, len(wide.Buckets), wide.Total, wide.Missing)
+	}
+
+	if _, err := s.Aggregate(ctx, AggregateRequest{Kind: AggHistogram, Interval: "500ms"}); err == nil {
+		t.Error("sub-second interval accepted")
+	}
+	if _, err := s.Aggregate(ctx, AggregateRequest{Kind: AggTerms}); err == nil {
+		t.Error("terms without a field accepted")
+	}
+}
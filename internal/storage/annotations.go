@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+// Annotation target types.
+const (
+	TargetEvent   = "event"
+	TargetFinding = "finding"
+)
+
+const annotationsFile = "annotations.jsonl"
+
+// ValidateAnnotation checks an annotation before it is stored and normalizes its value.
+func ValidateAnnotation(a *model.Annotation) error {
+	if a.TargetType != TargetEvent && a.TargetType != TargetFinding {
+		return fmt.Errorf("unknown annotation target %q", a.TargetType)
+	}
+	if a.TargetID == "" {
+		return fmt.Errorf("annotation target id required")
+	}
+	if a.Author == "" {
+		return fmt.Errorf("annotation author required")
+	}
+	a.Value = strings.TrimSpace(a.Value)
+	switch a.Kind {
+	case model.AnnotationTag:
+		if a.Value == "" || strings.ContainsAny(a.Value, " \t\n") {
+			return fmt.Errorf("invalid tag %q", a.Value)
+		}
+		a.Value = strings.ToLower(a.Value)
+	case model.AnnotationNote:
+		if a.Value == "" {
+			return fmt.Errorf("empty note")
+		}
+	case model.AnnotationVerdict:
+		switch a.Value {
+		case model.VerdictFalsePositive, model.VerdictTruePositive, model.VerdictBenign:
+		default:
+			return fmt.Errorf("unknown verdict %q", a.Value)
+		}
+	case model.AnnotationBookmark:
+		a.Value = ""
+	default:
+		return fmt.Errorf("unknown annotation kind %q", a.Kind)
+	}
+	return nil
+}
+
+func newAnnotationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("ann-%d", time.Now().UnixNano())
+	}
+	return "ann-" + hex.EncodeToString(b)
+}
+
+// AddAnnotation validates a, stamps its ID and time, and appends it to the case.
+func (f *FileStorage) AddAnnotation(ctx context.Context, a model.Annotation) (model.Annotation, error) {
+	if err := ValidateAnnotation(&a); err != nil {
+		return a, err
+	}
+	a.ID = newAnnotationID()
+	a.CreatedAt = time.Now().UTC()
+	a.Deleted = false
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return a, f.appendJSONL(annotationsFile, a)
+}
+
+// DeleteAnnotation retracts an annotation. The original record stays in the log for audit.
+func (f *FileStorage) DeleteAnnotation(ctx context.Context, id, author string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	idx, err := f.loadAnnotations()
+	if err != nil {
+		return err
+	}
+	for _, list := range idx {
+		for _, a := range list {
+			if a.ID == id {
+				return f.appendJSONL(annotationsFile, model.Annotation{
+					ID: id, TargetType: a.TargetType, TargetID: a.TargetID, Kind: a.Kind,
+					Author: author, CreatedAt: time.Now().UTC(), Deleted: true,
+				})
+			}
+		}
+	}
+	return fmt.Errorf("annotation %s not found", id)
+}
+
+// QueryAnnotations returns the live annotations on one target, or on every target when
+// targetType is empty, oldest first.
+func (f *FileStorage) QueryAnnotations(ctx context.Context, targetType, targetID string) ([]model.Annotation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	idx, err := f.loadAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	if targetType != "" {
+		return idx[annotationKey(targetType, targetID)], nil
+	}
+	var out []model.Annotation
+	for _, list := range idx {
+		out = append(out, list...)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func annotationKey(targetType, targetID string) string {
+	return targetType + "|" + targetID
+}
+
+// loadAnnotations folds the annotation log into live annotations per target. Callers hold f.mu.
+func (f *FileStorage) loadAnnotations() (map[string][]model.Annotation, error) {
+	idx := make(map[string][]model.Annotation)
+	file, err := os.Open(filepath.Join(f.dataDir(), annotationsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	deleted := make(map[string]bool)
+	var all []model.Annotation
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var a model.Annotation
+		if err := json.Unmarshal(scanner.Bytes(), &a); err != nil {
+			continue
+		}
+		if a.Deleted {
+			deleted[a.ID] = true
+			continue
+		}
+		all = append(all, a)
+	}
+	for _, a := range all {
+		if !deleted[a.ID] {
+			k := annotationKey(a.TargetType, a.TargetID)
+			idx[k] = append(idx[k], a)
+		}
+	}
+	return idx, scanner.Err()
+}
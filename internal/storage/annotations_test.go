@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+func TestAnnotations(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveTimeline(ctx, []model.TimelineEvent{
+		{ID: "ev-1", Source: "EventLog", Action: "Logon"},
+		{ID: "ev-2", Source: "EventLog", Action: "Logoff"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	add := func(kind, value string) model.Annotation {
+		t.Helper()
+		a, err := s.AddAnnotation(ctx, model.Annotation{TargetType: TargetEvent, TargetID: "ev-1", Kind: kind, Value: value, Author: "jdoe"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	add(model.AnnotationTag, " Lateral ")
+	star := add(model.AnnotationBookmark, "")
+	add(model.AnnotationVerdict, model.VerdictTruePositive)
+	add(model.AnnotationVerdict, model.VerdictFalsePositive)
+
+	if _, err := s.AddAnnotation(ctx, model.Annotation{TargetType: TargetEvent, TargetID: "ev-1", Kind: "verdict", Value: "maybe", Author: "jdoe"}); err == nil {
+		t.Error("unknown verdict accepted")
+	}
+
+	search := func(term string) []model.TimelineEvent {
+		t.Helper()
+		events, err := s.SearchTimeline(ctx, &model.TimelineFilter{SearchTerm: term})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+	if got := search("tag:lateral"); len(got) != 1 || got[0].ID != "ev-1" || len(got[0].Annotations) != 4 {
+		t.Fatalf("tag:lateral = %+v", got)
+	}
+	// Free text finds annotation values, which the stored timeline lines do not contain.
+	add(model.AnnotationNote, "beacon to staging server")
+	if got := search("staging"); len(got) != 1 || got[0].ID != "ev-1" {
+		t.Errorf("free text over notes = %+v", got)
+	}
+	if got := search("Lateral AND Logon"); len(got) != 1 {
+		t.Errorf("free text over tags = %+v", got)
+	}
+	if got := search("verdict:true_positive"); len(got) != 0 {
+		t.Errorf("superseded verdict still matches: %+v", got)
+	}
+	if got := search("verdict:false_positive"); len(got) != 1 {
+		t.Errorf("verdict:false_positive = %+v", got)
+	}
+
+	// ScanMatching streams the same matches without paging and stops on the callback's error.
+	var ids []string
+	stop := errors.New("stop")
+	err = s.ScanMatching(ctx, &model.TimelineFilter{SearchTerm: "Logon OR Logoff"}, func(ev model.TimelineEvent) error {
+		ids = append(ids, ev.ID)
+		return stop
+	})
+	if err != stop || len(ids) != 1 || ids[0] != "ev-1" {
+		t.Errorf("ScanMatching = %v, %v", ids, err)
+	}
+
+	if err := s.DeleteAnnotation(ctx, star.ID, "jdoe"); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("is:bookmarked"); len(got) != 0 {
+		t.Errorf("deleted bookmark still matches: %+v", got)
+	}
+	anns, err := s.QueryAnnotations(ctx, TargetEvent, "ev-1")
+	if err != nil || len(anns) != 4 || anns[0].Value != "lateral" || anns[0].CreatedAt.IsZero() {
+		t.Errorf("annotations = %+v, %v", anns, err)
+	}
+}
+
+// Scans release the store lock before calling back, so a callback may write to the store;
+// what it writes is not part of the running scan.
+func TestScanCallbacksUseStore(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveTimeline(ctx, []model.TimelineEvent{{ID: "ev-1", Action: "Logon"}, {ID: "ev-2", Action: "Logon"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	seen := 0
+	go func() {
+		done <- s.ScanTimeline(ctx, func(ev model.TimelineEvent) error {
+			seen++
+			if _, err := s.AddAnnotation(ctx, model.Annotation{TargetType: TargetEvent, TargetID: ev.ID, Kind: model.AnnotationTag, Value: "seen", Author: "jdoe"}); err != nil {
+				return err
+			}
+			return s.SaveTimeline(ctx, []model.TimelineEvent{{ID: ev.ID + "-copy"}})
+		})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ScanTimeline deadlocked on a callback using the store")
+	}
+	if seen != 2 {
+		t.Errorf("scanned %d events, want the 2 stored at the start", seen)
+	}
+
+	err = s.ScanMatching(ctx, &model.TimelineFilter{SearchTerm: "Logon"}, func(ev model.TimelineEvent) error {
+		_, err := s.QueryAnnotations(ctx, TargetEvent, ev.ID)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := s.SearchTimeline(ctx, &model.TimelineFilter{SearchTerm: "tag:seen"}); len(events) != 2 {
+		t.Errorf("tagged events = %d, want 2", len(events))
+	}
+}
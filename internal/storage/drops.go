@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const dropsFile = "drops.jsonl"
+
+// Drop stages.
+const (
+	DropStageNoise  = "noise"  // noise-reduction profile rules of the event log parser
+	DropStageBudget = "budget" // event budget quotas and per-log limits
+)
+
+// DropRecord counts the events one ingest rule discarded, from one evidence file or, for
+// budget quotas, from one source and host, so that no event is dropped without a trace.
+// Run names the triage run that recorded it.
+type DropRecord struct {
+	Run      string    `json:"run,omitempty"`
+	Stage    string    `json:"stage"`
+	Profile  string    `json:"profile"`
+	Rule     string    `json:"rule"`
+	Source   string    `json:"source,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	Evidence string    `json:"evidence,omitempty"`
+	Dropped  int       `json:"dropped"`
+	At       time.Time `json:"at"`
+}
+
+// DropSummary totals the drops of one rule (and host) over all evidence, as of the latest
+// triage run of each.
+type DropSummary struct {
+	Stage   string `json:"stage"`
+	Profile string `json:"profile"`
+	Rule    string `json:"rule"`
+	Source  string `json:"source,omitempty"`
+	Host    string `json:"host,omitempty"`
+	Dropped int    `json:"dropped"`
+	Files   int    `json:"files"`
+}
+
+// RecordDrops appends drop counts to the case.
+func (f *FileStorage) RecordDrops(ctx context.Context, records []DropRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now().UTC()
+	for _, r := range records {
+		if r.At.IsZero() {
+			r.At = now
+		}
+		if err := f.appendJSONL(dropsFile, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryDrops returns the recorded drop counts.
+func (f *FileStorage) QueryDrops(ctx context.Context) ([]DropRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := []DropRecord{}
+	err := scanJSONL(filepath.Join(f.dataDir(), dropsFile), func(line []byte) {
+		var r DropRecord
+		if json.Unmarshal(line, &r) == nil {
+			out = append(out, r)
+		}
+	})
+	return out, err
+}
+
+// SummarizeDrops totals drop records per stage, profile, rule and host, largest first.
+// Triaging evidence again drops the same events again, so only the latest run that recorded
+// drops for an evidence file (or, for budget quotas, a source and host) is counted.
+func SummarizeDrops(records []DropRecord) []DropSummary {
+	type scope struct{ evidence, source, host string }
+	latest := make(map[scope]string)
+	for _, r := range records { // appended in run order
+		latest[scope{r.Evidence, r.Source, r.Host}] = r.Run
+	}
+
+	type key struct{ stage, profile, rule, source, host string }
+	idx := make(map[key]int)
+	out := []DropSummary{}
+	files := make(map[key]map[string]bool)
+	for _, r := range records {
+		if latest[scope{r.Evidence, r.Source, r.Host}] != r.Run {
+			continue
+		}
+		k := key{r.Stage, r.Profile, r.Rule, r.Source, r.Host}
+		i, ok := idx[k]
+		if !ok {
+			i = len(out)
+			idx[k] = i
+			out = append(out, DropSummary{Stage: r.Stage, Profile: r.Profile, Rule: r.Rule, Source: r.Source, Host: r.Host})
+			files[k] = make(map[string]bool)
+		}
+		out[i].Dropped += r.Dropped
+		if r.Evidence != "" {
+			files[k][r.Evidence] = true
+			out[i].Files = len(files[k])
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Dropped > out[j].Dropped })
+	return out
+}
@@ -0,0 +1,31 @@
+package storage
+
+import "testing"
+
+func TestSummarizeDropsLatestRun(t *testing.T) {
+	const security, system = `C:\Logs\Security.evtx`, `C:\Logs\System.evtx`
+	noise := func(run, evidence string, n int) DropRecord {
+		return DropRecord{Run: run, Stage: DropStageNoise, Profile: "standard", Rule: "noisy-logons", Evidence: evidence, Dropped: n}
+	}
+	budget := func(run, host string, n int) DropRecord {
+		return DropRecord{Run: run, Stage: DropStageBudget, Profile: "balanced", Rule: "quota", Source: "EventLog", Host: host, Dropped: n}
+	}
+	records := []DropRecord{
+		noise("run-1", security, 100), noise("run-1", system, 10), budget("run-1", "WS01", 50),
+		// Security.evtx and WS01 are triaged again; System.evtx is not.
+		noise("run-2", security, 100), budget("run-2", "WS01", 50), budget("run-2", "WS02", 5),
+	}
+	got := SummarizeDrops(records)
+	if len(got) != 3 {
+		t.Fatalf("summary = %+v", got)
+	}
+	if s := got[0]; s.Rule != "noisy-logons" || s.Dropped != 110 || s.Files != 2 {
+		t.Errorf("noise = %+v, want 110 events from 2 files", s)
+	}
+	if s := got[1]; s.Host != "WS01" || s.Dropped != 50 {
+		t.Errorf("budget WS01 = %+v, want 50", s)
+	}
+	if s := got[2]; s.Host != "WS02" || s.Dropped != 5 {
+		t.Errorf("budget WS02 = %+v, want 5", s)
+	}
+}
@@ -7,11 +7,14 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync" // Removed sort
+	"time"
 
+	"gtrace/internal/query"
 	"gtrace/pkg/model"
 
 	_ "modernc.org/sqlite"
@@ -45,7 +48,7 @@ func (f *FileStorage) ensureFiles() error {
 	if err := os.MkdirAll(f.dataDir(), 0o755); err != nil {
 		return fmt.Errorf("create data dir: %w", err)
 	}
-	files := []string{"artifacts.jsonl", "timeline.jsonl", "findings.jsonl", "evidence.jsonl"}
+	files := []string{"artifacts.jsonl", "timeline.jsonl", "findings.jsonl", "evidence.jsonl", annotationsFile, provenanceFile, hostsFile, dropsFile}
 	for _, name := range files {
 		p := filepath.Join(f.dataDir(), name)
 		if _, err := os.Stat(p); err != nil {
@@ -84,12 +87,35 @@ func (f *FileStorage) Reset(ctx context.Context) error {
 func (f *FileStorage) RegisterEvidence(ctx context.Context, loc EvidenceLocation) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	record := map[string]any{
-		"path":       loc.Path,
-		"size_bytes": loc.SizeBytes,
-		"is_dir":     loc.IsDir,
+	if loc.RegisteredAt.IsZero() {
+		loc.RegisteredAt = time.Now().UTC()
 	}
-	return f.appendJSONL("evidence.jsonl", record)
+	return f.appendJSONL("evidence.jsonl", loc)
+}
+
+// QueryEvidence returns the evidence registered in the case, in registration order.
+func (f *FileStorage) QueryEvidence(ctx context.Context) ([]EvidenceLocation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.Open(filepath.Join(f.dataDir(), "evidence.jsonl"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var out []EvidenceLocation
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var loc EvidenceLocation
+		if err := json.Unmarshal(scanner.Bytes(), &loc); err != nil {
+			continue
+		}
+		out = append(out, loc)
+	}
+	return out, scanner.Err()
 }
 
 func (f *FileStorage) SaveArtifacts(ctx context.Context, artifacts []model.Artifact) error {
@@ -103,15 +129,19 @@ func (f *FileStorage) SaveArtifacts(ctx context.Context, artifacts []model.Artif
 	return nil
 }
 
+// SaveTimeline appends events, skipping records already stored (see TimelineWriter).
 func (f *FileStorage) SaveTimeline(ctx context.Context, events []model.TimelineEvent) error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
+	w, err := f.NewTimelineWriter(ctx)
+	if err != nil {
+		return err
+	}
 	for _, e := range events {
-		if err := f.appendJSONL("timeline.jsonl", e); err != nil {
+		if _, err := w.Write(e); err != nil {
+			w.Close()
 			return err
 		}
 	}
-	return nil
+	return w.Close()
 }
 
 func (f *FileStorage) SaveFindings(ctx context.Context, findings []model.Finding) error {
@@ -125,6 +155,25 @@ func (f *FileStorage) SaveFindings(ctx context.Context, findings []model.Finding
 	return nil
 }
 
+// findingRecord is a line of findings.jsonl: a finding, or the retraction of one.
+type findingRecord struct {
+	model.Finding
+	Retracted bool `json:"retracted,omitempty"`
+}
+
+// RetractFindings withdraws findings that a re-run no longer produces, such as a hunt that
+// stopped matching. Saving a finding under the same ID later brings it back.
+func (f *FileStorage) RetractFindings(ctx context.Context, ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range ids {
+		if err := f.appendJSONL("findings.jsonl", findingRecord{Finding: model.Finding{ID: id}, Retracted: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // NewStreamWriter creates a buffered writer for efficient bulk ingestion.
 // Caller is responsible for calling closeFunc.
 func (f *FileStorage) NewStreamWriter(name string) (writeFunc func(v any) error, closeFunc func() error, err error) {
@@ -175,25 +224,6 @@ func (f *FileStorage) SearchTimeline(ctx context.Context, filter *model.Timeline
 	if filter == nil {
 		return nil, fmt.Errorf("filter required")
 	}
-	f.mu.Lock()
-	defer f.mu.Unlock()
-
-	path := filepath.Join(f.dataDir(), "timeline.jsonl")
-	file, err := os.Open(path)
-	if err != nil {
-		// If file doesn't exist, return empty
-		if os.IsNotExist(err) {
-			return []model.TimelineEvent{}, nil
-		}
-		return nil, err
-	}
-	defer file.Close()
-
-	var events []model.TimelineEvent
-	scanner := bufio.NewScanner(file)
-	// Handle huge lines
-	buf := make([]byte, 0, 1024*1024)
-	scanner.Buffer(buf, 10*1024*1024) // 10MB max
 
 	page := filter.Page
 	if page < 1 {
@@ -206,53 +236,120 @@ func (f *FileStorage) SearchTimeline(ctx context.Context, filter *model.Timeline
 
 	offset := (page - 1) * pageSize
 	skipped := 0 // Matched but skipped due to pagination
-	count := 0   // Matched and collected
+	events := []model.TimelineEvent{}
+
+	err := f.scanFiltered(ctx, filter, func(ev *model.TimelineEvent) bool {
+		// 3. Pagination
+		if skipped < offset {
+			skipped++
+			return true
+		}
+		events = append(events, *ev)
+		return len(events) < pageSize
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
 
-	// Trim search term
-	filter.SearchTerm = strings.TrimSpace(filter.SearchTerm)
-	termBytes := []byte(strings.ToLower(filter.SearchTerm))
-	hasTerm := len(termBytes) > 0
+// timelineSnapshot is what a timeline scan reads: the annotations and host clocks as of its
+// start and the events stored by then.
+type timelineSnapshot struct {
+	annotations map[string][]model.Annotation
+	clocks      *hostClocks
+	file        *os.File // nil when nothing has been stored yet
+	size        int64
+}
 
-	// Special Filters parsing (e.g. "eid:4688")
-	var explicitEID string
-	var cleanTerm = filter.SearchTerm
+// snapshotTimeline holds f.mu only while opening the timeline, so a scan neither blocks
+// writers nor deadlocks a callback that uses the store. Events appended later are not read.
+// Callers must not hold f.mu.
+func (f *FileStorage) snapshotTimeline() (*timelineSnapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
-	if strings.HasPrefix(strings.ToLower(cleanTerm), "eid:") {
-		explicitEID = strings.TrimPrefix(strings.ToLower(cleanTerm), "eid:")
-		hasTerm = false // Disable generic grep, use specific logic
-	} else if strings.HasPrefix(strings.ToLower(cleanTerm), "id:") {
-		explicitEID = strings.TrimPrefix(strings.ToLower(cleanTerm), "id:")
-		hasTerm = false
+	annotations, err := f.loadAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	clocks, err := f.loadHostClocks()
+	if err != nil {
+		return nil, err
+	}
+	s := &timelineSnapshot{annotations: annotations, clocks: clocks}
+	file, err := os.Open(filepath.Join(f.dataDir(), "timeline.jsonl"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
 	}
+	s.file, s.size = file, info.Size()
+	return s, nil
+}
+
+// scanner reads the snapshot's events line by line, allowing lines up to 10MB.
+func (s *timelineSnapshot) scanner() *bufio.Scanner {
+	scanner := bufio.NewScanner(io.NewSectionReader(s.file, 0, s.size))
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	return scanner
+}
 
-	// If explicit EID, we might want to grep for it too to avoid unmarshal cost
-	// But "EventID" is in Details... "EventID":"4688"
-	var eidBytes []byte
-	if explicitEID != "" {
-		eidBytes = []byte(explicitEID)
+func (s *timelineSnapshot) close() {
+	if s.file != nil {
+		s.file.Close()
+	}
+}
+
+// scanFiltered streams the events matching filter's search term, source, artifact, level and
+// time range to fn until it returns false. Host clocks and annotations are applied. Callers
+// must not hold f.mu.
+func (f *FileStorage) scanFiltered(ctx context.Context, filter *model.TimelineFilter, fn func(ev *model.TimelineEvent) bool) error {
+	// The search term is a query (see package query); free words grep the whole event.
+	q, err := query.Parse(filter.SearchTerm)
+	if err != nil {
+		return err
+	}
+	var literals [][]byte
+	for _, l := range q.Literals() {
+		literals = append(literals, []byte(l))
 	}
 
-	// fmt.Printf("DEBUG SEARCH: Term='%s' Source='%s' EID='%s'\n", filter.SearchTerm, filter.Source, explicitEID)
+	snap, err := f.snapshotTimeline()
+	if err != nil {
+		return err
+	}
+	defer snap.close()
+	if snap.file == nil {
+		return nil // nothing to match
+	}
+	annotations, clocks := snap.annotations, snap.clocks
+	scanner := snap.scanner()
 
+lines:
 	for scanner.Scan() {
 		// Check cancellation
 		if ctx.Err() != nil {
-			return nil, ctx.Err()
+			return ctx.Err()
 		}
 
 		line := scanner.Bytes()
-		lowerLine := bytes.ToLower(line)
 
 		// 1. Fast Filter (Loki Mode)
 		// Optimization: Case-insensitive grep before Unmarshal
-		// We trust that if EID is explicitly requested, "4688" MUST appear in the line.
-		if explicitEID != "" {
-			if !bytes.Contains(lowerLine, eidBytes) {
-				continue
-			}
-		} else if hasTerm {
-			if !bytes.Contains(lowerLine, termBytes) {
-				continue
+		if len(literals) > 0 {
+			lowerLine := bytes.ToLower(line)
+			for _, l := range literals {
+				if !bytes.Contains(lowerLine, l) {
+					continue lines
+				}
 			}
 		}
 
@@ -261,18 +358,6 @@ func (f *FileStorage) SearchTimeline(ctx context.Context, filter *model.Timeline
 			continue
 		}
 
-		// 2. Structured Filters
-		if explicitEID != "" {
-			val, ok := ev.Details["EventID"]
-			// Robust comparison: handle string/int/float types from JSON interface{}
-			if !ok || fmt.Sprintf("%v", val) != explicitEID {
-				continue
-			}
-		}
-
-		// Prevent unused var error for eidBytes (temporary hack or logic restoration)
-		_ = eidBytes
-
 		// 2. Structured Filters
 		if filter.Artifact != "" && ev.Artifact != filter.Artifact {
 			continue
@@ -291,6 +376,7 @@ func (f *FileStorage) SearchTimeline(ctx context.Context, filter *model.Timeline
 			}
 		}
 
+		clocks.apply(&ev)
 		if filter.TimeStart != nil && ev.EventTime.Before(*filter.TimeStart) {
 			continue
 		}
@@ -298,25 +384,15 @@ func (f *FileStorage) SearchTimeline(ctx context.Context, filter *model.Timeline
 			continue
 		}
 
-		// 3. Pagination
-		if skipped < offset {
-			skipped++
+		ev.Annotations = annotations[annotationKey(TargetEvent, ev.ID)]
+		if !q.Match(&ev) {
 			continue
 		}
-
-		events = append(events, ev)
-		count++
-
-		if count >= pageSize {
+		if !fn(&ev) {
 			break
 		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	return events, nil
+	return scanner.Err()
 }
 
 // Legacy wrapper
@@ -325,6 +401,55 @@ func (f *FileStorage) QueryTimeline(ctx context.Context, filter *model.TimelineF
 	return f.SearchTimeline(ctx, filter)
 }
 
+// ScanMatching streams the events matching filter, as SearchTimeline finds them but without
+// paging, to fn in file order. Returning an error from fn stops the scan and is passed back
+// to the caller.
+func (f *FileStorage) ScanMatching(ctx context.Context, filter *model.TimelineFilter, fn func(ev model.TimelineEvent) error) error {
+	if filter == nil {
+		return fmt.Errorf("filter required")
+	}
+
+	var fnErr error
+	err := f.scanFiltered(ctx, filter, func(ev *model.TimelineEvent) bool {
+		fnErr = fn(*ev)
+		return fnErr == nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+// ScanTimeline streams every event stored when the scan starts to fn in file order. fn may
+// use the store. Returning an error from fn stops the scan and is passed back to the caller.
+func (f *FileStorage) ScanTimeline(ctx context.Context, fn func(ev model.TimelineEvent) error) error {
+	snap, err := f.snapshotTimeline()
+	if err != nil {
+		return err
+	}
+	defer snap.close()
+	if snap.file == nil {
+		return nil
+	}
+
+	scanner := snap.scanner()
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var ev model.TimelineEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		snap.clocks.apply(&ev)
+		ev.Annotations = snap.annotations[annotationKey(TargetEvent, ev.ID)]
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
 // CountTimelineEvents returns the total number of events in storage.
 func (f *FileStorage) CountTimelineEvents(ctx context.Context) (int, error) {
 	f.mu.Lock()
@@ -406,19 +531,49 @@ func (f *FileStorage) QueryFindings(ctx context.Context) ([]model.Finding, error
 	}
 	defer file.Close()
 
+	annotations, err := f.loadAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	// A finding saved again under the same ID (a re-run analyzer or hunt) replaces the
+	// earlier record in place; a retraction removes it.
 	var findings []model.Finding
+	var live []bool
+	index := make(map[string]int)
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		var fi model.Finding
-		if err := json.Unmarshal(scanner.Bytes(), &fi); err != nil {
+		var rec findingRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		fi := rec.Finding
+		i, ok := index[fi.ID]
+		if rec.Retracted {
+			if ok {
+				live[i] = false
+			}
 			continue
 		}
+		fi.Annotations = annotations[annotationKey(TargetFinding, fi.ID)]
+		if ok && fi.ID != "" {
+			findings[i], live[i] = fi, true
+			continue
+		}
+		index[fi.ID] = len(findings)
 		findings = append(findings, fi)
+		live = append(live, true)
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
-	return findings, nil
+	out := findings[:0]
+	for i, fi := range findings {
+		if live[i] {
+			out = append(out, fi)
+		}
+	}
+	return out, nil
 }
 
 func (f *FileStorage) ExecuteSQLQuery(ctx context.Context, query string) ([]map[string]any, error) {
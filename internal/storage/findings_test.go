@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"gtrace/pkg/model"
+)
+
+func TestFindingsReplaceAndRetract(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func() []string {
+		t.Helper()
+		findings, err := s.QueryFindings(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, f := range findings {
+			out = append(out, f.ID+":"+f.Title)
+		}
+		return out
+	}
+	same := func(got []string, want ...string) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := s.SaveFindings(ctx, []model.Finding{
+		{ID: "hunt-a", Title: "1 hit"},
+		{ID: "hunt-b", Title: "2 hits"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// A re-run: hunt-a matches more, hunt-b no longer matches.
+	if err := s.RetractFindings(ctx, []string{"hunt-b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveFindings(ctx, []model.Finding{{ID: "hunt-a", Title: "3 hits"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(); !same(got, "hunt-a:3 hits") {
+		t.Fatalf("findings = %v", got)
+	}
+
+	// A retracted finding that matches again comes back.
+	if err := s.SaveFindings(ctx, []model.Finding{{ID: "hunt-b", Title: "1 hit"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(); !same(got, "hunt-a:3 hits", "hunt-b:1 hit") {
+		t.Fatalf("findings = %v", got)
+	}
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+const hostsFile = "hosts.jsonl"
+
+// HostClock is what the case knows about a host's clock: its time zone, read from the SYSTEM
+// hive, and a skew set by the examiner. Host "*" applies to hosts without an entry of their own.
+type HostClock struct {
+	Host        string              `json:"host"`
+	Timezone    *model.HostTimezone `json:"timezone,omitempty"`
+	SkewSeconds int64               `json:"skew_seconds"` // how far the host clock ran ahead of true time
+	SkewReason  string              `json:"skew_reason,omitempty"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// hostRecord is one line of the hosts log; nil fields leave the previous value.
+type hostRecord struct {
+	Host        string              `json:"host"`
+	Timezone    *model.HostTimezone `json:"timezone,omitempty"`
+	SkewSeconds *int64              `json:"skew_seconds,omitempty"`
+	SkewReason  string              `json:"skew_reason,omitempty"`
+	Author      string              `json:"author,omitempty"`
+	At          time.Time           `json:"at"`
+}
+
+// SetHostTimezone records the time zone a host was configured with.
+func (f *FileStorage) SetHostTimezone(ctx context.Context, host string, tz model.HostTimezone) error {
+	if host == "" {
+		return fmt.Errorf("host required")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.appendJSONL(hostsFile, hostRecord{Host: host, Timezone: &tz, At: time.Now().UTC()})
+}
+
+// SetHostSkew records how far a host's clock ran ahead of true time (negative when it ran
+// behind). Event times of the host are corrected when they are read, never rewritten.
+func (f *FileStorage) SetHostSkew(ctx context.Context, host string, skew time.Duration, reason, author string) error {
+	if host == "" {
+		return fmt.Errorf("host required")
+	}
+	secs := int64(skew / time.Second)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.appendJSONL(hostsFile, hostRecord{Host: host, SkewSeconds: &secs, SkewReason: reason, Author: author, At: time.Now().UTC()})
+}
+
+// QueryHostClocks returns the clock settings of every known host, sorted by name.
+func (f *FileStorage) QueryHostClocks(ctx context.Context) ([]HostClock, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	idx, err := f.loadHostClocks()
+	if err != nil {
+		return nil, err
+	}
+	return idx.list, nil
+}
+
+// hostClocks resolves the clock settings that apply to an event.
+type hostClocks struct {
+	list   []HostClock
+	byName map[string]*HostClock // short lower-case host name
+}
+
+// loadHostClocks folds the hosts log. Callers hold f.mu.
+func (f *FileStorage) loadHostClocks() (*hostClocks, error) {
+	clocks := make(map[string]*HostClock)
+	err := scanJSONL(filepath.Join(f.dataDir(), hostsFile), func(line []byte) {
+		var r hostRecord
+		if json.Unmarshal(line, &r) != nil || r.Host == "" {
+			return
+		}
+		key := shortHost(r.Host)
+		c := clocks[key]
+		if c == nil {
+			c = &HostClock{Host: r.Host}
+			clocks[key] = c
+		}
+		if r.Timezone != nil {
+			c.Timezone = r.Timezone
+		}
+		if r.SkewSeconds != nil {
+			c.SkewSeconds = *r.SkewSeconds
+			c.SkewReason = r.SkewReason
+		}
+		c.UpdatedAt = r.At
+	})
+	if err != nil {
+		return nil, err
+	}
+	idx := &hostClocks{byName: make(map[string]*HostClock)}
+	for _, c := range clocks {
+		idx.list = append(idx.list, *c)
+	}
+	sort.Slice(idx.list, func(i, j int) bool { return idx.list[i].Host < idx.list[j].Host })
+	for i := range idx.list {
+		idx.byName[shortHost(idx.list[i].Host)] = &idx.list[i]
+	}
+	return idx, nil
+}
+
+// shortHost reduces "WS01.corp.local" to "ws01", so that the SYSTEM hive's ComputerName and
+// the FQDN in event logs name the same host.
+func shortHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "*" {
+		return host
+	}
+	if i := strings.IndexByte(host, '.'); i > 0 {
+		host = host[:i]
+	}
+	return host
+}
+
+// lookup returns the settings for host. Events that name no host belong to the only host of
+// a single-host case.
+func (h *hostClocks) lookup(host string) *HostClock {
+	if c := h.byName[shortHost(host)]; c != nil && host != "" {
+		return c
+	}
+	if host == "" {
+		var only *HostClock
+		for i := range h.list {
+			if h.list[i].Host != "*" {
+				if only != nil {
+					only = nil
+					break
+				}
+				only = &h.list[i]
+			}
+		}
+		if only != nil {
+			return only
+		}
+	}
+	return h.byName["*"]
+}
+
+// apply corrects ev for its host's clock skew and sets its host UTC offset. Times parsers
+// could only read in host-local time are converted to UTC here.
+func (h *hostClocks) apply(ev *model.TimelineEvent) {
+	if len(h.list) == 0 {
+		return
+	}
+	c := h.lookup(ev.Host())
+	if c == nil {
+		return
+	}
+	if c.Timezone != nil && ev.Details["TimeBase"] == "host-local" {
+		ev.EventTime = c.Timezone.ToUTC(ev.EventTime)
+		ev.Details["TimeBase"] = "converted-from-host-local"
+	}
+	if c.SkewSeconds != 0 && !ev.EventTime.IsZero() {
+		ev.EventTime = ev.EventTime.Add(-time.Duration(c.SkewSeconds) * time.Second)
+		ev.SkewSeconds = c.SkewSeconds
+	}
+	if c.Timezone != nil {
+		ev.UTCOffset = c.Timezone.OffsetAt(ev.EventTime)
+	}
+}
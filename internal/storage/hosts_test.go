@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+func TestHostClocksAppliedAtQueryTime(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)
+	if err := s.SaveTimeline(ctx, []model.TimelineEvent{
+		{ID: "ev-1", EventTime: when, Source: "EventLog", Details: map[string]string{"Computer": "WS01.corp.local"}},
+		{ID: "ev-2", EventTime: when, Source: "EventLog", Details: map[string]string{"Computer": "DC01.corp.local"}},
+		// Non-EVTX events carry the host stamped at ingest from their collection.
+		{ID: "ev-3", EventTime: when, Source: "Prefetch", Details: map[string]string{"Hostname": "WS01"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetHostTimezone(ctx, "WS01", model.HostTimezone{Name: "Tokyo Standard Time", Bias: -540}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetHostSkew(ctx, "ws01", 90*time.Second, "NTP offset in System log", "jdoe"); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := s.QueryTimeline(ctx, &model.TimelineFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events", len(events))
+	}
+	byID := make(map[string]model.TimelineEvent)
+	for _, ev := range events {
+		byID[ev.ID] = ev
+	}
+	ws, dc := byID["ev-1"], byID["ev-2"]
+	if !ws.EventTime.Equal(when.Add(-90*time.Second)) || ws.SkewSeconds != 90 || ws.UTCOffset != 540 {
+		t.Errorf("WS01 event = %s skew=%d offset=%d", ws.EventTime, ws.SkewSeconds, ws.UTCOffset)
+	}
+	if !dc.EventTime.Equal(when) || dc.SkewSeconds != 0 || dc.UTCOffset != 0 {
+		t.Errorf("DC01 event changed: %s skew=%d offset=%d", dc.EventTime, dc.SkewSeconds, dc.UTCOffset)
+	}
+	if pf := byID["ev-3"]; !pf.EventTime.Equal(when.Add(-90*time.Second)) || pf.UTCOffset != 540 {
+		t.Errorf("WS01 Prefetch event = %s skew=%d offset=%d", pf.EventTime, pf.SkewSeconds, pf.UTCOffset)
+	}
+
+	// The time filter sees corrected times.
+	end := when.Add(-time.Minute)
+	events, err = s.QueryTimeline(ctx, &model.TimelineFilter{TimeEnd: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].ID == "ev-2" || events[1].ID == "ev-2" {
+		t.Errorf("filtered events = %+v", events)
+	}
+
+	clocks, err := s.QueryHostClocks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clocks) != 1 || clocks[0].Timezone == nil || clocks[0].SkewSeconds != 90 {
+		t.Errorf("clocks = %+v", clocks)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"gtrace/pkg/model"
 )
@@ -14,17 +15,29 @@ type Storage interface {
 	RegisterEvidence(ctx context.Context, loc EvidenceLocation) error
 	SaveArtifacts(ctx context.Context, artifacts []model.Artifact) error
 	SaveTimeline(ctx context.Context, events []model.TimelineEvent) error
+	NewTimelineWriter(ctx context.Context) (EventWriter, error)
 	SaveFindings(ctx context.Context, findings []model.Finding) error
+	RetractFindings(ctx context.Context, ids []string) error
 	QueryTimeline(ctx context.Context, filter *model.TimelineFilter) ([]model.TimelineEvent, error)
 	QueryFindings(ctx context.Context) ([]model.Finding, error)
+	AddAnnotation(ctx context.Context, a model.Annotation) (model.Annotation, error)
+	DeleteAnnotation(ctx context.Context, id, author string) error
+	QueryAnnotations(ctx context.Context, targetType, targetID string) ([]model.Annotation, error)
+	SetHostTimezone(ctx context.Context, host string, tz model.HostTimezone) error
+	RecordDrops(ctx context.Context, records []DropRecord) error
 	NewStreamWriter(name string) (writeFunc func(v any) error, closeFunc func() error, err error)
 }
 
 // EvidenceLocation is a minimal record of imported evidence paths.
 type EvidenceLocation struct {
-	Path      string
-	SizeBytes int64
-	IsDir     bool
+	Path         string    `json:"path"`
+	SizeBytes    int64     `json:"size_bytes"`
+	IsDir        bool      `json:"is_dir"`
+	SHA256       string    `json:"sha256,omitempty"`
+	Parsers      []string  `json:"parsers,omitempty"`
+	RegisteredAt time.Time `json:"registered_at,omitempty"`
+	// LiveHost is the machine live triage read the file from; empty for collected evidence.
+	LiveHost string `json:"live_host,omitempty"`
 }
 
 // sqliteStub is a lightweight in-memory placeholder until real DB wiring exists.
@@ -33,6 +46,7 @@ type sqliteStub struct {
 	events    []model.TimelineEvent
 	artifacts []model.Artifact
 	findings  []model.Finding
+	notes     []model.Annotation
 }
 
 // NewSQLiteStub returns a placeholder that satisfies Storage without external deps.
@@ -66,6 +80,43 @@ func (s *sqliteStub) SaveTimeline(ctx context.Context, events []model.TimelineEv
 	return nil
 }
 
+func (s *sqliteStub) SetHostTimezone(ctx context.Context, host string, tz model.HostTimezone) error {
+	return nil
+}
+
+func (s *sqliteStub) RecordDrops(ctx context.Context, records []DropRecord) error {
+	return nil
+}
+
+func (s *sqliteStub) NewTimelineWriter(ctx context.Context) (EventWriter, error) {
+	return &stubWriter{s: s, seen: make(map[string]bool)}, nil
+}
+
+type stubWriter struct {
+	s                   *sqliteStub
+	seen                map[string]bool
+	written, duplicates int
+}
+
+func (w *stubWriter) Write(ev model.TimelineEvent) (bool, error) {
+	w.s.mu.Lock()
+	defer w.s.mu.Unlock()
+	if ev.Fingerprint != "" {
+		if w.seen[dedupKey(ev)] {
+			w.duplicates++
+			return false, nil
+		}
+		w.seen[dedupKey(ev)] = true
+	}
+	w.s.events = append(w.s.events, ev)
+	w.written++
+	return true, nil
+}
+
+func (w *stubWriter) Stats() (int, int) { return w.written, w.duplicates }
+
+func (w *stubWriter) Close() error { return nil }
+
 func (s *sqliteStub) SaveFindings(ctx context.Context, findings []model.Finding) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -73,6 +124,23 @@ func (s *sqliteStub) SaveFindings(ctx context.Context, findings []model.Finding)
 	return nil
 }
 
+func (s *sqliteStub) RetractFindings(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	retracted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		retracted[id] = true
+	}
+	kept := s.findings[:0]
+	for _, f := range s.findings {
+		if !retracted[f.ID] {
+			kept = append(kept, f)
+		}
+	}
+	s.findings = kept
+	return nil
+}
+
 func (s *sqliteStub) QueryTimeline(ctx context.Context, filter *model.TimelineFilter) ([]model.TimelineEvent, error) {
 	if filter == nil {
 		return nil, errors.New("filter required")
@@ -88,6 +156,42 @@ func (s *sqliteStub) QueryFindings(ctx context.Context) ([]model.Finding, error)
 	return append([]model.Finding(nil), s.findings...), nil
 }
 
+func (s *sqliteStub) AddAnnotation(ctx context.Context, a model.Annotation) (model.Annotation, error) {
+	if err := ValidateAnnotation(&a); err != nil {
+		return a, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a.ID = newAnnotationID()
+	a.CreatedAt = time.Now().UTC()
+	s.notes = append(s.notes, a)
+	return a, nil
+}
+
+func (s *sqliteStub) DeleteAnnotation(ctx context.Context, id, author string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, a := range s.notes {
+		if a.ID == id {
+			s.notes = append(s.notes[:i], s.notes[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("annotation not found")
+}
+
+func (s *sqliteStub) QueryAnnotations(ctx context.Context, targetType, targetID string) ([]model.Annotation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []model.Annotation
+	for _, a := range s.notes {
+		if targetType == "" || (a.TargetType == targetType && a.TargetID == targetID) {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
 func (s *sqliteStub) NewStreamWriter(name string) (func(v any) error, func() error, error) {
 	return func(v any) error { return nil }, func() error { return nil }, nil
 }
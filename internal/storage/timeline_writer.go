@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+const provenanceFile = "provenance.jsonl"
+
+// Sighting records that an already-stored event was read again from other evidence,
+// e.g. a second copy of the same Security.evtx in an overlapping collection.
+type Sighting struct {
+	EventID     string            `json:"event_id"`
+	EvidenceRef model.EvidenceRef `json:"evidence_ref"`
+	SeenAt      time.Time         `json:"seen_at"`
+}
+
+// EventWriter appends events to a case timeline. Write reports false for a record that is
+// already stored.
+type EventWriter interface {
+	Write(ev model.TimelineEvent) (bool, error)
+	Stats() (written, duplicates int)
+	Close() error
+}
+
+// TimelineWriter appends events to the case timeline and skips records that are already
+// stored, matching on the host and model.TimelineEvent.Fingerprint (see dedupKey). A skipped
+// record read from evidence not seen before is kept as a Sighting of the stored event.
+type TimelineWriter struct {
+	f          *FileStorage
+	file       *os.File
+	bw         *bufio.Writer
+	seen       map[string]string // dedupKey → stored event ID
+	sightings  map[string]bool   // event ID + evidence → already recorded
+	written    int
+	duplicates int
+}
+
+// NewTimelineWriter opens the timeline for appending and indexes what it already holds.
+// The writer holds no lock between calls; use one writer per ingest.
+func (f *FileStorage) NewTimelineWriter(ctx context.Context) (EventWriter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.ensureFiles(); err != nil {
+		return nil, err
+	}
+	w := &TimelineWriter{f: f, seen: make(map[string]string), sightings: make(map[string]bool)}
+
+	path := filepath.Join(f.dataDir(), "timeline.jsonl")
+	if err := scanJSONL(path, func(line []byte) {
+		var ev model.TimelineEvent
+		if json.Unmarshal(line, &ev) == nil && ev.Fingerprint != "" {
+			w.seen[dedupKey(ev)] = ev.ID
+			w.sightings[sightingKey(ev.ID, ev.EvidenceRef)] = true
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if err := scanJSONL(filepath.Join(f.dataDir(), provenanceFile), func(line []byte) {
+		var s Sighting
+		if json.Unmarshal(line, &s) == nil {
+			w.sightings[sightingKey(s.EventID, s.EvidenceRef)] = true
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w.file = file
+	w.bw = bufio.NewWriter(file)
+	return w, nil
+}
+
+// dedupKey is what makes two records the same stored event: the fingerprint on the same host.
+// Fingerprints leave the evidence path out, and records of hives, Prefetch or the file system
+// name no host until the pipeline stamps the collection's one; without the host the same
+// service installed on two machines would be stored once.
+func dedupKey(ev model.TimelineEvent) string {
+	return strings.ToLower(ev.Host()) + "|" + ev.Fingerprint
+}
+
+func sightingKey(id string, ref model.EvidenceRef) string {
+	return id + "|" + ref.SourcePath + "|" + ref.SHA256
+}
+
+// Write stores ev unless its fingerprint is already in the timeline for the same host, and
+// reports whether it was written. Events without a fingerprint are always written.
+func (w *TimelineWriter) Write(ev model.TimelineEvent) (bool, error) {
+	if ev.Fingerprint != "" {
+		key := dedupKey(ev)
+		if id, dup := w.seen[key]; dup {
+			w.duplicates++
+			sk := sightingKey(id, ev.EvidenceRef)
+			if w.sightings[sk] {
+				return false, nil
+			}
+			w.sightings[sk] = true
+			w.f.mu.Lock()
+			defer w.f.mu.Unlock()
+			return false, w.f.appendJSONL(provenanceFile, Sighting{EventID: id, EvidenceRef: ev.EvidenceRef, SeenAt: time.Now().UTC()})
+		}
+		w.seen[key] = ev.ID
+		w.sightings[sightingKey(ev.ID, ev.EvidenceRef)] = true
+	}
+
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return false, err
+	}
+	if _, err := w.bw.Write(append(b, '\n')); err != nil {
+		return false, err
+	}
+	w.written++
+	return true, nil
+}
+
+// Stats returns how many events were written and how many were skipped as duplicates.
+func (w *TimelineWriter) Stats() (written, duplicates int) {
+	return w.written, w.duplicates
+}
+
+// Close flushes and closes the timeline.
+func (w *TimelineWriter) Close() error {
+	if err := w.bw.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// QueryProvenance returns every piece of evidence an event was read from: the evidence it
+// was stored from first, then later sightings.
+func (f *FileStorage) QueryProvenance(ctx context.Context, eventID string) ([]model.EvidenceRef, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var refs []model.EvidenceRef
+	err := scanJSONL(filepath.Join(f.dataDir(), "timeline.jsonl"), func(line []byte) {
+		if len(refs) > 0 {
+			return
+		}
+		var ev struct {
+			ID          string            `json:"id"`
+			EvidenceRef model.EvidenceRef `json:"evidence_ref"`
+		}
+		if json.Unmarshal(line, &ev) == nil && ev.ID == eventID {
+			refs = append(refs, ev.EvidenceRef)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = scanJSONL(filepath.Join(f.dataDir(), provenanceFile), func(line []byte) {
+		var s Sighting
+		if json.Unmarshal(line, &s) == nil && s.EventID == eventID {
+			refs = append(refs, s.EvidenceRef)
+		}
+	})
+	return refs, err
+}
+
+// scanJSONL calls fn for each line of a JSONL file; a missing file has no lines.
+func scanJSONL(path string, fn func(line []byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		fn(scanner.Bytes())
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+func TestTimelineWriterDedup(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	when := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	record := func(path, sha string, recordID string) model.TimelineEvent {
+		ev := model.TimelineEvent{
+			EventTime:   when,
+			Source:      "EventLog",
+			Action:      "Logon",
+			Subject:     "alice",
+			Details:     map[string]string{"EventID": "4624", "EventRecordID": recordID},
+			EvidenceRef: model.EvidenceRef{SourcePath: path, SHA256: sha, Offset: 4096},
+		}
+		ev.Fingerprint = model.EventFingerprint("evtx", ev)
+		ev.ID = model.StableEventID(sha, "evtx", ev.EvidenceRef.Offset, ev.Fingerprint)
+		return ev
+	}
+
+	// Same Security.evtx collected twice (live and from a VSS copy) plus one unrelated record.
+	first := record(`C:\Windows\System32\winevt\Logs\Security.evtx`, "aaaa", "17")
+	copyOf := record(`E:\vss1\Security.evtx`, "bbbb", "17")
+	other := record(`C:\Windows\System32\winevt\Logs\Security.evtx`, "aaaa", "18")
+	if first.Fingerprint != copyOf.Fingerprint {
+		t.Fatal("same record fingerprints differently across copies")
+	}
+	if first.ID != record(first.EvidenceRef.SourcePath, "aaaa", "17").ID {
+		t.Fatal("event ID not stable")
+	}
+
+	w, err := s.NewTimelineWriter(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []model.TimelineEvent{first, copyOf, other, copyOf} {
+		if _, err := w.Write(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if written, dups := w.Stats(); written != 2 || dups != 2 {
+		t.Fatalf("written=%d duplicates=%d, want 2 and 2", written, dups)
+	}
+
+	// A re-run over the same evidence adds nothing.
+	if err := s.SaveTimeline(ctx, []model.TimelineEvent{first, other}); err != nil {
+		t.Fatal(err)
+	}
+	events, err := s.QueryTimeline(ctx, &model.TimelineFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("timeline has %d events, want 2", len(events))
+	}
+
+	refs, err := s.QueryProvenance(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 || refs[0].SHA256 != "aaaa" || refs[1].SHA256 != "bbbb" {
+		t.Fatalf("provenance = %+v", refs)
+	}
+}
+
+func TestTimelineWriterKeepsRepeatedRecords(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InitCase(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two visits to the same URL in the same second: identical rows with no record number.
+	visit := func(sha string, repeat int) model.TimelineEvent {
+		ev := model.TimelineEvent{
+			EventTime:   time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			Source:      "Browser",
+			Action:      "Visit",
+			Subject:     "https://example.com/",
+			EvidenceRef: model.EvidenceRef{SourcePath: "History", SHA256: sha},
+		}
+		ev.Fingerprint = model.RepeatFingerprint(model.EventFingerprint("browser", ev), repeat)
+		ev.ID = model.StableEventID(sha, "browser", 0, ev.Fingerprint)
+		return ev
+	}
+	if visit("aaaa", 0).Fingerprint != model.EventFingerprint("browser", visit("aaaa", 0)) {
+		t.Fatal("the first occurrence must keep the plain fingerprint")
+	}
+
+	w, err := s.NewTimelineWriter(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second copy of the database repeats the rows the same way and adds nothing.
+	for _, ev := range []model.TimelineEvent{visit("aaaa", 0), visit("aaaa", 1), visit("bbbb", 0), visit("bbbb", 1)} {
+		if _, err := w.Write(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if written, dups := w.Stats(); written != 2 || dups != 2 {
+		t.Fatalf("written=%d duplicates=%d, want 2 and 2", written, dups)
+	}
+}
@@ -0,0 +1,592 @@
+package yara
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// value is the result of a condition expression. Booleans are 0 and 1; undefined values,
+// such as reads past the end of the file, make comparisons and arithmetic undefined and
+// count as false, as in YARA.
+type value struct {
+	n     int64
+	undef bool
+}
+
+var undefined = value{undef: true}
+
+func boolValue(b bool) value {
+	if b {
+		return value{n: 1}
+	}
+	return value{}
+}
+
+func truth(v value) bool {
+	return !v.undef && v.n != 0
+}
+
+type node interface {
+	eval(sc *scanCtx) value
+}
+
+type numNode int64
+
+func (n numNode) eval(*scanCtx) value { return value{n: int64(n)} }
+
+type filesizeNode struct{}
+
+func (filesizeNode) eval(sc *scanCtx) value { return value{n: int64(len(sc.data))} }
+
+type ruleNode string
+
+func (n ruleNode) eval(sc *scanCtx) value {
+	return boolValue(sc.results[ruleKey(sc.namespace, string(n))])
+}
+
+type notNode struct{ x node }
+
+func (n notNode) eval(sc *scanCtx) value {
+	v := n.x.eval(sc)
+	if v.undef {
+		return v
+	}
+	return boolValue(v.n == 0)
+}
+
+type unaryNode struct {
+	op string // - or ~
+	x  node
+}
+
+func (n unaryNode) eval(sc *scanCtx) value {
+	v := n.x.eval(sc)
+	if v.undef {
+		return v
+	}
+	if n.op == "-" {
+		return value{n: -v.n}
+	}
+	return value{n: ^v.n}
+}
+
+type binNode struct {
+	op   string
+	l, r node
+}
+
+func (n binNode) eval(sc *scanCtx) value {
+	switch n.op {
+	case "and":
+		return boolValue(truth(n.l.eval(sc)) && truth(n.r.eval(sc)))
+	case "or":
+		return boolValue(truth(n.l.eval(sc)) || truth(n.r.eval(sc)))
+	}
+	l, r := n.l.eval(sc), n.r.eval(sc)
+	if l.undef || r.undef {
+		return undefined
+	}
+	a, b := l.n, r.n
+	switch n.op {
+	case "==":
+		return boolValue(a == b)
+	case "!=":
+		return boolValue(a != b)
+	case "<":
+		return boolValue(a < b)
+	case "<=":
+		return boolValue(a <= b)
+	case ">":
+		return boolValue(a > b)
+	case ">=":
+		return boolValue(a >= b)
+	case "|":
+		return value{n: a | b}
+	case "^":
+		return value{n: a ^ b}
+	case "&":
+		return value{n: a & b}
+	case "<<", ">>":
+		if b < 0 || b >= 64 {
+			return value{}
+		}
+		if n.op == "<<" {
+			return value{n: a << uint(b)}
+		}
+		return value{n: a >> uint(b)}
+	case "+":
+		return value{n: a + b}
+	case "-":
+		return value{n: a - b}
+	case "*":
+		return value{n: a * b}
+	case "\\", "%":
+		if b == 0 {
+			return undefined
+		}
+		if n.op == "%" {
+			return value{n: a % b}
+		}
+		return value{n: a / b}
+	}
+	return undefined
+}
+
+// readNode is uint8(off) and friends.
+type readNode struct {
+	size   int
+	signed bool
+	be     bool
+	off    node
+}
+
+func (n readNode) eval(sc *scanCtx) value {
+	o := n.off.eval(sc)
+	if o.undef || o.n < 0 || o.n+int64(n.size) > int64(len(sc.data)) {
+		return undefined
+	}
+	b := sc.data[o.n : o.n+int64(n.size)]
+	var order binary.ByteOrder = binary.LittleEndian
+	if n.be {
+		order = binary.BigEndian
+	}
+	switch n.size {
+	case 1:
+		if n.signed {
+			return value{n: int64(int8(b[0]))}
+		}
+		return value{n: int64(b[0])}
+	case 2:
+		if n.signed {
+			return value{n: int64(int16(order.Uint16(b)))}
+		}
+		return value{n: int64(order.Uint16(b))}
+	}
+	if n.signed {
+		return value{n: int64(int32(order.Uint32(b)))}
+	}
+	return value{n: int64(order.Uint32(b))}
+}
+
+// span is an "in (lo..hi)" range; nil means the whole file.
+type span struct{ lo, hi node }
+
+func (s *span) contains(sc *scanCtx, off int) bool {
+	if s == nil {
+		return true
+	}
+	lo, hi := s.lo.eval(sc), s.hi.eval(sc)
+	return !lo.undef && !hi.undef && int64(off) >= lo.n && int64(off) <= hi.n
+}
+
+// strNode is $a, $a at N or $a in (lo..hi).
+type strNode struct {
+	id string
+	at node
+	in *span
+}
+
+func (n strNode) eval(sc *scanCtx) value {
+	h := sc.strings[n.id]
+	if n.at != nil {
+		at := n.at.eval(sc)
+		if at.undef {
+			return value{}
+		}
+		for _, off := range h.offsets {
+			if int64(off) == at.n {
+				return value{n: 1}
+			}
+		}
+		return value{}
+	}
+	for _, off := range h.offsets {
+		if n.in.contains(sc, off) {
+			return value{n: 1}
+		}
+	}
+	return value{}
+}
+
+// countNode is #a, optionally in a range.
+type countNode struct {
+	id string
+	in *span
+}
+
+func (n countNode) eval(sc *scanCtx) value {
+	c := 0
+	for _, off := range sc.strings[n.id].offsets {
+		if n.in.contains(sc, off) {
+			c++
+		}
+	}
+	return value{n: int64(c)}
+}
+
+// offsetNode is @a[i], or !a[i] for the match length; i counts from 1.
+type offsetNode struct {
+	id     string
+	index  node
+	length bool
+}
+
+func (n offsetNode) eval(sc *scanCtx) value {
+	i := value{n: 1}
+	if n.index != nil {
+		i = n.index.eval(sc)
+	}
+	h := sc.strings[n.id]
+	if i.undef || i.n < 1 || i.n > int64(len(h.offsets)) {
+		return undefined
+	}
+	if n.length {
+		return value{n: int64(h.lengths[i.n-1])}
+	}
+	return value{n: int64(h.offsets[i.n-1])}
+}
+
+// ofNode is "any/all/none/N of" a set of strings or rules.
+type ofNode struct {
+	quant string // any, all or none; empty uses count
+	count node
+	ids   []string // string identifiers, or rule names when rules is set
+	rules bool
+	in    *span
+}
+
+func (n ofNode) eval(sc *scanCtx) value {
+	matched := 0
+	for _, id := range n.ids {
+		if n.rules {
+			if sc.results[ruleKey(sc.namespace, id)] {
+				matched++
+			}
+			continue
+		}
+		if truth(strNode{id: id, in: n.in}.eval(sc)) {
+			matched++
+		}
+	}
+	switch n.quant {
+	case "any":
+		return boolValue(matched > 0)
+	case "all":
+		return boolValue(matched == len(n.ids))
+	case "none":
+		return boolValue(matched == 0)
+	}
+	c := n.count.eval(sc)
+	if c.undef {
+		return value{}
+	}
+	return boolValue(int64(matched) >= c.n)
+}
+
+// Operator precedence, loosest first; the nil level is "not".
+var binLevels = [][]string{
+	{"or"},
+	{"and"},
+	nil,
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "\\", "%"},
+}
+
+func (p *parser) parseCondition() (node, error) {
+	return p.expr(0)
+}
+
+func isOp(t token, ops ...string) bool {
+	if t.kind != tIdent && t.kind != tPunct {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expr(level int) (node, error) {
+	if level == len(binLevels) {
+		return p.unary()
+	}
+	t, err := p.lex.peek(0)
+	if err != nil {
+		return nil, err
+	}
+	if binLevels[level] == nil {
+		if t.kind == tIdent && t.text == "not" {
+			p.lex.next()
+			x, err := p.expr(level)
+			return notNode{x}, err
+		}
+		return p.expr(level + 1)
+	}
+	l, err := p.expr(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, err := p.lex.peek(0)
+		if err != nil {
+			return nil, err
+		}
+		if !isOp(t, binLevels[level]...) {
+			return l, nil
+		}
+		p.lex.next()
+		r, err := p.expr(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = binNode{op: t.text, l: l, r: r}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	t, err := p.lex.peek(0)
+	if err != nil {
+		return nil, err
+	}
+	if t.kind == tPunct && (t.text == "-" || t.text == "~") {
+		p.lex.next()
+		x, err := p.unary()
+		return unaryNode{op: t.text, x: x}, err
+	}
+	return p.primary()
+}
+
+var reads = map[string]readNode{
+	"uint8": {size: 1}, "uint16": {size: 2}, "uint32": {size: 4},
+	"int8": {size: 1, signed: true}, "int16": {size: 2, signed: true}, "int32": {size: 4, signed: true},
+	"uint16be": {size: 2, be: true}, "uint32be": {size: 4, be: true},
+	"int16be": {size: 2, signed: true, be: true}, "int32be": {size: 4, signed: true, be: true},
+}
+
+func (p *parser) primary() (node, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.kind {
+	case tNumber:
+		if next, _ := p.lex.peek(0); isOp(next, "of") {
+			return p.of("", numNode(t.num))
+		}
+		return numNode(t.num), nil
+	case tPunct:
+		if t.text == "(" {
+			x, err := p.expr(0)
+			if err != nil {
+				return nil, err
+			}
+			_, err = p.expect(tPunct, ")")
+			return x, err
+		}
+	case tVar:
+		id, err := p.stringID(t)
+		if err != nil {
+			return nil, err
+		}
+		n := strNode{id: id}
+		next, _ := p.lex.peek(0)
+		switch {
+		case isOp(next, "at"):
+			p.lex.next()
+			n.at, err = p.unary()
+		case isOp(next, "in"):
+			p.lex.next()
+			n.in, err = p.span()
+		}
+		return n, err
+	case tCount:
+		id, err := p.stringID(t)
+		if err != nil {
+			return nil, err
+		}
+		n := countNode{id: id}
+		if next, _ := p.lex.peek(0); isOp(next, "in") {
+			p.lex.next()
+			n.in, err = p.span()
+		}
+		return n, err
+	case tOffset, tLength:
+		id, err := p.stringID(t)
+		if err != nil {
+			return nil, err
+		}
+		n := offsetNode{id: id, length: t.kind == tLength}
+		if next, _ := p.lex.peek(0); isOp(next, "[") {
+			p.lex.next()
+			if n.index, err = p.expr(0); err != nil {
+				return nil, err
+			}
+			_, err = p.expect(tPunct, "]")
+		}
+		return n, err
+	case tIdent:
+		switch t.text {
+		case "true":
+			return numNode(1), nil
+		case "false":
+			return numNode(0), nil
+		case "filesize":
+			return filesizeNode{}, nil
+		case "any", "all", "none":
+			return p.of(t.text, nil)
+		case "for", "entrypoint", "defined", "matches", "contains":
+			return nil, p.errorf(t.line, "%s is not supported by the built-in scanner; use an external yara binary", t.text)
+		}
+		if r, ok := reads[t.text]; ok {
+			if _, err := p.expect(tPunct, "("); err != nil {
+				return nil, err
+			}
+			if r.off, err = p.expr(0); err != nil {
+				return nil, err
+			}
+			_, err = p.expect(tPunct, ")")
+			return r, err
+		}
+		if p.known[t.text] {
+			return ruleNode(t.text), nil
+		}
+		if next, _ := p.lex.peek(0); isOp(next, ".") {
+			return nil, p.errorf(t.line, "modules are not supported by the built-in scanner (%s)", t.text)
+		}
+		return nil, p.errorf(t.line, "undefined identifier %s", t.text)
+	}
+	return nil, p.errorf(t.line, "unexpected %s in condition", t)
+}
+
+// stringID resolves the string a $, #, @ or ! token refers to.
+func (p *parser) stringID(t token) (string, error) {
+	id := "$" + t.text[1:]
+	if id == "$" || strings.HasSuffix(id, "*") {
+		return "", p.errorf(t.line, "%s is only allowed in string sets", t.text)
+	}
+	for _, s := range p.rule.strings {
+		if s.id == id {
+			return id, nil
+		}
+	}
+	return "", p.errorf(t.line, "undefined string identifier %s", id)
+}
+
+func (p *parser) span() (*span, error) {
+	if _, err := p.expect(tPunct, "("); err != nil {
+		return nil, err
+	}
+	lo, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tPunct, ".."); err != nil {
+		return nil, err
+	}
+	hi, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tPunct, ")"); err != nil {
+		return nil, err
+	}
+	return &span{lo: lo, hi: hi}, nil
+}
+
+// of parses the rest of "<quantifier> of them", "... of ($a, $b*)" or "... of (rule1, r*)",
+// with an optional "in (lo..hi)" for strings.
+func (p *parser) of(quant string, count node) (node, error) {
+	if _, err := p.expect(tIdent, "of"); err != nil {
+		return nil, err
+	}
+	n := ofNode{quant: quant, count: count}
+	t, err := p.lex.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.kind == tIdent && t.text == "them":
+		for _, s := range p.rule.strings {
+			n.ids = append(n.ids, s.id)
+		}
+	case t.kind == tPunct && t.text == "(":
+		for {
+			item, err := p.lex.next()
+			if err != nil {
+				return nil, err
+			}
+			ids, err := p.setItem(item, &n)
+			if err != nil {
+				return nil, err
+			}
+			n.ids = append(n.ids, ids...)
+			sep, err := p.lex.next()
+			if err != nil {
+				return nil, err
+			}
+			if sep.kind == tPunct && sep.text == ")" {
+				break
+			}
+			if sep.kind != tPunct || sep.text != "," {
+				return nil, p.errorf(sep.line, "expected , or ), found %s", sep)
+			}
+		}
+	default:
+		return nil, p.errorf(t.line, "expected them or a set, found %s", t)
+	}
+	if len(n.ids) == 0 {
+		return nil, p.errorf(t.line, "empty set")
+	}
+	if next, _ := p.lex.peek(0); isOp(next, "in") && !n.rules {
+		p.lex.next()
+		if n.in, err = p.span(); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// setItem resolves one member of a string or rule set, expanding a trailing * wildcard.
+func (p *parser) setItem(t token, n *ofNode) ([]string, error) {
+	var ids []string
+	switch t.kind {
+	case tVar:
+		if len(n.ids) > 0 && n.rules {
+			return nil, p.errorf(t.line, "cannot mix strings and rules in a set")
+		}
+		prefix, wild := strings.CutSuffix(t.text, "*")
+		for _, s := range p.rule.strings {
+			if s.id == prefix || wild && strings.HasPrefix(s.id, prefix) {
+				ids = append(ids, s.id)
+			}
+		}
+	case tIdent:
+		if len(n.ids) > 0 && !n.rules {
+			return nil, p.errorf(t.line, "cannot mix strings and rules in a set")
+		}
+		n.rules = true
+		prefix, wild := t.text, false
+		if next, _ := p.lex.peek(0); isOp(next, "*") {
+			p.lex.next()
+			wild = true
+		}
+		for name := range p.known {
+			if name == prefix || wild && strings.HasPrefix(name, prefix) {
+				ids = append(ids, name)
+			}
+		}
+	default:
+		return nil, p.errorf(t.line, "unexpected %s in set", t)
+	}
+	if len(ids) == 0 {
+		return nil, p.errorf(t.line, "%s matches nothing", t.text)
+	}
+	return ids, nil
+}
@@ -0,0 +1,184 @@
+package yara
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ExecScanner runs an installed yara binary (4.0 or later), for rules that use modules or
+// other features the built-in scanner lacks.
+type ExecScanner struct {
+	Binary    string   // path to yara; "yara" from PATH when empty
+	RuleFiles []string // rule source files
+}
+
+// NewExecScanner checks that the binary can be run and expands rule directories.
+func NewExecScanner(binary string, rulePaths ...string) (*ExecScanner, error) {
+	if binary == "" {
+		binary = "yara"
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("yara binary: %w", err)
+	}
+	files, err := RuleFiles(rulePaths...)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no YARA rule files in %s", strings.Join(rulePaths, ", "))
+	}
+	return &ExecScanner{Binary: path, RuleFiles: files}, nil
+}
+
+// ScanFile runs yara on one file and parses its output.
+func (e *ExecScanner) ScanFile(ctx context.Context, path string) ([]Match, error) {
+	args := append([]string{"-s", "-m", "-g", "-w"}, e.RuleFiles...)
+	cmd := exec.CommandContext(ctx, e.Binary, append(args, path)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("yara %s: %v: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+	return parseExecOutput(out)
+}
+
+// parseExecOutput reads "rule [tags] [meta] file" lines, each followed by the rule's
+// "0xoffset:$id: data" string lines.
+func parseExecOutput(out []byte) ([]Match, error) {
+	var matches []Match
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "0x") && len(matches) > 0 {
+			s, err := parseExecString(line)
+			if err != nil {
+				return nil, err
+			}
+			m := &matches[len(matches)-1]
+			m.Strings = append(m.Strings, s)
+			continue
+		}
+		m, err := parseExecRule(line)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, sc.Err()
+}
+
+func parseExecRule(line string) (Match, error) {
+	name, rest, _ := strings.Cut(line, " ")
+	m := Match{Rule: name}
+	tags, rest, ok := bracketed(rest)
+	if !ok {
+		return m, fmt.Errorf("unexpected yara output %q", line)
+	}
+	if tags != "" {
+		m.Tags = strings.Split(tags, ",")
+	}
+	meta, _, ok := bracketed(rest)
+	if !ok {
+		return m, fmt.Errorf("unexpected yara output %q", line)
+	}
+	m.Meta = make(map[string]string)
+	for meta != "" {
+		key, v, _ := strings.Cut(meta, "=")
+		var value string
+		if strings.HasPrefix(v, `"`) {
+			end := closingQuote(v)
+			value = unescape(v[1:end])
+			v = v[end+1:]
+		} else {
+			value, v, _ = strings.Cut(v, ",")
+			v = "," + v
+		}
+		m.Meta[key] = value
+		meta = strings.TrimPrefix(v, ",")
+	}
+	return m, nil
+}
+
+// bracketed splits "[body] rest", honouring quoted strings in body.
+func bracketed(s string) (body, rest string, ok bool) {
+	if !strings.HasPrefix(s, "[") {
+		return "", s, false
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			i = closingQuote(s[i:]) + i
+		case ']':
+			return s[1:i], strings.TrimPrefix(s[i+1:], " "), true
+		}
+	}
+	return "", s, false
+}
+
+// closingQuote returns the index of the quote ending the string s starts with.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+func parseExecString(line string) (StringMatch, error) {
+	off, rest, _ := strings.Cut(line, ":")
+	id, data, ok := strings.Cut(rest, ": ")
+	n, err := strconv.ParseInt(strings.TrimPrefix(off, "0x"), 16, 64)
+	if err != nil || !ok || !strings.HasPrefix(id, "$") {
+		return StringMatch{}, fmt.Errorf("unexpected yara output %q", line)
+	}
+	raw := unescape(data)
+	return StringMatch{ID: id, Offset: n, Length: len(raw), Data: printable([]byte(raw))}, nil
+}
+
+// unescape decodes the \xNN, \n, \t, \" and \\ escapes yara prints.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'x':
+			if v, err := strconv.ParseUint(s[i+1:min(i+3, len(s))], 16, 8); err == nil && i+3 <= len(s) {
+				b.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+			b.WriteString(`\x`)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,335 @@
+package yara
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Hex string tokens. Hex strings are matched by a small backtracking matcher rather than a
+// regular expression, because Go's regexp decodes the input as UTF-8 and cannot match
+// arbitrary bytes above 0x7F.
+const (
+	hexByte = iota // a byte under a nibble mask, optionally negated
+	hexJump        // [min-max] arbitrary bytes; max < 0 is unbounded
+	hexAlt         // ( a | b ) alternatives
+)
+
+type hexToken struct {
+	kind        int
+	value, mask byte
+	not         bool
+	min, max    int
+	alts        [][]hexToken
+}
+
+// parseHex parses the body of a hex string, without the braces.
+func parseHex(body string) (*hexPattern, error) {
+	var b strings.Builder
+	for _, line := range strings.Split(body, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		b.WriteString(strings.Join(strings.Fields(line), ""))
+	}
+	h := &hexParser{s: b.String()}
+	toks, err := h.seq()
+	if err != nil {
+		return nil, err
+	}
+	if h.pos < len(h.s) {
+		return nil, fmt.Errorf("unexpected %q in hex string", h.s[h.pos])
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("empty hex string")
+	}
+	if toks[0].kind == hexJump || toks[len(toks)-1].kind == hexJump {
+		return nil, fmt.Errorf("hex string cannot start or end with a jump")
+	}
+	return compileHex(toks), nil
+}
+
+type hexParser struct {
+	s   string
+	pos int
+}
+
+// seq parses tokens up to the end, a | or a ).
+func (h *hexParser) seq() ([]hexToken, error) {
+	var toks []hexToken
+	for h.pos < len(h.s) {
+		switch c := h.s[h.pos]; c {
+		case '|', ')':
+			return toks, nil
+		case '(':
+			h.pos++
+			t := hexToken{kind: hexAlt}
+			for {
+				alt, err := h.seq()
+				if err != nil {
+					return nil, err
+				}
+				if len(alt) == 0 {
+					return nil, fmt.Errorf("empty alternative in hex string")
+				}
+				t.alts = append(t.alts, alt)
+				if h.pos >= len(h.s) {
+					return nil, fmt.Errorf("unterminated alternative in hex string")
+				}
+				h.pos++
+				if h.s[h.pos-1] == ')' {
+					break
+				}
+			}
+			toks = append(toks, t)
+		case '[':
+			end := strings.IndexByte(h.s[h.pos:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated jump in hex string")
+			}
+			t, err := parseJump(h.s[h.pos+1 : h.pos+end])
+			if err != nil {
+				return nil, err
+			}
+			h.pos += end + 1
+			toks = append(toks, t)
+		default:
+			t := hexToken{kind: hexByte}
+			if c == '~' {
+				t.not = true
+				h.pos++
+			}
+			if h.pos+2 > len(h.s) {
+				return nil, fmt.Errorf("odd number of digits in hex string")
+			}
+			for _, d := range []byte(h.s[h.pos : h.pos+2]) {
+				t.value <<= 4
+				t.mask <<= 4
+				if d == '?' {
+					continue
+				}
+				v, err := strconv.ParseUint(string(d), 16, 8)
+				if err != nil {
+					return nil, fmt.Errorf("bad hex digit %q", d)
+				}
+				t.value |= byte(v)
+				t.mask |= 0xF
+			}
+			if t.not && t.mask == 0 {
+				return nil, fmt.Errorf("~?? is not allowed in hex strings")
+			}
+			h.pos += 2
+			toks = append(toks, t)
+		}
+	}
+	return toks, nil
+}
+
+func parseJump(s string) (hexToken, error) {
+	t := hexToken{kind: hexJump, max: -1}
+	lo, hi, ranged := strings.Cut(s, "-")
+	var err error
+	if lo != "" {
+		if t.min, err = strconv.Atoi(lo); err != nil {
+			return t, fmt.Errorf("bad jump [%s]", s)
+		}
+	}
+	switch {
+	case !ranged:
+		t.max = t.min
+	case hi != "":
+		if t.max, err = strconv.Atoi(hi); err != nil || t.max < t.min {
+			return t, fmt.Errorf("bad jump [%s]", s)
+		}
+	}
+	return t, nil
+}
+
+// maxHexJump bounds unbounded jumps ([-], [n-]), as YARA bounds them at RE_MAX_RANGE.
+const maxHexJump = 32767
+
+const (
+	// hexCheckInterval is how many match steps pass between checks for a cancelled scan.
+	hexCheckInterval = 4096
+	// maxHexMemo bounds the branch outcomes a matcher remembers.
+	maxHexMemo = 1 << 20
+)
+
+// hexPattern is a compiled hex string: its tokens as a program of nodes, each naming the node
+// that follows it, so that a partial match is identified by a node and a position alone.
+type hexPattern struct {
+	nodes     []hexNode
+	start     int
+	anchorOff int    // offset of anchor from the start of a match
+	anchor    []byte // exact bytes every match has at anchorOff
+}
+
+type hexNode struct {
+	kind        int
+	value, mask byte
+	not         bool
+	min, max    int
+	alts        []int // hexAlt: first node of each alternative
+	next        int   // node after this one; -1 ends the match
+}
+
+func compileHex(toks []hexToken) *hexPattern {
+	p := &hexPattern{}
+	p.start = p.compile(toks, -1)
+	p.anchorOff, p.anchor = hexAnchor(toks)
+	return p
+}
+
+// compile appends the nodes of toks, last first, and returns the first; next follows the
+// last token.
+func (p *hexPattern) compile(toks []hexToken, next int) int {
+	for i := len(toks) - 1; i >= 0; i-- {
+		t := toks[i]
+		n := hexNode{kind: t.kind, value: t.value, mask: t.mask, not: t.not, min: t.min, max: t.max, next: next}
+		if t.kind == hexJump && n.max < 0 {
+			n.max = maxHexJump
+		}
+		for _, alt := range t.alts {
+			n.alts = append(n.alts, p.compile(alt, next))
+		}
+		p.nodes = append(p.nodes, n)
+		next = len(p.nodes) - 1
+	}
+	return next
+}
+
+// hexState is a partial match: the node to match next, at a position of the data.
+type hexState struct {
+	node, pos int
+}
+
+// hexMatcher matches a pattern at successive positions. Jumps and alternatives are the only
+// branches; their outcome at a position is remembered, so that nested jumps cost the number
+// of distinct states rather than the product of their lengths. An outcome does not depend on
+// where the match started, so it also serves later candidate positions.
+type hexMatcher struct {
+	ctx   context.Context
+	p     *hexPattern
+	data  []byte
+	memo  map[hexState]int
+	steps int
+	err   error
+}
+
+// matchAt returns the end of a match starting at pos, or -1. Jumps take the shortest length
+// that lets the rest match, alternatives the first that does.
+func (m *hexMatcher) matchAt(pos int) int {
+	if len(m.memo) > maxHexMemo {
+		m.memo = make(map[hexState]int)
+	}
+	return m.match(m.p.start, pos)
+}
+
+func (m *hexMatcher) match(n, pos int) int {
+	for n >= 0 {
+		nd := &m.p.nodes[n]
+		if nd.kind != hexByte {
+			return m.branch(n, pos)
+		}
+		if pos >= len(m.data) || (m.data[pos]&nd.mask == nd.value) == nd.not {
+			return -1
+		}
+		pos++
+		n = nd.next
+	}
+	return pos
+}
+
+func (m *hexMatcher) branch(n, pos int) int {
+	key := hexState{n, pos}
+	if end, ok := m.memo[key]; ok {
+		return end
+	}
+	if m.cancelled() {
+		return -1
+	}
+	end := -1
+	nd := &m.p.nodes[n]
+	if nd.kind == hexJump {
+		max := min(nd.max, len(m.data)-pos)
+		for k := nd.min; k <= max && end < 0; k++ {
+			end = m.match(nd.next, pos+k)
+		}
+	} else {
+		for _, alt := range nd.alts {
+			if end = m.match(alt, pos); end >= 0 {
+				break
+			}
+		}
+	}
+	m.memo[key] = end
+	return end
+}
+
+// cancelled counts a step and reports whether the scan was cancelled, checking the context
+// every hexCheckInterval steps.
+func (m *hexMatcher) cancelled() bool {
+	if m.err == nil {
+		if m.steps++; m.steps%hexCheckInterval == 0 {
+			m.err = m.ctx.Err()
+		}
+	}
+	return m.err != nil
+}
+
+// hexAnchor returns the longest run of exact bytes in the fixed-length prefix of toks and
+// its offset, so that candidate positions can be found with bytes.Index.
+func hexAnchor(toks []hexToken) (int, []byte) {
+	var best, run []byte
+	bestOff, runOff := 0, 0
+	for i, t := range toks {
+		if t.kind != hexByte {
+			break
+		}
+		if t.mask != 0xFF || t.not {
+			run = nil
+			continue
+		}
+		if run == nil {
+			runOff = i
+		}
+		run = append(run, t.value)
+		if len(run) > len(best) {
+			best, bestOff = run, runOff
+		}
+	}
+	return bestOff, best
+}
+
+// searchHex records the matches of p in data. It stops with the context's error when the
+// scan is cancelled.
+func searchHex(ctx context.Context, p *hexPattern, data []byte, h *stringHits) error {
+	m := &hexMatcher{ctx: ctx, p: p, data: data, memo: make(map[hexState]int)}
+	if len(p.anchor) == 0 {
+		for pos := range data {
+			if m.cancelled() {
+				return m.err
+			}
+			if end := m.matchAt(pos); end >= 0 && !h.add(pos, end-pos) {
+				break
+			}
+		}
+		return m.err
+	}
+	for from := 0; ; {
+		i := bytes.Index(data[from:], p.anchor)
+		if i < 0 || m.cancelled() {
+			return m.err
+		}
+		from += i + 1
+		start := from - 1 - p.anchorOff
+		if start < 0 {
+			continue
+		}
+		if end := m.matchAt(start); end >= 0 && !h.add(start, end-start) {
+			return m.err
+		}
+	}
+}
@@ -0,0 +1,605 @@
+package yara
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type tokKind int
+
+const (
+	tEOF    tokKind = iota
+	tIdent          // keyword or identifier
+	tString         // text string literal, unescaped
+	tNumber
+	tVar    // $name; text is "$name", or "$" alone
+	tCount  // #name
+	tOffset // @name
+	tLength // !name
+	tPunct  // operator or delimiter
+)
+
+type token struct {
+	kind tokKind
+	text string
+	num  int64
+	line int
+}
+
+func (t token) String() string {
+	if t.kind == tEOF {
+		return "end of file"
+	}
+	return strconv.Quote(t.text)
+}
+
+// lexer splits rule source into tokens. Hex strings and regular expressions are read raw
+// by the parser, since their syntax depends on context.
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	buf  []token
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src, line: 1}
+}
+
+func (l *lexer) peek(n int) (token, error) {
+	for len(l.buf) <= n {
+		t, err := l.scan()
+		if err != nil {
+			return t, err
+		}
+		l.buf = append(l.buf, t)
+	}
+	return l.buf[n], nil
+}
+
+func (l *lexer) next() (token, error) {
+	t, err := l.peek(0)
+	if err != nil {
+		return t, err
+	}
+	l.buf = l.buf[1:]
+	return t, nil
+}
+
+func (l *lexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return fmt.Errorf("line %d: unterminated comment", l.line)
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+2+end], "\n")
+			l.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isIdent(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+func (l *lexer) ident() string {
+	start := l.pos
+	for l.pos < len(l.src) && isIdent(l.src[l.pos], l.pos == start) {
+		l.pos++
+	}
+	return l.src[start:l.pos]
+}
+
+func (l *lexer) scan() (token, error) {
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+	t := token{line: l.line}
+	if l.pos >= len(l.src) {
+		return t, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case isIdent(c, true):
+		t.kind, t.text = tIdent, l.ident()
+	case c >= '0' && c <= '9':
+		return l.number(t)
+	case c == '"':
+		return l.quoted(t)
+	case c == '$' || c == '#' || c == '@' || c == '!' && l.pos+1 < len(l.src) && isIdent(l.src[l.pos+1], true):
+		l.pos++
+		t.kind = map[byte]tokKind{'$': tVar, '#': tCount, '@': tOffset, '!': tLength}[c]
+		t.text = string(c) + l.ident()
+		if l.pos < len(l.src) && l.src[l.pos] == '*' && c == '$' {
+			l.pos++
+			t.text += "*"
+		}
+	default:
+		t.kind = tPunct
+		for _, op := range []string{"==", "!=", "<=", ">=", "..", "<<", ">>"} {
+			if strings.HasPrefix(l.src[l.pos:], op) {
+				t.text = op
+				l.pos += 2
+				return t, nil
+			}
+		}
+		if !strings.ContainsRune("{}()[]:=<>+-*\\%&|^~,.", rune(c)) {
+			return t, fmt.Errorf("line %d: unexpected character %q", l.line, c)
+		}
+		t.text = string(c)
+		l.pos++
+	}
+	return t, nil
+}
+
+func (l *lexer) number(t token) (token, error) {
+	start := l.pos
+	base := 10
+	if strings.HasPrefix(l.src[l.pos:], "0x") || strings.HasPrefix(l.src[l.pos:], "0X") {
+		base = 16
+		l.pos += 2
+		start = l.pos
+	}
+	for l.pos < len(l.src) && (l.src[l.pos] >= '0' && l.src[l.pos] <= '9' || base == 16 && strings.ContainsRune("abcdefABCDEF", rune(l.src[l.pos]))) {
+		l.pos++
+	}
+	n, err := strconv.ParseInt(l.src[start:l.pos], base, 64)
+	if err != nil {
+		return t, fmt.Errorf("line %d: bad number %q", l.line, l.src[start:l.pos])
+	}
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "KB"):
+		n *= 1024
+		l.pos += 2
+	case strings.HasPrefix(l.src[l.pos:], "MB"):
+		n *= 1024 * 1024
+		l.pos += 2
+	}
+	t.kind, t.num, t.text = tNumber, n, strconv.FormatInt(n, 10)
+	return t, nil
+}
+
+func (l *lexer) quoted(t token) (token, error) {
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			t.kind, t.text = tString, b.String()
+			return t, nil
+		case '\n':
+			return t, fmt.Errorf("line %d: unterminated string", t.line)
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.src) {
+				break
+			}
+			switch e := l.src[l.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(e)
+			case 'x':
+				if l.pos+2 >= len(l.src) {
+					return t, fmt.Errorf("line %d: bad \\x escape", l.line)
+				}
+				v, err := strconv.ParseUint(l.src[l.pos+1:l.pos+3], 16, 8)
+				if err != nil {
+					return t, fmt.Errorf("line %d: bad \\x escape", l.line)
+				}
+				b.WriteByte(byte(v))
+				l.pos += 2
+			default:
+				return t, fmt.Errorf("line %d: unknown escape \\%c", l.line, e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return t, fmt.Errorf("line %d: unterminated string", t.line)
+}
+
+// raw reads a hex string or regular expression that starts at the next non-space
+// character, if there is one. It must only be called with an empty lookahead buffer.
+func (l *lexer) raw() (kind byte, body, flags string, err error) {
+	if err := l.skipSpace(); err != nil {
+		return 0, "", "", err
+	}
+	if l.pos >= len(l.src) {
+		return 0, "", "", nil
+	}
+	switch l.src[l.pos] {
+	case '{':
+		end := strings.IndexByte(l.src[l.pos:], '}')
+		if end < 0 {
+			return 0, "", "", fmt.Errorf("line %d: unterminated hex string", l.line)
+		}
+		body = l.src[l.pos+1 : l.pos+end]
+		l.line += strings.Count(body, "\n")
+		l.pos += end + 1
+		return '{', body, "", nil
+	case '/':
+		var b strings.Builder
+		for l.pos++; l.pos < len(l.src) && l.src[l.pos] != '/'; l.pos++ {
+			c := l.src[l.pos]
+			if c == '\n' {
+				return 0, "", "", fmt.Errorf("line %d: unterminated regular expression", l.line)
+			}
+			if c == '\\' && l.pos+1 < len(l.src) {
+				l.pos++
+				if l.src[l.pos] != '/' {
+					b.WriteByte('\\')
+				}
+				c = l.src[l.pos]
+			}
+			b.WriteByte(c)
+		}
+		if l.pos >= len(l.src) {
+			return 0, "", "", fmt.Errorf("line %d: unterminated regular expression", l.line)
+		}
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == 'i' || l.src[l.pos] == 's') {
+			l.pos++
+		}
+		return '/', b.String(), l.src[start:l.pos], nil
+	}
+	return 0, "", "", nil
+}
+
+// parser builds rules from tokens.
+type parser struct {
+	lex       *lexer
+	file      string
+	namespace string          // of the compiled file; included files share it
+	known     map[string]bool // rules defined so far, for rule references
+	included  map[string]bool
+	rule      *rule // rule being parsed
+}
+
+func (p *parser) errorf(line int, format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", p.file, line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) expect(kind tokKind, text string) (token, error) {
+	t, err := p.lex.next()
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", p.file, err)
+	}
+	if t.kind != kind || text != "" && t.text != text {
+		want := text
+		if want == "" {
+			want = map[tokKind]string{tIdent: "identifier", tString: "string", tNumber: "number", tVar: "string identifier"}[kind]
+		}
+		return t, p.errorf(t.line, "expected %s, found %s", want, t)
+	}
+	return t, nil
+}
+
+func (p *parser) parseFile() ([]*rule, error) {
+	if p.known == nil {
+		p.known = make(map[string]bool)
+		p.included = map[string]bool{p.file: true}
+	}
+	var rules []*rule
+	for {
+		t, err := p.lex.next()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.file, err)
+		}
+		if t.kind == tEOF {
+			return rules, nil
+		}
+		if t.kind != tIdent {
+			return nil, p.errorf(t.line, "expected rule, found %s", t)
+		}
+		switch t.text {
+		case "import":
+			name, _ := p.lex.next()
+			return nil, p.errorf(t.line, "modules are not supported by the built-in scanner (import %s); use an external yara binary", name)
+		case "include":
+			inc, err := p.include()
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, inc...)
+			continue
+		}
+		r := &rule{namespace: p.namespace}
+		for t.text == "private" || t.text == "global" {
+			if t.text == "private" {
+				r.private = true
+			} else {
+				r.global = true
+			}
+			if t, err = p.expect(tIdent, ""); err != nil {
+				return nil, err
+			}
+		}
+		if t.text != "rule" {
+			return nil, p.errorf(t.line, "expected rule, found %s", t)
+		}
+		if err := p.parseRule(r); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+}
+
+func (p *parser) include() ([]*rule, error) {
+	t, err := p.expect(tString, "")
+	if err != nil {
+		return nil, err
+	}
+	path := t.text
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(p.file), path)
+	}
+	if p.included[path] {
+		return nil, p.errorf(t.line, "recursive include of %s", t.text)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, p.errorf(t.line, "include: %v", err)
+	}
+	sub := &parser{lex: newLexer(string(data)), file: path, namespace: p.namespace, known: p.known, included: p.included}
+	p.included[path] = true
+	defer delete(p.included, path)
+	return sub.parseFile()
+}
+
+func (p *parser) parseRule(r *rule) error {
+	name, err := p.expect(tIdent, "")
+	if err != nil {
+		return err
+	}
+	if p.known[name.text] {
+		return p.errorf(name.line, "duplicate rule %s", name.text)
+	}
+	r.name = name.text
+	p.rule = r
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	if t.kind == tPunct && t.text == ":" {
+		for {
+			if t, err = p.lex.next(); err != nil {
+				return err
+			}
+			if t.kind != tIdent {
+				break
+			}
+			r.tags = append(r.tags, t.text)
+		}
+	}
+	if t.kind != tPunct || t.text != "{" {
+		return p.errorf(t.line, "expected {, found %s", t)
+	}
+	for {
+		t, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		if t.kind != tIdent {
+			return p.errorf(t.line, "expected meta, strings or condition, found %s", t)
+		}
+		if _, err := p.expect(tPunct, ":"); err != nil {
+			return err
+		}
+		switch t.text {
+		case "meta":
+			err = p.parseMeta(r)
+		case "strings":
+			err = p.parseStrings(r)
+		case "condition":
+			if r.condition, err = p.parseCondition(); err != nil {
+				return err
+			}
+			if _, err := p.expect(tPunct, "}"); err != nil {
+				return err
+			}
+			p.known[r.name] = true
+			return nil
+		default:
+			return p.errorf(t.line, "unknown section %s", t.text)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// sectionEnd reports whether the next tokens start another section.
+func (p *parser) sectionEnd() (bool, error) {
+	t, err := p.lex.peek(0)
+	if err != nil {
+		return false, err
+	}
+	if t.kind != tIdent {
+		return true, nil
+	}
+	t2, err := p.lex.peek(1)
+	if err != nil {
+		return false, err
+	}
+	return t2.kind == tPunct && t2.text == ":", nil
+}
+
+func (p *parser) parseMeta(r *rule) error {
+	r.meta = make(map[string]string)
+	for {
+		if end, err := p.sectionEnd(); end || err != nil {
+			return err
+		}
+		key, _ := p.lex.next()
+		if _, err := p.expect(tPunct, "="); err != nil {
+			return err
+		}
+		v, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case v.kind == tString || v.kind == tNumber:
+		case v.kind == tIdent && (v.text == "true" || v.text == "false"):
+		case v.kind == tPunct && v.text == "-":
+			n, err := p.expect(tNumber, "")
+			if err != nil {
+				return err
+			}
+			v.text = "-" + n.text
+		default:
+			return p.errorf(v.line, "bad meta value %s", v)
+		}
+		r.meta[key.text] = v.text
+	}
+}
+
+func (p *parser) parseStrings(r *rule) error {
+	for {
+		t, err := p.lex.peek(0)
+		if err != nil {
+			return err
+		}
+		if t.kind != tVar {
+			return nil
+		}
+		p.lex.next()
+		if strings.HasSuffix(t.text, "*") {
+			return p.errorf(t.line, "bad string identifier %s", t.text)
+		}
+		s := &ruleString{id: t.text}
+		if s.id == "$" {
+			s.id = fmt.Sprintf("$_anon%d", len(r.strings)+1)
+		}
+		for _, o := range r.strings {
+			if o.id == s.id {
+				return p.errorf(t.line, "duplicate string identifier %s", s.id)
+			}
+		}
+		if _, err := p.expect(tPunct, "="); err != nil {
+			return err
+		}
+		kind, body, flags, err := p.lex.raw()
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.file, err)
+		}
+		var text *token
+		switch kind {
+		case '{':
+			if s.hex, err = parseHex(body); err != nil {
+				return p.errorf(t.line, "%s: %v", s.id, err)
+			}
+		case '/':
+		default:
+			v, err := p.expect(tString, "")
+			if err != nil {
+				return err
+			}
+			text = &v
+		}
+		ascii, wide, err := p.modifiers(s, kind)
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case '/':
+			if wide {
+				return p.errorf(t.line, "%s: wide regular expressions are not supported", s.id)
+			}
+			expr := body
+			if strings.Contains(flags, "s") {
+				expr = "(?s)" + expr
+			}
+			if strings.Contains(flags, "i") || s.nocase {
+				expr = "(?i)" + expr
+			}
+			if s.re, err = regexp.Compile(expr); err != nil {
+				return p.errorf(t.line, "%s: %v", s.id, err)
+			}
+		case 0:
+			if text.text == "" {
+				return p.errorf(t.line, "%s: empty string", s.id)
+			}
+			lit := []byte(text.text)
+			if s.nocase {
+				lit = asciiLower(lit)
+			}
+			if ascii || !wide {
+				s.text = append(s.text, lit)
+				s.wide = append(s.wide, false)
+			}
+			if wide {
+				w := make([]byte, 0, 2*len(lit))
+				for _, c := range lit {
+					w = append(w, c, 0)
+				}
+				s.text = append(s.text, w)
+				s.wide = append(s.wide, true)
+			}
+		}
+		r.strings = append(r.strings, s)
+	}
+}
+
+// modifiers reads the modifiers after a string and reports whether ascii and wide were given.
+func (p *parser) modifiers(s *ruleString, kind byte) (ascii, wide bool, err error) {
+	for {
+		t, err := p.lex.peek(0)
+		if err != nil {
+			return false, false, err
+		}
+		if t.kind != tIdent {
+			return ascii, wide, nil
+		}
+		if t2, err := p.lex.peek(1); err != nil || t2.kind == tPunct && t2.text == ":" {
+			return ascii, wide, err // next section
+		}
+		p.lex.next()
+		ok := true
+		switch t.text {
+		case "private":
+			s.private = true
+		case "nocase":
+			s.nocase, ok = true, kind != '{'
+		case "ascii":
+			ascii, ok = true, kind != '{'
+		case "wide":
+			wide, ok = true, kind != '{'
+		case "fullword":
+			s.fullword, ok = true, kind == 0
+		case "xor", "base64", "base64wide":
+			return false, false, p.errorf(t.line, "%s: modifier %s is not supported by the built-in scanner", s.id, t.text)
+		default:
+			return false, false, p.errorf(t.line, "%s: unknown modifier %s", s.id, t.text)
+		}
+		if !ok {
+			return false, false, p.errorf(t.line, "%s: modifier %s not allowed here", s.id, t.text)
+		}
+	}
+}
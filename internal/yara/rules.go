@@ -0,0 +1,375 @@
+// Package yara scans files with YARA rules. The built-in scanner is pure Go and so works in
+// the static build; it compiles the commonly used part of the YARA language (text strings
+// with nocase/wide/ascii/fullword, hex strings with wildcards, jumps and alternatives,
+// regular expressions, and conditions over them, filesize and uintXX reads). Rules that need
+// modules or other features are rejected with an error naming the feature; for those, the
+// ExecScanner runs an installed yara binary behind the same Scanner interface.
+package yara
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxStringMatches bounds the offsets recorded per string, as YARA does.
+const maxStringMatches = 1000
+
+// Match is a rule that matched a file.
+type Match struct {
+	Rule      string            `json:"rule"`
+	Namespace string            `json:"namespace,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Meta      map[string]string `json:"meta,omitempty"`
+	Strings   []StringMatch     `json:"strings,omitempty"`
+}
+
+// StringMatch is one occurrence of a rule string.
+type StringMatch struct {
+	ID     string `json:"id"`
+	Offset int64  `json:"offset"`
+	Length int    `json:"length"`
+	Data   string `json:"data"` // printable text, or hex for binary data
+}
+
+// Rules is a compiled rule set; it implements Scanner.
+type Rules struct {
+	rules []*rule
+}
+
+type rule struct {
+	name      string
+	namespace string
+	tags      []string
+	meta      map[string]string
+	private   bool
+	global    bool
+	strings   []*ruleString
+	condition node
+}
+
+// ruleString is one entry of a rule's strings section.
+type ruleString struct {
+	id       string
+	private  bool
+	text     [][]byte // text: the literal as ASCII and/or UTF-16LE
+	wide     []bool   // whether text[i] is the UTF-16LE form
+	nocase   bool
+	fullword bool
+	hex      *hexPattern
+	re       *regexp.Regexp
+}
+
+// Compile compiles rule source. name labels errors and is the rules' namespace.
+func Compile(src, name string) (*Rules, error) {
+	p := &parser{lex: newLexer(src), file: name, namespace: name}
+	rs, err := p.parseFile()
+	if err != nil {
+		if !strings.HasPrefix(err.Error(), name+":") {
+			err = fmt.Errorf("%s: %w", name, err)
+		}
+		return nil, err
+	}
+	return &Rules{rules: rs}, nil
+}
+
+// Load compiles rule files; directories contribute their .yar and .yara files. Each file is
+// its own namespace, as with yarac: rule names need only be unique within it.
+func Load(paths ...string) (*Rules, error) {
+	files, err := RuleFiles(paths...)
+	if err != nil {
+		return nil, err
+	}
+	out := &Rules{}
+	seen := make(map[string]bool)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := Compile(string(data), f)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs.rules {
+			k := ruleKey(r.namespace, r.name)
+			if seen[k] {
+				return nil, fmt.Errorf("%s: duplicate rule %s", f, r.name)
+			}
+			seen[k] = true
+		}
+		out.rules = append(out.rules, rs.rules...)
+	}
+	return out, nil
+}
+
+// RuleFiles expands directories into the .yar and .yara files below them, sorted.
+func RuleFiles(paths ...string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".yar", ".yara":
+				if !d.IsDir() {
+					files = append(files, path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Len returns the number of rules.
+func (r *Rules) Len() int {
+	return len(r.rules)
+}
+
+// ScanFile scans the contents of a file.
+func (r *Rules) ScanFile(ctx context.Context, path string) ([]Match, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.ScanBytes(ctx, data)
+}
+
+// ScanBytes scans a buffer and returns the non-private rules that matched, in rule order.
+// A global rule that does not match makes every rule of its namespace fail.
+func (r *Rules) ScanBytes(ctx context.Context, data []byte) ([]Match, error) {
+	sc := &scanCtx{data: data, results: make(map[string]bool)}
+	failed := make(map[string]bool) // namespaces with a global rule that did not match
+	var out []Match
+	for _, ru := range r.rules {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if failed[ru.namespace] {
+			continue
+		}
+		sc.namespace = ru.namespace
+		sc.strings = make(map[string]*stringHits, len(ru.strings))
+		for _, s := range ru.strings {
+			h, err := s.search(ctx, sc)
+			if err != nil {
+				return nil, err
+			}
+			sc.strings[s.id] = h
+		}
+		ok := truth(ru.condition.eval(sc))
+		sc.results[ruleKey(ru.namespace, ru.name)] = ok
+		if ru.global && !ok {
+			failed[ru.namespace] = true
+			continue
+		}
+		if !ok || ru.private {
+			continue
+		}
+		m := Match{Rule: ru.name, Namespace: ru.namespace, Tags: ru.tags, Meta: ru.meta}
+		for _, s := range ru.strings {
+			if s.private {
+				continue
+			}
+			h := sc.strings[s.id]
+			for i, off := range h.offsets {
+				m.Strings = append(m.Strings, StringMatch{ID: s.id, Offset: int64(off), Length: h.lengths[i], Data: printable(data[off : off+h.lengths[i]])})
+			}
+		}
+		out = append(out, m)
+	}
+	if len(failed) > 0 {
+		kept := out[:0]
+		for _, m := range out {
+			if !failed[m.Namespace] {
+				kept = append(kept, m)
+			}
+		}
+		out = kept
+	}
+	return out, nil
+}
+
+// scanCtx is the state of one scan.
+type scanCtx struct {
+	data    []byte
+	lower   []byte // data with ASCII letters folded, built on first nocase string
+	strings map[string]*stringHits
+	results map[string]bool // rules evaluated so far, by ruleKey
+
+	namespace string // of the rule being evaluated, which rule references resolve in
+}
+
+// ruleKey names a rule within the scanned rule set.
+func ruleKey(namespace, name string) string {
+	return namespace + ":" + name
+}
+
+func (sc *scanCtx) folded() []byte {
+	if sc.lower == nil {
+		sc.lower = bytes.ToLower(sc.data)
+		if len(sc.lower) != len(sc.data) { // non-ASCII case mappings change lengths
+			sc.lower = asciiLower(sc.data)
+		}
+	}
+	return sc.lower
+}
+
+func asciiLower(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// stringHits are the occurrences of a string, in offset order.
+type stringHits struct {
+	offsets []int
+	lengths []int
+}
+
+func (h *stringHits) add(off, n int) bool {
+	h.offsets = append(h.offsets, off)
+	h.lengths = append(h.lengths, n)
+	return len(h.offsets) < maxStringMatches
+}
+
+func (s *ruleString) search(ctx context.Context, sc *scanCtx) (*stringHits, error) {
+	h := &stringHits{}
+	data := sc.data
+	switch {
+	case s.re != nil:
+		for _, loc := range s.re.FindAllIndex(data, maxStringMatches) {
+			if loc[1] > loc[0] {
+				h.add(loc[0], loc[1]-loc[0])
+			}
+		}
+	case s.hex != nil:
+		if err := searchHex(ctx, s.hex, data, h); err != nil {
+			return nil, err
+		}
+	default:
+		hay := data
+		if s.nocase {
+			hay = sc.folded()
+		}
+		for j, lit := range s.text {
+			for pos := 0; ; {
+				i := bytes.Index(hay[pos:], lit)
+				if i < 0 {
+					break
+				}
+				off := pos + i
+				if (!s.fullword || isWord(data, off, len(lit), s.wide[j])) && !h.add(off, len(lit)) {
+					break
+				}
+				pos = off + 1
+			}
+		}
+		if len(s.text) > 1 {
+			sortHits(h)
+		}
+	}
+	return h, nil
+}
+
+// isWord reports whether a fullword match is delimited by non-alphanumeric characters;
+// for wide strings, by characters that are not alphanumeric UTF-16LE.
+func isWord(data []byte, off, n int, wide bool) bool {
+	if !wide {
+		return (off == 0 || !isAlnum(data[off-1])) && (off+n >= len(data) || !isAlnum(data[off+n]))
+	}
+	if off >= 2 && isAlnum(data[off-2]) && data[off-1] == 0 {
+		return false
+	}
+	return off+n+1 >= len(data) || !isAlnum(data[off+n]) || data[off+n+1] != 0
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func sortHits(h *stringHits) {
+	idx := make([]int, len(h.offsets))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return h.offsets[idx[a]] < h.offsets[idx[b]] })
+	offs, lens := make([]int, len(idx)), make([]int, len(idx))
+	for i, j := range idx {
+		offs[i], lens[i] = h.offsets[j], h.lengths[j]
+	}
+	h.offsets, h.lengths = offs, lens
+}
+
+// printable renders matched bytes as text when they are printable ASCII (or UTF-16LE
+// thereof), and as hex otherwise.
+func printable(b []byte) string {
+	const max = 64
+	if len(b) > max {
+		b = b[:max]
+	}
+	text := true
+	for i, c := range b {
+		if (c < 0x20 || c > 0x7e) && !(c == 0 && i%2 == 1) {
+			text = false
+			break
+		}
+	}
+	if text {
+		return string(bytes.ReplaceAll(b, []byte{0}, nil))
+	}
+	return fmt.Sprintf("% X", b)
+}
+
+// GlobalPath is the rule directory shared by every case of the current user.
+func GlobalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gtrace", "yara"), nil
+}
+
+// CasePath is the case rule directory, beside the case data directory.
+func CasePath(casePath string) string {
+	return filepath.Join(casePath, "yara")
+}
+
+// DefaultPaths returns the global and case rule directories that exist.
+func DefaultPaths(casePath string) []string {
+	var paths []string
+	if p, err := GlobalPath(); err == nil {
+		paths = append(paths, p)
+	}
+	var out []string
+	for _, p := range append(paths, CasePath(casePath)) {
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,173 @@
+package yara
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gtrace/pkg/model"
+)
+
+// Scanner scans one file. Rules (the built-in scanner) and ExecScanner implement it.
+type Scanner interface {
+	ScanFile(ctx context.Context, path string) ([]Match, error)
+}
+
+// DefaultMaxSize is the largest file scanned when no limit is given.
+const DefaultMaxSize = 64 << 20
+
+// Hit is a rule that matched a target.
+type Hit struct {
+	Target Target `json:"target"`
+	Match  Match  `json:"match"`
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size"`
+}
+
+// Stats counts the targets of a scan.
+type Stats struct {
+	Scanned int `json:"scanned"`
+	Skipped int `json:"skipped"` // too large, unreadable or rejected by the scanner
+}
+
+// Scan runs s over the targets, skipping files larger than maxSize (DefaultMaxSize when 0).
+// Files that cannot be scanned are counted as skipped; only cancellation stops the scan.
+func Scan(ctx context.Context, s Scanner, targets []Target, maxSize int64, progress func(done, total int)) ([]Hit, Stats, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	var hits []Hit
+	var stats Stats
+	for i, t := range targets {
+		if err := ctx.Err(); err != nil {
+			return nil, stats, err
+		}
+		if progress != nil {
+			progress(i, len(targets))
+		}
+		info, err := os.Stat(t.Path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxSize {
+			stats.Skipped++
+			continue
+		}
+		matches, err := s.ScanFile(ctx, t.Path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, stats, ctx.Err()
+			}
+			stats.Skipped++
+			continue
+		}
+		stats.Scanned++
+		if len(matches) == 0 {
+			continue
+		}
+		sum, _ := hashFile(t.Path)
+		for _, m := range matches {
+			hits = append(hits, Hit{Target: t, Match: m, SHA256: sum, Size: info.Size()})
+		}
+	}
+	if progress != nil {
+		progress(len(targets), len(targets))
+	}
+	return hits, stats, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// maxListedStrings bounds the string matches described and referenced by a finding.
+const maxListedStrings = 20
+
+var (
+	severities  = map[string]bool{"critical": true, "high": true, "medium": true, "low": true, "informational": true}
+	techniqueID = regexp.MustCompile(`(?i)^T\d{4}(\.\d{3})?$`)
+)
+
+// Finding records the hit. Its ID is derived from the rule and file, so a later scan
+// replaces it. The severity comes from the rule's "severity" meta (high by default) and
+// ATT&CK techniques from its "attack" or "mitre_attack" meta and T-number tags.
+func (h Hit) Finding() model.Finding {
+	m, t := h.Match, h.Target
+	sum := sha1.Sum([]byte(t.Path))
+	f := model.Finding{
+		ID:       fmt.Sprintf("yara-%s-%s", m.Rule, hex.EncodeToString(sum[:6])),
+		Severity: "high",
+		Title:    fmt.Sprintf("YARA rule %s matched %s", m.Rule, baseName(t.Ref)),
+		RuleID:   "yara:" + m.Rule,
+		EventIDs: t.EventIDs,
+	}
+	if s := strings.ToLower(m.Meta["severity"]); severities[s] {
+		f.Severity = s
+	}
+
+	var desc strings.Builder
+	if d := m.Meta["description"]; d != "" {
+		desc.WriteString(d + "\n")
+	}
+	switch t.Kind {
+	case KindReferenced:
+		fmt.Fprintf(&desc, "%s, referenced as %s by %s (%d events), ", t.Path, t.Ref, strings.Join(t.Sources, ", "), len(t.EventIDs))
+	default:
+		fmt.Fprintf(&desc, "Evidence file %s, ", t.Path)
+	}
+	fmt.Fprintf(&desc, "%d bytes, SHA256 %s.", h.Size, h.SHA256)
+	if len(m.Strings) > 0 {
+		desc.WriteString("\nMatched strings:")
+	}
+	for i, s := range m.Strings {
+		if i == maxListedStrings {
+			fmt.Fprintf(&desc, "\n  ... and %d more", len(m.Strings)-i)
+			break
+		}
+		fmt.Fprintf(&desc, "\n  %s at 0x%x: %s", s.ID, s.Offset, s.Data)
+		f.EvidenceRefs = append(f.EvidenceRefs, model.EvidenceRef{SourcePath: t.Path, Offset: s.Offset, Size: int64(s.Length), SHA256: h.SHA256})
+	}
+	f.Description = desc.String()
+	if len(f.EvidenceRefs) == 0 {
+		f.EvidenceRefs = []model.EvidenceRef{{SourcePath: t.Path, SHA256: h.SHA256}}
+	}
+	if h.SHA256 != "" {
+		f.IOCs = append(f.IOCs, model.IOCMaterial{Type: "sha256", Value: h.SHA256, Note: "YARA " + m.Rule})
+	}
+
+	var ids []string
+	for _, key := range []string{"attack", "mitre_attack"} {
+		ids = append(ids, strings.FieldsFunc(m.Meta[key], func(r rune) bool { return r == ',' || r == ' ' || r == ';' })...)
+	}
+	ids = append(ids, m.Tags...)
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		id = strings.ToUpper(id)
+		if techniqueID.MatchString(id) && !seen[id] {
+			seen[id] = true
+			f.Attack = append(f.Attack, model.AttackRef{TechniqueID: id})
+		}
+	}
+	return f
+}
+
+// baseName returns the file name of a Windows or local path.
+func baseName(p string) string {
+	if i := strings.LastIndexAny(p, `\/`); i >= 0 && i < len(p)-1 {
+		return p[i+1:]
+	}
+	return filepath.Base(p)
+}
@@ -0,0 +1,254 @@
+package yara
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gtrace/pkg/model"
+)
+
+// Target kinds.
+const (
+	KindEvidence   = "evidence"   // a file of the evidence set
+	KindReferenced = "referenced" // a binary named by Prefetch, Amcache or ShimCache
+)
+
+// maxTargetEvents bounds the referencing events kept per target.
+const maxTargetEvents = 20
+
+// Target is a file to scan.
+type Target struct {
+	Path     string   `json:"path"`                // file on disk
+	Ref      string   `json:"ref"`                 // the path as the evidence or artifact names it
+	Kind     string   `json:"kind"`                // KindEvidence or KindReferenced
+	Sources  []string `json:"sources,omitempty"`   // artifacts that referenced the file
+	EventIDs []string `json:"event_ids,omitempty"` // events that referenced the file
+}
+
+// ScanFunc streams the timeline to fn.
+type ScanFunc func(ctx context.Context, fn func(ev model.TimelineEvent) error) error
+
+// EvidenceTargets lists the files of the evidence set; directories contribute every
+// regular file below them.
+func EvidenceTargets(ctx context.Context, paths []string) ([]Target, error) {
+	var out []Target
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			out = append(out, Target{Path: path, Ref: path, Kind: KindEvidence})
+		}
+	}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue // evidence moved or removed since triage
+		}
+		if !info.IsDir() {
+			add(p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d os.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				return nil // unreadable subtree
+			}
+			if d.Type().IsRegular() {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// ReferencedPath returns the path of the binary an execution artifact names, or "".
+func ReferencedPath(ev *model.TimelineEvent) string {
+	switch strings.ToLower(ev.Artifact) {
+	case "prefetch":
+		return ev.Details["executable_path"]
+	case "amcache", "shimcache":
+		return ev.Details["path"]
+	}
+	return ""
+}
+
+// ReferencedTargets resolves the binaries named by Prefetch, Amcache and ShimCache events.
+// A path is looked up inside the image the artifact was collected from, taken to be the
+// part of the artifact's evidence path before its Windows, Users or ProgramData directory.
+// For artifacts read from the running system (live reports true for their evidence path), a
+// path not found there is also tried as is, on this system. Events of a target are returned
+// as well, for allowlist checks.
+func ReferencedTargets(ctx context.Context, scan ScanFunc, live func(sourcePath string) bool) ([]Target, []model.TimelineEvent, error) {
+	index := make(map[string]int)
+	var out []Target
+	var events []model.TimelineEvent
+	err := scan(ctx, func(ev model.TimelineEvent) error {
+		ref := ReferencedPath(&ev)
+		if ref == "" {
+			return nil
+		}
+		path := Resolve(ref, ev.EvidenceRef.SourcePath, live != nil && live(ev.EvidenceRef.SourcePath))
+		if path == "" {
+			return nil
+		}
+		key := strings.ToLower(path)
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, Target{Path: path, Ref: ref, Kind: KindReferenced})
+		}
+		t := &out[i]
+		if !contains(t.Sources, ev.Artifact) {
+			t.Sources = append(t.Sources, ev.Artifact)
+		}
+		if len(t.EventIDs) < maxTargetEvents {
+			t.EventIDs = append(t.EventIDs, ev.ID)
+			events = append(events, ev)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out, events, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	volumePrefix = regexp.MustCompile(`(?i)^(\\\\\?\\|\\\?\?\\)?(\\?volume\{[^}]*\}|\\?device\\harddiskvolume\d+|[a-z]:|sysvol)?\\`)
+	envVars      = strings.NewReplacer(
+		"%systemroot%", `\windows`, "%windir%", `\windows`, "%systemdrive%", "",
+		"%programfiles%", `\program files`, "%programfiles(x86)%", `\program files (x86)`,
+		"%programdata%", `\programdata`,
+	)
+	rootDirs = map[string]bool{"windows": true, "users": true, "programdata": true, "program files": true, "program files (x86)": true, "documents and settings": true}
+)
+
+// relativeWindowsPath strips the volume or drive of a Windows path and returns the
+// components below it, or nil for paths that do not name a file on a volume.
+func relativeWindowsPath(ref string) []string {
+	ref = strings.ReplaceAll(strings.TrimSpace(ref), "/", `\`)
+	if strings.HasPrefix(ref, "%") {
+		if i := strings.Index(ref[1:], "%"); i >= 0 {
+			ref = envVars.Replace(strings.ToLower(ref[:i+2])) + ref[i+2:]
+		}
+	}
+	loc := volumePrefix.FindStringIndex(ref)
+	if loc == nil {
+		return nil
+	}
+	var parts []string
+	for _, p := range strings.Split(ref[loc[1]:], `\`) {
+		switch p {
+		case "", ".":
+		case "..":
+			return nil
+		default:
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// imageRoot returns the directory an artifact's evidence path is collected below, e.g.
+// /cases/host1/C for /cases/host1/C/Windows/Prefetch/CMD.EXE-1234.pf, or "".
+func imageRoot(sourcePath string) string {
+	if sourcePath == "" {
+		return ""
+	}
+	clean := filepath.Clean(sourcePath)
+	parts := strings.FieldsFunc(clean, func(r rune) bool { return r == '/' || r == '\\' })
+	for i, p := range parts {
+		if !rootDirs[strings.ToLower(p)] {
+			continue
+		}
+		root := strings.Join(parts[:i], string(filepath.Separator))
+		if filepath.IsAbs(clean) && !filepath.IsAbs(root) {
+			root = string(filepath.Separator) + root
+		}
+		if vol := filepath.VolumeName(clean); vol != "" && i == 1 {
+			root = vol + string(filepath.Separator)
+		}
+		return root
+	}
+	return ""
+}
+
+// Resolve finds the file a Windows path refers to: inside the image holding sourcePath,
+// matching names case-insensitively, and, with live set, on the running system. It
+// returns "" when the file is not found.
+func Resolve(ref, sourcePath string, live bool) string {
+	parts := relativeWindowsPath(ref)
+	if len(parts) == 0 {
+		return ""
+	}
+	if root := imageRoot(sourcePath); root != "" {
+		if p := lookupFold(root, parts); p != "" {
+			return p
+		}
+	}
+	if live {
+		drive := os.Getenv("SystemDrive")
+		if len(ref) > 2 && ref[1] == ':' {
+			drive = ref[:2]
+		}
+		if drive != "" {
+			p := drive + `\` + strings.Join(parts, `\`)
+			if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+// lookupFold joins parts to root, matching each component case-insensitively.
+func lookupFold(root string, parts []string) string {
+	dir := root
+	for i, name := range parts {
+		next := filepath.Join(dir, name)
+		if _, err := os.Lstat(next); err != nil {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				return ""
+			}
+			next = ""
+			for _, e := range entries {
+				if strings.EqualFold(e.Name(), name) {
+					next = filepath.Join(dir, e.Name())
+					break
+				}
+			}
+			if next == "" {
+				return ""
+			}
+		}
+		if i == len(parts)-1 {
+			if info, err := os.Stat(next); err != nil || !info.Mode().IsRegular() {
+				return ""
+			}
+		}
+		dir = next
+	}
+	return dir
+}
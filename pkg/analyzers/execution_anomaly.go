@@ -60,7 +60,9 @@ func (a *ExecutionAnomalyAnalyzer) Analyze(ctx context.Context, in pluginsdk.Ana
 				Title:    "Process Execution without Prefetch Evidence",
 				Description: fmt.Sprintf("Process %s (PID: %s) is running but no corresponding Prefetch file was found. This could indicate time-stomping, prefetch disabling, or execution from a location that does not generate prefetch (e.g. some removable media configurations).",
 					proc.Subject, proc.Details["pid"]),
-				RuleID: "exec-anomaly-no-prefetch",
+				RuleID:   "exec-anomaly-no-prefetch",
+				EventIDs: []string{proc.ID},
+				Attack:   []model.AttackRef{{TechniqueID: "T1070"}},
 				EvidenceRefs: []model.EvidenceRef{
 					proc.EvidenceRef,
 				},
@@ -0,0 +1,299 @@
+package analyzers
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+const (
+	defaultMaxPrevalence = 0.1
+	defaultMinHosts      = 3
+	// maxStackFindings bounds the findings of one category, rarest first.
+	maxStackFindings = 200
+	// maxStackEventIDs bounds the events listed on a finding.
+	maxStackEventIDs = 20
+)
+
+// HostStackingAnalyzer compares persistence and execution artifacts across the hosts of a
+// case (least frequency of occurrence) and reports entries found on few of them: a Run key
+// on 1 of 40 hosts, a service binary unique to two workstations. Run keys are stacked from
+// SOFTWARE and NTUSER.DAT hives and from Sysmon registry events alike.
+type HostStackingAnalyzer struct {
+	// MaxPrevalence is the largest share of hosts an entry may appear on and still be
+	// reported (default 0.1, i.e. 10%).
+	MaxPrevalence float64
+	// MinHosts is how many hosts must have data for a category before it is stacked (default 3).
+	MinHosts int
+}
+
+func (a *HostStackingAnalyzer) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:        "host-stacking",
+		Version:     "1.0.0",
+		Type:        "analyzer",
+		Platforms:   []string{"windows"},
+		Description: "Least-frequency-of-occurrence stacking of services, scheduled tasks, Run keys, WMI persistence, Amcache hashes and Prefetch executables across hosts.",
+		Input: pluginsdk.IODecl{
+			Kind: "timeline",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "finding",
+		},
+	}
+}
+
+// stackCategory describes one kind of artifact that is compared across hosts.
+type stackCategory struct {
+	title    string
+	severity string
+	attack   []string
+}
+
+var stackCategories = map[string]stackCategory{
+	"service":  {"Rare service", "medium", []string{"T1543.003"}},
+	"task":     {"Rare scheduled task", "medium", []string{"T1053.005"}},
+	"run_key":  {"Rare Run key", "medium", []string{"T1547.001"}},
+	"wmi":      {"Rare WMI persistence", "medium", []string{"T1546.003"}},
+	"amcache":  {"Rare executable hash (Amcache)", "low", nil},
+	"prefetch": {"Rare executable (Prefetch)", "low", nil},
+}
+
+// stackEntry is what an event contributes to a stack: a normalized key and how to show it.
+type stackEntry struct {
+	category string
+	key      string
+	label    string
+}
+
+var userSID = regexp.MustCompile(`(?i)^((hku|hkey_users)\\s-1-5-21-[\d-]+(_classes)?|hkcu|hkey_current_user)\\`)
+
+// stackEntryOf classifies an event, or returns false for events that are not stacked.
+func stackEntryOf(ev model.TimelineEvent) (stackEntry, bool) {
+	d := ev.Details
+	switch {
+	case ev.Artifact == "Service" && d["ServiceName"] != "":
+		return serviceEntry(d["ServiceName"], firstNonEmpty(d["ImagePath"], d["ServiceDll"])), true
+	case ev.Source == "EventLog" && d["EventID"] == "7045" && d["ServiceName"] != "":
+		return serviceEntry(d["ServiceName"], firstNonEmpty(d["ImagePath"], d["ServiceFileName"])), true
+	case ev.Artifact == "ScheduledTask":
+		cmd := strings.TrimSpace(ev.Subject + " " + d["arguments"])
+		return stackEntry{"task", strings.ToLower(d["task_name"] + "|" + cmd), d["task_name"] + ": " + cmd}, true
+	case ev.Source == "EventLog" && d["EventID"] == "4698" && d["TaskName"] != "":
+		return stackEntry{"task", strings.ToLower(path.Base(strings.ReplaceAll(d["TaskName"], `\`, "/"))), d["TaskName"]}, true
+	case ev.Source == "EventLog" && (d["EventID"] == "12" || d["EventID"] == "13") && isRunKey(d["TargetObject"]):
+		return runKeyEntry(d["TargetObject"], d["Details"]), true
+	case ev.Artifact == "RunKey" && d["TargetObject"] != "":
+		return runKeyEntry(d["TargetObject"], d["Command"]), true
+	case ev.Source == "WMI" && ev.Subject != "":
+		return stackEntry{"wmi", strings.ToLower(ev.Artifact + "|" + ev.Subject), ev.Artifact + ": " + ev.Subject}, true
+	case ev.Source == "Amcache" && d["sha1"] != "":
+		return stackEntry{"amcache", strings.ToLower(d["sha1"]), d["sha1"] + " " + d["path"]}, true
+	case ev.Source == "Prefetch" && ev.Subject != "":
+		key := strings.ToUpper(ev.Subject)
+		if d["hash"] != "" {
+			key += "-" + strings.ToUpper(d["hash"])
+		}
+		return stackEntry{"prefetch", key, key}, true
+	}
+	return stackEntry{}, false
+}
+
+func serviceEntry(name, image string) stackEntry {
+	return stackEntry{"service", strings.ToLower(name + "|" + image), name + " (" + image + ")"}
+}
+
+// runKeyEntry stacks a Run key value, as written by Sysmon or read from a hive.
+func runKeyEntry(obj, command string) stackEntry {
+	// The SID of HKU\<sid>\... differs per user and host, and a user hive is read as HKCU;
+	// stack on the key path.
+	obj = userSID.ReplaceAllString(obj, `HKU\*\`)
+	return stackEntry{"run_key", strings.ToLower(obj + "|" + command), obj + " = " + command}
+}
+
+func isRunKey(obj string) bool {
+	obj = strings.ToLower(obj)
+	return strings.Contains(obj, `\currentversion\run\`) || strings.Contains(obj, `\currentversion\runonce\`)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (a *HostStackingAnalyzer) Analyze(ctx context.Context, in pluginsdk.AnalyzeRequest) (*pluginsdk.AnalyzeResponse, error) {
+	maxPrevalence := a.MaxPrevalence
+	if maxPrevalence <= 0 {
+		maxPrevalence = defaultMaxPrevalence
+	}
+	minHosts := a.MinHosts
+	if minHosts <= 0 {
+		minHosts = defaultMinHosts
+	}
+
+	hosts := newHostResolver(in.Timeline)
+
+	type stack struct {
+		label  string
+		hosts  map[string]bool
+		events []model.TimelineEvent
+	}
+	stacks := make(map[string]map[string]*stack) // category -> key -> stack
+	population := make(map[string]map[string]bool)
+	for _, ev := range in.Timeline {
+		e, ok := stackEntryOf(ev)
+		if !ok {
+			continue
+		}
+		host := hosts.resolve(ev)
+		if population[e.category] == nil {
+			population[e.category] = make(map[string]bool)
+			stacks[e.category] = make(map[string]*stack)
+		}
+		population[e.category][host] = true
+		s := stacks[e.category][e.key]
+		if s == nil {
+			s = &stack{label: e.label, hosts: make(map[string]bool)}
+			stacks[e.category][e.key] = s
+		}
+		s.hosts[host] = true
+		s.events = append(s.events, ev)
+	}
+
+	var findings []model.Finding
+	for _, catName := range sortedKeys(stacks) {
+		cat := stackCategories[catName]
+		total := len(population[catName])
+		if total < minHosts {
+			continue
+		}
+		type outlier struct {
+			key string
+			*stack
+		}
+		var outliers []outlier
+		for key, s := range stacks[catName] {
+			if float64(len(s.hosts))/float64(total) <= maxPrevalence {
+				outliers = append(outliers, outlier{key, s})
+			}
+		}
+		sort.Slice(outliers, func(i, j int) bool {
+			if len(outliers[i].hosts) != len(outliers[j].hosts) {
+				return len(outliers[i].hosts) < len(outliers[j].hosts)
+			}
+			return outliers[i].key < outliers[j].key
+		})
+		if len(outliers) > maxStackFindings {
+			outliers = outliers[:maxStackFindings]
+		}
+		for _, o := range outliers {
+			names := make([]string, 0, len(o.hosts))
+			for h := range o.hosts {
+				names = append(names, hosts.display[h])
+			}
+			sort.Strings(names)
+			sum := sha1.Sum([]byte(o.key))
+			f := model.Finding{
+				ID:       fmt.Sprintf("stack-%s-%s", catName, hex.EncodeToString(sum[:6])),
+				Severity: cat.severity,
+				Title:    fmt.Sprintf("%s on %d of %d hosts: %s", cat.title, len(o.hosts), total, o.label),
+				Description: fmt.Sprintf("%s appears on %d of %d hosts with %s data (%.1f%%, threshold %.1f%%). Hosts: %s.",
+					o.label, len(o.hosts), total, catName, 100*float64(len(o.hosts))/float64(total), 100*maxPrevalence, strings.Join(names, ", ")),
+				RuleID: "host-stacking-" + catName,
+			}
+			seenRef := make(map[string]bool)
+			for _, ev := range o.events {
+				if len(f.EventIDs) < maxStackEventIDs {
+					f.EventIDs = append(f.EventIDs, ev.ID)
+				}
+				if ref := ev.EvidenceRef; ref.SourcePath != "" && !seenRef[ref.SourcePath] {
+					seenRef[ref.SourcePath] = true
+					f.EvidenceRefs = append(f.EvidenceRefs, ref)
+				}
+			}
+			for _, t := range cat.attack {
+				f.Attack = append(f.Attack, model.AttackRef{TechniqueID: t})
+			}
+			findings = append(findings, f)
+		}
+	}
+	return &pluginsdk.AnalyzeResponse{Findings: findings}, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hostResolver attributes events to hosts. Event logs name their computer; registry, Prefetch
+// and Amcache events do not, so they take the host of the collection they were read from:
+// the name event logs of the same collection carry, or else the collection directory name.
+type hostResolver struct {
+	byRoot  map[string]string
+	display map[string]string // short lower-case name -> name as first seen
+}
+
+func newHostResolver(timeline []model.TimelineEvent) *hostResolver {
+	r := &hostResolver{byRoot: make(map[string]string), display: make(map[string]string)}
+	votes := make(map[string]map[string]int)
+	for _, ev := range timeline {
+		h := ev.Host()
+		if h == "" || ev.EvidenceRef.SourcePath == "" {
+			continue
+		}
+		root := strings.ToLower(model.CollectionRoot(ev.EvidenceRef.SourcePath))
+		if votes[root] == nil {
+			votes[root] = make(map[string]int)
+		}
+		votes[root][r.name(h)]++
+	}
+	for root, v := range votes {
+		best := ""
+		for h, n := range v {
+			if best == "" || n > v[best] || (n == v[best] && h < best) {
+				best = h
+			}
+		}
+		r.byRoot[root] = best
+	}
+	return r
+}
+
+// name normalizes a host name to its short lower-case form and remembers how it was written.
+func (r *hostResolver) name(h string) string {
+	if i := strings.IndexByte(h, '.'); i > 0 {
+		h = h[:i]
+	}
+	short := strings.ToLower(h)
+	if _, ok := r.display[short]; !ok {
+		r.display[short] = h
+	}
+	return short
+}
+
+func (r *hostResolver) resolve(ev model.TimelineEvent) string {
+	if h := ev.Host(); h != "" {
+		return r.name(h)
+	}
+	root := model.CollectionRoot(ev.EvidenceRef.SourcePath)
+	if h, ok := r.byRoot[strings.ToLower(root)]; ok {
+		return h
+	}
+	return r.name(model.CollectionName(root))
+}
@@ -0,0 +1,105 @@
+package analyzers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+func TestHostStacking(t *testing.T) {
+	var timeline []model.TimelineEvent
+	service := func(host, name, image string) {
+		timeline = append(timeline, model.TimelineEvent{
+			ID: fmt.Sprintf("svc-%s-%s", host, name), Source: "Registry", Artifact: "Service", Subject: name,
+			Details:     map[string]string{"ServiceName": name, "ImagePath": image},
+			EvidenceRef: model.EvidenceRef{SourcePath: `/cases/` + host + `/C/Windows/System32/config/SYSTEM`},
+		})
+	}
+	runKey := func(host, sid, value string) {
+		timeline = append(timeline, model.TimelineEvent{
+			ID: "run-" + host, Source: "EventLog",
+			Details: map[string]string{"EventID": "13", "Computer": host + ".corp.local",
+				"TargetObject": `HKU\` + sid + `\Software\Microsoft\Windows\CurrentVersion\Run\Updater`, "Details": value},
+			EvidenceRef: model.EvidenceRef{SourcePath: `/cases/` + host + `/C/Windows/System32/winevt/Logs/Microsoft-Windows-Sysmon%4Operational.evtx`},
+		})
+	}
+	for i, host := range []string{"collection-a", "collection-b", "WS03", "WS04"} {
+		service(host, "Spooler", `C:\Windows\System32\spoolsv.exe`)
+		runKey(fmt.Sprintf("WS0%d", i+1), fmt.Sprintf("S-1-5-21-1-2-3-%d", 1000+i), `C:\Program Files\Updater\up.exe`)
+	}
+	service("WS03", "WinSvcHelper", `C:\Users\Public\helper.exe`)
+	// Event logs of the first two collections name their hosts.
+	timeline[1].EvidenceRef.SourcePath = `/cases/collection-a/C/Windows/System32/winevt/Logs/Sysmon.evtx`
+	timeline[3].EvidenceRef.SourcePath = `/cases/collection-b/C/Windows/System32/winevt/Logs/Sysmon.evtx`
+
+	a := &HostStackingAnalyzer{MaxPrevalence: 0.25}
+	resp, err := a.Analyze(context.Background(), pluginsdk.AnalyzeRequest{Timeline: timeline})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Findings) != 1 {
+		t.Fatalf("findings = %+v", resp.Findings)
+	}
+	f := resp.Findings[0]
+	if f.RuleID != "host-stacking-service" || !strings.Contains(f.Title, "1 of 4 hosts") || !strings.Contains(f.Description, "Hosts: WS03.") ||
+		len(f.EventIDs) != 1 || f.EventIDs[0] != "svc-WS03-WinSvcHelper" || len(f.Attack) != 1 {
+		t.Errorf("finding = %+v", f)
+	}
+
+	// Below MinHosts nothing is stacked.
+	resp, err = (&HostStackingAnalyzer{MaxPrevalence: 0.25, MinHosts: 5}).Analyze(context.Background(), pluginsdk.AnalyzeRequest{Timeline: timeline})
+	if err != nil || len(resp.Findings) != 0 {
+		t.Errorf("MinHosts 5: %v %+v", err, resp.Findings)
+	}
+}
+
+func TestHostStackingHiveRunKeys(t *testing.T) {
+	const runPath = `\Microsoft\Windows\CurrentVersion\Run\`
+	var timeline []model.TimelineEvent
+	for i := 1; i <= 3; i++ {
+		host := fmt.Sprintf("WS0%d", i)
+		timeline = append(timeline, model.TimelineEvent{
+			ID: "sysmon-" + host, Source: "EventLog",
+			Details: map[string]string{"EventID": "13", "Computer": host,
+				"TargetObject": fmt.Sprintf(`HKU\S-1-5-21-1-2-3-%d\Software`, 1000+i) + runPath + "Updater", "Details": `C:\Updater\up.exe`},
+		})
+	}
+	// WS04 has no Sysmon; its Run keys are read from its hives.
+	hive := func(id, obj, command, hivePath string) {
+		timeline = append(timeline, model.TimelineEvent{
+			ID: id, Source: "Registry", Artifact: "RunKey",
+			Details:     map[string]string{"TargetObject": obj, "Command": command},
+			EvidenceRef: model.EvidenceRef{SourcePath: "/cases/WS04/C/" + hivePath},
+		})
+	}
+	hive("hive-updater", `HKCU\Software`+runPath+"Updater", `C:\Updater\up.exe`, "Users/bob/NTUSER.DAT")
+	hive("hive-evil", `HKLM\SOFTWARE`+runPath+"Sync", `C:\Users\Public\sync.exe`, "Windows/System32/config/SOFTWARE")
+
+	resp, err := (&HostStackingAnalyzer{MaxPrevalence: 0.25}).Analyze(context.Background(), pluginsdk.AnalyzeRequest{Timeline: timeline})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The user Run key read from NTUSER.DAT stacks with the Sysmon ones of the other hosts.
+	if len(resp.Findings) != 1 {
+		t.Fatalf("findings = %+v", resp.Findings)
+	}
+	if f := resp.Findings[0]; f.RuleID != "host-stacking-run_key" || !strings.Contains(f.Title, "1 of 4 hosts") || f.EventIDs[0] != "hive-evil" {
+		t.Errorf("finding = %+v", f)
+	}
+}
+
+func TestCollectionHost(t *testing.T) {
+	for path, want := range map[string]string{
+		`/cases/WS07/C/Windows/Prefetch/CMD.EXE-1234.pf`: "WS07",
+		`E:\triage\DC01\Windows\System32\config\SYSTEM`:  "DC01",
+		`C:\Windows\System32\config\SYSTEM`:              "unknown",
+	} {
+		if got := model.CollectionName(model.CollectionRoot(path)); got != want {
+			t.Errorf("%s: host %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package analyzers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+)
+
+// LateralGraph is a directed graph of source → destination authentications across hosts.
+type LateralGraph struct {
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []*GraphEdge `json:"edges"`
+}
+
+// GraphNode is a host, or a bare IP when the source machine is not otherwise named.
+type GraphNode struct {
+	ID   string   `json:"id"`
+	Kind string   `json:"kind"` // "host" or "ip"
+	IPs  []string `json:"ips,omitempty"`
+}
+
+// GraphEdge aggregates every authentication from Source to Target.
+type GraphEdge struct {
+	Source     string    `json:"source"`
+	Target     string    `json:"target"`
+	Count      int       `json:"count"`
+	Users      []string  `json:"users"`
+	Methods    []string  `json:"methods"` // rdp, network, explicit-creds, ...
+	LogonTypes []string  `json:"logon_types,omitempty"`
+	Elevated   bool      `json:"elevated"`
+	First      time.Time `json:"first"`
+	Last       time.Time `json:"last"`
+	EventIDs   []string  `json:"event_ids"`
+
+	refs []model.EvidenceRef
+}
+
+// BuildLateralGraph links each remote session's source to the host it landed on, and each
+// 4648 explicit-credential use from its host to the target server.
+func BuildLateralGraph(sessions []*LogonSession) *LateralGraph {
+	g := &graphBuilder{nodes: make(map[string]*GraphNode), edges: make(map[string]*GraphEdge)}
+
+	for _, s := range sessions {
+		dst := normalizeHostName(s.Host)
+		if src := s.sourceNode(); src != "" && dst != "" && s.Start != nil {
+			g.node(dst, "")
+			g.node(src, s.SourceIP)
+			e := g.edge(src, dst)
+			e.add(*s.Start, accountName(s.Domain, s.User), sessionMethod(s), s.EventIDs[:1], s.refs[:1])
+			if lt := logonTypeLabel(s.LogonType); lt != "" && !containsString(e.LogonTypes, lt) {
+				e.LogonTypes = append(e.LogonTypes, lt)
+			}
+			e.Elevated = e.Elevated || s.Elevated
+		}
+
+		for _, c := range s.ExplicitCreds {
+			target := normalizeHostName(c.Target)
+			if target == "" || target == dst || target == "LOCALHOST" {
+				continue
+			}
+			g.node(dst, "")
+			g.node(target, "")
+			g.edge(dst, target).add(c.Time, c.User, "explicit-creds", []string{c.EventID}, nil)
+		}
+	}
+
+	out := &LateralGraph{Nodes: []*GraphNode{}, Edges: []*GraphEdge{}}
+	for _, n := range g.nodes {
+		out.Nodes = append(out.Nodes, n)
+	}
+	for _, e := range g.edges {
+		sort.Strings(e.Users)
+		sort.Strings(e.Methods)
+		out.Edges = append(out.Edges, e)
+	}
+	sort.Slice(out.Nodes, func(i, j int) bool { return out.Nodes[i].ID < out.Nodes[j].ID })
+	sort.Slice(out.Edges, func(i, j int) bool {
+		if out.Edges[i].Source != out.Edges[j].Source {
+			return out.Edges[i].Source < out.Edges[j].Source
+		}
+		return out.Edges[i].Target < out.Edges[j].Target
+	})
+	return out
+}
+
+type graphBuilder struct {
+	nodes map[string]*GraphNode
+	edges map[string]*GraphEdge
+}
+
+func (g *graphBuilder) node(id, ip string) {
+	n := g.nodes[id]
+	if n == nil {
+		kind := "host"
+		if net.ParseIP(id) != nil {
+			kind = "ip"
+		}
+		n = &GraphNode{ID: id, Kind: kind}
+		g.nodes[id] = n
+	}
+	if ip != "" && ip != id && isRemoteAddress(ip) && !containsString(n.IPs, ip) {
+		n.IPs = append(n.IPs, ip)
+	}
+}
+
+func (g *graphBuilder) edge(src, dst string) *GraphEdge {
+	key := src + "\x00" + dst
+	e := g.edges[key]
+	if e == nil {
+		e = &GraphEdge{Source: src, Target: dst}
+		g.edges[key] = e
+	}
+	return e
+}
+
+func (e *GraphEdge) add(at time.Time, user, method string, eventIDs []string, refs []model.EvidenceRef) {
+	e.Count++
+	if user != "" && !containsString(e.Users, user) {
+		e.Users = append(e.Users, user)
+	}
+	if !containsString(e.Methods, method) {
+		e.Methods = append(e.Methods, method)
+	}
+	if e.First.IsZero() || at.Before(e.First) {
+		e.First = at
+	}
+	if at.After(e.Last) {
+		e.Last = at
+	}
+	e.EventIDs = append(e.EventIDs, eventIDs...)
+	e.refs = append(e.refs, refs...)
+}
+
+// normalizeHostName upper-cases a host and strips the DNS suffix so "ws01.corp.local" and the
+// NetBIOS "WS01" meet on one node. IP addresses are left as-is.
+func normalizeHostName(h string) string {
+	h = strings.TrimSpace(strings.TrimPrefix(cleanField(h), `\\`))
+	if h == "" || net.ParseIP(h) != nil {
+		return h
+	}
+	if i := strings.IndexByte(h, '.'); i > 0 {
+		h = h[:i]
+	}
+	return strings.ToUpper(h)
+}
+
+func accountName(domain, user string) string {
+	if domain == "" || user == "" {
+		return user
+	}
+	return domain + `\` + user
+}
+
+// WriteJSON writes the graph as indented JSON.
+func (g *LateralGraph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph in GraphML for Gephi, yEd, Cytoscape and friends.
+func (g *LateralGraph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "kind", For: "node", AttrName: "kind", AttrType: "string"},
+			{ID: "ips", For: "node", AttrName: "ips", AttrType: "string"},
+			{ID: "count", For: "edge", AttrName: "count", AttrType: "int"},
+			{ID: "users", For: "edge", AttrName: "users", AttrType: "string"},
+			{ID: "methods", For: "edge", AttrName: "methods", AttrType: "string"},
+			{ID: "logon_types", For: "edge", AttrName: "logon_types", AttrType: "string"},
+			{ID: "elevated", For: "edge", AttrName: "elevated", AttrType: "boolean"},
+			{ID: "first", For: "edge", AttrName: "first", AttrType: "string"},
+			{ID: "last", For: "edge", AttrName: "last", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "lateral-movement", EdgeDefault: "directed"},
+	}
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: n.ID, Data: []graphMLData{
+			{Key: "kind", Value: n.Kind},
+			{Key: "ips", Value: strings.Join(n.IPs, ",")},
+		}})
+	}
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     "e" + strconv.Itoa(i),
+			Source: e.Source,
+			Target: e.Target,
+			Data: []graphMLData{
+				{Key: "count", Value: strconv.Itoa(e.Count)},
+				{Key: "users", Value: strings.Join(e.Users, ",")},
+				{Key: "methods", Value: strings.Join(e.Methods, ",")},
+				{Key: "logon_types", Value: strings.Join(e.LogonTypes, ",")},
+				{Key: "elevated", Value: strconv.FormatBool(e.Elevated)},
+				{Key: "first", Value: e.First.UTC().Format(time.RFC3339)},
+				{Key: "last", Value: e.Last.UTC().Format(time.RFC3339)},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
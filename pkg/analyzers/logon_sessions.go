@@ -0,0 +1,478 @@
+package analyzers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+const (
+	lsmChannel = "Microsoft-Windows-TerminalServices-LocalSessionManager/Operational"
+	rcmChannel = "Microsoft-Windows-TerminalServices-RemoteConnectionManager/Operational"
+
+	// rdpMatchWindow is how far a LocalSessionManager 21 may trail its 4624 type 10.
+	rdpMatchWindow = 30 * time.Second
+	// maxSessionProcesses bounds the process list kept per session.
+	maxSessionProcesses = 500
+)
+
+var logonTypeNames = map[string]string{
+	"2":  "Interactive",
+	"3":  "Network",
+	"4":  "Batch",
+	"5":  "Service",
+	"7":  "Unlock",
+	"8":  "NetworkCleartext",
+	"9":  "NewCredentials",
+	"10": "RemoteInteractive",
+	"11": "CachedInteractive",
+	"12": "CachedRemoteInteractive",
+	"13": "CachedUnlock",
+}
+
+// LogonSession is one logon reconstructed from Security and TerminalServices events.
+type LogonSession struct {
+	Host              string            `json:"host"`
+	LogonID           string            `json:"logon_id,omitempty"`
+	User              string            `json:"user"`
+	Domain            string            `json:"domain,omitempty"`
+	LogonType         string            `json:"logon_type,omitempty"`
+	LogonTypeName     string            `json:"logon_type_name,omitempty"`
+	Start             *time.Time        `json:"start,omitempty"`
+	End               *time.Time        `json:"end,omitempty"`
+	DurationSeconds   int64             `json:"duration_seconds,omitempty"`
+	EndReason         string            `json:"end_reason,omitempty"`
+	SourceIP          string            `json:"source_ip,omitempty"`
+	SourceWorkstation string            `json:"source_workstation,omitempty"`
+	AuthPackage       string            `json:"auth_package,omitempty"`
+	Elevated          bool              `json:"elevated"`
+	Privileges        string            `json:"privileges,omitempty"`
+	RDP               bool              `json:"rdp"`
+	RDPSessionID      string            `json:"rdp_session_id,omitempty"`
+	Activity          []SessionActivity `json:"activity,omitempty"`
+	ExplicitCreds     []ExplicitCred    `json:"explicit_creds,omitempty"` // 4648s raised from this session
+	Processes         []SessionProcess  `json:"processes,omitempty"`
+	EventIDs          []string          `json:"event_ids"`
+
+	refs []model.EvidenceRef
+}
+
+// SessionActivity records RDP state changes (shell start, disconnect, reconnect).
+type SessionActivity struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Address string    `json:"address,omitempty"`
+}
+
+// ExplicitCred is a 4648: alternate credentials used from a session against a target.
+type ExplicitCred struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Target  string    `json:"target"`
+	Process string    `json:"process,omitempty"`
+	EventID string    `json:"event_id"`
+}
+
+// SessionProcess is a process started inside a session.
+type SessionProcess struct {
+	Time        time.Time `json:"time"`
+	EventID     string    `json:"event_id"`
+	Image       string    `json:"image"`
+	CommandLine string    `json:"command_line,omitempty"`
+}
+
+// Remote reports whether the session was established from another machine.
+func (s *LogonSession) Remote() bool {
+	return s.sourceNode() != ""
+}
+
+// sourceNode names the originating machine, preferring the workstation name over the IP.
+func (s *LogonSession) sourceNode() string {
+	if w := normalizeHostName(s.SourceWorkstation); w != "" && w != normalizeHostName(s.Host) {
+		return w
+	}
+	if isRemoteAddress(s.SourceIP) {
+		return s.SourceIP
+	}
+	return ""
+}
+
+// BuildLogonSessions stitches 4624/4634/4647/4672/4648, TerminalServices 21-25 and 1149 and
+// process creation events into sessions keyed by host and LogonId.
+func BuildLogonSessions(events []model.TimelineEvent) []*LogonSession {
+	sorted := make([]model.TimelineEvent, 0, len(events))
+	for _, ev := range events {
+		if ev.Details["EventID"] != "" {
+			sorted = append(sorted, ev)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].EventTime.Before(sorted[j].EventTime) })
+
+	b := &sessionBuilder{byLogon: make(map[string]*LogonSession), byRDP: make(map[string]*LogonSession)}
+	for _, ev := range sorted {
+		b.add(ev)
+	}
+
+	for _, s := range b.sessions {
+		if s.Start != nil && s.End != nil {
+			s.DurationSeconds = int64(s.End.Sub(*s.Start).Seconds())
+		}
+	}
+	return b.sessions
+}
+
+type sessionBuilder struct {
+	sessions []*LogonSession
+	byLogon  map[string]*LogonSession // host|logonid → latest session
+	byRDP    map[string]*LogonSession // host|rdp session id → latest session
+	pending  []rdpAuth                // 1149s waiting for their session
+}
+
+// rdpAuth is a RemoteConnectionManager 1149 (network-level authentication succeeded).
+type rdpAuth struct {
+	host, user, ip string
+	at             time.Time
+}
+
+func (b *sessionBuilder) add(ev model.TimelineEvent) {
+	d := ev.Details
+	host := d["Computer"]
+	channel := d["Channel"]
+
+	switch {
+	case channel == "Security":
+		b.addSecurity(ev, host)
+	case strings.EqualFold(channel, lsmChannel):
+		b.addLocalSessionManager(ev, host)
+	case strings.EqualFold(channel, rcmChannel) && d["EventID"] == "1149":
+		user := d["Param1"]
+		if dom := d["Param2"]; dom != "" {
+			user = dom + `\` + user
+		}
+		b.pending = append(b.pending, rdpAuth{host: host, user: user, ip: d["Param3"], at: ev.EventTime})
+	case strings.EqualFold(channel, "Microsoft-Windows-Sysmon/Operational") && d["EventID"] == "1":
+		if s := b.byLogon[logonKey(host, d["LogonId"])]; s != nil {
+			s.addProcess(ev, d["Image"], d["CommandLine"])
+		}
+	}
+}
+
+func (b *sessionBuilder) addSecurity(ev model.TimelineEvent, host string) {
+	d := ev.Details
+	switch d["EventID"] {
+	case "4624":
+		at := ev.EventTime
+		s := &LogonSession{
+			Host:              host,
+			LogonID:           strings.ToLower(d["TargetLogonId"]),
+			User:              d["TargetUserName"],
+			Domain:            d["TargetDomainName"],
+			LogonType:         d["LogonType"],
+			LogonTypeName:     logonTypeNames[d["LogonType"]],
+			Start:             &at,
+			SourceIP:          cleanField(d["IpAddress"]),
+			SourceWorkstation: cleanField(d["WorkstationName"]),
+			AuthPackage:       cleanField(d["AuthenticationPackageName"]),
+			// %%1842 is "Yes" in the ElevatedToken message table.
+			Elevated: d["ElevatedToken"] == "%%1842",
+		}
+		s.track(ev)
+		b.sessions = append(b.sessions, s)
+		b.byLogon[logonKey(host, s.LogonID)] = s
+		b.claimRDPAuth(s)
+
+	case "4672":
+		if s := b.byLogon[logonKey(host, d["SubjectLogonId"])]; s != nil {
+			s.Elevated = true
+			s.Privileges = strings.Join(strings.Fields(d["PrivilegeList"]), " ")
+			s.track(ev)
+		}
+
+	case "4634", "4647":
+		if s := b.byLogon[logonKey(host, d["TargetLogonId"])]; s != nil {
+			s.close(ev.EventTime, map[string]string{"4634": "logoff", "4647": "user-initiated logoff"}[d["EventID"]])
+			s.track(ev)
+		}
+
+	case "4648":
+		s := b.byLogon[logonKey(host, d["SubjectLogonId"])]
+		if s == nil {
+			// The originating logon predates the log; keep the 4648 on a placeholder session.
+			s = &LogonSession{Host: host, LogonID: strings.ToLower(d["SubjectLogonId"]), User: d["SubjectUserName"], Domain: d["SubjectDomainName"]}
+			b.sessions = append(b.sessions, s)
+			b.byLogon[logonKey(host, s.LogonID)] = s
+		}
+		target := cleanField(d["TargetServerName"])
+		if target == "" {
+			target = cleanField(d["TargetInfo"])
+		}
+		s.ExplicitCreds = append(s.ExplicitCreds, ExplicitCred{
+			Time:    ev.EventTime,
+			User:    d["TargetUserName"],
+			Target:  target,
+			Process: cleanField(d["ProcessName"]),
+			EventID: ev.ID,
+		})
+		s.track(ev)
+
+	case "4688":
+		logon := d["TargetLogonId"]
+		if logon == "" || logon == "0x0" {
+			logon = d["SubjectLogonId"]
+		}
+		if s := b.byLogon[logonKey(host, logon)]; s != nil {
+			s.addProcess(ev, d["NewProcessName"], cleanField(d["CommandLine"]))
+		}
+	}
+}
+
+func (b *sessionBuilder) addLocalSessionManager(ev model.TimelineEvent, host string) {
+	d := ev.Details
+	rdpKey := host + "|" + d["SessionID"]
+	address := cleanField(d["Address"])
+	if strings.EqualFold(address, "LOCAL") {
+		address = ""
+	}
+
+	switch d["EventID"] {
+	case "21":
+		s := b.matchRDPLogon(host, d["User"], ev.EventTime)
+		if s == nil {
+			at := ev.EventTime
+			domain, user := splitAccount(d["User"])
+			s = &LogonSession{Host: host, User: user, Domain: domain, Start: &at, SourceIP: address}
+			b.sessions = append(b.sessions, s)
+			b.claimRDPAuth(s)
+		}
+		s.RDP = address != "" || s.LogonType == "10"
+		s.RDPSessionID = d["SessionID"]
+		if s.SourceIP == "" {
+			s.SourceIP = address
+		}
+		s.track(ev)
+		b.byRDP[rdpKey] = s
+
+	case "22", "24", "25":
+		if s := b.byRDP[rdpKey]; s != nil {
+			action := map[string]string{"22": "shell start", "24": "disconnect", "25": "reconnect"}[d["EventID"]]
+			s.Activity = append(s.Activity, SessionActivity{Time: ev.EventTime, Action: action, Address: address})
+			s.track(ev)
+		}
+
+	case "23":
+		if s := b.byRDP[rdpKey]; s != nil {
+			s.close(ev.EventTime, "rdp logoff")
+			s.track(ev)
+		}
+	}
+}
+
+// matchRDPLogon finds the 4624 type 10 that a LocalSessionManager 21 belongs to.
+func (b *sessionBuilder) matchRDPLogon(host, account string, at time.Time) *LogonSession {
+	_, user := splitAccount(account)
+	for i := len(b.sessions) - 1; i >= 0; i-- {
+		s := b.sessions[i]
+		if s.Start == nil {
+			continue
+		}
+		if at.Sub(*s.Start) > rdpMatchWindow {
+			break
+		}
+		if s.Host == host && s.LogonType == "10" && s.RDPSessionID == "" && strings.EqualFold(s.User, user) {
+			return s
+		}
+	}
+	return nil
+}
+
+// claimRDPAuth attaches a preceding 1149 to s, filling in the source address.
+func (b *sessionBuilder) claimRDPAuth(s *LogonSession) {
+	for i, a := range b.pending {
+		_, user := splitAccount(a.user)
+		if a.host != s.Host || !strings.EqualFold(user, s.User) || s.Start.Sub(a.at) > rdpMatchWindow || s.Start.Before(a.at) {
+			continue
+		}
+		s.RDP = true
+		if s.SourceIP == "" || !isRemoteAddress(s.SourceIP) {
+			s.SourceIP = a.ip
+		}
+		b.pending = append(b.pending[:i], b.pending[i+1:]...)
+		return
+	}
+}
+
+func (s *LogonSession) track(ev model.TimelineEvent) {
+	s.EventIDs = append(s.EventIDs, ev.ID)
+	s.refs = append(s.refs, ev.EvidenceRef)
+}
+
+func (s *LogonSession) close(at time.Time, reason string) {
+	if s.End != nil {
+		return
+	}
+	s.End = &at
+	s.EndReason = reason
+}
+
+func (s *LogonSession) addProcess(ev model.TimelineEvent, image, cmd string) {
+	if len(s.Processes) >= maxSessionProcesses {
+		return
+	}
+	s.Processes = append(s.Processes, SessionProcess{Time: ev.EventTime, EventID: ev.ID, Image: image, CommandLine: cmd})
+}
+
+func logonKey(host, logonID string) string {
+	return strings.ToLower(host) + "|" + strings.ToLower(logonID)
+}
+
+// splitAccount splits DOMAIN\user.
+func splitAccount(account string) (domain, user string) {
+	if i := strings.LastIndex(account, `\`); i >= 0 {
+		return account[:i], account[i+1:]
+	}
+	return "", account
+}
+
+// cleanField drops the "-" placeholder Windows writes for empty values.
+func cleanField(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "-" {
+		return ""
+	}
+	return v
+}
+
+func isRemoteAddress(ip string) bool {
+	switch ip {
+	case "", "-", "127.0.0.1", "::1", "0.0.0.0", "::":
+		return false
+	}
+	return true
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// LogonSessionAnalyzer reconstructs logon sessions and reports remote execution: network
+// logons that started processes on the target (WMI, WinRM, scheduled or service execution
+// with the caller's token). Ordinary remote authentications (file shares, domain controller
+// logons, RDP) only feed the session list and the lateral-movement graph.
+type LogonSessionAnalyzer struct{}
+
+func (a *LogonSessionAnalyzer) Manifest() pluginsdk.Manifest {
+	return pluginsdk.Manifest{
+		Name:      "logon-sessions",
+		Version:   "1.0.0",
+		Type:      "analyzer",
+		Platforms: []string{"windows"},
+		Input: pluginsdk.IODecl{
+			Kind: "timeline",
+		},
+		Output: pluginsdk.IODecl{
+			Artifact: "finding",
+		},
+	}
+}
+
+func (a *LogonSessionAnalyzer) Analyze(ctx context.Context, in pluginsdk.AnalyzeRequest) (*pluginsdk.AnalyzeResponse, error) {
+	type execEdge struct {
+		src, dst  string
+		users     []string
+		images    []string
+		sessions  int
+		processes int
+		elevated  bool
+		first     time.Time
+		eventIDs  []string
+		refs      []model.EvidenceRef
+	}
+	edges := make(map[string]*execEdge)
+	var order []string
+	for _, s := range BuildLogonSessions(in.Timeline) {
+		if sessionMethod(s) != "network" || len(s.Processes) == 0 || s.Start == nil {
+			continue
+		}
+		src, dst := s.sourceNode(), normalizeHostName(s.Host)
+		if src == "" || dst == "" {
+			continue
+		}
+		key := src + "\x00" + dst
+		e := edges[key]
+		if e == nil {
+			e = &execEdge{src: src, dst: dst, first: *s.Start}
+			edges[key] = e
+			order = append(order, key)
+		}
+		e.sessions++
+		e.processes += len(s.Processes)
+		e.elevated = e.elevated || s.Elevated
+		if user := accountName(s.Domain, s.User); !containsString(e.users, user) {
+			e.users = append(e.users, user)
+		}
+		for _, p := range s.Processes {
+			if image := p.Image; len(e.images) < 5 && !containsString(e.images, image) {
+				e.images = append(e.images, image)
+			}
+			e.eventIDs = append(e.eventIDs, p.EventID)
+		}
+		e.eventIDs = append(e.eventIDs, s.EventIDs[0])
+		e.refs = append(e.refs, s.refs[:1]...)
+	}
+
+	var findings []model.Finding
+	for _, key := range order {
+		e := edges[key]
+		severity := "medium"
+		if e.elevated {
+			severity = "high"
+		}
+		findings = append(findings, model.Finding{
+			ID:       fmt.Sprintf("lateral-%s-%s", e.src, e.dst),
+			Severity: severity,
+			Title:    fmt.Sprintf("Remote execution %s -> %s", e.src, e.dst),
+			Description: fmt.Sprintf("%d network logon(s) from %s to %s as %s started %d process(es), first at %s: %s.",
+				e.sessions, e.src, e.dst, strings.Join(e.users, ", "), e.processes,
+				e.first.Format(time.RFC3339), strings.Join(e.images, ", ")),
+			RuleID:       "lateral-remote-execution",
+			EventIDs:     e.eventIDs,
+			EvidenceRefs: e.refs,
+			Attack:       []model.AttackRef{{TechniqueID: "T1021", TacticIDs: []string{"TA0008"}}},
+		})
+	}
+	return &pluginsdk.AnalyzeResponse{Findings: findings}, nil
+}
+
+// sessionMethod classifies how a remote session authenticated.
+func sessionMethod(s *LogonSession) string {
+	switch {
+	case s.RDP || s.LogonType == "10" || s.LogonType == "12":
+		return "rdp"
+	case s.LogonType == "3" || s.LogonType == "8":
+		return "network"
+	case s.LogonType != "":
+		return strings.ToLower(logonTypeNames[s.LogonType])
+	}
+	return "unknown"
+}
+
+func logonTypeLabel(t string) string {
+	if name := logonTypeNames[t]; name != "" {
+		return t + " (" + name + ")"
+	}
+	if _, err := strconv.Atoi(t); err == nil {
+		return t
+	}
+	return ""
+}
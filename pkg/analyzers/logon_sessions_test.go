@@ -0,0 +1,119 @@
+package analyzers
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"testing"
+	"time"
+
+	"gtrace/pkg/model"
+	"gtrace/pkg/pluginsdk"
+)
+
+func logonEvent(id, host, channel, eid string, at time.Time, details map[string]string) model.TimelineEvent {
+	d := map[string]string{"EventID": eid, "Channel": channel, "Computer": host}
+	for k, v := range details {
+		d[k] = v
+	}
+	return model.TimelineEvent{ID: id, EventTime: at, Source: "EventLog", Details: d}
+}
+
+func TestBuildLogonSessionsRDP(t *testing.T) {
+	base := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	host := "SRV01.corp.local"
+	events := []model.TimelineEvent{
+		logonEvent("1149", host, rcmChannel, "1149", base, map[string]string{"Param1": "alice", "Param2": "CORP", "Param3": "10.0.0.5"}),
+		logonEvent("4624", host, "Security", "4624", base.Add(time.Second), map[string]string{
+			"TargetUserName": "alice", "TargetDomainName": "CORP", "TargetLogonId": "0x3E7A1", "LogonType": "10",
+			"IpAddress": "10.0.0.5", "WorkstationName": "WS07",
+		}),
+		logonEvent("4672", host, "Security", "4672", base.Add(time.Second), map[string]string{"SubjectLogonId": "0x3e7a1", "PrivilegeList": "SeDebugPrivilege\n\t\tSeBackupPrivilege"}),
+		logonEvent("21", host, lsmChannel, "21", base.Add(2*time.Second), map[string]string{"User": `CORP\alice`, "SessionID": "3", "Address": "10.0.0.5"}),
+		logonEvent("4688", host, "Security", "4688", base.Add(time.Minute), map[string]string{"SubjectLogonId": "0x3e7a1", "TargetLogonId": "0x0", "NewProcessName": `C:\Windows\System32\whoami.exe`}),
+		logonEvent("4648", host, "Security", "4648", base.Add(2*time.Minute), map[string]string{"SubjectLogonId": "0x3e7a1", "TargetUserName": "admin", "TargetServerName": "dc01.corp.local"}),
+		logonEvent("24", host, lsmChannel, "24", base.Add(3*time.Minute), map[string]string{"SessionID": "3", "Address": "10.0.0.5"}),
+		logonEvent("4634", host, "Security", "4634", base.Add(10*time.Minute), map[string]string{"TargetLogonId": "0x3E7A1"}),
+	}
+
+	sessions := BuildLogonSessions(events)
+	if len(sessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(sessions))
+	}
+	s := sessions[0]
+	if !s.RDP || !s.Elevated || s.RDPSessionID != "3" || s.DurationSeconds != 599 {
+		t.Errorf("unexpected session: %+v", s)
+	}
+	if len(s.Processes) != 1 || len(s.Activity) != 1 || len(s.ExplicitCreds) != 1 {
+		t.Errorf("processes/activity/creds = %d/%d/%d", len(s.Processes), len(s.Activity), len(s.ExplicitCreds))
+	}
+
+	g := BuildLateralGraph(sessions)
+	if len(g.Edges) != 2 {
+		t.Fatalf("got %d edges, want 2: %+v", len(g.Edges), g.Edges)
+	}
+	if e := g.Edges[1]; e.Source != "WS07" || e.Target != "SRV01" || e.Methods[0] != "rdp" || !e.Elevated {
+		t.Errorf("unexpected RDP edge: %+v", e)
+	}
+	if e := g.Edges[0]; e.Source != "SRV01" || e.Target != "DC01" || e.Methods[0] != "explicit-creds" {
+		t.Errorf("unexpected 4648 edge: %+v", e)
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteGraphML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("GraphML does not parse: %v", err)
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Errorf("GraphML has %d nodes / %d edges", len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+
+	// RDP and explicit credentials are graph edges, not findings.
+	resp, err := (&LogonSessionAnalyzer{}).Analyze(context.Background(), pluginsdk.AnalyzeRequest{Timeline: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Findings) != 0 {
+		t.Errorf("got %d findings, want none: %+v", len(resp.Findings), resp.Findings)
+	}
+}
+
+func TestLogonSessionAnalyzerRemoteExecution(t *testing.T) {
+	base := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	host := "SRV02.corp.local"
+	network := func(id, logonID, workstation string, at time.Time) model.TimelineEvent {
+		return logonEvent(id, host, "Security", "4624", at, map[string]string{
+			"TargetUserName": "svc_admin", "TargetDomainName": "CORP", "TargetLogonId": logonID, "LogonType": "3",
+			"IpAddress": "10.0.0.9", "WorkstationName": workstation,
+		})
+	}
+	var events []model.TimelineEvent
+	// A file server sees a network logon per share access; none of them runs anything.
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("share-%d", i)
+		events = append(events, network(id, fmt.Sprintf("0x%x", 0x1000+i), "WS10", base.Add(time.Duration(i)*time.Second)))
+	}
+	events = append(events,
+		network("wmi-logon", "0x9F01", "WS09", base.Add(time.Minute)),
+		logonEvent("4672", host, "Security", "4672", base.Add(time.Minute), map[string]string{"SubjectLogonId": "0x9f01"}),
+		logonEvent("4688", host, "Security", "4688", base.Add(61*time.Second), map[string]string{
+			"SubjectLogonId": "0x9F01", "NewProcessName": `C:\Windows\System32\cmd.exe`, "CommandLine": `cmd.exe /q /c whoami 1> \\127.0.0.1\ADMIN$\__1714640461 2>&1`,
+		}),
+	)
+
+	resp, err := (&LogonSessionAnalyzer{}).Analyze(context.Background(), pluginsdk.AnalyzeRequest{Timeline: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Findings) != 1 {
+		t.Fatalf("got %d findings, want 1: %+v", len(resp.Findings), resp.Findings)
+	}
+	f := resp.Findings[0]
+	if f.ID != "lateral-WS09-SRV02" || f.Severity != "high" || f.RuleID != "lateral-remote-execution" || len(f.EventIDs) != 2 {
+		t.Errorf("finding = %+v", f)
+	}
+}
@@ -41,6 +41,8 @@ func (a *TempExecutionAnalyzer) Analyze(ctx context.Context, in pluginsdk.Analyz
 				Title:       "Execution from temp-like path",
 				Description: fmt.Sprintf("Timeline %s executed from %s", ev.ID, path),
 				RuleID:      "temp-exec",
+				EventIDs:    []string{ev.ID},
+				Attack:      []model.AttackRef{{TechniqueID: "T1204.002"}},
 				EvidenceRefs: []model.EvidenceRef{
 					ev.EvidenceRef,
 				},
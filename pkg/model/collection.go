@@ -0,0 +1,44 @@
+package model
+
+import (
+	"path"
+	"strings"
+)
+
+// volumeDirs are top-level directories of a Windows volume; whatever precedes them in an
+// evidence path is the collection the file came from.
+var volumeDirs = []string{"/windows/", "/users/", "/programdata/", "/$recycle.bin/", "/documents and settings/", "/program files"}
+
+// CollectionRoot returns the collection an evidence path was read from: the part before the
+// Windows volume directories (/cases/WS07/C for /cases/WS07/C/Windows/...), in forward-slash
+// form, or the file's directory when the path has none.
+func CollectionRoot(p string) string {
+	q := strings.ReplaceAll(p, `\`, "/")
+	lower := strings.ToLower(q)
+	if len(lower) != len(q) {
+		q = lower // keep byte offsets aligned
+	}
+	cut := -1
+	for _, d := range volumeDirs {
+		if i := strings.Index(lower, d); i >= 0 && (cut < 0 || i < cut) {
+			cut = i
+		}
+	}
+	if cut < 0 {
+		return path.Dir(q)
+	}
+	return q[:cut]
+}
+
+// CollectionName names a collection after its directory, skipping a drive-letter level
+// ("/cases/WS07/C" is WS07).
+func CollectionName(root string) string {
+	for root != "" && root != "/" && root != "." {
+		base := path.Base(root)
+		if len(strings.TrimSuffix(base, ":")) > 1 {
+			return base
+		}
+		root = path.Dir(root)
+	}
+	return "unknown"
+}
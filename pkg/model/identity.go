@@ -0,0 +1,68 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventFingerprint hashes what a record says, independent of the evidence copy it was read
+// from: the parser, source, time, action, subject and the parser's own details. Internal "_"
+// annotations (Sigma hits), the artifact file name and the evidence path are left out, so the
+// same record read from two copies of a log fingerprints the same.
+func EventFingerprint(parser string, ev TimelineEvent) string {
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(strconv.Itoa(len(s))))
+		h.Write([]byte{':'})
+		h.Write([]byte(s))
+	}
+	write(parser)
+	write(ev.Source)
+	write(ev.EventTime.UTC().Format(time.RFC3339Nano))
+	write(ev.Action)
+	write(ev.Subject)
+
+	keys := make([]string, 0, len(ev.Details))
+	for k, v := range ev.Details {
+		if v != "" && !strings.HasPrefix(k, "_") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		write(k)
+		write(ev.Details[k])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// RepeatFingerprint distinguishes the nth repeat (n >= 1) of a record within one parse of one
+// evidence file. Records without a record number of their own can be identical in every field
+// (two equal rows of a history database); numbering them keeps them apart, while another copy
+// of the same evidence repeats them the same way and still fingerprints the same.
+func RepeatFingerprint(fingerprint string, n int) string {
+	if n <= 0 {
+		return fingerprint
+	}
+	sum := sha256.Sum256([]byte(fingerprint + "#" + strconv.Itoa(n)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// StableEventID derives an event ID from the evidence hash, the parser, the record offset and
+// the record fingerprint. Re-parsing the same evidence yields the same IDs, so annotations and
+// references survive a re-run.
+func StableEventID(evidenceSHA256, parser string, offset int64, fingerprint string) string {
+	h := sha256.New()
+	h.Write([]byte(evidenceSHA256))
+	h.Write([]byte{0})
+	h.Write([]byte(parser))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(offset, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(fingerprint))
+	return "ev-" + hex.EncodeToString(h.Sum(nil)[:16])
+}
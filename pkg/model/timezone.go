@@ -0,0 +1,146 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp semantics: what TimelineEvent.EventTime measures. EventTime is always UTC.
+const (
+	TimeCreated      = "created"
+	TimeModified     = "modified"
+	TimeAccessed     = "accessed"
+	TimeLastRun      = "last_run"
+	TimeLastLogon    = "last_logon"
+	TimeKeyWrite     = "key_write" // registry key LastWriteTime
+	TimeLogged       = "logged"    // when the record was written to its log or history
+	TimeDeleted      = "deleted"
+	TimeConnected    = "connected"
+	TimeDisconnected = "disconnected"
+	TimeCollected    = "collected" // observed on a live system while collecting
+)
+
+// TZRule is the relative transition date of a Windows time zone: the Week-th (5 = last)
+// Weekday (0 = Sunday) of Month, at Hour:Minute local time.
+type TZRule struct {
+	Month   int `json:"month"`
+	Week    int `json:"week"`
+	Weekday int `json:"weekday"`
+	Hour    int `json:"hour"`
+	Minute  int `json:"minute"`
+}
+
+// String formats r POSIX-style: "M3.5.0/02:00".
+func (r TZRule) String() string {
+	return fmt.Sprintf("M%d.%d.%d/%02d:%02d", r.Month, r.Week, r.Weekday, r.Hour, r.Minute)
+}
+
+// ParseTZRule parses the String form.
+func ParseTZRule(s string) (TZRule, error) {
+	var r TZRule
+	if _, err := fmt.Sscanf(s, "M%d.%d.%d/%d:%d", &r.Month, &r.Week, &r.Weekday, &r.Hour, &r.Minute); err != nil {
+		return r, fmt.Errorf("invalid transition rule %q", s)
+	}
+	if r.Month < 1 || r.Month > 12 || r.Week < 1 || r.Week > 5 || r.Weekday < 0 || r.Weekday > 6 {
+		return r, fmt.Errorf("invalid transition rule %q", s)
+	}
+	return r, nil
+}
+
+// date returns the local wall-clock time of the transition in year, as a UTC-located time.
+func (r TZRule) date(year int) time.Time {
+	first := time.Date(year, time.Month(r.Month), 1, r.Hour, r.Minute, 0, 0, time.UTC)
+	day := first.AddDate(0, 0, (r.Weekday-int(first.Weekday())+7)%7+7*(r.Week-1))
+	for day.Month() != first.Month() {
+		day = day.AddDate(0, 0, -7) // week 5 means the last one
+	}
+	return day
+}
+
+// HostTimezone is the Windows TIME_ZONE_INFORMATION of a host, as read from the SYSTEM hive.
+// Biases are in minutes with UTC = local + bias. Dynamic DST tables are not modelled; the
+// transition rules in effect on the host apply to every year.
+type HostTimezone struct {
+	Name          string  `json:"name"` // TimeZoneKeyName, e.g. "W. Europe Standard Time"
+	StandardName  string  `json:"standard_name,omitempty"`
+	DaylightName  string  `json:"daylight_name,omitempty"`
+	Bias          int     `json:"bias"`
+	StandardBias  int     `json:"standard_bias,omitempty"`
+	DaylightBias  int     `json:"daylight_bias,omitempty"`
+	StandardStart *TZRule `json:"standard_start,omitempty"`
+	DaylightStart *TZRule `json:"daylight_start,omitempty"`
+}
+
+// OffsetAt returns the host's offset from UTC in minutes (east positive) at the instant t.
+func (z HostTimezone) OffsetAt(t time.Time) int {
+	std := -(z.Bias + z.StandardBias)
+	if z.StandardStart == nil || z.DaylightStart == nil {
+		return std
+	}
+	dst := -(z.Bias + z.DaylightBias)
+	t = t.UTC()
+	year := t.Add(time.Duration(std) * time.Minute).Year()
+	// Daylight time starts on the standard clock and ends on the daylight clock.
+	start := z.DaylightStart.date(year).Add(-time.Duration(std) * time.Minute)
+	end := z.StandardStart.date(year).Add(-time.Duration(dst) * time.Minute)
+	var inDST bool
+	if start.Before(end) {
+		inDST = !t.Before(start) && t.Before(end)
+	} else { // southern hemisphere
+		inDST = !t.Before(start) || t.Before(end)
+	}
+	if inDST {
+		return dst
+	}
+	return std
+}
+
+// ToUTC interprets a wall-clock reading taken on the host (its location is ignored) as UTC.
+func (z HostTimezone) ToUTC(local time.Time) time.Time {
+	wall := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC)
+	// Offsets differ by at most the DST delta; a second pass settles readings near a transition.
+	utc := wall.Add(-time.Duration(z.OffsetAt(wall)) * time.Minute)
+	return wall.Add(-time.Duration(z.OffsetAt(utc)) * time.Minute)
+}
+
+// Metadata flattens z into artifact metadata; ParseHostTimezone reverses it.
+func (z HostTimezone) Metadata() map[string]string {
+	m := map[string]string{
+		"TimeZoneKeyName": z.Name,
+		"StandardName":    z.StandardName,
+		"DaylightName":    z.DaylightName,
+		"Bias":            strconv.Itoa(z.Bias),
+		"StandardBias":    strconv.Itoa(z.StandardBias),
+		"DaylightBias":    strconv.Itoa(z.DaylightBias),
+	}
+	if z.StandardStart != nil && z.DaylightStart != nil {
+		m["StandardStart"] = z.StandardStart.String()
+		m["DaylightStart"] = z.DaylightStart.String()
+	}
+	return m
+}
+
+// ParseHostTimezone reads a timezone back from artifact metadata written by Metadata.
+func ParseHostTimezone(m map[string]string) (HostTimezone, error) {
+	z := HostTimezone{Name: m["TimeZoneKeyName"], StandardName: m["StandardName"], DaylightName: m["DaylightName"]}
+	var err error
+	if z.Bias, err = strconv.Atoi(m["Bias"]); err != nil {
+		return z, fmt.Errorf("timezone bias: %w", err)
+	}
+	z.StandardBias, _ = strconv.Atoi(m["StandardBias"])
+	z.DaylightBias, _ = strconv.Atoi(m["DaylightBias"])
+	if s, d := strings.TrimSpace(m["StandardStart"]), strings.TrimSpace(m["DaylightStart"]); s != "" && d != "" {
+		std, err := ParseTZRule(s)
+		if err != nil {
+			return z, err
+		}
+		day, err := ParseTZRule(d)
+		if err != nil {
+			return z, err
+		}
+		z.StandardStart, z.DaylightStart = &std, &day
+	}
+	return z, nil
+}
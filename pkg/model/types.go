@@ -33,17 +33,22 @@ type Artifact struct {
 }
 
 type TimelineEvent struct {
-	ID          string            `json:"id"`
-	EventTime   time.Time         `json:"event_time"`
-	UTCOffset   int               `json:"utc_offset,omitempty"`
-	Source      string            `json:"source"`
-	Artifact    string            `json:"artifact"`
-	Action      string            `json:"action"`
-	Subject     string            `json:"subject,omitempty"`
-	Details     map[string]string `json:"details,omitempty"`
-	Confidence  string            `json:"confidence,omitempty"`
-	EvidenceRef EvidenceRef       `json:"evidence_ref"`
-	IOCHits     []string          `json:"ioc_hits,omitempty"`
+	ID            string            `json:"id"`
+	EventTime     time.Time         `json:"event_time"`               // always UTC
+	UTCOffset     int               `json:"utc_offset,omitempty"`     // host offset in minutes at EventTime, set at query time
+	TimeSemantics string            `json:"time_semantics,omitempty"` // what EventTime measures (TimeCreated, TimeKeyWrite, ...)
+	SkewSeconds   int64             `json:"skew_seconds,omitempty"`   // host clock skew removed from EventTime at query time
+	Source        string            `json:"source"`
+	Artifact      string            `json:"artifact"`
+	Action        string            `json:"action"`
+	Subject       string            `json:"subject,omitempty"`
+	Details       map[string]string `json:"details,omitempty"`
+	Confidence    string            `json:"confidence,omitempty"`
+	EvidenceRef   EvidenceRef       `json:"evidence_ref"`
+	IOCHits       []string          `json:"ioc_hits,omitempty"`
+	Attack        []AttackRef       `json:"attack,omitempty"`
+	Annotations   []Annotation      `json:"annotations,omitempty"`
+	Fingerprint   string            `json:"fingerprint,omitempty"` // see EventFingerprint
 }
 
 type Finding struct {
@@ -53,7 +58,89 @@ type Finding struct {
 	Description  string        `json:"description,omitempty"`
 	RuleID       string        `json:"rule_id,omitempty"`
 	EvidenceRefs []EvidenceRef `json:"evidence_refs,omitempty"`
+	EventIDs     []string      `json:"event_ids,omitempty"` // timeline events the finding rests on
 	IOCs         []IOCMaterial `json:"iocs,omitempty"`
+	Attack       []AttackRef   `json:"attack,omitempty"`
+	Annotations  []Annotation  `json:"annotations,omitempty"`
+	Suppression  *Suppression  `json:"suppression,omitempty"` // set when an allowlist entry matched
+}
+
+// Suppression records why an allowlist silenced or downgraded a detection.
+type Suppression struct {
+	Action           string `json:"action"` // "suppress" or "downgrade"
+	Reason           string `json:"reason"`
+	EntryID          string `json:"entry_id,omitempty"`
+	Matched          string `json:"matched,omitempty"` // the path, command line, signer or hash that matched
+	OriginalSeverity string `json:"original_severity,omitempty"`
+}
+
+// AttackRef is an ATT&CK technique and the tactics it was observed under.
+// Analyzers may set only TechniqueID; the names are filled in from the embedded ATT&CK data.
+type AttackRef struct {
+	TechniqueID string   `json:"technique_id"`
+	Technique   string   `json:"technique,omitempty"`
+	ParentID    string   `json:"parent_id,omitempty"`
+	Parent      string   `json:"parent,omitempty"`
+	TacticIDs   []string `json:"tactic_ids,omitempty"`
+	Tactics     []string `json:"tactics,omitempty"`
+}
+
+// Annotation kinds.
+const (
+	AnnotationTag      = "tag"
+	AnnotationBookmark = "bookmark"
+	AnnotationNote     = "note"
+	AnnotationVerdict  = "verdict"
+)
+
+// Verdicts an analyst can record with an AnnotationVerdict.
+const (
+	VerdictFalsePositive = "false_positive"
+	VerdictTruePositive  = "true_positive"
+	VerdictBenign        = "benign"
+)
+
+// Annotation is analyst state (a tag, bookmark, note or verdict) attached to an event or
+// finding by its ID. Annotations are append-only; Deleted marks a retraction of ID.
+type Annotation struct {
+	ID         string    `json:"id"`
+	TargetType string    `json:"target_type"` // "event" or "finding"
+	TargetID   string    `json:"target_id"`
+	Kind       string    `json:"kind"`
+	Value      string    `json:"value,omitempty"`
+	Author     string    `json:"author"`
+	CreatedAt  time.Time `json:"created_at"`
+	Deleted    bool      `json:"deleted,omitempty"`
+}
+
+// AnnotationValues returns the values of the annotations of one kind, oldest first.
+func AnnotationValues(anns []Annotation, kind string) []string {
+	var out []string
+	for _, a := range anns {
+		if a.Kind == kind {
+			out = append(out, a.Value)
+		}
+	}
+	return out
+}
+
+// Bookmarked reports whether anns contain a bookmark.
+func Bookmarked(anns []Annotation) bool {
+	for _, a := range anns {
+		if a.Kind == AnnotationBookmark {
+			return true
+		}
+	}
+	return false
+}
+
+// Verdict returns the most recent verdict in anns, or "".
+func Verdict(anns []Annotation) string {
+	v := AnnotationValues(anns, AnnotationVerdict)
+	if len(v) == 0 {
+		return ""
+	}
+	return v[len(v)-1]
 }
 
 type IOCMaterial struct {
@@ -79,3 +166,23 @@ type TimelineFilter struct {
 	Source     string
 	Level      string
 }
+
+// Host returns the machine an event was recorded on, when the parser knows it.
+func (e TimelineEvent) Host() string {
+	for _, k := range []string{"Computer", "Hostname"} {
+		if v := e.Details[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// User returns the account an event is attributed to, when the parser knows it.
+func (e TimelineEvent) User() string {
+	for _, k := range []string{"User", "TargetUserName", "SubjectUserName", "Username"} {
+		if v := e.Details[k]; v != "" && v != "-" {
+			return v
+		}
+	}
+	return ""
+}
@@ -56,12 +56,13 @@ func (p *LNKStubParser) Parse(ctx context.Context, in pluginsdk.ParseRequest) (*
 	}
 
 	event := model.TimelineEvent{
-		ID:        "lnk-stub-event-1",
-		EventTime: now,
-		Source:    "lnk",
-		Artifact:  artifact.ID,
-		Action:    "execute",
-		Details:   map[string]string{"note": "stub event", "path": in.EvidencePath},
+		ID:            "lnk-stub-event-1",
+		EventTime:     now,
+		TimeSemantics: model.TimeCollected,
+		Source:        "lnk",
+		Artifact:      artifact.ID,
+		Action:        "execute",
+		Details:       map[string]string{"note": "stub event", "path": in.EvidencePath},
 		EvidenceRef: model.EvidenceRef{
 			SourcePath: in.EvidencePath,
 			SHA256:     "stub",
@@ -17,6 +17,27 @@ type Manifest struct {
 	Output      IODecl   `json:"output" yaml:"output"`
 	Permissions []string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
 	Entry       string   `json:"entry" yaml:"entry"`
+	// FieldMappings extend the ECS/OCSF normalization table for the events this plugin emits.
+	FieldMappings []FieldMapping `json:"field_mappings,omitempty" yaml:"field_mappings,omitempty"`
+}
+
+// FieldMapping maps one kind of timeline event onto Elastic ECS and OCSF.
+//
+// Source is required; Artifact, Channel and EventIDs narrow the match, and the most specific
+// mapping wins. ECS and OCSF map a dotted target field to candidate Details keys, tried in
+// order. "$subject" and "$action" name the event's Subject and Action, and "Key[Name]" reads
+// Name out of a "Name=value,..." list such as Sysmon's Hashes.
+type FieldMapping struct {
+	Source       string              `json:"source" yaml:"source"`
+	Artifact     string              `json:"artifact,omitempty" yaml:"artifact,omitempty"`
+	Channel      string              `json:"channel,omitempty" yaml:"channel,omitempty"`
+	EventIDs     []string            `json:"event_ids,omitempty" yaml:"event_ids,omitempty"`
+	ECSCategory  []string            `json:"ecs_category,omitempty" yaml:"ecs_category,omitempty"`
+	ECSType      []string            `json:"ecs_type,omitempty" yaml:"ecs_type,omitempty"`
+	ECS          map[string][]string `json:"ecs,omitempty" yaml:"ecs,omitempty"`
+	OCSFClass    int                 `json:"ocsf_class_uid,omitempty" yaml:"ocsf_class_uid,omitempty"`
+	OCSFActivity int                 `json:"ocsf_activity_id,omitempty" yaml:"ocsf_activity_id,omitempty"`
+	OCSF         map[string][]string `json:"ocsf,omitempty" yaml:"ocsf,omitempty"`
 }
 
 type IODecl struct {
@@ -39,6 +60,17 @@ type ParseRequest struct {
 type ParseResponse struct {
 	Artifacts []model.Artifact      `json:"artifacts"`
 	Events    []model.TimelineEvent `json:"events,omitempty"`
+	// Findings lets parsers report anomalies they can judge from a single artifact
+	// (e.g. a service running from a user-writable path) without a separate analyzer pass.
+	// EventIDs of a finding name events the parser flagged with an _Alert detail, by the ID
+	// the parser gave them; the pipeline rewrites them to the IDs the events are stored under.
+	Findings []model.Finding `json:"findings,omitempty"`
+	// Dropped counts events the parser discarded on purpose (noise reduction), by rule, so
+	// the pipeline can report them.
+	Dropped map[string]int `json:"dropped,omitempty"`
+	// Truncated counts records the parser left unread because a limit (max_events, a scan
+	// limit) stopped it, by limit. These are budget drops, unlike the rules of Dropped.
+	Truncated map[string]int `json:"truncated,omitempty"`
 }
 
 type AnalyzeRequest struct {